@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/5w1tchy/books-api/internal/storage/blob"
+	storage "github.com/5w1tchy/books-api/internal/storage/s3"
+)
+
+// newBlobStore builds the object store selected by STORAGE_BACKEND:
+//
+//	r2 (default)  Cloudflare R2 / S3 via AWS_* variables
+//	fs            local directory STORAGE_FS_ROOT; presigned URLs are served
+//	              by the API itself under /blobs/ and signed with STORAGE_FS_SECRET
+//	memory        in-process, lost on restart (development and tests only)
+func newBlobStore(ctx context.Context) (blob.BlobStore, error) {
+	production := strings.EqualFold(os.Getenv("APP_ENV"), "production")
+
+	switch backend := strings.ToLower(os.Getenv("STORAGE_BACKEND")); backend {
+	case "", "r2", "s3":
+		return storage.NewR2Client(ctx)
+
+	case "fs":
+		root := os.Getenv("STORAGE_FS_ROOT")
+		if root == "" {
+			root = "./data/blobs"
+		}
+		secret := []byte(os.Getenv("STORAGE_FS_SECRET"))
+		if len(secret) == 0 {
+			if production {
+				return nil, fmt.Errorf("STORAGE_FS_SECRET is required when STORAGE_BACKEND=fs in production")
+			}
+			// Dev convenience: URLs stop working after a restart.
+			secret = make([]byte, 32)
+			_, _ = rand.Read(secret)
+			log.Printf("WARN: STORAGE_FS_SECRET not set; using a random key for presigned URLs")
+		}
+		return blob.NewFS(root, strings.TrimSuffix(os.Getenv("STORAGE_FS_BASE_URL"), "/"), secret)
+
+	case "memory":
+		if production {
+			return nil, fmt.Errorf("STORAGE_BACKEND=memory is not allowed in production")
+		}
+		log.Printf("WARN: STORAGE_BACKEND=memory; uploaded files are lost on restart")
+		return blob.NewMemory(), nil
+
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_BACKEND %q (want r2, fs or memory)", backend)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/5w1tchy/books-api/internal/storage/blob"
+	storebooks "github.com/5w1tchy/books-api/internal/store/books"
+)
+
+// runCoverBackfill implements `api cover-backfill [-limit N] [-variants] [-dry-run] [-json]`.
+// It fills in the placeholder of covers uploaded before it was computed.
+func runCoverBackfill(db *sql.DB, blobs blob.BlobStore, args []string) int {
+	fs := flag.NewFlagSet("cover-backfill", flag.ContinueOnError)
+	limit := fs.Int("limit", 0, "process at most this many books (0 = all)")
+	variants := fs.Bool("variants", false, "also render variants for covers that have none")
+	dryRun := fs.Bool("dry-run", false, "decode covers but don't write anything")
+	asJSON := fs.Bool("json", false, "print the result as JSON")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Hour)
+	defer cancel()
+
+	res, err := storebooks.BackfillCovers(ctx, db, blobs, storebooks.BackfillCoversOptions{
+		Limit:    *limit,
+		Variants: *variants,
+		DryRun:   *dryRun,
+	})
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(res)
+	} else {
+		verb := "updated"
+		if *dryRun {
+			verb = "would update"
+		}
+		fmt.Printf("scanned %d covers, %s %d, %d variant objects\n", res.Scanned, verb, res.Updated, res.Variants)
+		for id, e := range res.Failed {
+			fmt.Fprintf(os.Stderr, "failed %s: %s\n", id, e)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "backfill:", err)
+		return 1
+	}
+	if len(res.Failed) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
+	"database/sql"
 	"fmt"
 	"log"
 	"net/http"
@@ -10,8 +12,12 @@ import (
 
 	mw "github.com/5w1tchy/books-api/internal/api/middlewares"
 	"github.com/5w1tchy/books-api/internal/api/router"
+	"github.com/5w1tchy/books-api/internal/mail"
 	"github.com/5w1tchy/books-api/internal/metrics/viewqueue"
 	"github.com/5w1tchy/books-api/internal/repository/sqlconnect"
+	"github.com/5w1tchy/books-api/internal/storage/blob"
+	"github.com/5w1tchy/books-api/internal/uploads/pending"
+	"github.com/5w1tchy/books-api/internal/uploads/tus"
 	validatePkg "github.com/5w1tchy/books-api/internal/validate"
 	"github.com/5w1tchy/books-api/pkg/utils"
 	"github.com/joho/godotenv"
@@ -32,6 +38,28 @@ func main() {
 	}
 	defer db.Close()
 
+	// Object storage is built once and injected everywhere
+	blobs, err := newBlobStore(context.Background())
+	if err != nil {
+		log.Fatalf("failed to init object storage: %v", err)
+	}
+
+	// One-off maintenance subcommands run instead of the server
+	if len(os.Args) > 1 {
+		var run func(*sql.DB, blob.BlobStore, []string) int
+		switch os.Args[1] {
+		case "storage-gc":
+			run = runStorageGC
+		case "cover-backfill":
+			run = runCoverBackfill
+		}
+		if run != nil {
+			code := run(db, blobs, os.Args[2:])
+			db.Close()
+			os.Exit(code)
+		}
+	}
+
 	// --- start bounded view queue (2 workers, buffer 10k) ---
 	viewqueue.Start(db, 10000, 2)
 	defer viewqueue.Shutdown()
@@ -75,17 +103,36 @@ func main() {
 	if err := validatePkg.PingRedis(rdb, 2*time.Second); err != nil {
 		log.Fatalf("Redis connection failed: %v", err)
 	}
+
+	// Abort expired resumable uploads (tus) and their staged parts
+	stopTus := tus.StartJanitor(rdb, blobs, 15*time.Minute)
+	defer stopTus()
+	// ...and presigned uploads that were never completed
+	stopPending := pending.New(rdb, blobs).StartJanitor(15 * time.Minute)
+	defer stopPending()
 	for _, w := range validatePkg.HardeningWarnings(os.Getenv("APP_ENV")) {
 		log.Printf("WARN: %s", w)
 	}
 
+	// -------- Transactional email (queued, retried in the background) ----------
+	mailer, err := mail.FromEnv()
+	if err != nil {
+		log.Fatalf("failed to init mailer: %v", err)
+	}
+	outbox := mail.NewQueue(mailer, mail.QueueOptions{})
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = outbox.Close(ctx)
+	}()
+
 	// -------- Rate limiting: token-bucket only ----------
 	tb := mw.NewRedisTokenBucket(rdb, 5, 20, mw.PerIPKey("tb"))
 
 	hppOptions := mw.DefaultHPPOptions()
 
 	secureMux := utils.ApplyMiddleware(
-		router.Router(db, rdb),
+		router.Router(db, rdb, blobs, outbox),
 		mw.Recovery, // Catch panics first
 		mw.RequestID,
 		mw.Cors,
@@ -97,6 +144,17 @@ func main() {
 		mw.SecurityHeaders,
 	)
 
+	// Presigned URLs of the filesystem store point back at the API. They are
+	// self-authenticating and can be large, so they bypass the JSON middleware
+	// (body size limit, rate limit, compression).
+	handler := secureMux
+	if files, ok := blobs.(http.Handler); ok {
+		root := http.NewServeMux()
+		root.Handle(blob.Prefix, utils.ApplyMiddleware(files, mw.Recovery, mw.RequestID, mw.Cors))
+		root.Handle("/", secureMux)
+		handler = root
+	}
+
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "3000"
@@ -104,7 +162,7 @@ func main() {
 
 	server := &http.Server{
 		Addr:         ":" + port,
-		Handler:      secureMux,
+		Handler:      handler,
 		ReadTimeout:  15 * time.Second, // Time to read request headers + body
 		WriteTimeout: 15 * time.Second, // Time to write response
 		IdleTimeout:  60 * time.Second, // Keep-alive timeout
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/5w1tchy/books-api/internal/storage/blob"
+	"github.com/5w1tchy/books-api/internal/storage/gc"
+)
+
+// runStorageGC implements `api storage-gc [-grace 72h] [-json] [-delete [-yes]]`.
+// Without -delete it only reports.
+func runStorageGC(db *sql.DB, blobs blob.BlobStore, args []string) int {
+	fs := flag.NewFlagSet("storage-gc", flag.ContinueOnError)
+	grace := fs.Duration("grace", gc.DefaultGrace, "only delete orphans older than this")
+	del := fs.Bool("delete", false, "delete orphans older than the grace period")
+	yes := fs.Bool("yes", false, "skip the confirmation prompt")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *grace < gc.MinGrace {
+		fmt.Fprintf(os.Stderr, "grace must be at least %s\n", gc.MinGrace)
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	rep, err := gc.Scan(ctx, db, blobs, *grace, time.Now())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "scan:", err)
+		return 1
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(rep)
+	} else {
+		printGCReport(rep)
+	}
+
+	keys := rep.DeletableKeys()
+	if !*del || len(keys) == 0 {
+		return 0
+	}
+	if !*yes {
+		fmt.Printf("Delete %d objects? Type \"delete\" to confirm: ", len(keys))
+		line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if strings.TrimSpace(line) != "delete" {
+			fmt.Println("aborted")
+			return 1
+		}
+	}
+
+	deleted, failed := gc.Delete(ctx, blobs, keys)
+	fmt.Printf("deleted %d objects\n", len(deleted))
+	for k, e := range failed {
+		fmt.Fprintf(os.Stderr, "failed %s: %s\n", k, e)
+	}
+	if len(failed) > 0 {
+		return 1
+	}
+	return 0
+}
+
+func printGCReport(rep *gc.Report) {
+	fmt.Printf("scanned %d objects against %d references (grace %s)\n", rep.Objects, rep.References, rep.Grace)
+	fmt.Printf("orphans: %d (%d bytes), %d past grace\n", len(rep.Orphans), rep.OrphanBytes, rep.Deletable)
+	for _, o := range rep.Orphans {
+		mark := " "
+		if o.Deletable {
+			mark = "*"
+		}
+		fmt.Printf("  %s %-8s %10d  %s  %s\n", mark, o.Kind, o.Size, o.LastModified.Format(time.RFC3339), o.Key)
+	}
+	fmt.Printf("dangling references: %d\n", len(rep.Dangling))
+	for _, d := range rep.Dangling {
+		fmt.Printf("    %s.%s -> %s (%s)\n", d.Slug, d.Field, d.Key, d.BookID)
+	}
+}
@@ -0,0 +1,150 @@
+package admin
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/5w1tchy/books-api/internal/security/apikey"
+)
+
+// GET /admin/api-keys
+func (h *Handler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.Sto.ListAPIKeys(r.Context())
+	if err != nil {
+		writeError(w, 500, "list_failed")
+		return
+	}
+	writeJSON(w, 200, map[string]any{"data": keys})
+}
+
+// GET /admin/api-keys/{id}
+func (h *Handler) GetAPIKey(w http.ResponseWriter, r *http.Request) {
+	key, err := h.Sto.GetAPIKey(r.Context(), pathID(r))
+	if err != nil || key == nil {
+		writeError(w, 404, "not_found")
+		return
+	}
+	writeJSON(w, 200, key)
+}
+
+// POST /admin/api-keys
+// The key is in this response only; it can't be recovered later.
+func (h *Handler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	adminID := getAdminID(r.Context())
+
+	var body CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, 400, "invalid_body")
+		return
+	}
+	body.Name = strings.TrimSpace(body.Name)
+	if body.Name == "" || len(body.Name) > 100 {
+		writeError(w, 400, "invalid_name")
+		return
+	}
+	if !apikey.ValidScopes(body.Scopes) {
+		writeError(w, 400, "invalid_scopes")
+		return
+	}
+	if body.ExpiresAt != nil && !body.ExpiresAt.After(time.Now()) {
+		writeError(w, 400, "invalid_expiry")
+		return
+	}
+
+	if !h.checkRateLimit(r.Context(), w, "apikey_create", adminID, 20, time.Hour) {
+		return
+	}
+
+	secret, hash, prefix, err := apikey.Generate()
+	if err != nil {
+		writeError(w, 500, "create_failed")
+		return
+	}
+	key, err := h.Sto.CreateAPIKey(r.Context(), APIKeyRow{
+		Name:      body.Name,
+		Prefix:    prefix,
+		Scopes:    body.Scopes,
+		CreatedBy: adminID,
+		ExpiresAt: body.ExpiresAt,
+	}, hash)
+	if err != nil {
+		writeError(w, 500, "create_failed")
+		return
+	}
+
+	_ = h.Sto.InsertAudit(r.Context(), adminID, "apikey.create", key.ID, map[string]any{"name": key.Name, "scopes": key.Scopes})
+	writeJSON(w, 201, CreateAPIKeyResponse{APIKeyRow: *key, Key: secret})
+}
+
+// PATCH /admin/api-keys/{id}
+func (h *Handler) UpdateAPIKey(w http.ResponseWriter, r *http.Request) {
+	adminID := getAdminID(r.Context())
+	id := pathID(r)
+
+	var body UpdateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, 400, "invalid_body")
+		return
+	}
+	if body.Name != nil {
+		name := strings.TrimSpace(*body.Name)
+		if name == "" || len(name) > 100 {
+			writeError(w, 400, "invalid_name")
+			return
+		}
+		body.Name = &name
+	}
+	if body.Scopes != nil && !apikey.ValidScopes(body.Scopes) {
+		writeError(w, 400, "invalid_scopes")
+		return
+	}
+	if body.ExpiresAt != nil && !body.ExpiresAt.After(time.Now()) {
+		writeError(w, 400, "invalid_expiry")
+		return
+	}
+
+	if !h.checkRateLimit(r.Context(), w, "apikey_update", adminID, 50, time.Hour) {
+		return
+	}
+
+	key, err := h.Sto.UpdateAPIKey(r.Context(), id, body)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, 404, "not_found")
+		return
+	}
+	if err != nil {
+		writeError(w, 500, "update_failed")
+		return
+	}
+
+	_ = h.Sto.InsertAudit(r.Context(), adminID, "apikey.update", id, body)
+	writeJSON(w, 200, key)
+}
+
+// DELETE /admin/api-keys/{id}
+// Revokes the key; the row stays for the audit trail.
+func (h *Handler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	adminID := getAdminID(r.Context())
+	id := pathID(r)
+
+	if !h.checkRateLimit(r.Context(), w, "apikey_revoke", adminID, 50, time.Hour) {
+		return
+	}
+
+	err := h.Sto.RevokeAPIKey(r.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, 404, "not_found")
+		return
+	}
+	if err != nil {
+		writeError(w, 500, "revoke_failed")
+		return
+	}
+
+	_ = h.Sto.InsertAudit(r.Context(), adminID, "apikey.revoke", id, nil)
+	writeJSON(w, 204, nil)
+}
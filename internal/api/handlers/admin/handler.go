@@ -3,19 +3,29 @@ package admin
 import (
 	"database/sql"
 
+	"github.com/5w1tchy/books-api/internal/storage/blob"
 	"github.com/redis/go-redis/v9"
 )
 
 type Handler struct {
-	DB  *sql.DB
-	RDB *redis.Client
-	Sto Store
+	DB    *sql.DB
+	RDB   *redis.Client
+	Sto   Store
+	Blobs blob.BlobStore
+
+	// Verify sends verification emails; optional, set by the router.
+	Verify VerificationSender
+	// Sessions manages users' refresh sessions; optional, set by the router.
+	Sessions SessionManager
+	// Locks manages login lockouts; optional, set by the router.
+	Locks LockManager
 }
 
-func NewHandler(db *sql.DB, rdb *redis.Client, store Store) *Handler {
+func NewHandler(db *sql.DB, rdb *redis.Client, store Store, blobs blob.BlobStore) *Handler {
 	return &Handler{
-		DB:  db,
-		RDB: rdb,
-		Sto: store,
+		DB:    db,
+		RDB:   rdb,
+		Sto:   store,
+		Blobs: blobs,
 	}
 }
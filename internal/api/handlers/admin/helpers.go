@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/5w1tchy/books-api/internal/api/middlewares"
+	"github.com/5w1tchy/books-api/internal/security/rbac"
 )
 
 // ===== HTTP Helpers =====
@@ -71,8 +72,10 @@ func (h *Handler) checkRateLimit(ctx context.Context, w http.ResponseWriter, act
 
 // ===== Validation =====
 
+// validateRole checks the shape of a role name; SetRole then checks that
+// the role is defined.
 func validateRole(role string) bool {
-	return role == "admin" || role == "user"
+	return rbac.ValidRoleName(role)
 }
 
 func validatePagination(page, size int) (int, int) {
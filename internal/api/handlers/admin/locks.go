@@ -0,0 +1,74 @@
+package admin
+
+import (
+	"net/http"
+	"time"
+)
+
+// GET /admin/locks
+// Accounts locked after too many failed sign-ins, including emails that
+// have no account.
+func (h *Handler) ListLocks(w http.ResponseWriter, r *http.Request) {
+	if h.Locks == nil {
+		writeError(w, 503, "locks_unavailable")
+		return
+	}
+	locks, err := h.Locks.ListLocks(r.Context())
+	if err != nil {
+		writeError(w, 500, "list_locks_failed")
+		return
+	}
+	writeJSON(w, 200, map[string]any{"data": locks})
+}
+
+// GET /admin/users/{id}/lock
+func (h *Handler) GetLock(w http.ResponseWriter, r *http.Request) {
+	if h.Locks == nil {
+		writeError(w, 503, "locks_unavailable")
+		return
+	}
+	user, err := h.Sto.GetUser(r.Context(), pathID(r))
+	if err != nil || user == nil {
+		writeError(w, 404, "not_found")
+		return
+	}
+	lock, err := h.Locks.LockStatus(r.Context(), user.Email)
+	if err != nil {
+		writeError(w, 500, "lock_status_failed")
+		return
+	}
+	lock.UserID = user.ID
+	writeJSON(w, 200, lock)
+}
+
+// DELETE /admin/users/{id}/lock
+// Unlocks the account and resets its failed sign-in count.
+func (h *Handler) ClearLock(w http.ResponseWriter, r *http.Request) {
+	adminID := getAdminID(r.Context())
+	userID := pathID(r)
+
+	if !h.canModerate(w, r, userID) {
+		return
+	}
+	if !h.checkRateLimit(r.Context(), w, "lock_clear", adminID, 100, time.Hour) {
+		return
+	}
+	if h.Locks == nil {
+		writeError(w, 503, "locks_unavailable")
+		return
+	}
+	user, err := h.Sto.GetUser(r.Context(), userID)
+	if err != nil || user == nil {
+		writeError(w, 404, "not_found")
+		return
+	}
+
+	wasLocked, err := h.Locks.ClearLock(r.Context(), user.Email)
+	if err != nil {
+		writeError(w, 500, "clear_lock_failed")
+		return
+	}
+
+	_ = h.Sto.InsertAudit(r.Context(), adminID, "user.lock_clear", userID, map[string]any{"was_locked": wasLocked})
+	writeJSON(w, 204, nil)
+}
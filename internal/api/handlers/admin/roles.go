@@ -0,0 +1,164 @@
+package admin
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/5w1tchy/books-api/internal/api/middlewares"
+	"github.com/5w1tchy/books-api/internal/security/rbac"
+)
+
+// GET /admin/permissions
+func (h *Handler) ListPermissions(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, 200, map[string]any{"data": rbac.Permissions})
+}
+
+// GET /admin/roles
+func (h *Handler) ListRoles(w http.ResponseWriter, r *http.Request) {
+	roles, err := h.Sto.ListRoles(r.Context())
+	if err != nil {
+		writeError(w, 500, "list_failed")
+		return
+	}
+	writeJSON(w, 200, map[string]any{"data": roles})
+}
+
+// GET /admin/roles/{name}
+func (h *Handler) GetRole(w http.ResponseWriter, r *http.Request) {
+	role, err := h.Sto.GetRole(r.Context(), r.PathValue("name"))
+	if err != nil || role == nil {
+		writeError(w, 404, "not_found")
+		return
+	}
+	writeJSON(w, 200, role)
+}
+
+// POST /admin/roles
+func (h *Handler) CreateRole(w http.ResponseWriter, r *http.Request) {
+	adminID := getAdminID(r.Context())
+
+	var body CreateRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, 400, "invalid_body")
+		return
+	}
+	body.Name = strings.TrimSpace(body.Name)
+	if !rbac.ValidRoleName(body.Name) {
+		writeError(w, 400, "invalid_name")
+		return
+	}
+	if !rbac.ValidPermissions(body.Permissions) {
+		writeError(w, 400, "invalid_permissions")
+		return
+	}
+	if !canGrant(r, body.Permissions) {
+		writeError(w, 403, "permission_escalation")
+		return
+	}
+
+	if !h.checkRateLimit(r.Context(), w, "role_create", adminID, 20, time.Hour) {
+		return
+	}
+
+	role, err := h.Sto.CreateRole(r.Context(), body)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, 409, "role_exists")
+		return
+	}
+	if err != nil {
+		writeError(w, 500, "create_failed")
+		return
+	}
+
+	_ = h.Sto.InsertAudit(r.Context(), adminID, "role.create", "", body)
+	writeJSON(w, 201, role)
+}
+
+// PATCH /admin/roles/{name}
+// The admin role is fixed so that someone can always manage roles.
+func (h *Handler) UpdateRole(w http.ResponseWriter, r *http.Request) {
+	adminID := getAdminID(r.Context())
+	name := r.PathValue("name")
+
+	if name == rbac.RoleAdmin {
+		writeError(w, 400, "role_immutable")
+		return
+	}
+
+	var body UpdateRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, 400, "invalid_body")
+		return
+	}
+	if !rbac.ValidPermissions(body.Permissions) {
+		writeError(w, 400, "invalid_permissions")
+		return
+	}
+
+	current, err := h.Sto.GetRole(r.Context(), name)
+	if err != nil || current == nil {
+		writeError(w, 404, "not_found")
+		return
+	}
+	// Editing a role both takes away its old permissions and grants the new ones
+	if !canGrant(r, current.Permissions) || (body.Permissions != nil && !canGrant(r, body.Permissions)) {
+		writeError(w, 403, "permission_escalation")
+		return
+	}
+
+	if !h.checkRateLimit(r.Context(), w, "role_update", adminID, 50, time.Hour) {
+		return
+	}
+
+	role, err := h.Sto.UpdateRole(r.Context(), name, body)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, 404, "not_found")
+		return
+	}
+	if err != nil {
+		writeError(w, 500, "update_failed")
+		return
+	}
+
+	_ = h.Sto.InsertAudit(r.Context(), adminID, "role.update", "", map[string]any{"name": name, "change": body})
+	writeJSON(w, 200, role)
+}
+
+// DELETE /admin/roles/{name}
+func (h *Handler) DeleteRole(w http.ResponseWriter, r *http.Request) {
+	adminID := getAdminID(r.Context())
+	name := r.PathValue("name")
+
+	if !h.checkRateLimit(r.Context(), w, "role_delete", adminID, 50, time.Hour) {
+		return
+	}
+
+	err := h.Sto.DeleteRole(r.Context(), name)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		writeError(w, 404, "not_found")
+		return
+	case errors.Is(err, ErrRoleBuiltin):
+		writeError(w, 400, "role_builtin")
+		return
+	case errors.Is(err, ErrRoleInUse):
+		writeError(w, 409, "role_in_use")
+		return
+	case err != nil:
+		writeError(w, 500, "delete_failed")
+		return
+	}
+
+	_ = h.Sto.InsertAudit(r.Context(), adminID, "role.delete", "", map[string]string{"name": name})
+	writeJSON(w, 204, nil)
+}
+
+// canGrant reports whether the caller's own role covers perms, so staff
+// can't hand out (or take away) more than they have.
+func canGrant(r *http.Request, perms []string) bool {
+	return rbac.Covers(middlewares.PermissionsFrom(r.Context()), perms)
+}
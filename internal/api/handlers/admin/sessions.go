@@ -0,0 +1,56 @@
+package admin
+
+import (
+	"net/http"
+	"time"
+)
+
+// GET /admin/users/{id}/sessions
+func (h *Handler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	userID := pathID(r)
+
+	if !h.canModerate(w, r, userID) {
+		return
+	}
+	if h.Sessions == nil {
+		writeError(w, 503, "sessions_unavailable")
+		return
+	}
+	sessions, err := h.Sessions.ListSessions(r.Context(), userID)
+	if err != nil {
+		writeError(w, 500, "list_sessions_failed")
+		return
+	}
+	writeJSON(w, 200, map[string]any{"sessions": sessions})
+}
+
+// DELETE /admin/users/{id}/sessions/{sid}
+func (h *Handler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	adminID := getAdminID(r.Context())
+	userID := pathID(r)
+	sid := r.PathValue("sid")
+
+	if !h.canModerate(w, r, userID) {
+		return
+	}
+	if !h.checkRateLimit(r.Context(), w, "session_revoke", adminID, 100, time.Hour) {
+		return
+	}
+	if h.Sessions == nil {
+		writeError(w, 503, "sessions_unavailable")
+		return
+	}
+
+	found, err := h.Sessions.RevokeSession(r.Context(), userID, sid)
+	if err != nil {
+		writeError(w, 500, "revoke_session_failed")
+		return
+	}
+	if !found {
+		writeError(w, 404, "session_not_found")
+		return
+	}
+
+	_ = h.Sto.InsertAudit(r.Context(), adminID, "user.session_revoke", userID, map[string]any{"session_id": sid})
+	writeJSON(w, 204, nil)
+}
@@ -0,0 +1,81 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/5w1tchy/books-api/internal/storage/gc"
+)
+
+type storageGCRequest struct {
+	Grace   string `json:"grace"`
+	Confirm string `json:"confirm"`
+}
+
+// GET /admin/storage/gc?grace=72h
+// Dry run: reports orphaned objects and dangling references.
+func (h *Handler) StorageGCReport(w http.ResponseWriter, r *http.Request) {
+	grace, ok := parseGrace(w, r.URL.Query().Get("grace"))
+	if !ok {
+		return
+	}
+	rep, err := gc.Scan(r.Context(), h.DB, h.Blobs, grace, time.Now())
+	if err != nil {
+		writeError(w, 500, "scan_failed")
+		return
+	}
+	writeJSON(w, 200, rep)
+}
+
+// POST /admin/storage/gc
+// Deletes deletable orphans. The bucket is rescanned and the request's
+// confirm token must match, so only a reviewed set is ever deleted.
+func (h *Handler) StorageGCDelete(w http.ResponseWriter, r *http.Request) {
+	adminID := getAdminID(r.Context())
+
+	var body storageGCRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Confirm == "" {
+		writeError(w, 400, "confirm_token_required")
+		return
+	}
+	grace, ok := parseGrace(w, body.Grace)
+	if !ok {
+		return
+	}
+	if !h.checkRateLimit(r.Context(), w, "storage_gc", adminID, 10, time.Hour) {
+		return
+	}
+
+	rep, err := gc.Scan(r.Context(), h.DB, h.Blobs, grace, time.Now())
+	if err != nil {
+		writeError(w, 500, "scan_failed")
+		return
+	}
+	if rep.Token != body.Confirm {
+		writeJSON(w, 409, map[string]any{"error": "confirm_token_mismatch", "report": rep})
+		return
+	}
+
+	deleted, failed := gc.Delete(r.Context(), h.Blobs, rep.DeletableKeys())
+	_ = h.Sto.InsertAudit(r.Context(), adminID, "storage.gc", "", map[string]any{
+		"grace": rep.Grace, "deleted": len(deleted), "failed": len(failed), "dangling": len(rep.Dangling),
+	})
+	writeJSON(w, 200, map[string]any{
+		"deleted":  deleted,
+		"failed":   failed,
+		"dangling": rep.Dangling,
+	})
+}
+
+func parseGrace(w http.ResponseWriter, s string) (time.Duration, bool) {
+	if s == "" {
+		return gc.DefaultGrace, true
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < gc.MinGrace {
+		writeError(w, 400, "invalid_grace")
+		return 0, false
+	}
+	return d, true
+}
@@ -2,7 +2,10 @@ package admin
 
 import (
 	"context"
+	"errors"
 	"time"
+
+	"github.com/5w1tchy/books-api/internal/auth"
 )
 
 // ===== DTOs =====
@@ -11,7 +14,7 @@ type UserRow struct {
 	ID            string     `json:"id"`
 	Email         string     `json:"email"`
 	Username      string     `json:"username"`
-	Role          string     `json:"role"`   // "user" | "admin"
+	Role          string     `json:"role"`   // a public.roles name
 	Status        string     `json:"status"` // "active" | "banned"
 	EmailVerified *time.Time `json:"email_verified_at"`
 	CreatedAt     time.Time  `json:"created_at"`
@@ -26,6 +29,31 @@ type AuditRow struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// APIKeyRow never carries the key itself, which is only returned once by
+// CreateAPIKey.
+type APIKeyRow struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedBy  string     `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+// RoleRow is a role and the permissions it grants (internal/security/rbac).
+type RoleRow struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Permissions []string  `json:"permissions"`
+	Builtin     bool      `json:"builtin"`
+	Users       int       `json:"users"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
 // ===== Filters =====
 
 type ListFilter struct {
@@ -53,6 +81,37 @@ type SetRoleRequest struct {
 	Role string `json:"role"`
 }
 
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type CreateRoleRequest struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+// UpdateRoleRequest changes only the fields that are present.
+type UpdateRoleRequest struct {
+	Description *string  `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+// UpdateAPIKeyRequest changes only the fields that are present.
+type UpdateAPIKeyRequest struct {
+	Name      *string    `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// CreateAPIKeyResponse is the only time the key is shown.
+type CreateAPIKeyResponse struct {
+	APIKeyRow
+	Key string `json:"key"`
+}
+
 type StatsResponse struct {
 	UsersTotal     int `json:"users_total"`
 	UsersVerified  int `json:"users_verified"`
@@ -79,4 +138,44 @@ type Store interface {
 
 	// Admins
 	AdminCount(ctx context.Context) (int, error)
+
+	// API keys
+	ListAPIKeys(ctx context.Context) ([]APIKeyRow, error)
+	GetAPIKey(ctx context.Context, id string) (*APIKeyRow, error)
+	CreateAPIKey(ctx context.Context, k APIKeyRow, hash string) (*APIKeyRow, error)
+	UpdateAPIKey(ctx context.Context, id string, req UpdateAPIKeyRequest) (*APIKeyRow, error)
+	RevokeAPIKey(ctx context.Context, id string) error
+
+	// Roles
+	ListRoles(ctx context.Context) ([]RoleRow, error)
+	GetRole(ctx context.Context, name string) (*RoleRow, error)
+	CreateRole(ctx context.Context, req CreateRoleRequest) (*RoleRow, error)
+	UpdateRole(ctx context.Context, name string, req UpdateRoleRequest) (*RoleRow, error)
+	DeleteRole(ctx context.Context, name string) error
+}
+
+// VerificationSender emails a fresh verification link (auth.VerifyDeps).
+type VerificationSender interface {
+	SendVerification(ctx context.Context, userID, locale string, enforceQuota bool) (string, error)
 }
+
+// SessionManager lists and revokes a user's signed-in sessions
+// (auth.SessionStore).
+type SessionManager interface {
+	ListSessions(ctx context.Context, userID string) ([]auth.Session, error)
+	RevokeSession(ctx context.Context, userID, sessionID string) (bool, error)
+	RevokeSessions(ctx context.Context, userID string) error
+}
+
+// LockManager views and clears per-account login lockouts (auth.Lockout).
+type LockManager interface {
+	LockStatus(ctx context.Context, email string) (auth.AccountLock, error)
+	ListLocks(ctx context.Context) ([]auth.AccountLock, error)
+	ClearLock(ctx context.Context, email string) (bool, error)
+}
+
+// Role store errors.
+var (
+	ErrRoleBuiltin = errors.New("built-in role")
+	ErrRoleInUse   = errors.New("role is assigned to users")
+)
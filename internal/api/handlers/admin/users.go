@@ -1,10 +1,16 @@
 package admin
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
+
+	"github.com/5w1tchy/books-api/internal/auth"
+	"github.com/5w1tchy/books-api/internal/mail"
+	"github.com/5w1tchy/books-api/internal/security/rbac"
 )
 
 // GET /admin/users
@@ -57,6 +63,9 @@ func (h *Handler) BanUser(w http.ResponseWriter, r *http.Request) {
 		writeError(w, 400, "cannot_self_ban")
 		return
 	}
+	if !h.canModerate(w, r, userID) {
+		return
+	}
 
 	if !h.checkRateLimit(r.Context(), w, "ban", adminID, 20, time.Hour) {
 		return
@@ -76,6 +85,10 @@ func (h *Handler) UnbanUser(w http.ResponseWriter, r *http.Request) {
 	adminID := getAdminID(r.Context())
 	userID := pathID(r)
 
+	if !h.canModerate(w, r, userID) {
+		return
+	}
+
 	if !h.checkRateLimit(r.Context(), w, "unban", adminID, 20, time.Hour) {
 		return
 	}
@@ -89,6 +102,27 @@ func (h *Handler) UnbanUser(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, 204, nil)
 }
 
+// canModerate reports whether the caller's role covers everything the
+// target's role grants, so a moderator can't ban, sign out or unlock an
+// admin. It writes the error response when it returns false.
+func (h *Handler) canModerate(w http.ResponseWriter, r *http.Request, userID string) bool {
+	target, err := h.Sto.GetUser(r.Context(), userID)
+	if err != nil || target == nil {
+		writeError(w, 404, "not_found")
+		return false
+	}
+	role, err := h.Sto.GetRole(r.Context(), target.Role)
+	if err != nil {
+		writeError(w, 500, "role_lookup_failed")
+		return false
+	}
+	if !canGrant(r, role.Permissions) {
+		writeError(w, 403, "permission_escalation")
+		return false
+	}
+	return true
+}
+
 // POST /admin/users/{id}/role
 func (h *Handler) SetRole(w http.ResponseWriter, r *http.Request) {
 	adminID := getAdminID(r.Context())
@@ -99,9 +133,33 @@ func (h *Handler) SetRole(w http.ResponseWriter, r *http.Request) {
 		writeError(w, 400, "invalid_role")
 		return
 	}
+	role, err := h.Sto.GetRole(r.Context(), body.Role)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, 400, "invalid_role")
+		return
+	}
+	if err != nil {
+		writeError(w, 500, "role_lookup_failed")
+		return
+	}
+
+	// Only hand out, or take away, a role you could have granted yourself
+	target, err := h.Sto.GetUser(r.Context(), userID)
+	if err != nil || target == nil {
+		writeError(w, 404, "not_found")
+		return
+	}
+	if !canGrant(r, role.Permissions) {
+		writeError(w, 403, "permission_escalation")
+		return
+	}
+	if current, err := h.Sto.GetRole(r.Context(), target.Role); err == nil && !canGrant(r, current.Permissions) {
+		writeError(w, 403, "permission_escalation")
+		return
+	}
 
 	// Prevent demoting yourself if you're the last admin
-	if adminID == userID && body.Role != "admin" {
+	if adminID == userID && body.Role != rbac.RoleAdmin {
 		count, err := h.Sto.AdminCount(r.Context())
 		if err != nil {
 			writeError(w, 500, "check_admins_failed")
@@ -131,6 +189,9 @@ func (h *Handler) LogoutAll(w http.ResponseWriter, r *http.Request) {
 	adminID := getAdminID(r.Context())
 	userID := pathID(r)
 
+	if !h.canModerate(w, r, userID) {
+		return
+	}
 	if !h.checkRateLimit(r.Context(), w, "logoutall", adminID, 50, time.Hour) {
 		return
 	}
@@ -139,6 +200,9 @@ func (h *Handler) LogoutAll(w http.ResponseWriter, r *http.Request) {
 		writeError(w, 500, "logout_all_failed")
 		return
 	}
+	if h.Sessions != nil {
+		_ = h.Sessions.RevokeSessions(r.Context(), userID) // already dead via token_version; this frees Redis
+	}
 
 	_ = h.Sto.InsertAudit(r.Context(), adminID, "user.logout_all", userID, nil)
 	writeJSON(w, 204, nil)
@@ -153,7 +217,29 @@ func (h *Handler) ResendVerification(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Hook to your mailer (no-op here, but we audit the attempt)
-	_ = h.Sto.InsertAudit(r.Context(), adminID, "user.resend_verification", userID, nil)
+	if h.Verify == nil {
+		writeError(w, 503, "mailer_unavailable")
+		return
+	}
+
+	// The user's quota doesn't apply; the admin rate limit above does
+	locale := mail.DefaultLocale
+	if l := r.URL.Query().Get("locale"); l != "" {
+		locale = mail.Locale(l)
+	}
+	_, err := h.Verify.SendVerification(r.Context(), userID, locale, false)
+	switch {
+	case errors.Is(err, auth.ErrAlreadyVerified):
+		writeError(w, 409, "already_verified")
+		return
+	case errors.Is(err, sql.ErrNoRows):
+		writeError(w, 404, "not_found")
+		return
+	case err != nil:
+		writeError(w, 500, "send_failed")
+		return
+	}
+
+	_ = h.Sto.InsertAudit(r.Context(), adminID, "user.resend_verification", userID, map[string]string{"locale": locale})
 	writeJSON(w, 204, nil)
 }
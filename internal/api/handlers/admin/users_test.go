@@ -0,0 +1,119 @@
+package admin
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/5w1tchy/books-api/internal/api/middlewares"
+	"github.com/5w1tchy/books-api/internal/security/rbac"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// fakeStore is an in-memory Store covering users and roles; other methods
+// panic via the nil embedded interface.
+type fakeStore struct {
+	Store
+	users  map[string]*UserRow
+	roles  map[string]*RoleRow
+	status map[string]string
+}
+
+func (f *fakeStore) GetUser(ctx context.Context, id string) (*UserRow, error) {
+	if u, ok := f.users[id]; ok {
+		return u, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeStore) GetRole(ctx context.Context, name string) (*RoleRow, error) {
+	if r, ok := f.roles[name]; ok {
+		return r, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeStore) SetUserStatus(ctx context.Context, id, status string) error {
+	f.status[id] = status
+	return nil
+}
+
+func (f *fakeStore) InsertAudit(ctx context.Context, adminID, action, targetID string, meta any) error {
+	return nil
+}
+
+// moderatorPerms mirrors the seeded moderator role.
+var moderatorPerms = []string{rbac.UsersRead, rbac.UsersBan, rbac.UsersManage, rbac.AuditRead, rbac.StatsRead}
+
+func newBanHandler(t *testing.T) (*Handler, *fakeStore) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+
+	sto := &fakeStore{
+		users: map[string]*UserRow{
+			"admin-1": {ID: "admin-1", Role: rbac.RoleAdmin},
+			"user-1":  {ID: "user-1", Role: rbac.RoleUser},
+		},
+		roles: map[string]*RoleRow{
+			rbac.RoleAdmin: {Name: rbac.RoleAdmin, Permissions: []string{rbac.All}},
+			"moderator":    {Name: "moderator", Permissions: moderatorPerms},
+			rbac.RoleUser:  {Name: rbac.RoleUser},
+		},
+		status: map[string]string{},
+	}
+	return &Handler{RDB: rdb, Sto: sto}, sto
+}
+
+func moderatorRequest(target string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/admin/users/"+target+"/ban", nil)
+	r.SetPathValue("id", target)
+	ctx := middlewares.WithUserID(r.Context(), "mod-1")
+	ctx = middlewares.WithPermissions(ctx, moderatorPerms)
+	return r.WithContext(ctx)
+}
+
+func TestModeratorCannotBanAdmin(t *testing.T) {
+	h, sto := newBanHandler(t)
+
+	for name, fn := range map[string]http.HandlerFunc{"ban": h.BanUser, "unban": h.UnbanUser} {
+		rec := httptest.NewRecorder()
+		fn(rec, moderatorRequest("admin-1"))
+		if rec.Code != http.StatusForbidden {
+			t.Fatalf("%s admin: %d %s, want 403", name, rec.Code, rec.Body)
+		}
+	}
+	if _, ok := sto.status["admin-1"]; ok {
+		t.Fatalf("admin status changed to %q", sto.status["admin-1"])
+	}
+
+	rec := httptest.NewRecorder()
+	h.BanUser(rec, moderatorRequest("user-1"))
+	if rec.Code != http.StatusNoContent || sto.status["user-1"] != "banned" {
+		t.Fatalf("ban user: %d, status %q", rec.Code, sto.status["user-1"])
+	}
+}
+
+func TestModeratorCannotManageAdmin(t *testing.T) {
+	h, _ := newBanHandler(t)
+
+	handlers := map[string]http.HandlerFunc{
+		"logout-all":     h.LogoutAll,
+		"list sessions":  h.ListSessions,
+		"revoke session": h.RevokeSession,
+		"clear lock":     h.ClearLock,
+	}
+	for name, fn := range handlers {
+		req := moderatorRequest("admin-1")
+		req.SetPathValue("sid", "s-1")
+		rec := httptest.NewRecorder()
+		fn(rec, req)
+		if rec.Code != http.StatusForbidden {
+			t.Fatalf("%s on admin: %d %s, want 403", name, rec.Code, rec.Body)
+		}
+	}
+}
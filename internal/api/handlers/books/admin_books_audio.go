@@ -2,21 +2,45 @@ package books
 
 import (
 	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
 	"time"
 
-	storage "github.com/5w1tchy/books-api/internal/storage/s3"
+	"github.com/5w1tchy/books-api/internal/api/middlewares"
+	"github.com/5w1tchy/books-api/internal/media/audio"
+	"github.com/5w1tchy/books-api/internal/storage/blob"
+	storebooks "github.com/5w1tchy/books-api/internal/store/books"
+	"github.com/5w1tchy/books-api/internal/uploads/pending"
+	"github.com/redis/go-redis/v9"
 )
 
+type audioUploadRequest struct {
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	SHA256      string `json:"sha256"` // base64 (as S3 expects) or hex
+}
+
 type audioUploadResponse struct {
-	UploadURL string `json:"upload_url"`
+	UploadURL string            `json:"upload_url"`
+	ObjectKey string            `json:"object_key"`
+	Headers   map[string]string `json:"headers"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+type audioCompleteRequest struct {
 	ObjectKey string `json:"object_key"`
 }
 
 // POST /admin/books/{key}/audio
-func GenerateBookAudioURLHandler(db *sql.DB) http.HandlerFunc {
+// Phase one: returns a presigned PUT URL. The book is not touched until
+// POST /admin/books/{key}/audio/complete verifies the object.
+func GenerateBookAudioURLHandler(db *sql.DB, rdb *redis.Client, blobs blob.BlobStore) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 
@@ -29,12 +53,35 @@ func GenerateBookAudioURLHandler(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		// Get existing audio_key to delete old one
-		var oldAudioKey sql.NullString
-		err := db.QueryRowContext(ctx, `
-			SELECT audio_key FROM books WHERE id::text = $1 OR slug = $1
-		`, bookKey).Scan(&oldAudioKey)
-		
+		// Optional body; older clients send none and get an mp3 URL
+		var req audioUploadRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+			w.Header().Set("Content-Type", "application/json")
+			http.Error(w, `{"error":"invalid JSON body"}`, http.StatusBadRequest)
+			return
+		}
+		if req.ContentType == "" {
+			req.ContentType = "audio/mpeg"
+		}
+		ext, ok := audioExtByType[req.ContentType]
+		if !ok {
+			w.Header().Set("Content-Type", "application/json")
+			http.Error(w, `{"error":"invalid audio type"}`, http.StatusBadRequest)
+			return
+		}
+		if req.Size < 0 || req.Size > maxAudioSize {
+			w.Header().Set("Content-Type", "application/json")
+			http.Error(w, `{"error":"audio too large (max 200MB)"}`, http.StatusRequestEntityTooLarge)
+			return
+		}
+		sum, err := normalizeSHA256(req.SHA256)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			http.Error(w, `{"error":"sha256 must be 32 bytes, base64 or hex"}`, http.StatusBadRequest)
+			return
+		}
+
+		ref, err := storebooks.GetAudioRef(ctx, db, bookKey)
 		if err == sql.ErrNoRows {
 			w.Header().Set("Content-Type", "application/json")
 			http.Error(w, `{"error":"book not found"}`, http.StatusNotFound)
@@ -42,69 +89,205 @@ func GenerateBookAudioURLHandler(db *sql.DB) http.HandlerFunc {
 		}
 		if err != nil {
 			w.Header().Set("Content-Type", "application/json")
-			http.Error(w, fmt.Sprintf(`{"error":"database error: %v"}`, err), http.StatusInternalServerError)
+			http.Error(w, `{"error":"database error"}`, http.StatusInternalServerError)
 			fmt.Printf("❌ Audio upload: database error for book %s: %v\n", bookKey, err)
 			return
 		}
 
-		// Initialize R2 client
-		r2, err := storage.NewR2Client(ctx)
+		// Object path (unique filename)
+		objectKey := fmt.Sprintf("books/%s-summary-%d%s", ref.Slug, time.Now().Unix(), ext)
+
+		put, err := blobs.PresignPut(ctx, objectKey, blob.PutOptions{
+			ContentType: req.ContentType,
+			Size:        req.Size,
+			SHA256:      sum,
+		})
 		if err != nil {
 			w.Header().Set("Content-Type", "application/json")
-			http.Error(w, fmt.Sprintf(`{"error":"storage init failed: %v"}`, err), http.StatusInternalServerError)
-			fmt.Printf("❌ Audio upload: R2 client init failed: %v\n", err)
+			http.Error(w, `{"error":"failed to generate upload url"}`, http.StatusInternalServerError)
+			fmt.Printf("❌ Audio upload: presigned URL generation failed for %s: %v\n", objectKey, err)
 			return
 		}
 
-		// Object path (unique filename)
-		objectKey := fmt.Sprintf("books/%s-summary-%d.mp3", bookKey, time.Now().Unix())
+		now := time.Now().UTC()
+		p := pending.Upload{
+			ObjectKey:   objectKey,
+			BookID:      ref.BookID,
+			Kind:        "audio",
+			ContentType: req.ContentType,
+			Size:        req.Size,
+			SHA256:      sum,
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(pending.DefaultTTL),
+		}
+		p.CreatedBy, _ = middlewares.UserIDFrom(ctx)
+		if err := pending.New(rdb, blobs).Put(ctx, p); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			http.Error(w, `{"error":"failed to record pending upload"}`, http.StatusInternalServerError)
+			fmt.Printf("❌ Audio upload: pending record failed for %s: %v\n", objectKey, err)
+			return
+		}
 
-		uploadURL, err := r2.GeneratePresignedUploadURL(ctx, objectKey, "audio/mpeg")
+		fmt.Printf("✅ Generated audio upload URL for book %s: %s\n", bookKey, objectKey)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(audioUploadResponse{
+			UploadURL: put.URL,
+			ObjectKey: objectKey,
+			Headers:   put.Headers,
+			ExpiresAt: p.ExpiresAt,
+		})
+	}
+}
+
+// POST /admin/books/{key}/audio/complete
+// Phase two: verifies the uploaded object (size, type, checksum, real audio
+// header), swaps it onto the book and deletes the previous audio.
+func CompleteBookAudioHandler(db *sql.DB, rdb *redis.Client, blobs blob.BlobStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		bookKey := r.PathValue("key")
+
+		var req audioCompleteRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ObjectKey == "" {
+			w.Header().Set("Content-Type", "application/json")
+			http.Error(w, `{"error":"object_key is required"}`, http.StatusBadRequest)
+			return
+		}
+
+		ref, err := storebooks.GetAudioRef(ctx, db, bookKey)
+		if err == sql.ErrNoRows {
+			w.Header().Set("Content-Type", "application/json")
+			http.Error(w, `{"error":"book not found"}`, http.StatusNotFound)
+			return
+		}
 		if err != nil {
 			w.Header().Set("Content-Type", "application/json")
-			http.Error(w, fmt.Sprintf(`{"error":"failed to generate upload url: %v"}`, err), http.StatusInternalServerError)
-			fmt.Printf("❌ Audio upload: presigned URL generation failed for %s: %v\n", objectKey, err)
+			http.Error(w, `{"error":"database error"}`, http.StatusInternalServerError)
 			return
 		}
 
-		result, err := db.ExecContext(ctx, `
-			UPDATE books
-			SET audio_key = $1
-			WHERE id::text = $2 OR slug = $2
-		`, objectKey, bookKey)
+		store := pending.New(rdb, blobs)
+		p, err := store.Claim(ctx, req.ObjectKey)
+		if errors.Is(err, pending.ErrNotFound) {
+			w.Header().Set("Content-Type", "application/json")
+			http.Error(w, `{"error":"no pending upload for this object (expired or already completed)"}`, http.StatusNotFound)
+			return
+		}
 		if err != nil {
 			w.Header().Set("Content-Type", "application/json")
-			http.Error(w, fmt.Sprintf(`{"error":"failed to save audio key: %v"}`, err), http.StatusInternalServerError)
-			fmt.Printf("❌ Audio upload: DB update failed for %s: %v\n", bookKey, err)
+			http.Error(w, `{"error":"failed to load pending upload"}`, http.StatusInternalServerError)
+			return
+		}
+		if p.BookID != ref.BookID || p.Kind != "audio" {
+			_ = store.Put(ctx, *p)
+			w.Header().Set("Content-Type", "application/json")
+			http.Error(w, `{"error":"object does not belong to this book"}`, http.StatusConflict)
 			return
 		}
 
-		rowsAffected, _ := result.RowsAffected()
+		head, err := blobs.Head(ctx, p.ObjectKey)
+		if errors.Is(err, blob.ErrNotFound) {
+			// Not there (yet); leave it pending so the client can retry.
+			_ = store.Put(ctx, *p)
+			w.Header().Set("Content-Type", "application/json")
+			http.Error(w, `{"error":"object has not been uploaded yet"}`, http.StatusConflict)
+			return
+		}
+		if err != nil {
+			_ = store.Put(ctx, *p)
+			w.Header().Set("Content-Type", "application/json")
+			http.Error(w, `{"error":"failed to inspect uploaded object"}`, http.StatusBadGateway)
+			fmt.Printf("❌ Audio complete: head %s failed: %v\n", p.ObjectKey, err)
+			return
+		}
 
-		if rowsAffected == 0 {
+		info, verr := verifyPendingAudio(blob.NewReaderAt(ctx, blobs, p.ObjectKey), p, head)
+		if verr != nil {
+			_ = blobs.Delete(ctx, p.ObjectKey)
 			w.Header().Set("Content-Type", "application/json")
-			http.Error(w, `{"error":"book not found"}`, http.StatusNotFound)
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(map[string]string{"error": verr.Error()})
+			return
+		}
+
+		old, err := storebooks.SwapAudio(ctx, db, p.BookID, p.ObjectKey, audioMeta(info))
+		if err != nil {
+			_ = store.Put(ctx, *p)
+			w.Header().Set("Content-Type", "application/json")
+			http.Error(w, `{"error":"failed to save audio key"}`, http.StatusInternalServerError)
+			fmt.Printf("❌ Audio complete: DB update failed for %s: %v\n", bookKey, err)
 			return
 		}
 
-		// Delete old audio from R2 if it exists
-		if oldAudioKey.Valid && oldAudioKey.String != "" {
-			if err := r2.DeleteObject(ctx, oldAudioKey.String); err != nil {
+		// Delete old audio if it exists
+		if old != "" && old != p.ObjectKey {
+			if err := blobs.Delete(ctx, old); err != nil {
 				// Log but don't fail - old file deletion is not critical
-				fmt.Printf("⚠️ Warning: failed to delete old audio %s: %v\n", oldAudioKey.String, err)
+				fmt.Printf("⚠️ Warning: failed to delete old audio %s: %v\n", old, err)
 			} else {
-				fmt.Printf("✅ Deleted old audio: %s\n", oldAudioKey.String)
+				fmt.Printf("✅ Deleted old audio: %s\n", old)
 			}
 		}
 
-		resp := audioUploadResponse{
-			UploadURL: uploadURL,
-			ObjectKey: objectKey,
-		}
-
-		fmt.Printf("✅ Generated audio upload URL for book %s: %s\n", bookKey, objectKey)
+		fmt.Printf("✅ Audio upload completed for book %s: %s\n", bookKey, p.ObjectKey)
 
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(resp)
+		json.NewEncoder(w).Encode(map[string]any{
+			"status":            "success",
+			"object_key":        p.ObjectKey,
+			"size":              head.Size,
+			"checksum_verified": p.SHA256 != "",
+			"audio": map[string]any{
+				"format":           info.Format,
+				"codec":            info.Codec,
+				"duration_seconds": info.Duration.Seconds(),
+				"bitrate":          info.Bitrate,
+				"sample_rate":      info.SampleRate,
+				"channels":         info.Channels,
+			},
+		})
+	}
+}
+
+// verifyPendingAudio checks the stored object against what the client declared
+// and probes its header.
+func verifyPendingAudio(ra io.ReaderAt, p *pending.Upload, head blob.Info) (audio.Info, error) {
+	if head.Size <= 0 || head.Size > maxAudioSize {
+		return audio.Info{}, fmt.Errorf("uploaded object has invalid size %d", head.Size)
+	}
+	if p.Size > 0 && head.Size != p.Size {
+		return audio.Info{}, fmt.Errorf("size mismatch: declared %d, stored %d", p.Size, head.Size)
+	}
+	if ct, _, _ := mime.ParseMediaType(head.ContentType); ct != p.ContentType {
+		return audio.Info{}, fmt.Errorf("content type mismatch: declared %s, stored %s", p.ContentType, head.ContentType)
+	}
+	if p.SHA256 != "" && head.ChecksumSHA256 != p.SHA256 {
+		return audio.Info{}, errors.New("checksum mismatch")
+	}
+	info, err := audio.Probe(ra, head.Size)
+	if err != nil {
+		return audio.Info{}, fmt.Errorf("invalid audio file: %v", err)
+	}
+	if !audio.Matches(p.ContentType, info) {
+		return audio.Info{}, fmt.Errorf("%v: declared %s, found %s", errAudioMismatch, p.ContentType, info.Format)
+	}
+	return info, nil
+}
+
+// normalizeSHA256 accepts a SHA-256 digest as base64 or hex and returns base64.
+func normalizeSHA256(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	if len(s) == 64 {
+		if b, err := hex.DecodeString(s); err == nil {
+			return base64.StdEncoding.EncodeToString(b), nil
+		}
+	}
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || len(b) != 32 {
+		return "", errors.New("invalid sha256")
 	}
+	return s, nil
 }
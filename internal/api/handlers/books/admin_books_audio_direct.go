@@ -2,16 +2,17 @@ package books
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"time"
 
-	storage "github.com/5w1tchy/books-api/internal/storage/s3"
+	"github.com/5w1tchy/books-api/internal/storage/blob"
+	storebooks "github.com/5w1tchy/books-api/internal/store/books"
 )
 
 // PUT /admin/books/{key}/audio/upload - Direct upload through backend (CORS workaround)
-func DirectAudioUploadHandler(db *sql.DB) http.HandlerFunc {
+func DirectAudioUploadHandler(db *sql.DB, blobs blob.BlobStore) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 		bookKey := r.PathValue("key")
@@ -22,11 +23,11 @@ func DirectAudioUploadHandler(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		// Get existing audio_key to delete old one
-		var oldAudioKey sql.NullString
+		// Resolve the book; SwapAudio reads the old audio key under a row lock
+		var bookID string
 		err := db.QueryRowContext(ctx, `
-			SELECT audio_key FROM books WHERE id::text = $1 OR slug = $1
-		`, bookKey).Scan(&oldAudioKey)
+			SELECT id::text FROM books WHERE id::text = $1 OR slug = $1
+		`, bookKey).Scan(&bookID)
 		
 		if err == sql.ErrNoRows {
 			w.Header().Set("Content-Type", "application/json")
@@ -54,61 +55,56 @@ func DirectAudioUploadHandler(db *sql.DB) http.HandlerFunc {
 		}
 		defer file.Close()
 
-		// Validate content type
+		// Verify the real format from the file header; the declared type must agree
 		contentType := header.Header.Get("Content-Type")
-		if contentType != "audio/mpeg" && contentType != "audio/mp3" && 
-		   contentType != "audio/ogg" && contentType != "audio/wav" {
+		if contentType == "application/octet-stream" {
+			contentType = ""
+		}
+		if contentType != "" && !allowedAudioC[contentType] {
 			w.Header().Set("Content-Type", "application/json")
 			http.Error(w, `{"error":"invalid audio type"}`, http.StatusBadRequest)
 			return
 		}
-
-		// Initialize R2 client
-		r2, err := storage.NewR2Client(ctx)
+		info, err := probeAudioUpload(file, header.Size, contentType)
 		if err != nil {
 			w.Header().Set("Content-Type", "application/json")
-			http.Error(w, fmt.Sprintf(`{"error":"storage init failed: %v"}`, err), http.StatusInternalServerError)
+			http.Error(w, fmt.Sprintf(`{"error":"invalid audio file: %v"}`, err), http.StatusBadRequest)
 			return
 		}
+		contentType = info.ContentType()
 
 		// Generate object key
-		objectKey := fmt.Sprintf("books/%s-summary-%d.mp3", bookKey, time.Now().Unix())
+		objectKey := fmt.Sprintf("books/%s-summary-%d%s", bookKey, time.Now().Unix(), info.Ext())
 
-		// Upload to R2
-		if err := uploadFileToR2(ctx, r2, objectKey, file, contentType, header.Size); err != nil {
+		// Upload to storage
+		if err := blobs.Put(ctx, objectKey, file, header.Size, contentType); err != nil {
 			w.Header().Set("Content-Type", "application/json")
 			http.Error(w, fmt.Sprintf(`{"error":"upload failed: %v"}`, err), http.StatusInternalServerError)
 			return
 		}
 
 		// Update DB
-		result, err := db.ExecContext(ctx, `
-			UPDATE books
-			SET audio_key = $1
-			WHERE id::text = $2 OR slug = $2
-		`, objectKey, bookKey)
-		if err != nil {
-			// Cleanup uploaded file
-			_ = r2.DeleteObject(ctx, objectKey)
+		old, err := storebooks.SwapAudio(ctx, db, bookID, objectKey, audioMeta(info))
+		if err == sql.ErrNoRows {
+			_ = blobs.Delete(ctx, objectKey)
 			w.Header().Set("Content-Type", "application/json")
-			http.Error(w, fmt.Sprintf(`{"error":"failed to save audio key: %v"}`, err), http.StatusInternalServerError)
+			http.Error(w, `{"error":"book not found"}`, http.StatusNotFound)
 			return
 		}
-
-		rows, _ := result.RowsAffected()
-		if rows == 0 {
-			_ = r2.DeleteObject(ctx, objectKey)
+		if err != nil {
+			// Cleanup uploaded file
+			_ = blobs.Delete(ctx, objectKey)
 			w.Header().Set("Content-Type", "application/json")
-			http.Error(w, `{"error":"book not found"}`, http.StatusNotFound)
+			http.Error(w, fmt.Sprintf(`{"error":"failed to save audio key: %v"}`, err), http.StatusInternalServerError)
 			return
 		}
 
-		// Delete old audio from R2 if it exists
-		if oldAudioKey.Valid && oldAudioKey.String != "" {
-			if err := r2.DeleteObject(ctx, oldAudioKey.String); err != nil {
-				fmt.Printf("⚠️ Warning: failed to delete old audio %s: %v\n", oldAudioKey.String, err)
+		// Delete the audio this upload replaced, as read under the lock
+		if old != "" && old != objectKey {
+			if err := blobs.Delete(ctx, old); err != nil {
+				fmt.Printf("⚠️ Warning: failed to delete old audio %s: %v\n", old, err)
 			} else {
-				fmt.Printf("✅ Deleted old audio: %s\n", oldAudioKey.String)
+				fmt.Printf("✅ Deleted old audio: %s\n", old)
 			}
 		}
 
@@ -116,6 +112,17 @@ func DirectAudioUploadHandler(db *sql.DB) http.HandlerFunc {
 
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
-		io.WriteString(w, `{"status":"success","message":"audio uploaded successfully"}`)
+		json.NewEncoder(w).Encode(map[string]any{
+			"status":  "success",
+			"message": "audio uploaded successfully",
+			"audio": map[string]any{
+				"format":           info.Format,
+				"codec":            info.Codec,
+				"duration_seconds": info.Duration.Seconds(),
+				"bitrate":          info.Bitrate,
+				"sample_rate":      info.SampleRate,
+				"channels":         info.Channels,
+			},
+		})
 	}
 }
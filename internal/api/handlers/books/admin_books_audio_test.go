@@ -0,0 +1,128 @@
+package books
+
+import (
+	"bytes"
+	"encoding/binary"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/5w1tchy/books-api/internal/storage/blob"
+	"github.com/5w1tchy/books-api/internal/uploads/pending"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// wavBytes builds a one-second 8 kHz mono PCM WAV.
+func wavBytes() []byte {
+	data := make([]byte, 16000)
+	var b bytes.Buffer
+	b.WriteString("RIFF")
+	binary.Write(&b, binary.LittleEndian, uint32(36+len(data)))
+	b.WriteString("WAVEfmt ")
+	for _, v := range []any{uint32(16), uint16(1), uint16(1), uint32(8000), uint32(16000), uint16(2), uint16(16)} {
+		binary.Write(&b, binary.LittleEndian, v)
+	}
+	b.WriteString("data")
+	binary.Write(&b, binary.LittleEndian, uint32(len(data)))
+	b.Write(data)
+	return b.Bytes()
+}
+
+func TestVerifyPendingAudio(t *testing.T) {
+	file := wavBytes()
+	sum := "n4bQgYhMfWWaL+qgxVrQFaO/TxsrC4Is0V1sFbDwCgg=" // any 32-byte value; compared, not computed
+	p := &pending.Upload{ContentType: "audio/wav", Size: int64(len(file)), SHA256: sum}
+	head := blob.Info{Size: int64(len(file)), ContentType: "audio/wav", ChecksumSHA256: sum}
+
+	info, err := verifyPendingAudio(bytes.NewReader(file), p, head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Format != "wav" || info.Duration.Seconds() != 1 {
+		t.Errorf("unexpected info: %+v", info)
+	}
+
+	cases := map[string]func(p *pending.Upload, h *blob.Info){
+		"size":     func(p *pending.Upload, h *blob.Info) { p.Size++ },
+		"type":     func(p *pending.Upload, h *blob.Info) { h.ContentType = "audio/mpeg" },
+		"checksum": func(p *pending.Upload, h *blob.Info) { h.ChecksumSHA256 = "" },
+		"declared": func(p *pending.Upload, h *blob.Info) { p.ContentType, h.ContentType = "audio/mpeg", "audio/mpeg" },
+	}
+	for name, mutate := range cases {
+		pc, hc := *p, head
+		mutate(&pc, &hc)
+		if _, err := verifyPendingAudio(bytes.NewReader(file), &pc, hc); err == nil {
+			t.Errorf("%s: expected verification error", name)
+		}
+	}
+}
+
+func TestNormalizeSHA256(t *testing.T) {
+	hexSum := strings.Repeat("ab", 32)
+	b64, err := normalizeSHA256(hexSum)
+	if err != nil || b64 != "q6urq6urq6urq6urq6urq6urq6urq6urq6urq6urq6s=" {
+		t.Errorf("hex input: %q %v", b64, err)
+	}
+	if same, err := normalizeSHA256(b64); err != nil || same != b64 {
+		t.Errorf("base64 input: %q %v", same, err)
+	}
+	if _, err := normalizeSHA256("dG9vIHNob3J0"); err == nil {
+		t.Error("short digest accepted")
+	}
+}
+
+func TestDirectAudioUploadSwapsUnderLock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	blobs := blob.NewMemory()
+	old := "books/dune-summary-1.wav"
+	if err := blobs.Put(t.Context(), old, bytes.NewReader(wavBytes()), int64(len(wavBytes())), "audio/wav"); err != nil {
+		t.Fatal(err)
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Disposition": {`form-data; name="audio"; filename="dune.wav"`},
+		"Content-Type":        {"audio/wav"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(wavBytes())
+	mw.Close()
+
+	mock.ExpectQuery(`SELECT id::text FROM books`).WithArgs("dune").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("b-1"))
+	// The key to delete is the one read under the lock, not before the upload
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT audio_key FROM books WHERE id::text = $1 FOR UPDATE`)).WithArgs("b-1").
+		WillReturnRows(sqlmock.NewRows([]string{"audio_key"}).AddRow(old))
+	mock.ExpectExec(`UPDATE books`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	req := httptest.NewRequest(http.MethodPut, "/admin/books/dune/audio/upload", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.SetPathValue("key", "dune")
+	rec := httptest.NewRecorder()
+	DirectAudioUploadHandler(db, blobs).ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("upload: %d %s", rec.Code, rec.Body)
+	}
+	if _, err := blobs.Head(t.Context(), old); err == nil {
+		t.Error("replaced audio not deleted")
+	}
+	if objs, _ := blobs.List(t.Context(), "books/", false); len(objs) != 1 || objs[0].Key == old {
+		t.Errorf("objects after upload: %+v", objs)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
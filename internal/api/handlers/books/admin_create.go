@@ -13,7 +13,9 @@ import (
 	"time"
 
 	httpx "github.com/5w1tchy/books-api/internal/api/httpx"
-	storage "github.com/5w1tchy/books-api/internal/storage/s3"
+	"github.com/5w1tchy/books-api/internal/media/audio"
+	"github.com/5w1tchy/books-api/internal/media/cover"
+	"github.com/5w1tchy/books-api/internal/storage/blob"
 	storebooks "github.com/5w1tchy/books-api/internal/store/books"
 	"github.com/redis/go-redis/v9"
 )
@@ -61,7 +63,7 @@ type adminCreateResp struct {
 
 // === Handler ===
 
-func AdminCreate(db *sql.DB, _ *redis.Client) http.Handler {
+func AdminCreate(db *sql.DB, _ *redis.Client, blobs blob.BlobStore) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			httpx.ErrorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
@@ -82,8 +84,9 @@ func AdminCreate(db *sql.DB, _ *redis.Client) http.Handler {
 			audioContentType string
 			coverContentType string
 			audioFile        multipart.File
+			audioInfo        audio.Info
 			coverFile        multipart.File
-			r2client         *storage.S3Client
+			coverImg         storebooks.CoverImage
 		)
 
 		ct := r.Header.Get("Content-Type")
@@ -157,19 +160,6 @@ func AdminCreate(db *sql.DB, _ *redis.Client) http.Handler {
 			return
 		}
 
-		// Initialize R2 client if we have any files
-		if audioFound || coverFound {
-			log.Printf("🔧 Initializing R2 client (audioFound=%v, coverFound=%v)", audioFound, coverFound)
-			var err error
-			r2client, err = storage.NewR2Client(ctx)
-			if err != nil {
-				log.Printf("[admin books] r2 init error: %v", err)
-				httpx.ErrorJSON(w, http.StatusInternalServerError, "storage client init failed")
-				return
-			}
-			log.Printf("✅ R2 client initialized successfully")
-		}
-
 		// Handle audio upload
 		if audioFound {
 			log.Printf("📤 Starting audio upload...")
@@ -178,46 +168,42 @@ func AdminCreate(db *sql.DB, _ *redis.Client) http.Handler {
 				return
 			}
 
-			// sniff if missing
-			if audioContentType == "" {
-				head := make([]byte, 512)
-				n, _ := audioFile.Read(head)
-				_, _ = audioFile.Seek(0, io.SeekStart)
-				audioContentType = http.DetectContentType(head[:n])
+			// verify the real format from the header; a declared type must agree
+			if audioContentType == "application/octet-stream" {
+				audioContentType = ""
 			}
-			if !allowedAudioC[audioContentType] {
+			if audioContentType != "" && !allowedAudioC[audioContentType] {
 				log.Printf("❌ Unsupported audio type: %s", audioContentType)
 				httpx.ErrorJSON(w, http.StatusBadRequest, "unsupported audio content type")
 				return
 			}
+			info, err := probeAudioUpload(audioFile, audioSize, audioContentType)
+			if err != nil {
+				log.Printf("❌ Audio rejected: %v", err)
+				httpx.ErrorJSON(w, http.StatusBadRequest, fmt.Sprintf("invalid audio file: %v", err))
+				return
+			}
+			audioInfo = info
+			audioContentType = info.ContentType()
 
 			// build object key from TITLE (slugify for a safe path)
 			safe := slugifyTitle(in.Title)
 			if safe == "" {
 				safe = fmt.Sprintf("book-%d", time.Now().UnixNano())
 			}
-			ext := ".mp3"
-			switch audioContentType {
-			case "audio/ogg":
-				ext = ".ogg"
-			case "audio/mp4":
-				ext = ".m4a"
-			case "audio/wav", "audio/x-wav":
-				ext = ".wav"
-			}
-			audioKey = path.Join("books", fmt.Sprintf("%s-audio-%d%s", safe, time.Now().Unix(), ext))
+			audioKey = path.Join("books", fmt.Sprintf("%s-audio-%d%s", safe, time.Now().Unix(), audioInfo.Ext()))
 			log.Printf("📝 Audio key: %s", audioKey)
 
-			if err := uploadFileToR2(ctx, r2client, audioKey, audioFile, audioContentType, audioSize); err != nil {
+			if err := blobs.Put(ctx, audioKey, audioFile, audioSize, audioContentType); err != nil {
 				log.Printf("[admin books] audio upload error: %v", err)
 				httpx.ErrorJSON(w, http.StatusInternalServerError, fmt.Sprintf("audio upload failed: %v", err))
 				return
 			}
 			_ = audioFile.Close()
-			log.Printf("✅ Audio uploaded successfully to R2")
+			log.Printf("✅ Audio uploaded successfully to storage")
 
 			// optional presigned GET for immediate preview
-			if url, err := r2client.GeneratePresignedDownloadURL(ctx, audioKey); err == nil {
+			if url, err := blobs.PresignGet(ctx, audioKey, blob.DefaultPresignTTL); err == nil {
 				audioURL = url
 				log.Printf("🔗 Audio presigned URL generated")
 			}
@@ -228,8 +214,8 @@ func AdminCreate(db *sql.DB, _ *redis.Client) http.Handler {
 			log.Printf("📤 Starting cover upload...")
 			if coverSize > maxCoverSize {
 				// Cleanup audio if already uploaded
-				if audioFound && r2client != nil && audioKey != "" {
-					_ = r2client.DeleteObject(ctx, audioKey)
+				if audioKey != "" {
+					_ = blobs.Delete(ctx, audioKey)
 				}
 				httpx.ErrorJSON(w, http.StatusBadRequest, "cover too large (max 10MB)")
 				return
@@ -244,43 +230,51 @@ func AdminCreate(db *sql.DB, _ *redis.Client) http.Handler {
 			}
 			if !allowedCoverC[coverContentType] {
 				// Cleanup audio if already uploaded
-				if audioFound && r2client != nil && audioKey != "" {
-					_ = r2client.DeleteObject(ctx, audioKey)
+				if audioKey != "" {
+					_ = blobs.Delete(ctx, audioKey)
 				}
 				log.Printf("❌ Unsupported cover type: %s", coverContentType)
 				httpx.ErrorJSON(w, http.StatusBadRequest, "unsupported cover type (use jpeg, png, or webp)")
 				return
 			}
 
-			// build object key: books/covers/<slug>-<timestamp>.<ext>
+			// decode it and render variants; the stored type follows the real encoding
+			data, err := readCover(coverFile)
+			var processed *cover.Processed
+			if err == nil {
+				processed, err = processCover(data)
+			}
+			if err != nil {
+				if audioKey != "" {
+					_ = blobs.Delete(ctx, audioKey)
+				}
+				log.Printf("❌ Cover rejected: %v", err)
+				httpx.ErrorJSON(w, http.StatusBadRequest, err.Error())
+				return
+			}
+
+			// object key: books/covers/<slug>-<timestamp>.<ext> (+ variants beside it)
 			safe := slugifyTitle(in.Title)
 			if safe == "" {
 				safe = fmt.Sprintf("book-%d", time.Now().UnixNano())
 			}
-			ext := ".jpg"
-			switch coverContentType {
-			case "image/png":
-				ext = ".png"
-			case "image/webp":
-				ext = ".webp"
-			}
-			coverKey = path.Join("books/covers", fmt.Sprintf("%s-%d%s", safe, time.Now().Unix(), ext))
-			log.Printf("📝 Cover key: %s", coverKey)
-
-			if err := uploadFileToR2(ctx, r2client, coverKey, coverFile, coverContentType, coverSize); err != nil {
+			coverImg, err = storeCover(ctx, blobs, safe, time.Now().Unix(), data, processed)
+			if err != nil {
 				log.Printf("[admin books] cover upload error: %v", err)
 				// Cleanup audio if already uploaded
-				if audioFound && r2client != nil && audioKey != "" {
-					_ = r2client.DeleteObject(ctx, audioKey)
+				if audioKey != "" {
+					_ = blobs.Delete(ctx, audioKey)
 				}
 				httpx.ErrorJSON(w, http.StatusInternalServerError, fmt.Sprintf("cover upload failed: %v", err))
 				return
 			}
+			coverKey = coverImg.Key
+			log.Printf("📝 Cover key: %s (%d variants)", coverKey, len(coverImg.Variants))
 			_ = coverFile.Close()
-			log.Printf("✅ Cover uploaded successfully to R2")
+			log.Printf("✅ Cover uploaded successfully to storage")
 
 			// optional presigned GET for immediate preview
-			if url, err := r2client.GeneratePresignedDownloadURL(ctx, coverKey); err == nil {
+			if url, err := blobs.PresignGet(ctx, coverKey, blob.DefaultPresignTTL); err == nil {
 				coverURL = url
 				log.Printf("🔗 Cover presigned URL generated")
 			}
@@ -301,14 +295,10 @@ func AdminCreate(db *sql.DB, _ *redis.Client) http.Handler {
 		if err != nil {
 			log.Printf("[admin books] create error: %v", err)
 			// cleanup uploaded files if create fails
-			if r2client != nil {
-				if audioKey != "" {
-					_ = r2client.DeleteObject(ctx, audioKey)
-				}
-				if coverKey != "" {
-					_ = r2client.DeleteObject(ctx, coverKey)
-				}
+			if audioKey != "" {
+				_ = blobs.Delete(ctx, audioKey)
 			}
+			storebooks.DeleteCoverObjects(ctx, blobs, coverKey)
 			if strings.Contains(strings.ToLower(err.Error()), "code_exists") {
 				httpx.ErrorJSON(w, http.StatusConflict, "coda already exists")
 				return
@@ -326,18 +316,22 @@ func AdminCreate(db *sql.DB, _ *redis.Client) http.Handler {
 			argIdx := 1
 
 			if audioKey != "" {
-				query += fmt.Sprintf("audio_key = $%d", argIdx)
-				args = append(args, audioKey)
-				argIdx++
+				m := audioMeta(audioInfo)
+				query += fmt.Sprintf("audio_key = $%d, audio_format = $%d, audio_duration_ms = $%d, audio_bitrate = $%d, audio_sample_rate = $%d, audio_channels = $%d",
+					argIdx, argIdx+1, argIdx+2, argIdx+3, argIdx+4, argIdx+5)
+				args = append(args, audioKey, m.Format, m.DurationMS, m.Bitrate, m.SampleRate, m.Channels)
+				argIdx += 6
 			}
 
 			if coverKey != "" {
 				if audioKey != "" {
 					query += ", "
 				}
-				query += fmt.Sprintf("cover_url = $%d", argIdx)
-				args = append(args, coverKey)
-				argIdx++
+				ph := coverImg.Placeholder
+				query += fmt.Sprintf("cover_url = $%d, cover_variants = $%d, cover_width = $%d, cover_height = $%d, cover_color = $%d, cover_blurhash = $%d",
+					argIdx, argIdx+1, argIdx+2, argIdx+3, argIdx+4, argIdx+5)
+				args = append(args, coverKey, storebooks.VariantsJSON(coverImg.Variants), ph.Width, ph.Height, ph.Color, ph.BlurHash)
+				argIdx += 6
 			}
 
 			query += fmt.Sprintf(" WHERE id = $%d", argIdx)
@@ -346,19 +340,18 @@ func AdminCreate(db *sql.DB, _ *redis.Client) http.Handler {
 			log.Printf("🔍 SQL Query: %s with args: %v", query, args)
 			_, err := db.ExecContext(ctx, query, args...)
 			if err != nil {
-				if r2client != nil {
-					if audioKey != "" {
-						_ = r2client.DeleteObject(ctx, audioKey)
-					}
-					if coverKey != "" {
-						_ = r2client.DeleteObject(ctx, coverKey)
-					}
+				if audioKey != "" {
+					_ = blobs.Delete(ctx, audioKey)
 				}
+				storebooks.DeleteCoverObjects(ctx, blobs, coverKey)
 				log.Printf("[admin books] attach files failed: %v", err)
 				httpx.ErrorJSON(w, http.StatusInternalServerError, "failed to attach files to book")
 				return
 			}
 			log.Printf("✅ Files attached successfully to database")
+			if coverKey != "" {
+				book.Cover = coverImg.Placeholder
+			}
 		}
 
 		resp := adminCreateResp{
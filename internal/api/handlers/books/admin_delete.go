@@ -6,13 +6,14 @@ import (
 	"log"
 	"net/http"
 
+	"github.com/5w1tchy/books-api/internal/storage/blob"
 	storebooks "github.com/5w1tchy/books-api/internal/store/books"
 	storeforyou "github.com/5w1tchy/books-api/internal/store/foryou"
 	"github.com/redis/go-redis/v9"
 )
 
 // AdminDelete: DELETE /admin/books/{key}
-func AdminDelete(db *sql.DB, rdb *redis.Client) http.Handler {
+func AdminDelete(db *sql.DB, rdb *redis.Client, blobs blob.BlobStore) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodDelete {
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
@@ -26,7 +27,7 @@ func AdminDelete(db *sql.DB, rdb *redis.Client) http.Handler {
 			return
 		}
 
-		if err := storebooks.DeleteV2(r.Context(), db, key); err == sql.ErrNoRows {
+		if err := storebooks.DeleteV2(r.Context(), db, blobs, key); err == sql.ErrNoRows {
 			http.Error(w, `{"status":"error","error":"not found"}`, http.StatusNotFound)
 			return
 		} else if err != nil {
@@ -0,0 +1,166 @@
+package books
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	httpx "github.com/5w1tchy/books-api/internal/api/httpx"
+	"github.com/5w1tchy/books-api/internal/media/captions"
+	"github.com/5w1tchy/books-api/internal/storage/blob"
+	storebooks "github.com/5w1tchy/books-api/internal/store/books"
+)
+
+const maxCaptionsSize = 5 << 20 // 5 MB
+
+// PUT /admin/books/{key}/audio/captions
+// Accepts WebVTT or SRT, as multipart field "captions" or the raw request body.
+func AdminUploadCaptions(db *sql.DB, blobs blob.BlobStore) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		ref, ok := loadAudioRef(w, r, db)
+		if !ok {
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxCaptionsSize+1<<20)
+		var src io.Reader = r.Body
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+			f, _, err := r.FormFile("captions")
+			if err != nil {
+				httpx.ErrorJSON(w, http.StatusBadRequest, "missing captions file")
+				return
+			}
+			defer f.Close()
+			src = f
+		}
+		data, err := io.ReadAll(io.LimitReader(src, maxCaptionsSize+1))
+		if err != nil {
+			httpx.ErrorJSON(w, http.StatusBadRequest, "failed to read captions")
+			return
+		}
+		if len(data) > maxCaptionsSize {
+			httpx.ErrorJSON(w, http.StatusRequestEntityTooLarge, "captions too large (max 5MB)")
+			return
+		}
+
+		cues, err := captions.Parse(data, time.Duration(ref.DurationMS)*time.Millisecond)
+		if err != nil {
+			httpx.ErrorJSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		key := captions.Key(ref.AudioKey)
+		vtt := captions.WriteVTT(cues)
+		if err := blobs.Put(ctx, key, bytes.NewReader(vtt), int64(len(vtt)), "text/vtt; charset=utf-8"); err != nil {
+			log.Printf("[captions] upload %s: %v", key, err)
+			httpx.ErrorJSON(w, http.StatusInternalServerError, "captions upload failed")
+			return
+		}
+
+		httpx.OK(w, map[string]any{
+			"captions_key": key,
+			"cues":         len(cues),
+			"end_seconds":  cues[len(cues)-1].End.Seconds(),
+		})
+	})
+}
+
+// GET /books/{key}/audio/captions.vtt
+func GetCaptions(db *sql.DB, blobs blob.BlobStore) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		ref, ok := loadAudioRef(w, r, db)
+		if !ok {
+			return
+		}
+
+		key := captions.Key(ref.AudioKey)
+		obj, err := blobs.Get(ctx, key, blob.GetOptions{IfNoneMatch: r.Header.Get("If-None-Match")})
+		switch {
+		case errors.Is(err, blob.ErrNotModified):
+			// If-None-Match may be a list or *: send the object's own ETag
+			if info, herr := blobs.Head(ctx, key); herr == nil && info.ETag != "" {
+				w.Header().Set("ETag", info.ETag)
+			}
+			w.WriteHeader(http.StatusNotModified)
+			return
+		case errors.Is(err, blob.ErrNotFound):
+			httpx.ErrorJSON(w, http.StatusNotFound, "no captions for this audio")
+			return
+		case err != nil:
+			log.Printf("[captions] get %s: %v", ref.AudioKey, err)
+			httpx.ErrorJSON(w, http.StatusBadGateway, "failed to load captions")
+			return
+		}
+		defer obj.Body.Close()
+
+		w.Header().Set("Content-Type", "text/vtt; charset=utf-8")
+		w.Header().Set("Cache-Control", "private, max-age=300")
+		if obj.ETag != "" {
+			w.Header().Set("ETag", obj.ETag)
+		}
+		_, _ = io.Copy(w, obj.Body)
+	})
+}
+
+// GET /books/{key}/audio/transcript/search?q=&limit=
+// Returns matching cues with their timestamps so the player can seek.
+func SearchTranscript(db *sql.DB, blobs blob.BlobStore) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		q := strings.TrimSpace(r.URL.Query().Get("q"))
+		if len([]rune(q)) < 2 {
+			httpx.ErrorJSON(w, http.StatusBadRequest, "q must be at least 2 characters")
+			return
+		}
+		limit := 50
+		if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 200 {
+			limit = l
+		}
+
+		ref, ok := loadAudioRef(w, r, db)
+		if !ok {
+			return
+		}
+		data, _, err := blob.ReadAll(ctx, blobs, captions.Key(ref.AudioKey))
+		if err != nil {
+			httpx.ErrorJSON(w, http.StatusNotFound, "no captions for this audio")
+			return
+		}
+		cues, err := captions.Parse(data, 0)
+		if err != nil {
+			log.Printf("[captions] stored transcript for %s is invalid: %v", ref.BookID, err)
+			httpx.ErrorJSON(w, http.StatusInternalServerError, "stored transcript is invalid")
+			return
+		}
+
+		httpx.OK(w, map[string]any{
+			"query":   q,
+			"matches": captions.Search(cues, q, limit),
+		})
+	})
+}
+
+// loadAudioRef resolves {key} and writes the error response itself.
+func loadAudioRef(w http.ResponseWriter, r *http.Request, db *sql.DB) (storebooks.AudioRef, bool) {
+	ref, err := storebooks.GetAudioRef(r.Context(), db, r.PathValue("key"))
+	if err == sql.ErrNoRows {
+		httpx.ErrorJSON(w, http.StatusNotFound, "book not found")
+		return ref, false
+	} else if err != nil {
+		httpx.ErrorJSON(w, http.StatusInternalServerError, "failed to load book")
+		return ref, false
+	}
+	if ref.AudioKey == "" {
+		httpx.ErrorJSON(w, http.StatusNotFound, "book has no audio")
+		return ref, false
+	}
+	return ref, true
+}
@@ -0,0 +1,47 @@
+package books
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/5w1tchy/books-api/internal/media/captions"
+	"github.com/5w1tchy/books-api/internal/storage/blob"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestGetCaptionsNotModifiedSendsStoredETag(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	blobs := blob.NewMemory()
+	vtt := []byte("WEBVTT\n\n00:00.000 --> 00:01.000\nHello\n")
+	key := captions.Key("books/dune.mp3")
+	if err := blobs.Put(t.Context(), key, bytes.NewReader(vtt), int64(len(vtt)), "text/vtt; charset=utf-8"); err != nil {
+		t.Fatal(err)
+	}
+	info, err := blobs.Head(t.Context(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, inm := range []string{`"other", ` + info.ETag, "*"} {
+		mock.ExpectQuery(`SELECT b.id::text, b.slug, b.audio_key`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "slug", "audio_key", "audio_duration_ms"}).
+				AddRow("b-1", "dune", "books/dune.mp3", 60000))
+		req := httptest.NewRequest(http.MethodGet, "/books/dune/audio/captions.vtt", nil)
+		req.SetPathValue("key", "dune")
+		req.Header.Set("If-None-Match", inm)
+		rec := httptest.NewRecorder()
+		GetCaptions(db, blobs).ServeHTTP(rec, req)
+		if rec.Code != http.StatusNotModified || rec.Header().Get("ETag") != info.ETag {
+			t.Fatalf("If-None-Match %s: %d ETag %q", inm, rec.Code, rec.Header().Get("ETag"))
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,72 @@
+package books
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	httpx "github.com/5w1tchy/books-api/internal/api/httpx"
+	storebooks "github.com/5w1tchy/books-api/internal/store/books"
+)
+
+// GET /books/{key}/audio/chapters
+func GetAudioChapters(db *sql.DB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ref, err := storebooks.GetAudioRef(r.Context(), db, r.PathValue("key"))
+		if err == sql.ErrNoRows {
+			httpx.ErrorJSON(w, http.StatusNotFound, "book not found")
+			return
+		} else if err != nil {
+			httpx.ErrorJSON(w, http.StatusInternalServerError, "failed to load book")
+			return
+		}
+
+		chapters, err := storebooks.ListAudioChapters(r.Context(), db, ref.BookID)
+		if err != nil {
+			log.Printf("[audio chapters] list %s: %v", ref.BookID, err)
+			httpx.ErrorJSON(w, http.StatusInternalServerError, "failed to list chapters")
+			return
+		}
+		httpx.OK(w, chapters)
+	})
+}
+
+// PUT /admin/books/{key}/audio/chapters
+// Body: {"chapters":[{"title":"Intro","start_seconds":0}, ...]} replaces the full list.
+func AdminPutAudioChapters(db *sql.DB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ref, err := storebooks.GetAudioRef(r.Context(), db, r.PathValue("key"))
+		if err == sql.ErrNoRows {
+			httpx.ErrorJSON(w, http.StatusNotFound, "book not found")
+			return
+		} else if err != nil {
+			httpx.ErrorJSON(w, http.StatusInternalServerError, "failed to load book")
+			return
+		}
+		if ref.AudioKey == "" {
+			httpx.ErrorJSON(w, http.StatusConflict, "book has no audio")
+			return
+		}
+
+		var req struct {
+			Chapters []storebooks.AudioChapter `json:"chapters"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			httpx.ErrorJSON(w, http.StatusBadRequest, "invalid JSON")
+			return
+		}
+
+		chapters, err := storebooks.ReplaceAudioChapters(r.Context(), db, ref.BookID, req.Chapters, ref.DurationMS)
+		if errors.Is(err, storebooks.ErrInvalidChapters) {
+			httpx.ErrorJSON(w, http.StatusBadRequest, err.Error())
+			return
+		} else if err != nil {
+			log.Printf("[audio chapters] replace %s: %v", ref.BookID, err)
+			httpx.ErrorJSON(w, http.StatusInternalServerError, "failed to save chapters")
+			return
+		}
+		httpx.OK(w, chapters)
+	})
+}
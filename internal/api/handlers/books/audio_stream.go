@@ -0,0 +1,168 @@
+package books
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/5w1tchy/books-api/internal/storage/blob"
+	"github.com/5w1tchy/books-api/internal/store/shared"
+)
+
+// GET /books/{key}/audio/stream
+// Proxies the audio object so playback never hits an expired presigned URL.
+// Supports a single byte range, If-Range and If-None-Match; ETag is passed through.
+func StreamBookAudioHandler(db *sql.DB, blobs blob.BlobStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		cond, arg := shared.ResolveBookKeyCondArg(ctx, r.PathValue("key"))
+		var objectKey sql.NullString
+		err := db.QueryRowContext(ctx, `SELECT audio_key FROM books b WHERE `+cond, arg).Scan(&objectKey)
+		if err == sql.ErrNoRows {
+			http.Error(w, `{"error":"book not found"}`, http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, `{"error":"database error"}`, http.StatusInternalServerError)
+			return
+		}
+		if !objectKey.Valid || objectKey.String == "" {
+			http.Error(w, `{"error":"book has no audio"}`, http.StatusNotFound)
+			return
+		}
+
+		opt := blob.GetOptions{IfNoneMatch: r.Header.Get("If-None-Match")}
+		var ifRangeDate time.Time
+		if rng, ok := parseByteRange(r.Header.Get("Range")); ok {
+			opt.Range = rng
+			if ir := strings.TrimSpace(r.Header.Get("If-Range")); ir != "" {
+				switch {
+				case strings.HasPrefix(ir, `"`):
+					opt.IfMatch = ir
+				case strings.HasPrefix(ir, "W/"):
+					opt.Range = "" // weak validators never satisfy If-Range
+				default:
+					// A date only matches if it is exactly Last-Modified,
+					// checked once the object is open
+					if t, err := http.ParseTime(ir); err == nil {
+						ifRangeDate = t
+					} else {
+						opt.Range = ""
+					}
+				}
+			}
+		}
+
+		obj, err := blobs.Get(ctx, objectKey.String, opt)
+		if errors.Is(err, blob.ErrPreconditionFailed) && opt.Range != "" {
+			// If-Range validator is stale: send the whole current object.
+			opt.Range, opt.IfMatch = "", ""
+			obj, err = blobs.Get(ctx, objectKey.String, opt)
+		}
+		if err == nil && opt.Range != "" && !ifRangeDate.IsZero() &&
+			!obj.LastModified.Truncate(time.Second).Equal(ifRangeDate) {
+			obj.Body.Close()
+			opt.Range = ""
+			obj, err = blobs.Get(ctx, objectKey.String, opt)
+		}
+		switch {
+		case errors.Is(err, blob.ErrNotModified):
+			// If-None-Match may be a list or *: send the object's own ETag
+			if info, herr := blobs.Head(ctx, objectKey.String); herr == nil && info.ETag != "" {
+				w.Header().Set("ETag", info.ETag)
+			}
+			w.WriteHeader(http.StatusNotModified)
+			return
+		case errors.Is(err, blob.ErrInvalidRange):
+			if info, serr := blobs.Head(ctx, objectKey.String); serr == nil {
+				w.Header().Set("Content-Range", "bytes */"+strconv.FormatInt(info.Size, 10))
+			}
+			http.Error(w, `{"error":"range not satisfiable"}`, http.StatusRequestedRangeNotSatisfiable)
+			return
+		case errors.Is(err, blob.ErrNotFound):
+			http.Error(w, `{"error":"audio object missing"}`, http.StatusNotFound)
+			return
+		case err != nil:
+			log.Printf("[audio] stream %s: %v", objectKey.String, err)
+			http.Error(w, `{"error":"failed to open audio"}`, http.StatusBadGateway)
+			return
+		}
+		defer obj.Body.Close()
+
+		h := w.Header()
+		h.Set("Accept-Ranges", "bytes")
+		h.Set("Cache-Control", "private, no-transform")
+		ct := obj.ContentType
+		if ct == "" || ct == "application/octet-stream" {
+			ct = "audio/mpeg"
+		}
+		h.Set("Content-Type", ct)
+		h.Set("Content-Length", strconv.FormatInt(obj.ContentLength, 10))
+		if obj.ETag != "" {
+			h.Set("ETag", obj.ETag)
+		}
+		if !obj.LastModified.IsZero() {
+			h.Set("Last-Modified", obj.LastModified.UTC().Format(http.TimeFormat))
+		}
+
+		status := http.StatusOK
+		if obj.ContentRange != "" {
+			h.Set("Content-Range", obj.ContentRange)
+			status = http.StatusPartialContent
+		}
+		w.WriteHeader(status)
+		if r.Method == http.MethodHead {
+			return
+		}
+
+		// Long listens outlive the server's WriteTimeout.
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+		if _, err := io.Copy(w, obj.Body); err != nil && ctx.Err() == nil {
+			log.Printf("[audio] stream copy %s: %v", objectKey.String, err)
+		}
+	}
+}
+
+// parseByteRange validates a Range header and returns it normalised when it is a
+// single bytes range. Multi-range and malformed headers are ignored (full 200),
+// which RFC 9110 allows.
+func parseByteRange(h string) (string, bool) {
+	h = strings.TrimSpace(h)
+	spec, ok := strings.CutPrefix(h, "bytes=")
+	if !ok || strings.Contains(spec, ",") {
+		return "", false
+	}
+	first, last, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return "", false
+	}
+	first, last = strings.TrimSpace(first), strings.TrimSpace(last)
+
+	switch {
+	case first == "" && last == "":
+		return "", false
+	case first == "": // suffix: last N bytes
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n <= 0 {
+			return "", false
+		}
+	default:
+		a, err := strconv.ParseInt(first, 10, 64)
+		if err != nil || a < 0 {
+			return "", false
+		}
+		if last != "" {
+			b, err := strconv.ParseInt(last, 10, 64)
+			if err != nil || b < a {
+				return "", false
+			}
+		}
+	}
+	return "bytes=" + first + "-" + last, true
+}
@@ -6,11 +6,11 @@ import (
 	"fmt"
 	"net/http"
 
-	storage "github.com/5w1tchy/books-api/internal/storage/s3"
+	"github.com/5w1tchy/books-api/internal/storage/blob"
 )
 
 // GET /books/{key}/audio
-func GetBookAudioURLHandler(db *sql.DB) http.HandlerFunc {
+func GetBookAudioURLHandler(db *sql.DB, blobs blob.BlobStore) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 		bookKey := r.PathValue("key")
@@ -31,13 +31,7 @@ func GetBookAudioURLHandler(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		r2, err := storage.NewR2Client(ctx)
-		if err != nil {
-			http.Error(w, fmt.Sprintf(`{"error":"%v"}`, err), http.StatusInternalServerError)
-			return
-		}
-
-		url, err := r2.GeneratePresignedDownloadURL(ctx, objectKey)
+		url, err := blobs.PresignGet(ctx, objectKey, blob.DefaultPresignTTL)
 		if err != nil {
 			http.Error(w, fmt.Sprintf(`{"error":"%v"}`, err), http.StatusInternalServerError)
 			return
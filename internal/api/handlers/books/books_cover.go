@@ -5,13 +5,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
-	storage "github.com/5w1tchy/books-api/internal/storage/s3"
+	"github.com/5w1tchy/books-api/internal/storage/blob"
+	storebooks "github.com/5w1tchy/books-api/internal/store/books"
 )
 
 // POST /admin/books/{key}/cover
-func UploadBookCoverHandler(db *sql.DB) http.HandlerFunc {
+func UploadBookCoverHandler(db *sql.DB, blobs blob.BlobStore) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 		bookKey := r.PathValue("key")
@@ -21,12 +23,8 @@ func UploadBookCoverHandler(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		// Get existing cover_url to delete old one
-		var oldCoverURL sql.NullString
-		err := db.QueryRowContext(ctx, `
-			SELECT cover_url FROM books WHERE id::text = $1 OR slug = $1
-		`, bookKey).Scan(&oldCoverURL)
-		
+		// Resolve the book and its current cover (deleted after the swap)
+		ref, err := storebooks.GetCoverRef(ctx, db, bookKey)
 		if err == sql.ErrNoRows {
 			http.Error(w, `{"error":"book not found"}`, http.StatusNotFound)
 			return
@@ -56,67 +54,69 @@ func UploadBookCoverHandler(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		r2, err := storage.NewR2Client(ctx)
+		// Decode it; the stored type follows the real encoding, not the header
+		data, err := readCover(file)
 		if err != nil {
-			http.Error(w, fmt.Sprintf(`{"error":"%v"}`, err), http.StatusInternalServerError)
+			http.Error(w, fmt.Sprintf(`{"error":"%v"}`, err), http.StatusBadRequest)
 			return
 		}
-
-		// Generate object key
-		objectKey := fmt.Sprintf("books/covers/%s-%d.webp", bookKey, time.Now().Unix())
-
-		// Upload to R2
-		if err := uploadFileToR2(ctx, r2, objectKey, file, contentType, header.Size); err != nil {
-			http.Error(w, fmt.Sprintf(`{"error":"failed to upload: %v"}`, err), http.StatusInternalServerError)
+		processed, err := processCover(data)
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":"%v"}`, err), http.StatusBadRequest)
 			return
 		}
 
-		// Save in DB
-		result, err := db.ExecContext(ctx, `
-			UPDATE books
-			SET cover_url = $1
-			WHERE id::text = $2 OR slug = $2
-		`, objectKey, bookKey)
+		// Upload original + resized variants
+		img, err := storeCover(ctx, blobs, ref.Slug, time.Now().Unix(), data, processed)
 		if err != nil {
-			// Try to cleanup uploaded file
-			_ = r2.DeleteObject(ctx, objectKey)
-			http.Error(w, fmt.Sprintf(`{"error":"failed to save cover key: %v"}`, err), http.StatusInternalServerError)
+			http.Error(w, fmt.Sprintf(`{"error":"failed to upload: %v"}`, err), http.StatusInternalServerError)
 			return
 		}
+		objectKey := img.Key
 
-		rows, _ := result.RowsAffected()
-		if rows == 0 {
-			// Cleanup uploaded file
-			_ = r2.DeleteObject(ctx, objectKey)
-			http.Error(w, `{"error":"book not found"}`, http.StatusNotFound)
+		// Save in DB
+		if _, err := storebooks.SwapCover(ctx, db, ref.BookID, img); err != nil {
+			// Try to cleanup uploaded files
+			storebooks.DeleteCoverObjects(ctx, blobs, objectKey)
+			if err == sql.ErrNoRows {
+				http.Error(w, `{"error":"book not found"}`, http.StatusNotFound)
+				return
+			}
+			http.Error(w, fmt.Sprintf(`{"error":"failed to save cover key: %v"}`, err), http.StatusInternalServerError)
 			return
 		}
 
-		// Delete old cover from R2 if it exists
-		if oldCoverURL.Valid && oldCoverURL.String != "" {
-			if err := r2.DeleteObject(ctx, oldCoverURL.String); err != nil {
-				// Log but don't fail - old file deletion is not critical
-				fmt.Printf("Warning: failed to delete old cover %s: %v\n", oldCoverURL.String, err)
-			}
+		// Delete old cover (and its variants) if it exists
+		if ref.Key != "" && ref.Key != objectKey {
+			storebooks.DeleteCoverObjects(ctx, blobs, ref.Key)
 		}
 
 		// Generate download URL
-		downloadURL, err := r2.GeneratePresignedDownloadURL(ctx, objectKey)
+		downloadURL, err := blobs.PresignGet(ctx, objectKey, blob.DefaultPresignTTL)
 		if err != nil {
 			http.Error(w, fmt.Sprintf(`{"error":"uploaded but failed to generate url: %v"}`, err), http.StatusInternalServerError)
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]string{
-			"cover_url":  downloadURL,
-			"object_key": objectKey,
+		widths := make([]int, len(img.Variants))
+		for i, v := range img.Variants {
+			widths[i] = v.Width
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"cover_url":         downloadURL,
+			"object_key":        objectKey,
+			"content_type":      processed.ContentType(),
+			"width":             processed.Width,
+			"height":            processed.Height,
+			"variants":          widths,
+			"cover_placeholder": img.Placeholder,
 		})
 	}
 }
 
-// GET /books/{key}/cover - Redirects to presigned cover URL (just like audio)
-func GetBookCoverURLHandler(db *sql.DB) http.Handler {
+// GET /books/{key}/cover?w=256 - Redirects to presigned cover URL (just like audio)
+func GetBookCoverURLHandler(db *sql.DB, blobs blob.BlobStore) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 		key := r.PathValue("key")
@@ -126,14 +126,18 @@ func GetBookCoverURLHandler(db *sql.DB) http.Handler {
 			return
 		}
 
-		// Get cover_url from database
-		var coverURL sql.NullString
-		err := db.QueryRowContext(ctx, `
-            SELECT cover_url 
-            FROM books 
-            WHERE id::text = $1 OR slug = $1
-        `, key).Scan(&coverURL)
+		// Optional ?w= picks the nearest variant at least that wide
+		width := 0
+		if raw := r.URL.Query().Get("w"); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n <= 0 || n > 10000 {
+				http.Error(w, `{"error":"w must be a positive integer"}`, http.StatusBadRequest)
+				return
+			}
+			width = n
+		}
 
+		ref, err := storebooks.GetCoverRef(ctx, db, key)
 		if err == sql.ErrNoRows {
 			http.Error(w, `{"error":"book not found"}`, http.StatusNotFound)
 			return
@@ -143,19 +147,13 @@ func GetBookCoverURLHandler(db *sql.DB) http.Handler {
 			return
 		}
 
-		if !coverURL.Valid || coverURL.String == "" {
+		if ref.Key == "" {
 			http.Error(w, `{"error":"book has no cover"}`, http.StatusNotFound)
 			return
 		}
 
-		// Generate presigned download URL from R2
-		r2, err := storage.NewR2Client(ctx)
-		if err != nil {
-			http.Error(w, fmt.Sprintf(`{"error":"%v"}`, err), http.StatusInternalServerError)
-			return
-		}
-
-		downloadURL, err := r2.GeneratePresignedDownloadURL(ctx, coverURL.String)
+		// Generate presigned download URL
+		downloadURL, err := blobs.PresignGet(ctx, ref.VariantFor(width), blob.DefaultPresignTTL)
 		if err != nil {
 			http.Error(w, fmt.Sprintf(`{"error":"failed to generate url: %v"}`, err), http.StatusInternalServerError)
 			return
@@ -0,0 +1,53 @@
+package books
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/5w1tchy/books-api/internal/media/cover"
+	"github.com/5w1tchy/books-api/internal/storage/blob"
+	storebooks "github.com/5w1tchy/books-api/internal/store/books"
+)
+
+// errInvalidCover wraps decode failures so handlers can answer 400/422.
+var errInvalidCover = errors.New("invalid cover image")
+
+// readCover reads an uploaded cover, rejecting anything over maxCoverSize.
+func readCover(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxCoverSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxCoverSize {
+		return nil, fmt.Errorf("%w: larger than %d bytes", errInvalidCover, maxCoverSize)
+	}
+	return data, nil
+}
+
+// processCover decodes an uploaded cover and renders its variants.
+func processCover(data []byte) (*cover.Processed, error) {
+	p, err := cover.Process(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidCover, err)
+	}
+	return p, nil
+}
+
+// storeCover uploads the original under books/covers/<name>-<ts><ext>, using
+// the extension and Content-Type of its real encoding, plus its variants, and
+// returns them with the cover's placeholder.
+func storeCover(ctx context.Context, blobs blob.BlobStore, name string, ts int64, data []byte, p *cover.Processed) (storebooks.CoverImage, error) {
+	key := fmt.Sprintf("books/covers/%s-%d%s", name, ts, p.Ext())
+	if err := blobs.Put(ctx, key, bytes.NewReader(data), int64(len(data)), p.ContentType()); err != nil {
+		return storebooks.CoverImage{}, err
+	}
+	variants, err := storebooks.StoreCoverVariants(ctx, blobs, key, p)
+	if err != nil {
+		_ = blobs.Delete(ctx, key)
+		return storebooks.CoverImage{}, err
+	}
+	return storebooks.CoverImage{Key: key, Placeholder: storebooks.PlaceholderOf(p), Variants: variants}, nil
+}
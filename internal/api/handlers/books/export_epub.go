@@ -0,0 +1,120 @@
+package books
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/5w1tchy/books-api/internal/api/middlewares"
+	"github.com/5w1tchy/books-api/internal/export/epub"
+	"github.com/5w1tchy/books-api/internal/storage/blob"
+	storebooks "github.com/5w1tchy/books-api/internal/store/books"
+	storeuserbooks "github.com/5w1tchy/books-api/internal/store/userbooks"
+)
+
+// ExportEPUB: GET /books/{key}/export.epub[?notes=true]
+// Renders the summary (title, authors, summary, coda, cover) as an EPUB 3 file.
+// With notes=true the caller's own notes/highlights are appended as an appendix.
+func ExportEPUB(db *sql.DB, blobs blob.BlobStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		ctx := r.Context()
+
+		key := r.PathValue("key")
+		if key == "" {
+			w.Header().Set("Content-Type", "application/json")
+			http.Error(w, `{"status":"error","error":"missing key"}`, http.StatusBadRequest)
+			return
+		}
+
+		b, err := storebooks.FetchByKey(ctx, db, key)
+		if err == sql.ErrNoRows {
+			w.Header().Set("Content-Type", "application/json")
+			http.Error(w, `{"status":"error","error":"not found"}`, http.StatusNotFound)
+			return
+		} else if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			http.Error(w, `{"status":"error","error":"failed to fetch"}`, http.StatusInternalServerError)
+			return
+		}
+
+		book := epub.Book{
+			ID:      b.ID,
+			Title:   b.Title,
+			Authors: b.Authors,
+			Summary: b.Summary,
+			Coda:    b.Coda,
+		}
+
+		// Cover is best-effort: a storage hiccup should not block the export.
+		if b.CoverURL != nil && *b.CoverURL != "" {
+			if img, err := loadCover(ctx, blobs, *b.CoverURL); err != nil {
+				log.Printf("[epub] cover %s skipped: %v", *b.CoverURL, err)
+			} else {
+				book.Cover = img
+			}
+		}
+
+		if includeNotes, _ := strconv.ParseBool(r.URL.Query().Get("notes")); includeNotes {
+			userID, _ := middlewares.UserIDFrom(ctx)
+			notes, err := storeuserbooks.GetBookNotes(ctx, db, userID, b.ID)
+			if err != nil {
+				w.Header().Set("Content-Type", "application/json")
+				http.Error(w, `{"status":"error","error":"failed to load notes"}`, http.StatusInternalServerError)
+				return
+			}
+			for _, n := range notes {
+				book.Notes = append(book.Notes, epub.Note{
+					Kind:       n.NoteType,
+					Content:    n.Content,
+					PageNumber: n.PageNumber,
+					CreatedAt:  n.CreatedAt,
+				})
+			}
+		}
+
+		// Render into memory first so a failure can still produce a JSON error.
+		var buf bytes.Buffer
+		if err := epub.Write(&buf, book); err != nil {
+			log.Printf("[epub] render %s failed: %v", b.ID, err)
+			w.Header().Set("Content-Type", "application/json")
+			http.Error(w, `{"status":"error","error":"failed to render epub"}`, http.StatusInternalServerError)
+			return
+		}
+
+		filename := b.Slug
+		if filename == "" {
+			filename = slugifyTitle(b.Title)
+		}
+		w.Header().Set("Content-Type", "application/epub+zip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.epub"`, filename))
+		w.Header().Set("Cache-Control", "private, no-store")
+		_, _ = w.Write(buf.Bytes())
+	}
+}
+
+// loadCover downloads the cover object and resolves a media type EPUB readers accept.
+func loadCover(ctx context.Context, blobs blob.BlobStore, objectKey string) (*epub.Image, error) {
+	data, ct, err := blob.ReadAll(ctx, blobs, objectKey)
+	if err != nil {
+		return nil, err
+	}
+	if ct == "" || !strings.HasPrefix(ct, "image/") {
+		ct = http.DetectContentType(data)
+	}
+	if i := strings.Index(ct, ";"); i >= 0 {
+		ct = strings.TrimSpace(ct[:i])
+	}
+	if !epub.SupportedCover(ct) {
+		return nil, fmt.Errorf("unsupported cover type %q", ct)
+	}
+	return &epub.Image{Data: data, MediaType: ct}, nil
+}
@@ -0,0 +1,36 @@
+package books
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/5w1tchy/books-api/internal/storage/blob"
+)
+
+func TestLoadCover(t *testing.T) {
+	ctx := context.Background()
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	store := blob.NewMemory()
+	put := func(key string, data []byte, ct string) {
+		if err := store.Put(ctx, key, bytes.NewReader(data), int64(len(data)), ct); err != nil {
+			t.Fatal(err)
+		}
+	}
+	put("png-sniffed", png, "application/octet-stream")
+	put("jpeg", []byte("jpeg"), "image/jpeg; charset=binary")
+	put("avif", []byte("avif"), "image/avif")
+	put("junk", []byte("not an image"), "application/octet-stream")
+
+	for key, want := range map[string]string{"png-sniffed": "image/png", "jpeg": "image/jpeg"} {
+		img, err := loadCover(ctx, store, key)
+		if err != nil || img.MediaType != want {
+			t.Errorf("loadCover(%s) = %v, %v; want %s", key, img, err, want)
+		}
+	}
+	for _, key := range []string{"avif", "junk"} {
+		if img, err := loadCover(ctx, store, key); err == nil {
+			t.Errorf("loadCover(%s) = %s; want error", key, img.MediaType)
+		}
+	}
+}
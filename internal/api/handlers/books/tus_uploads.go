@@ -0,0 +1,163 @@
+package books
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/5w1tchy/books-api/internal/media/audio"
+	"github.com/5w1tchy/books-api/internal/storage/blob"
+	storebooks "github.com/5w1tchy/books-api/internal/store/books"
+	"github.com/5w1tchy/books-api/internal/uploads/tus"
+)
+
+// Resumable (tus) upload kinds. The client sends the declared type as
+// Upload-Metadata "filetype"; the object key extension follows it and the
+// finished file must really be that type before the book is touched.
+
+var audioExtByType = map[string]string{
+	"audio/mpeg":  ".mp3",
+	"audio/mp3":   ".mp3",
+	"audio/mp4":   ".m4a",
+	"audio/ogg":   ".ogg",
+	"audio/wav":   ".wav",
+	"audio/x-wav": ".wav",
+}
+
+var coverExtByType = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/jpg":  ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
+func declaredType(meta map[string]string) string {
+	if t := meta["filetype"]; t != "" {
+		return t
+	}
+	return meta["type"]
+}
+
+func resolveUploadBook(r *http.Request, db *sql.DB) (storebooks.AudioRef, error) {
+	ref, err := storebooks.GetAudioRef(r.Context(), db, r.PathValue("key"))
+	if err == sql.ErrNoRows {
+		return ref, tus.Reject(http.StatusNotFound, "book not found")
+	}
+	return ref, err
+}
+
+// TusAudioKind uploads a book's audio (POST /admin/books/{key}/audio/tus).
+func TusAudioKind(db *sql.DB) tus.Kind {
+	return tus.Kind{
+		Name:    "audio",
+		MaxSize: maxAudioSize,
+		Prepare: func(r *http.Request, _ int64, meta map[string]string) (tus.Target, error) {
+			ct := declaredType(meta)
+			ext, ok := audioExtByType[ct]
+			if !ok || !allowedAudioC[ct] {
+				return tus.Target{}, tus.Reject(http.StatusUnsupportedMediaType, "filetype metadata must be a supported audio type")
+			}
+			ref, err := resolveUploadBook(r, db)
+			if err != nil {
+				return tus.Target{}, err
+			}
+			return tus.Target{
+				BookID:      ref.BookID,
+				ObjectKey:   fmt.Sprintf("books/%s-summary-%d%s", ref.Slug, time.Now().Unix(), ext),
+				ContentType: ct,
+			}, nil
+		},
+		Finish: func(ctx context.Context, blobs blob.BlobStore, u *tus.Upload) error {
+			info, err := audio.Probe(blob.NewReaderAt(ctx, blobs, u.ObjectKey), u.Length)
+			if err != nil {
+				return tus.Reject(http.StatusUnprocessableEntity, "invalid audio file: %v", err)
+			}
+			if !audio.Matches(u.ContentType, info) {
+				return tus.Reject(http.StatusUnprocessableEntity, "%v: declared %s, found %s", errAudioMismatch, u.ContentType, info.Format)
+			}
+
+			old, err := storebooks.SwapAudio(ctx, db, u.BookID, u.ObjectKey, audioMeta(info))
+			if err == sql.ErrNoRows {
+				return tus.Reject(http.StatusNotFound, "book not found")
+			} else if err != nil {
+				return err
+			}
+			if old != "" && old != u.ObjectKey {
+				if err := blobs.Delete(ctx, old); err != nil {
+					log.Printf("[tus] failed to delete old audio %s: %v", old, err)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// TusCoverKind uploads a book's cover (POST /admin/books/{key}/cover/tus).
+func TusCoverKind(db *sql.DB) tus.Kind {
+	return tus.Kind{
+		Name:    "cover",
+		MaxSize: maxCoverSize,
+		Prepare: func(r *http.Request, _ int64, meta map[string]string) (tus.Target, error) {
+			ct := declaredType(meta)
+			ext, ok := coverExtByType[ct]
+			if !ok || !allowedCoverC[ct] {
+				return tus.Target{}, tus.Reject(http.StatusUnsupportedMediaType, "filetype metadata must be jpeg, png or webp")
+			}
+			ref, err := resolveUploadBook(r, db)
+			if err != nil {
+				return tus.Target{}, err
+			}
+			return tus.Target{
+				BookID:      ref.BookID,
+				ObjectKey:   fmt.Sprintf("books/covers/%s-%d%s", ref.Slug, time.Now().Unix(), ext),
+				ContentType: canonicalImageType(ct),
+			}, nil
+		},
+		Finish: func(ctx context.Context, blobs blob.BlobStore, u *tus.Upload) error {
+			data, _, err := blob.ReadAll(ctx, blobs, u.ObjectKey)
+			if err != nil {
+				return err
+			}
+			processed, err := processCover(data)
+			if err != nil {
+				return tus.Reject(http.StatusUnprocessableEntity, "%v", err)
+			}
+			if processed.ContentType() != u.ContentType {
+				return tus.Reject(http.StatusUnprocessableEntity, "cover content does not match declared type: declared %s, found %s", u.ContentType, processed.ContentType())
+			}
+			variants, err := storebooks.StoreCoverVariants(ctx, blobs, u.ObjectKey, processed)
+			if err != nil {
+				return err
+			}
+
+			old, err := storebooks.SwapCover(ctx, db, u.BookID, storebooks.CoverImage{
+				Key:         u.ObjectKey,
+				Placeholder: storebooks.PlaceholderOf(processed),
+				Variants:    variants,
+			})
+			if err != nil {
+				for _, v := range variants {
+					_ = blobs.Delete(ctx, v.Key)
+				}
+				if err == sql.ErrNoRows {
+					return tus.Reject(http.StatusNotFound, "book not found")
+				}
+				return err
+			}
+			if old != "" && old != u.ObjectKey {
+				storebooks.DeleteCoverObjects(ctx, blobs, old)
+			}
+			return nil
+		},
+	}
+}
+
+func canonicalImageType(ct string) string {
+	if ct == "image/jpg" {
+		return "image/jpeg"
+	}
+	return ct
+}
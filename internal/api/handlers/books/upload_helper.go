@@ -1,43 +1,40 @@
 package books
 
 import (
-	"context"
+	"errors"
 	"fmt"
+	"io"
 	"mime/multipart"
-	"net/http"
-	"strconv"
-	"time"
 
-	storage "github.com/5w1tchy/books-api/internal/storage/s3"
+	"github.com/5w1tchy/books-api/internal/media/audio"
+	storebooks "github.com/5w1tchy/books-api/internal/store/books"
 )
 
-// uploadFileToR2 creates a presigned PUT url and streams the file to it.
-// - file is the multipart.File opened by r.FormFile
-// - contentType should be validated before calling
-// - contentLength MUST be set (R2 rejects chunked uploads without it)
-func uploadFileToR2(ctx context.Context, r2 *storage.S3Client, objectKey string, file multipart.File, contentType string, contentLength int64) error {
-	uploadURL, err := r2.GeneratePresignedUploadURL(ctx, objectKey, contentType)
-	if err != nil {
-		return fmt.Errorf("generate presigned upload url: %w", err)
-	}
+// errAudioMismatch means the declared Content-Type disagrees with the file header.
+var errAudioMismatch = errors.New("audio content type does not match file")
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPut, uploadURL, file)
+// probeAudioUpload verifies an uploaded audio file by its header and rewinds it
+// for upload. declared may be empty; otherwise it must agree with the real format.
+func probeAudioUpload(file multipart.File, size int64, declared string) (audio.Info, error) {
+	info, err := audio.Probe(file, size)
 	if err != nil {
-		return fmt.Errorf("create put request: %w", err)
+		return audio.Info{}, err
 	}
-	req.Header.Set("Content-Type", contentType)
-	req.Header.Set("Content-Length", strconv.FormatInt(contentLength, 10))
-	req.ContentLength = contentLength // ensure no chunked encoding
-
-	client := &http.Client{Timeout: 2 * time.Minute}
-	resp, err := client.Do(req)
-	if err != nil {
-		return fmt.Errorf("put to r2 failed: %w", err)
+	if !audio.Matches(declared, info) {
+		return audio.Info{}, fmt.Errorf("%w: declared %s, found %s", errAudioMismatch, declared, info.Format)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return audio.Info{}, err
 	}
-	defer resp.Body.Close()
+	return info, nil
+}
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("r2 upload failed status=%d", resp.StatusCode)
+func audioMeta(info audio.Info) storebooks.AudioMeta {
+	return storebooks.AudioMeta{
+		Format:     info.Format,
+		DurationMS: info.Duration.Milliseconds(),
+		Bitrate:    info.Bitrate,
+		SampleRate: info.SampleRate,
+		Channels:   info.Channels,
 	}
-	return nil
 }
@@ -0,0 +1,305 @@
+package feeds
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	storebooks "github.com/5w1tchy/books-api/internal/store/books"
+	"github.com/5w1tchy/books-api/internal/store/foryou"
+	"github.com/5w1tchy/books-api/internal/syndication"
+)
+
+// Public Atom/RSS feeds: newest books, per category, per author and today's shorts.
+// Every route takes a trailing {format} segment: "atom" or "rss".
+
+const (
+	typeAtom = "application/atom+xml; charset=utf-8"
+	typeRSS  = "application/rss+xml; charset=utf-8"
+
+	feedLimit    = 30
+	excerptLen   = 400
+	maxAge       = 600 // seconds
+	siteName     = "Books API"
+	tagAuthority = "books-api"
+)
+
+type Handler struct {
+	DB *sql.DB
+}
+
+func New(db *sql.DB) *Handler { return &Handler{DB: db} }
+
+// item is the format-neutral shape both encoders are built from.
+type item struct {
+	ID         string // stable Atom id / RSS guid
+	Title      string
+	Link       string // absolute, see syndication.BookPageURL
+	Summary    string
+	Authors    []string
+	Categories []string
+	Published  time.Time
+	Updated    time.Time
+}
+
+type channel struct {
+	ID          string
+	Title       string
+	Description string
+	Path        string // self path without the format segment
+	Items       []item
+}
+
+// GET /feeds/new/{format}
+func (h *Handler) Newest(w http.ResponseWriter, r *http.Request) {
+	format, ok := parseFormat(w, r)
+	if !ok {
+		return
+	}
+	books, err := foryou.BuildNewest(r.Context(), h.DB, feedLimit, foryou.Fields{IncludeSummary: true})
+	if err != nil {
+		log.Printf("[feeds] newest: %v", err)
+		http.Error(w, "failed to load books", http.StatusInternalServerError)
+		return
+	}
+	ch := channel{
+		ID:          "urn:books-api:feeds:new",
+		Title:       siteName + ": new books",
+		Description: "Most recently added book summaries",
+		Path:        "/feeds/new",
+	}
+	for _, b := range books {
+		ch.Items = append(ch.Items, item{
+			ID:         "urn:uuid:" + b.ID,
+			Title:      b.Title,
+			Link:       syndication.BookPageURL(r, b.Slug),
+			Summary:    syndication.Excerpt(b.Summary, excerptLen),
+			Authors:    []string{b.Author},
+			Categories: b.CategorySlugs,
+			Published:  b.CreatedAt,
+			Updated:    b.UpdatedAt,
+		})
+	}
+	h.write(w, r, format, ch)
+}
+
+// GET /feeds/categories/{slug}/{format}
+func (h *Handler) Category(w http.ResponseWriter, r *http.Request) {
+	format, ok := parseFormat(w, r)
+	if !ok {
+		return
+	}
+	slug := strings.ToLower(strings.TrimSpace(r.PathValue("slug")))
+	ref, err := storebooks.GetCategoryRef(r.Context(), h.DB, slug)
+	if err == sql.ErrNoRows {
+		http.Error(w, "category not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "failed to load category", http.StatusInternalServerError)
+		return
+	}
+	ch := channel{
+		ID:          "urn:books-api:feeds:category:" + ref.Slug,
+		Title:       siteName + ": " + ref.Name,
+		Description: "New book summaries in " + ref.Name,
+		Path:        "/feeds/categories/" + url.PathEscape(ref.Slug),
+	}
+	h.listed(w, r, format, ch, storebooks.ListFilters{Categories: []string{ref.Slug}})
+}
+
+// GET /feeds/authors/{slug}/{format}
+func (h *Handler) Author(w http.ResponseWriter, r *http.Request) {
+	format, ok := parseFormat(w, r)
+	if !ok {
+		return
+	}
+	slug := strings.ToLower(strings.TrimSpace(r.PathValue("slug")))
+	ref, err := storebooks.GetAuthorRef(r.Context(), h.DB, slug)
+	if err == sql.ErrNoRows {
+		http.Error(w, "author not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "failed to load author", http.StatusInternalServerError)
+		return
+	}
+	ch := channel{
+		ID:          "urn:books-api:feeds:author:" + ref.Slug,
+		Title:       siteName + ": " + ref.Name,
+		Description: "New book summaries by " + ref.Name,
+		Path:        "/feeds/authors/" + url.PathEscape(ref.Slug),
+	}
+	h.listed(w, r, format, ch, storebooks.ListFilters{Authors: []string{ref.Slug}})
+}
+
+// GET /feeds/shorts/{format}
+// The shorts the for-you section has featured today (Asia/Tbilisi day
+// boundary). Only for-you picks them; polling the feed never does.
+func (h *Handler) Shorts(w http.ResponseWriter, r *http.Request) {
+	format, ok := parseFormat(w, r)
+	if !ok {
+		return
+	}
+	shorts, day, err := foryou.TodayShorts(r.Context(), h.DB, feedLimit)
+	if err != nil {
+		log.Printf("[feeds] shorts: %v", err)
+		http.Error(w, "failed to load shorts", http.StatusInternalServerError)
+		return
+	}
+	ch := channel{
+		ID:          "urn:books-api:feeds:shorts",
+		Title:       siteName + ": today's shorts",
+		Description: "Short takeaways picked for " + day.Format("2006-01-02"),
+		Path:        "/feeds/shorts",
+	}
+	for _, s := range shorts {
+		ch.Items = append(ch.Items, item{
+			// One entry per book per day, so readers see each day's pick as new.
+			ID:        "tag:" + tagAuthority + "," + day.Format("2006-01-02") + ":shorts/" + s.Book.ID,
+			Title:     s.Book.Title,
+			Link:      syndication.BookPageURL(r, s.Book.Slug),
+			Summary:   shortText(s.Content),
+			Authors:   []string{s.Book.Author},
+			Published: day,
+			Updated:   day,
+		})
+	}
+	h.write(w, r, format, ch)
+}
+
+// ---------- builders ----------
+
+func (h *Handler) listed(w http.ResponseWriter, r *http.Request, format string, ch channel, filt storebooks.ListFilters) {
+	filt.Limit = feedLimit
+	items, _, err := storebooks.List(r.Context(), h.DB, filt)
+	if err != nil {
+		log.Printf("[feeds] list: %v", err)
+		http.Error(w, "failed to list books", http.StatusInternalServerError)
+		return
+	}
+	for _, b := range items {
+		ch.Items = append(ch.Items, item{
+			ID:         "urn:uuid:" + b.ID,
+			Title:      b.Title,
+			Link:       syndication.BookPageURL(r, b.Slug),
+			Authors:    b.Authors,
+			Categories: b.CategorySlugs,
+			Published:  b.CreatedAt,
+			Updated:    b.UpdatedAt,
+		})
+	}
+	h.write(w, r, format, ch)
+}
+
+func (h *Handler) write(w http.ResponseWriter, r *http.Request, format string, ch channel) {
+	base := syndication.BaseURL(r)
+	updated := time.Unix(0, 0).UTC()
+	for _, it := range ch.Items {
+		if it.Updated.After(updated) {
+			updated = it.Updated
+		}
+	}
+
+	var doc any
+	contentType := typeAtom
+	if format == "rss" {
+		doc = buildRSS(base, ch, updated)
+		contentType = typeRSS
+	} else {
+		doc = buildAtom(base, ch, updated)
+	}
+
+	body, err := syndication.Render(doc)
+	if err != nil {
+		log.Printf("[feeds] render: %v", err)
+		http.Error(w, "failed to render feed", http.StatusInternalServerError)
+		return
+	}
+	syndication.WriteCached(w, r, contentType, body, updated, maxAge)
+}
+
+func buildAtom(base string, ch channel, updated time.Time) syndication.Feed {
+	f := syndication.Feed{
+		ID:       ch.ID,
+		Title:    ch.Title,
+		Subtitle: ch.Description,
+		Updated:  syndication.TimeString(updated),
+		Author:   &syndication.Person{Name: siteName, URI: base},
+		Links: []syndication.Link{
+			{Rel: "self", Href: base + ch.Path + "/atom", Type: "application/atom+xml"},
+			{Rel: "alternate", Href: base + ch.Path + "/rss", Type: "application/rss+xml"},
+		},
+	}
+	for _, it := range ch.Items {
+		e := syndication.Entry{
+			ID:        it.ID,
+			Title:     it.Title,
+			Updated:   syndication.TimeString(it.Updated),
+			Published: syndication.TimeString(it.Published),
+			Links:     []syndication.Link{{Rel: "alternate", Href: it.Link, Type: "text/html"}},
+		}
+		for _, a := range it.Authors {
+			e.Authors = append(e.Authors, syndication.Person{Name: a})
+		}
+		for _, c := range it.Categories {
+			e.Categories = append(e.Categories, syndication.Category{Term: c, Label: c})
+		}
+		if it.Summary != "" {
+			e.Summary = &syndication.Text{Type: "text", Value: it.Summary}
+		}
+		f.Entries = append(f.Entries, e)
+	}
+	return f
+}
+
+func buildRSS(base string, ch channel, updated time.Time) syndication.RSS {
+	doc := syndication.RSS{
+		Version:   "2.0",
+		XMLNSAtom: syndication.NSAtom,
+		XMLNSDC:   "http://purl.org/dc/elements/1.1/",
+		Channel: syndication.RSSChannel{
+			Title:         ch.Title,
+			Link:          base,
+			Description:   ch.Description,
+			Language:      "en",
+			LastBuildDate: syndication.RFC822(updated),
+			TTL:           maxAge / 60,
+			AtomLink:      &syndication.AtomLink{Href: base + ch.Path + "/rss", Rel: "self", Type: "application/rss+xml"},
+		},
+	}
+	for _, it := range ch.Items {
+		doc.Channel.Items = append(doc.Channel.Items, syndication.RSSItem{
+			Title:       it.Title,
+			Link:        it.Link,
+			Description: it.Summary,
+			Creator:     strings.Join(it.Authors, ", "),
+			Categories:  it.Categories,
+			GUID:        syndication.RSSGUID{IsPermaLink: "false", Value: it.ID},
+			PubDate:     syndication.RFC822(it.Published),
+		})
+	}
+	return doc
+}
+
+// ---------- helpers ----------
+
+func parseFormat(w http.ResponseWriter, r *http.Request) (string, bool) {
+	switch f := r.PathValue("format"); f {
+	case "atom", "rss":
+		return f, true
+	}
+	http.Error(w, "unknown feed format (use atom or rss)", http.StatusNotFound)
+	return "", false
+}
+
+// shortText unwraps b.short::text, which is stored as a JSON string.
+func shortText(raw string) string {
+	var s string
+	if err := json.Unmarshal([]byte(raw), &s); err == nil {
+		return s
+	}
+	return raw
+}
@@ -0,0 +1,308 @@
+package opds
+
+import (
+	"database/sql"
+	"encoding/xml"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	storebooks "github.com/5w1tchy/books-api/internal/store/books"
+	"github.com/5w1tchy/books-api/internal/syndication"
+	"github.com/5w1tchy/books-api/internal/validate"
+)
+
+// OPDS 1.2 catalog: navigation feeds (root, categories, authors) and
+// acquisition feeds (new, per category, per author, search) over storebooks.List.
+
+const (
+	typeNavigation  = "application/atom+xml;profile=opds-catalog;kind=navigation"
+	typeAcquisition = "application/atom+xml;profile=opds-catalog;kind=acquisition"
+	typeOpenSearch  = "application/opensearchdescription+xml"
+
+	relAcquisition = "http://opds-spec.org/acquisition"
+	relImage       = "http://opds-spec.org/image"
+	relThumbnail   = "http://opds-spec.org/image/thumbnail"
+	relSortNew     = "http://opds-spec.org/sort/new"
+
+	pageSize    = 50
+	catalogName = "Books API Catalog"
+)
+
+type Handler struct {
+	DB *sql.DB
+}
+
+func New(db *sql.DB) *Handler { return &Handler{DB: db} }
+
+// GET /opds
+func (h *Handler) Root(w http.ResponseWriter, r *http.Request) {
+	now := syndication.TimeString(time.Now())
+	f := newFeed("urn:books-api:opds:root", catalogName, "/opds", typeNavigation)
+	f.Updated = now
+	f.Entries = []syndication.Entry{
+		navEntry("urn:books-api:opds:new", "New books", "Most recently added summaries", "/opds/new", typeAcquisition, relSortNew, now),
+		navEntry("urn:books-api:opds:categories", "By category", "Browse summaries by category", "/opds/categories", typeNavigation, "subsection", now),
+		navEntry("urn:books-api:opds:authors", "By author", "Browse summaries by author", "/opds/authors", typeNavigation, "subsection", now),
+	}
+	writeFeed(w, typeNavigation, f)
+}
+
+// GET /opds/new
+func (h *Handler) Newest(w http.ResponseWriter, r *http.Request) {
+	f := newFeed("urn:books-api:opds:new", "New books", "/opds/new", typeAcquisition)
+	h.acquisition(w, r, f, storebooks.ListFilters{})
+}
+
+// GET /opds/categories
+func (h *Handler) Categories(w http.ResponseWriter, r *http.Request) {
+	refs, err := storebooks.ListCategoryRefs(r.Context(), h.DB)
+	if err != nil {
+		log.Printf("[opds] categories: %v", err)
+		http.Error(w, "failed to list categories", http.StatusInternalServerError)
+		return
+	}
+	f := newFeed("urn:books-api:opds:categories", "By category", "/opds/categories", typeNavigation)
+	h.navigation(w, f, refs, "category", "/opds/categories/")
+}
+
+// GET /opds/categories/{slug}
+func (h *Handler) Category(w http.ResponseWriter, r *http.Request) {
+	slug := strings.ToLower(strings.TrimSpace(r.PathValue("slug")))
+	ref, err := storebooks.GetCategoryRef(r.Context(), h.DB, slug)
+	if err == sql.ErrNoRows {
+		http.Error(w, "category not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "failed to load category", http.StatusInternalServerError)
+		return
+	}
+	self := "/opds/categories/" + url.PathEscape(ref.Slug)
+	f := newFeed("urn:books-api:opds:category:"+ref.Slug, ref.Name, self, typeAcquisition)
+	h.acquisition(w, r, f, storebooks.ListFilters{Categories: []string{ref.Slug}})
+}
+
+// GET /opds/authors
+func (h *Handler) Authors(w http.ResponseWriter, r *http.Request) {
+	refs, err := storebooks.ListAuthorRefs(r.Context(), h.DB)
+	if err != nil {
+		log.Printf("[opds] authors: %v", err)
+		http.Error(w, "failed to list authors", http.StatusInternalServerError)
+		return
+	}
+	f := newFeed("urn:books-api:opds:authors", "By author", "/opds/authors", typeNavigation)
+	h.navigation(w, f, refs, "author", "/opds/authors/")
+}
+
+// GET /opds/authors/{slug}
+func (h *Handler) Author(w http.ResponseWriter, r *http.Request) {
+	slug := strings.ToLower(strings.TrimSpace(r.PathValue("slug")))
+	ref, err := storebooks.GetAuthorRef(r.Context(), h.DB, slug)
+	if err == sql.ErrNoRows {
+		http.Error(w, "author not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "failed to load author", http.StatusInternalServerError)
+		return
+	}
+	self := "/opds/authors/" + url.PathEscape(ref.Slug)
+	f := newFeed("urn:books-api:opds:author:"+ref.Slug, ref.Name, self, typeAcquisition)
+	h.acquisition(w, r, f, storebooks.ListFilters{Authors: []string{ref.Slug}})
+}
+
+// GET /opds/search?q=
+func (h *Handler) Search(w http.ResponseWriter, r *http.Request) {
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		http.Error(w, "missing q", http.StatusBadRequest)
+		return
+	}
+	self := "/opds/search?q=" + url.QueryEscape(q)
+	f := newFeed("urn:books-api:opds:search:"+url.QueryEscape(q), "Search: "+q, self, typeAcquisition)
+	h.acquisition(w, r, f, storebooks.ListFilters{Q: q, MinSim: validate.ParseMinSim(q, "")})
+}
+
+// GET /opds/opensearch.xml
+func (h *Handler) OpenSearch(w http.ResponseWriter, r *http.Request) {
+	type osURL struct {
+		Type     string `xml:"type,attr"`
+		Template string `xml:"template,attr"`
+	}
+	doc := struct {
+		XMLName        xml.Name `xml:"http://a9.com/-/spec/opensearch/1.1/ OpenSearchDescription"`
+		ShortName      string   `xml:"ShortName"`
+		Description    string   `xml:"Description"`
+		InputEncoding  string   `xml:"InputEncoding"`
+		OutputEncoding string   `xml:"OutputEncoding"`
+		URL            osURL    `xml:"Url"`
+	}{
+		ShortName:      "Books",
+		Description:    "Search book summaries by title or author",
+		InputEncoding:  "UTF-8",
+		OutputEncoding: "UTF-8",
+		URL:            osURL{Type: typeAcquisition, Template: "/opds/search?q={searchTerms}"},
+	}
+	w.Header().Set("Content-Type", typeOpenSearch+"; charset=utf-8")
+	if err := syndication.WriteXML(w, doc); err != nil {
+		log.Printf("[opds] write opensearch: %v", err)
+	}
+}
+
+// ---------- feed builders ----------
+
+func (h *Handler) acquisition(w http.ResponseWriter, r *http.Request, f syndication.Feed, filt storebooks.ListFilters) {
+	page := 1
+	if p, err := strconv.Atoi(r.URL.Query().Get("page")); err == nil && p > 1 {
+		page = p
+	}
+	filt.Limit = pageSize
+	filt.Offset = (page - 1) * pageSize
+
+	items, total, err := storebooks.List(r.Context(), h.DB, filt)
+	if err != nil {
+		log.Printf("[opds] list: %v", err)
+		http.Error(w, "failed to list books", http.StatusInternalServerError)
+		return
+	}
+
+	var newest time.Time
+	for _, b := range items {
+		f.Entries = append(f.Entries, bookEntry(b))
+		if b.UpdatedAt.After(newest) {
+			newest = b.UpdatedAt
+		}
+	}
+	if newest.IsZero() {
+		newest = time.Now()
+	}
+	f.Updated = syndication.TimeString(newest)
+
+	f.XMLNSOS = syndication.NSOpenSearch
+	f.TotalResults = total
+	f.ItemsPerPage = pageSize
+	f.StartIndex = filt.Offset + 1
+
+	self := selfHref(f)
+	f.Links = append(f.Links, syndication.Link{Rel: "first", Href: pageHref(self, 1), Type: typeAcquisition})
+	if page > 1 {
+		f.Links = append(f.Links, syndication.Link{Rel: "previous", Href: pageHref(self, page-1), Type: typeAcquisition})
+	}
+	if filt.Offset+len(items) < total {
+		f.Links = append(f.Links, syndication.Link{Rel: "next", Href: pageHref(self, page+1), Type: typeAcquisition})
+	}
+	if page > 1 {
+		for i := range f.Links {
+			if f.Links[i].Rel == "self" {
+				f.Links[i].Href = pageHref(self, page)
+			}
+		}
+	}
+
+	writeFeed(w, typeAcquisition, f)
+}
+
+func (h *Handler) navigation(w http.ResponseWriter, f syndication.Feed, refs []storebooks.Ref, kind, prefix string) {
+	var newest time.Time
+	for _, ref := range refs {
+		content := strconv.Itoa(ref.Books) + " book"
+		if ref.Books != 1 {
+			content += "s"
+		}
+		f.Entries = append(f.Entries, navEntry(
+			"urn:books-api:opds:"+kind+":"+ref.Slug,
+			ref.Name, content,
+			prefix+url.PathEscape(ref.Slug), typeAcquisition, "subsection",
+			syndication.TimeString(ref.Updated),
+		))
+		if ref.Updated.After(newest) {
+			newest = ref.Updated
+		}
+	}
+	if newest.IsZero() {
+		newest = time.Now()
+	}
+	f.Updated = syndication.TimeString(newest)
+	writeFeed(w, typeNavigation, f)
+}
+
+func newFeed(id, title, self, selfType string) syndication.Feed {
+	return syndication.Feed{
+		XMLNSOPDS: syndication.NSOPDS,
+		XMLNSDC:   syndication.NSDC,
+		ID:        id,
+		Title:     title,
+		Author:    &syndication.Person{Name: catalogName},
+		Links: []syndication.Link{
+			{Rel: "self", Href: self, Type: selfType},
+			{Rel: "start", Href: "/opds", Type: typeNavigation, Title: catalogName},
+			{Rel: "search", Href: "/opds/opensearch.xml", Type: typeOpenSearch},
+		},
+	}
+}
+
+func navEntry(id, title, content, href, typ, rel, updated string) syndication.Entry {
+	return syndication.Entry{
+		ID:      id,
+		Title:   title,
+		Updated: updated,
+		Content: &syndication.Text{Type: "text", Value: content},
+		Links:   []syndication.Link{{Rel: rel, Href: href, Type: typ}},
+	}
+}
+
+func bookEntry(b storebooks.PublicBook) syndication.Entry {
+	e := syndication.Entry{
+		ID:        "urn:uuid:" + b.ID,
+		Title:     b.Title,
+		Updated:   syndication.TimeString(b.UpdatedAt),
+		Published: syndication.TimeString(b.CreatedAt),
+		Issued:    b.CreatedAt.UTC().Format("2006-01-02"),
+	}
+	for _, a := range b.Authors {
+		e.Authors = append(e.Authors, syndication.Person{Name: a})
+	}
+	for _, c := range b.CategorySlugs {
+		e.Categories = append(e.Categories, syndication.Category{Term: c, Label: c})
+	}
+
+	base := "/books/" + url.PathEscape(b.Slug)
+	e.Links = append(e.Links, syndication.Link{Rel: relAcquisition, Href: base + "/export.epub", Type: "application/epub+zip"})
+	if b.CoverURL != nil && *b.CoverURL != "" {
+		e.Links = append(e.Links,
+			syndication.Link{Rel: relImage, Href: base + "/cover"},
+			syndication.Link{Rel: relThumbnail, Href: base + "/cover"},
+		)
+	}
+	return e
+}
+
+func selfHref(f syndication.Feed) string {
+	for _, l := range f.Links {
+		if l.Rel == "self" {
+			return l.Href
+		}
+	}
+	return ""
+}
+
+func pageHref(self string, page int) string {
+	if page <= 1 {
+		return self
+	}
+	sep := "?"
+	if strings.Contains(self, "?") {
+		sep = "&"
+	}
+	return self + sep + "page=" + strconv.Itoa(page)
+}
+
+func writeFeed(w http.ResponseWriter, contentType string, f syndication.Feed) {
+	w.Header().Set("Content-Type", contentType+"; charset=utf-8")
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	if err := syndication.WriteXML(w, f); err != nil {
+		log.Printf("[opds] write feed: %v", err)
+	}
+}
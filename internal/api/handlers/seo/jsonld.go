@@ -0,0 +1,96 @@
+package seo
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+
+	storebooks "github.com/5w1tchy/books-api/internal/store/books"
+	"github.com/5w1tchy/books-api/internal/syndication"
+)
+
+// schema.org Book (https://schema.org/Book) as JSON-LD.
+type ldBook struct {
+	Context         string        `json:"@context"`
+	Type            string        `json:"@type"`
+	ID              string        `json:"@id"`
+	URL             string        `json:"url"`
+	Name            string        `json:"name"`
+	Author          []ldPerson    `json:"author,omitempty"`
+	Genre           []string      `json:"genre,omitempty"`
+	Image           string        `json:"image,omitempty"`
+	Abstract        string        `json:"abstract,omitempty"`
+	InLanguage      string        `json:"inLanguage"`
+	DatePublished   string        `json:"datePublished,omitempty"`
+	DateModified    string        `json:"dateModified,omitempty"`
+	AggregateRating *ldRating     `json:"aggregateRating,omitempty"`
+	Identifier      *ldIdentifier `json:"identifier,omitempty"`
+}
+
+type ldPerson struct {
+	Type string `json:"@type"`
+	Name string `json:"name"`
+	URL  string `json:"url,omitempty"`
+}
+
+// ldRating is emitted only when a book has ratings; there is no ratings source yet,
+// so it stays nil until one exists.
+type ldRating struct {
+	Type        string  `json:"@type"`
+	RatingValue float64 `json:"ratingValue"`
+	RatingCount int     `json:"ratingCount"`
+	BestRating  int     `json:"bestRating"`
+	WorstRating int     `json:"worstRating"`
+}
+
+type ldIdentifier struct {
+	Type       string `json:"@type"`
+	PropertyID string `json:"propertyID"`
+	Value      string `json:"value"`
+}
+
+// GET /books/{key}/jsonld
+func (h *Handler) BookJSONLD(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	b, err := storebooks.FetchSchemaBook(r.Context(), h.DB, key)
+	if err == sql.ErrNoRows {
+		http.Error(w, "book not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("[seo] jsonld %s: %v", key, err)
+		http.Error(w, "failed to load book", http.StatusInternalServerError)
+		return
+	}
+
+	page := syndication.BookPageURL(r, b.Slug)
+	doc := ldBook{
+		Context:       "https://schema.org",
+		Type:          "Book",
+		ID:            page,
+		URL:           page,
+		Name:          b.Title,
+		Abstract:      b.Short,
+		InLanguage:    contentLanguage,
+		DatePublished: b.CreatedAt.UTC().Format("2006-01-02"),
+		DateModified:  syndication.TimeString(b.UpdatedAt),
+		Identifier:    &ldIdentifier{Type: "PropertyValue", PropertyID: "uuid", Value: b.ID},
+	}
+	for _, a := range b.AuthorRefs {
+		doc.Author = append(doc.Author, ldPerson{Type: "Person", Name: a.Name, URL: syndication.AuthorPageURL(a.Slug)})
+	}
+	for _, c := range b.CategoryRefs {
+		doc.Genre = append(doc.Genre, c.Name)
+	}
+	if b.CoverURL != nil && *b.CoverURL != "" {
+		doc.Image = syndication.BaseURL(r) + "/books/" + url.PathEscape(b.Slug) + "/cover"
+	}
+
+	body, err := json.Marshal(doc)
+	if err != nil {
+		http.Error(w, "failed to encode", http.StatusInternalServerError)
+		return
+	}
+	syndication.WriteCached(w, r, typeJSONLD, body, b.UpdatedAt, maxAge)
+}
@@ -0,0 +1,177 @@
+package seo
+
+import (
+	"database/sql"
+	"encoding/json"
+	"html"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	storebooks "github.com/5w1tchy/books-api/internal/store/books"
+	"github.com/5w1tchy/books-api/internal/syndication"
+)
+
+// oEmbed 1.0 provider (https://oembed.com) for book and author pages on the
+// web app, and for book share cards.
+
+const (
+	providerName = "Books API"
+
+	embedWidth  = 480
+	embedHeight = 180
+	excerptLen  = 200
+)
+
+type oembedResponse struct {
+	Type            string `json:"type"` // "rich" for books, "link" for authors
+	Version         string `json:"version"`
+	Title           string `json:"title"`
+	AuthorName      string `json:"author_name,omitempty"`
+	AuthorURL       string `json:"author_url,omitempty"`
+	ProviderName    string `json:"provider_name"`
+	ProviderURL     string `json:"provider_url"`
+	CacheAge        int    `json:"cache_age"`
+	HTML            string `json:"html,omitempty"`
+	Width           int    `json:"width,omitempty"`
+	Height          int    `json:"height,omitempty"`
+	ThumbnailURL    string `json:"thumbnail_url,omitempty"`
+	ThumbnailWidth  int    `json:"thumbnail_width,omitempty"`
+	ThumbnailHeight int    `json:"thumbnail_height,omitempty"`
+}
+
+// GET /oembed?url=<book or author URL>[&format=json][&maxwidth=][&maxheight=]
+func (h *Handler) OEmbed(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if f := q.Get("format"); f != "" && f != "json" {
+		http.Error(w, "only json is supported", http.StatusNotImplemented)
+		return
+	}
+	raw := strings.TrimSpace(q.Get("url"))
+	if raw == "" {
+		http.Error(w, "missing url", http.StatusBadRequest)
+		return
+	}
+	target, err := url.Parse(raw)
+	if err != nil || target.Host == "" {
+		http.Error(w, "invalid url", http.StatusBadRequest)
+		return
+	}
+
+	// Book and author pages live on the web app; share cards on the API
+	base := syndication.BaseURL(r)
+	site := syndication.SiteURL()
+	if !sameHost(base, target) && (site == "" || !sameHost(site, target)) {
+		http.Error(w, "url is not served by this provider", http.StatusNotFound)
+		return
+	}
+
+	// /books/{key}[/share] or /authors/{slug}
+	parts := strings.Split(strings.Trim(target.Path, "/"), "/")
+	var resp oembedResponse
+	switch {
+	case len(parts) >= 2 && parts[0] == "books" && (len(parts) == 2 || (len(parts) == 3 && parts[2] == "share")):
+		resp, err = h.bookEmbed(r, base, parts[1])
+	case len(parts) == 2 && parts[0] == "authors":
+		resp, err = h.authorEmbed(r, parts[1])
+	default:
+		http.Error(w, "unsupported url", http.StatusNotFound)
+		return
+	}
+	if err == sql.ErrNoRows {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("[seo] oembed %s: %v", raw, err)
+		http.Error(w, "failed to resolve url", http.StatusInternalServerError)
+		return
+	}
+
+	resp.Version = "1.0"
+	resp.ProviderName = providerName
+	resp.ProviderURL = base
+	if site != "" {
+		resp.ProviderURL = site
+	}
+	resp.CacheAge = maxAge
+	if resp.HTML != "" {
+		resp.Width, resp.Height = fitEmbed(q.Get("maxwidth"), q.Get("maxheight"))
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(maxAge))
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func (h *Handler) bookEmbed(r *http.Request, base, key string) (oembedResponse, error) {
+	key, _ = url.PathUnescape(key)
+	b, err := storebooks.FetchSchemaBook(r.Context(), h.DB, key)
+	if err != nil {
+		return oembedResponse{}, err
+	}
+	page := syndication.BookPageURL(r, b.Slug)
+	byline := strings.Join(b.Authors, ", ")
+
+	resp := oembedResponse{Type: "rich", Title: b.Title, AuthorName: byline}
+	if len(b.AuthorRefs) > 0 {
+		resp.AuthorURL = syndication.AuthorPageURL(b.AuthorRefs[0].Slug)
+	}
+	if b.CoverURL != nil && *b.CoverURL != "" {
+		resp.ThumbnailURL = base + "/books/" + url.PathEscape(b.Slug) + "/cover"
+	}
+
+	var sb strings.Builder
+	sb.WriteString(`<blockquote class="books-api-embed"><p><a href="` + html.EscapeString(page) + `">`)
+	sb.WriteString(html.EscapeString(b.Title) + `</a>`)
+	if byline != "" {
+		sb.WriteString(` by ` + html.EscapeString(byline))
+	}
+	sb.WriteString(`</p>`)
+	if ex := syndication.Excerpt(shareText(b), excerptLen); ex != "" {
+		sb.WriteString(`<p>` + html.EscapeString(ex) + `</p>`)
+	}
+	sb.WriteString(`</blockquote>`)
+	resp.HTML = sb.String()
+	return resp, nil
+}
+
+func (h *Handler) authorEmbed(r *http.Request, slug string) (oembedResponse, error) {
+	slug, _ = url.PathUnescape(slug)
+	ref, err := storebooks.GetAuthorRef(r.Context(), h.DB, strings.ToLower(slug))
+	if err != nil {
+		return oembedResponse{}, err
+	}
+	return oembedResponse{
+		Type:       "link",
+		Title:      ref.Name,
+		AuthorName: ref.Name,
+		AuthorURL:  syndication.AuthorPageURL(ref.Slug),
+	}, nil
+}
+
+func sameHost(origin string, target *url.URL) bool {
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, target.Host)
+}
+
+// fitEmbed honours maxwidth/maxheight by shrinking the default card size.
+func fitEmbed(maxW, maxH string) (int, int) {
+	w, h := embedWidth, embedHeight
+	if n, err := strconv.Atoi(maxW); err == nil && n > 0 && n < w {
+		w = n
+	}
+	if n, err := strconv.Atoi(maxH); err == nil && n > 0 && n < h {
+		h = n
+	}
+	return w, h
+}
+
+// shareText prefers the short (written for sharing) over the summary.
+func shareText(b storebooks.SchemaBook) string {
+	if strings.TrimSpace(b.Short) != "" {
+		return b.Short
+	}
+	return b.Summary
+}
@@ -0,0 +1,113 @@
+package seo
+
+import (
+	"bytes"
+	"database/sql"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	storebooks "github.com/5w1tchy/books-api/internal/store/books"
+	"github.com/5w1tchy/books-api/internal/syndication"
+)
+
+// Share card: a minimal HTML page whose only job is to carry Open Graph/Twitter
+// meta for chat-app link previews and point humans at the real book page.
+var shareTmpl = template.Must(template.New("share").Parse(`<!DOCTYPE html>
+<html lang="{{.Lang}}">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}{{if .Byline}} by {{.Byline}}{{end}}</title>
+<meta name="description" content="{{.Description}}">
+<link rel="canonical" href="{{.PageURL}}">
+<link rel="alternate" type="application/json+oembed" href="{{.OEmbedURL}}" title="{{.Title}}">
+<meta property="og:type" content="book">
+<meta property="og:site_name" content="{{.Site}}">
+<meta property="og:title" content="{{.Title}}">
+<meta property="og:description" content="{{.Description}}">
+<meta property="og:url" content="{{.PageURL}}">
+{{- if .ImageURL}}
+<meta property="og:image" content="{{.ImageURL}}">
+<meta property="og:image:alt" content="Cover of {{.Title}}">
+{{- end}}
+{{- range .Authors}}
+<meta property="book:author" content="{{.}}">
+{{- end}}
+{{- range .Tags}}
+<meta property="book:tag" content="{{.}}">
+{{- end}}
+<meta name="twitter:card" content="{{if .ImageURL}}summary_large_image{{else}}summary{{end}}">
+<meta name="twitter:title" content="{{.Title}}">
+<meta name="twitter:description" content="{{.Description}}">
+{{- if .ImageURL}}
+<meta name="twitter:image" content="{{.ImageURL}}">
+{{- end}}
+</head>
+<body>
+<h1><a href="{{.PageURL}}">{{.Title}}</a></h1>
+{{- if .Byline}}
+<p>by {{.Byline}}</p>
+{{- end}}
+{{- if .Description}}
+<p>{{.Description}}</p>
+{{- end}}
+</body>
+</html>
+`))
+
+type shareView struct {
+	Lang        string
+	Site        string
+	Title       string
+	Byline      string
+	Authors     []string
+	Tags        []string
+	Description string
+	PageURL     string
+	ImageURL    string
+	OEmbedURL   string
+}
+
+// GET /books/{key}/share
+func (h *Handler) BookShare(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	b, err := storebooks.FetchSchemaBook(r.Context(), h.DB, key)
+	if err == sql.ErrNoRows {
+		http.Error(w, "book not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("[seo] share %s: %v", key, err)
+		http.Error(w, "failed to load book", http.StatusInternalServerError)
+		return
+	}
+
+	base := syndication.BaseURL(r)
+	page := syndication.BookPageURL(r, b.Slug)
+	v := shareView{
+		Lang:        contentLanguage,
+		Site:        providerName,
+		Title:       b.Title,
+		Byline:      strings.Join(b.Authors, ", "),
+		Authors:     b.Authors,
+		Description: syndication.Excerpt(shareText(b), excerptLen),
+		PageURL:     page,
+		OEmbedURL:   base + "/oembed?format=json&url=" + url.QueryEscape(page),
+	}
+	for _, c := range b.CategoryRefs {
+		v.Tags = append(v.Tags, c.Name)
+	}
+	// Crawlers follow the redirect to the presigned object.
+	if b.CoverURL != nil && *b.CoverURL != "" {
+		v.ImageURL = base + "/books/" + url.PathEscape(b.Slug) + "/cover"
+	}
+
+	var buf bytes.Buffer
+	if err := shareTmpl.Execute(&buf, v); err != nil {
+		log.Printf("[seo] share render: %v", err)
+		http.Error(w, "failed to render page", http.StatusInternalServerError)
+		return
+	}
+	syndication.WriteCached(w, r, "text/html; charset=utf-8", buf.Bytes(), b.UpdatedAt, maxAge)
+}
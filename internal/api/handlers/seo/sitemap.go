@@ -0,0 +1,112 @@
+package seo
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	storebooks "github.com/5w1tchy/books-api/internal/store/books"
+	"github.com/5w1tchy/books-api/internal/syndication"
+)
+
+// Search-engine facing endpoints: sitemaps and schema.org markup. All public.
+
+const (
+	typeXML    = "application/xml; charset=utf-8"
+	typeJSONLD = "application/ld+json; charset=utf-8"
+
+	maxAge = 3600 // seconds
+
+	// inLanguage for all summaries until books carry their own language.
+	contentLanguage = "en"
+)
+
+type Handler struct {
+	DB *sql.DB
+}
+
+func New(db *sql.DB) *Handler { return &Handler{DB: db} }
+
+// GET /sitemap.xml
+// Sitemap index pointing at /sitemaps/{n}.xml, one file per 50k URLs.
+// The pages listed live on the web app (APP_BASE_URL), so without one there
+// is no sitemap. Search engines accept them from this host when the site's
+// robots.txt points at /sitemap.xml.
+func (h *Handler) SitemapIndex(w http.ResponseWriter, r *http.Request) {
+	if syndication.SiteURL() == "" {
+		http.NotFound(w, r)
+		return
+	}
+	pages, err := storebooks.ListSitemapPages(r.Context(), h.DB, syndication.SitemapMaxURLs)
+	if err != nil {
+		log.Printf("[seo] sitemap pages: %v", err)
+		http.Error(w, "failed to build sitemap", http.StatusInternalServerError)
+		return
+	}
+
+	base := syndication.BaseURL(r)
+	var newest time.Time
+	idx := syndication.SitemapIndex{}
+	for _, p := range pages {
+		idx.Sitemaps = append(idx.Sitemaps, syndication.SitemapRef{
+			Loc:     base + "/sitemaps/" + strconv.Itoa(p.Page) + ".xml",
+			LastMod: lastMod(p.Updated),
+		})
+		if p.Updated.After(newest) {
+			newest = p.Updated
+		}
+	}
+	h.writeXML(w, r, idx, newest)
+}
+
+// GET /sitemaps/{file}  (file is "<n>.xml", 1-based)
+func (h *Handler) SitemapPage(w http.ResponseWriter, r *http.Request) {
+	site := syndication.SiteURL()
+	n, err := strconv.Atoi(strings.TrimSuffix(r.PathValue("file"), ".xml"))
+	if site == "" || err != nil || n < 1 {
+		http.NotFound(w, r)
+		return
+	}
+
+	entries, err := storebooks.ListSitemapEntries(r.Context(), h.DB, n, syndication.SitemapMaxURLs)
+	if err != nil {
+		log.Printf("[seo] sitemap page %d: %v", n, err)
+		http.Error(w, "failed to build sitemap", http.StatusInternalServerError)
+		return
+	}
+	if len(entries) == 0 && n > 1 {
+		http.NotFound(w, r)
+		return
+	}
+
+	var newest time.Time
+	set := syndication.URLSet{}
+	for _, e := range entries {
+		set.URLs = append(set.URLs, syndication.SitemapURL{Loc: site + e.Path, LastMod: lastMod(e.Updated)})
+		if e.Updated.After(newest) {
+			newest = e.Updated
+		}
+	}
+	h.writeXML(w, r, set, newest)
+}
+
+func (h *Handler) writeXML(w http.ResponseWriter, r *http.Request, doc any, modified time.Time) {
+	body, err := syndication.Render(doc)
+	if err != nil {
+		log.Printf("[seo] render: %v", err)
+		http.Error(w, "failed to render sitemap", http.StatusInternalServerError)
+		return
+	}
+	syndication.WriteCached(w, r, typeXML, body, modified, maxAge)
+}
+
+// lastMod uses W3C datetime; empty when unknown so the element is omitted.
+func lastMod(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return syndication.TimeString(t)
+}
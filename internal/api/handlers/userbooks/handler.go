@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/5w1tchy/books-api/internal/api/httpx"
 	"github.com/5w1tchy/books-api/internal/api/middlewares"
@@ -285,3 +286,80 @@ func GetNotes(db *sql.DB) http.Handler {
 		httpx.OK(w, notes)
 	})
 }
+
+// UpdateListening: POST /user/listening-progress
+func UpdateListening(db *sql.DB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middlewares.UserIDFrom(r.Context())
+		if !ok {
+			httpx.ErrorJSON(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+
+		var req struct {
+			BookID          string  `json:"book_id"`
+			PositionSeconds float64 `json:"position_seconds"`
+			PlaybackSpeed   float64 `json:"playback_speed"`
+			Device          string  `json:"device"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			httpx.ErrorJSON(w, http.StatusBadRequest, "invalid JSON")
+			return
+		}
+
+		if req.BookID == "" {
+			httpx.ErrorJSON(w, http.StatusBadRequest, "book_id is required")
+			return
+		}
+		if req.PositionSeconds < 0 {
+			httpx.ErrorJSON(w, http.StatusBadRequest, "position_seconds must be >= 0")
+			return
+		}
+		if req.PlaybackSpeed == 0 {
+			req.PlaybackSpeed = 1
+		}
+		if req.PlaybackSpeed < 0.25 || req.PlaybackSpeed > 4 {
+			httpx.ErrorJSON(w, http.StatusBadRequest, "playback_speed must be 0.25-4")
+			return
+		}
+		device := strings.TrimSpace(req.Device)
+		if device == "" {
+			device = r.UserAgent()
+		}
+
+		if err := storeuserbooks.UpdateListeningProgress(r.Context(), db, userID, req.BookID, req.PositionSeconds, req.PlaybackSpeed, device); err != nil {
+			httpx.ErrorJSON(w, http.StatusInternalServerError, "failed to update listening progress")
+			return
+		}
+
+		httpx.OKNoData(w)
+	})
+}
+
+// GetListening: GET /user/listening-progress/{bookId}
+func GetListening(db *sql.DB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middlewares.UserIDFrom(r.Context())
+		if !ok {
+			httpx.ErrorJSON(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+
+		bookID := r.PathValue("bookId")
+		if bookID == "" {
+			httpx.ErrorJSON(w, http.StatusBadRequest, "missing bookId")
+			return
+		}
+
+		progress, err := storeuserbooks.GetListeningProgress(r.Context(), db, userID, bookID)
+		if err == sql.ErrNoRows {
+			httpx.ErrorJSON(w, http.StatusNotFound, "no listening progress found")
+			return
+		} else if err != nil {
+			httpx.ErrorJSON(w, http.StatusInternalServerError, "failed to get listening progress")
+			return
+		}
+
+		httpx.OK(w, progress)
+	})
+}
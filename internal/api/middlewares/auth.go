@@ -1,6 +1,7 @@
 package middlewares
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"net/http"
@@ -9,8 +10,16 @@ import (
 	jwtutil "github.com/5w1tchy/books-api/internal/security/jwt"
 )
 
+// SessionChecker reports whether a user's refresh session is still live
+// (auth.SessionStore).
+type SessionChecker interface {
+	SessionActive(ctx context.Context, userID, sessionID string) (bool, error)
+}
+
 // RequireAuth verifies Bearer JWT, checks token_version against DB, then injects userID into context.
-func RequireAuth(db *sql.DB, next http.Handler) http.Handler {
+// Tokens tied to a session are refused once it is revoked, so signing a
+// device out takes effect at once; a nil sessions skips that check.
+func RequireAuth(db *sql.DB, sessions SessionChecker, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		raw := r.Header.Get("Authorization")
 		if raw == "" {
@@ -39,8 +48,22 @@ func RequireAuth(db *sql.DB, next http.Handler) http.Handler {
 			http.Error(w, "token revoked", http.StatusUnauthorized)
 			return
 		}
+		if claims.SessionID != "" && sessions != nil {
+			live, err := sessions.SessionActive(r.Context(), claims.Subject, claims.SessionID)
+			if err != nil {
+				http.Error(w, "session check failed", http.StatusServiceUnavailable)
+				return
+			}
+			if !live {
+				http.Error(w, "session revoked", http.StatusUnauthorized)
+				return
+			}
+		}
 
 		ctx := WithUserID(r.Context(), claims.Subject)
+		if claims.SessionID != "" {
+			ctx = WithSessionID(ctx, claims.SessionID)
+		}
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
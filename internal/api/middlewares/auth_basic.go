@@ -0,0 +1,204 @@
+package middlewares
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/5w1tchy/books-api/internal/security/password"
+	"github.com/redis/go-redis/v9"
+)
+
+// basicVerified caches successful argon2 verifications so catalog clients that send
+// Basic credentials on every request don't pay the hashing cost each time.
+// The key includes the stored hash, so a password change invalidates it.
+var basicVerified = &verifiedCache{entries: map[string]time.Time{}} // sha256(hash|plain) -> expiry
+
+const (
+	basicCacheTTL = 5 * time.Minute
+	basicCacheMax = 10000
+)
+
+// verifiedCache is a TTL set bounded to basicCacheMax entries. Expired
+// entries are swept on insert at most once per TTL; when it is still full,
+// an arbitrary entry makes room (that client just hashes again).
+type verifiedCache struct {
+	mu        sync.Mutex
+	entries   map[string]time.Time
+	lastSweep time.Time
+}
+
+func (c *verifiedCache) Has(key string, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	exp, ok := c.entries[key]
+	if ok && !now.Before(exp) {
+		delete(c.entries, key)
+		return false
+	}
+	return ok
+}
+
+func (c *verifiedCache) Add(key string, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if now.Sub(c.lastSweep) >= basicCacheTTL || len(c.entries) >= basicCacheMax {
+		for k, exp := range c.entries {
+			if !now.Before(exp) {
+				delete(c.entries, k)
+			}
+		}
+		c.lastSweep = now
+	}
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= basicCacheMax {
+		for k := range c.entries {
+			delete(c.entries, k)
+			break
+		}
+	}
+	c.entries[key] = now.Add(basicCacheTTL)
+}
+
+// AccountGuard throttles password guessing against one account. The auth
+// handler implements it with its login lockout, so Basic failures count
+// towards the same lock as failed /auth/login attempts.
+type AccountGuard interface {
+	// AllowLogin answers the request itself (429) and returns false while
+	// the account must wait or is locked.
+	AllowLogin(w http.ResponseWriter, r *http.Request, email string) bool
+	LoginFailed(r *http.Request, email, userID string)
+	LoginSucceeded(r *http.Request, email string)
+}
+
+// BasicOption adds brute-force protection to RequireAuthOrBasic.
+type BasicOption func(*basicOptions)
+
+type basicOptions struct {
+	rdb   *redis.Client
+	guard AccountGuard
+}
+
+// BasicLoginLimit makes failed Basic credentials spend the caller's per-IP
+// login budget (LOGIN_MAX_ATTEMPTS per LOGIN_WINDOW, shared with
+// LoginRateLimit) and refuses Basic once it is used up.
+func BasicLoginLimit(rdb *redis.Client) BasicOption {
+	return func(o *basicOptions) { o.rdb = rdb }
+}
+
+// BasicLockout applies the per-account lockout to Basic credentials.
+func BasicLockout(guard AccountGuard) BasicOption {
+	return func(o *basicOptions) { o.guard = guard }
+}
+
+// RequireAuthOrBasic accepts either a Bearer JWT (same checks as RequireAuth) or
+// HTTP Basic email/password credentials, for clients such as OPDS readers that
+// cannot run the token flow. Failures are answered with a Basic challenge.
+// Accounts with two-factor auth on are refused Basic; a password alone must
+// not get them in, so they use the bearer flow.
+func RequireAuthOrBasic(db *sql.DB, sessions SessionChecker, realm string, next http.Handler, opts ...BasicOption) http.Handler {
+	var o basicOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	bearerAuth := RequireAuth(db, sessions, next)
+	challenge := `Basic realm="` + realm + `", charset="UTF-8"`
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw := r.Header.Get("Authorization")
+		if _, err := bearer(raw); err == nil {
+			bearerAuth.ServeHTTP(w, r)
+			return
+		}
+
+		email, plain, ok := r.BasicAuth()
+		email = strings.TrimSpace(email)
+		if !ok || email == "" || plain == "" {
+			w.Header().Set("WWW-Authenticate", challenge)
+			http.Error(w, "authentication required", http.StatusUnauthorized)
+			return
+		}
+
+		ip := clientIP(r)
+		if o.rdb != nil && ip != "" {
+			if wait := loginBlocked(r.Context(), o.rdb, ip); wait > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				http.Error(w, "too many login attempts", http.StatusTooManyRequests)
+				return
+			}
+		}
+		if o.guard != nil && !o.guard.AllowLogin(w, r, email) {
+			return
+		}
+
+		var userID, hash, status string
+		var mfa bool
+		err := db.QueryRowContext(r.Context(),
+			`SELECT id::text, password_hash, COALESCE(status,'active'), totp_enabled_at IS NOT NULL FROM public.users WHERE email = $1`,
+			email).Scan(&userID, &hash, &status, &mfa)
+		if err != nil {
+			// Hash anyway, so response time doesn't tell which emails exist
+			hash = basicDummyHash()
+		}
+		ok, hashed := verifyBasic(plain, hash)
+		if err != nil || !ok {
+			if o.rdb != nil && ip != "" {
+				loginFailure(r.Context(), o.rdb, ip)
+			}
+			if o.guard != nil {
+				o.guard.LoginFailed(r, email, userID)
+			}
+			w.Header().Set("WWW-Authenticate", challenge)
+			http.Error(w, "invalid credentials", http.StatusUnauthorized)
+			return
+		}
+		// Refused only after the password check, and with the same answer,
+		// so neither shows without the password
+		if status == "banned" || mfa {
+			w.Header().Set("WWW-Authenticate", challenge)
+			http.Error(w, "invalid credentials", http.StatusUnauthorized)
+			return
+		}
+		// A cached verification is a client repeating known-good credentials;
+		// failures were already cleared when it was first checked
+		if hashed && o.guard != nil {
+			o.guard.LoginSucceeded(r, email)
+		}
+
+		ctx := WithUserID(r.Context(), userID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// verifyBasic checks plain against hash, from the cache when it can. hashed
+// reports whether argon2 actually ran.
+func verifyBasic(plain, hash string) (ok, hashed bool) {
+	sum := sha256.Sum256([]byte(hash + "|" + plain))
+	key := hex.EncodeToString(sum[:])
+
+	if basicVerified.Has(key, time.Now()) {
+		return true, false
+	}
+
+	ok, _, err := password.Verify(plain, hash)
+	if err != nil || !ok {
+		return false, true
+	}
+	basicVerified.Add(key, time.Now())
+	return true, true
+}
+
+// basicDummyHash is checked in place of a missing user's hash. Its password
+// is random and never kept, so nothing matches it.
+var basicDummyHash = sync.OnceValue(func() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	h, _ := password.Hash(hex.EncodeToString(b))
+	return h
+})
@@ -2,7 +2,12 @@ package middlewares
 
 import "context"
 
-const userIDKey ctxKey = 1
+const (
+	userIDKey    ctxKey = 1
+	sessionIDKey ctxKey = 2
+	apiKeyIDKey  ctxKey = 3
+	permsKey     ctxKey = 4
+)
 
 func WithUserID(ctx context.Context, userID string) context.Context {
 	return context.WithValue(ctx, userIDKey, userID)
@@ -12,3 +17,37 @@ func UserIDFrom(ctx context.Context) (string, bool) {
 	v, ok := ctx.Value(userIDKey).(string)
 	return v, ok && v != ""
 }
+
+// WithSessionID records the refresh session the access token belongs to.
+func WithSessionID(ctx context.Context, sessionID string) context.Context {
+	return context.WithValue(ctx, sessionIDKey, sessionID)
+}
+
+// SessionIDFrom returns the caller's session ID; tokens issued before
+// sessions existed have none.
+func SessionIDFrom(ctx context.Context) (string, bool) {
+	v, ok := ctx.Value(sessionIDKey).(string)
+	return v, ok && v != ""
+}
+
+// WithAPIKeyID records that the request authenticated with an API key.
+func WithAPIKeyID(ctx context.Context, keyID string) context.Context {
+	return context.WithValue(ctx, apiKeyIDKey, keyID)
+}
+
+// APIKeyIDFrom returns the ID of the API key the request used, if any.
+func APIKeyIDFrom(ctx context.Context) (string, bool) {
+	v, ok := ctx.Value(apiKeyIDKey).(string)
+	return v, ok && v != ""
+}
+
+// WithPermissions records what the caller's role grants.
+func WithPermissions(ctx context.Context, perms []string) context.Context {
+	return context.WithValue(ctx, permsKey, perms)
+}
+
+// PermissionsFrom returns the caller's permissions, set by RequirePermission.
+func PermissionsFrom(ctx context.Context) []string {
+	v, _ := ctx.Value(permsKey).([]string)
+	return v
+}
@@ -14,26 +14,83 @@ func Compression(next http.Handler) http.Handler {
 			return
 		}
 
-		// If gzip is accepted, wrap the ResponseWriter
-		gz := gzip.NewWriter(w)
-		defer gz.Close()
-		w.Header().Set("Content-Encoding", "gzip")
-
-		// Wrap the ResponseWriter with gzipResponseWriter
-		w = &gzipResponseWriter{
-			ResponseWriter: w,
-			Writer:         gz,
-		}
-		next.ServeHTTP(w, r)
+		// Wrap the ResponseWriter; whether to gzip is decided when the
+		// handler commits its headers (see gzipResponseWriter.WriteHeader).
+		gw := &gzipResponseWriter{ResponseWriter: w}
+		defer gw.Close()
+		next.ServeHTTP(gw, r)
 	})
 }
 
-// gzipResponseWriter wraps http.ResponseWriter to write gzipped responses
+// gzipResponseWriter wraps http.ResponseWriter to write gzipped responses.
+// Partial, empty and already-compressed bodies (audio, images, archives) pass through
+// untouched so byte ranges and Content-Length stay valid.
 type gzipResponseWriter struct {
 	http.ResponseWriter
-	Writer *gzip.Writer
+	Writer  *gzip.Writer
+	decided bool
+}
+
+func (g *gzipResponseWriter) WriteHeader(code int) {
+	if !g.decided {
+		g.decided = true
+		if shouldGzip(code, g.Header()) {
+			h := g.Header()
+			h.Set("Content-Encoding", "gzip")
+			h.Add("Vary", "Accept-Encoding")
+			h.Del("Content-Length")
+			g.Writer = gzip.NewWriter(g.ResponseWriter)
+		}
+	}
+	g.ResponseWriter.WriteHeader(code)
 }
 
 func (g *gzipResponseWriter) Write(b []byte) (int, error) {
+	if !g.decided {
+		if g.Header().Get("Content-Type") == "" {
+			g.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		g.WriteHeader(http.StatusOK)
+	}
+	if g.Writer == nil {
+		return g.ResponseWriter.Write(b)
+	}
 	return g.Writer.Write(b)
 }
+
+// Flush lets streaming handlers push gzip-buffered data to the client.
+func (g *gzipResponseWriter) Flush() {
+	if g.Writer != nil {
+		_ = g.Writer.Flush()
+	}
+	if f, ok := g.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (g *gzipResponseWriter) Unwrap() http.ResponseWriter { return g.ResponseWriter }
+
+func (g *gzipResponseWriter) Close() {
+	if g.Writer != nil {
+		_ = g.Writer.Close()
+	}
+}
+
+func shouldGzip(code int, h http.Header) bool {
+	switch code {
+	case http.StatusNoContent, http.StatusPartialContent, http.StatusNotModified,
+		http.StatusRequestedRangeNotSatisfiable:
+		return false
+	}
+	if h.Get("Content-Encoding") != "" || h.Get("Content-Range") != "" {
+		return false
+	}
+	ct := strings.ToLower(h.Get("Content-Type"))
+	for _, p := range []string{"audio/", "video/", "image/", "application/zip", "application/epub+zip", "application/gzip"} {
+		if strings.HasPrefix(ct, p) {
+			return false
+		}
+	}
+	return true
+}
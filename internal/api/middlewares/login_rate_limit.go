@@ -11,9 +11,7 @@ import (
 )
 
 func LoginRateLimit(rdb *redis.Client, next http.Handler) http.Handler {
-	// Defaults: 10 attempts per 5 minutes
-	max := envInt("LOGIN_MAX_ATTEMPTS", 10)
-	win := envDur("LOGIN_WINDOW", "5m")
+	max, win := loginLimits()
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ip := clientIP(r)           // reuse from rate_limiter.go
@@ -23,7 +21,7 @@ func LoginRateLimit(rdb *redis.Client, next http.Handler) http.Handler {
 		}
 
 		ctx := context.Background()
-		key := "rl:login:" + ip
+		key := loginKey(ip)
 
 		// INCR and set TTL if new
 		n, err := rdb.Incr(ctx, key).Result()
@@ -39,6 +37,38 @@ func LoginRateLimit(rdb *redis.Client, next http.Handler) http.Handler {
 	})
 }
 
+// loginLimits is the per-IP budget: LOGIN_MAX_ATTEMPTS per LOGIN_WINDOW,
+// 10 per 5 minutes by default.
+func loginLimits() (int, time.Duration) {
+	return envInt("LOGIN_MAX_ATTEMPTS", 10), envDur("LOGIN_WINDOW", "5m")
+}
+
+func loginKey(ip string) string { return "rl:login:" + ip }
+
+// loginBlocked reports how long ip must wait when it has used up its login
+// budget. Credential checks outside /auth/login (HTTP Basic) share the same
+// counter but only spend it on failures, since their clients send the
+// password with every request.
+func loginBlocked(ctx context.Context, rdb *redis.Client, ip string) time.Duration {
+	limit, _ := loginLimits()
+	pipe := rdb.Pipeline()
+	n := pipe.Get(ctx, loginKey(ip))
+	ttl := pipe.PTTL(ctx, loginKey(ip))
+	_, _ = pipe.Exec(ctx) // fail open, like LoginRateLimit
+	if c, err := n.Int(); err != nil || c < limit {
+		return 0
+	}
+	return max(ttl.Val(), time.Second)
+}
+
+// loginFailure spends one attempt of ip's login budget.
+func loginFailure(ctx context.Context, rdb *redis.Client, ip string) {
+	_, win := loginLimits()
+	if n, err := rdb.Incr(ctx, loginKey(ip)).Result(); err == nil && n == 1 {
+		_ = rdb.Expire(ctx, loginKey(ip), win).Err()
+	}
+}
+
 func envInt(k string, def int) int {
 	if v := os.Getenv(k); v != "" {
 		if n, err := strconv.Atoi(v); err == nil {
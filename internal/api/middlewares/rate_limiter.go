@@ -22,6 +22,9 @@ func PerIPKey(prefix string) KeyFunc {
 	}
 }
 
+// ClientIP returns the caller's IP the same way the rate limiters see it.
+func ClientIP(r *http.Request) string { return clientIP(r) }
+
 func clientIP(r *http.Request) string {
 	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
 		parts := strings.Split(xff, ",")
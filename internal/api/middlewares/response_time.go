@@ -30,6 +30,9 @@ func (w *rtWriter) Write(b []byte) (int, error) {
 	return w.ResponseWriter.Write(b)
 }
 
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (w *rtWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
 func ResponseTimeMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		rw := &rtWriter{
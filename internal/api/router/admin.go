@@ -3,62 +3,123 @@ package router
 import (
 	"database/sql"
 	"net/http"
+	"os"
+	"strconv"
 
 	admin "github.com/5w1tchy/books-api/internal/api/handlers/admin"
 	"github.com/5w1tchy/books-api/internal/api/handlers/books"
 	"github.com/5w1tchy/books-api/internal/api/middlewares"
+	"github.com/5w1tchy/books-api/internal/auth"
+	"github.com/5w1tchy/books-api/internal/security/apikey"
+	"github.com/5w1tchy/books-api/internal/security/rbac"
+	"github.com/5w1tchy/books-api/internal/storage/blob"
 	adminstore "github.com/5w1tchy/books-api/internal/store/admin"
+	"github.com/5w1tchy/books-api/internal/uploads/tus"
 	"github.com/redis/go-redis/v9"
 )
 
-// MountAdmin wires all /admin/* endpoints behind RequireRole(..., "admin").
-func MountAdmin(mux *http.ServeMux, db *sql.DB, rdb *redis.Client) {
+// MountAdmin wires all /admin/* endpoints, each behind the permission it
+// needs (RequirePermission), plus 2FA when AUTH_ADMIN_REQUIRE_2FA is true.
+func MountAdmin(mux *http.ServeMux, db *sql.DB, rdb *redis.Client, blobs blob.BlobStore, verify admin.VerificationSender, sessions *auth.SessionStore, locks admin.LockManager) {
 	// Gate helper
-	gate := func(next http.Handler) http.Handler {
-		return middlewares.RequireRole(db, "admin", next)
+	var roleOpts []middlewares.RoleOption
+	if on, _ := strconv.ParseBool(os.Getenv("AUTH_ADMIN_REQUIRE_2FA")); on {
+		roleOpts = append(roleOpts, middlewares.RequireMFA())
 	}
+	can := func(perm string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return middlewares.RequirePermission(db, sessions, perm, next, roleOpts...)
+		}
+	}
+	// Books endpoints also take API keys with the matching scope, refused
+	// under AUTH_ADMIN_REQUIRE_2FA while their issuer has 2FA off
+	keys := middlewares.NewAPIKeyAuth(db, rdb, roleOpts...)
+	booksRead := keys.Or(apikey.ScopeAdminBooksRead, can(rbac.BooksRead))
+	booksWrite := keys.Or(apikey.ScopeAdminBooksWrite, can(rbac.BooksWrite))
 
 	// --- Admin handler (users, stats, audit) ---
 	sto := adminstore.New(db)
-	adminH := &admin.Handler{DB: db, RDB: rdb, Sto: sto}
+	adminH := admin.NewHandler(db, rdb, sto, blobs)
+	adminH.Verify = verify
+	adminH.Sessions = sessions
+	adminH.Locks = locks
 
 	// Users management
-	mux.Handle("GET /admin/users", gate(http.HandlerFunc(adminH.ListUsers)))
-	mux.Handle("GET /admin/users/{id}", gate(http.HandlerFunc(adminH.GetUser)))
-	mux.Handle("POST /admin/users/{id}/ban", gate(http.HandlerFunc(adminH.BanUser)))
-	mux.Handle("POST /admin/users/{id}/unban", gate(http.HandlerFunc(adminH.UnbanUser)))
-	mux.Handle("POST /admin/users/{id}/role", gate(http.HandlerFunc(adminH.SetRole)))
-	mux.Handle("POST /admin/users/{id}/logout-all", gate(http.HandlerFunc(adminH.LogoutAll)))
-	mux.Handle("POST /admin/users/{id}/resend-verification", gate(http.HandlerFunc(adminH.ResendVerification)))
+	mux.Handle("GET /admin/users", can(rbac.UsersRead)(http.HandlerFunc(adminH.ListUsers)))
+	mux.Handle("GET /admin/users/{id}", can(rbac.UsersRead)(http.HandlerFunc(adminH.GetUser)))
+	mux.Handle("POST /admin/users/{id}/ban", can(rbac.UsersBan)(http.HandlerFunc(adminH.BanUser)))
+	mux.Handle("POST /admin/users/{id}/unban", can(rbac.UsersBan)(http.HandlerFunc(adminH.UnbanUser)))
+	mux.Handle("POST /admin/users/{id}/role", can(rbac.UsersRole)(http.HandlerFunc(adminH.SetRole)))
+	mux.Handle("POST /admin/users/{id}/logout-all", can(rbac.UsersManage)(http.HandlerFunc(adminH.LogoutAll)))
+	mux.Handle("POST /admin/users/{id}/resend-verification", can(rbac.UsersManage)(http.HandlerFunc(adminH.ResendVerification)))
+	mux.Handle("GET /admin/users/{id}/sessions", can(rbac.UsersManage)(http.HandlerFunc(adminH.ListSessions)))
+	mux.Handle("DELETE /admin/users/{id}/sessions/{sid}", can(rbac.UsersManage)(http.HandlerFunc(adminH.RevokeSession)))
+	mux.Handle("GET /admin/users/{id}/lock", can(rbac.UsersRead)(http.HandlerFunc(adminH.GetLock)))
+	mux.Handle("DELETE /admin/users/{id}/lock", can(rbac.UsersManage)(http.HandlerFunc(adminH.ClearLock)))
+	mux.Handle("GET /admin/locks", can(rbac.UsersRead)(http.HandlerFunc(adminH.ListLocks)))
+
+	// API keys (managed with an admin session only, never with a key)
+	mux.Handle("GET /admin/api-keys", can(rbac.APIKeysManage)(http.HandlerFunc(adminH.ListAPIKeys)))
+	mux.Handle("POST /admin/api-keys", can(rbac.APIKeysManage)(http.HandlerFunc(adminH.CreateAPIKey)))
+	mux.Handle("GET /admin/api-keys/{id}", can(rbac.APIKeysManage)(http.HandlerFunc(adminH.GetAPIKey)))
+	mux.Handle("PATCH /admin/api-keys/{id}", can(rbac.APIKeysManage)(http.HandlerFunc(adminH.UpdateAPIKey)))
+	mux.Handle("DELETE /admin/api-keys/{id}", can(rbac.APIKeysManage)(http.HandlerFunc(adminH.RevokeAPIKey)))
+
+	// Roles & permissions
+	mux.Handle("GET /admin/permissions", can(rbac.RolesManage)(http.HandlerFunc(adminH.ListPermissions)))
+	mux.Handle("GET /admin/roles", can(rbac.RolesManage)(http.HandlerFunc(adminH.ListRoles)))
+	mux.Handle("POST /admin/roles", can(rbac.RolesManage)(http.HandlerFunc(adminH.CreateRole)))
+	mux.Handle("GET /admin/roles/{name}", can(rbac.RolesManage)(http.HandlerFunc(adminH.GetRole)))
+	mux.Handle("PATCH /admin/roles/{name}", can(rbac.RolesManage)(http.HandlerFunc(adminH.UpdateRole)))
+	mux.Handle("DELETE /admin/roles/{name}", can(rbac.RolesManage)(http.HandlerFunc(adminH.DeleteRole)))
 
 	// Stats & audit
-	mux.Handle("GET /admin/stats", gate(http.HandlerFunc(adminH.Stats)))
-	mux.Handle("GET /admin/audit", gate(http.HandlerFunc(adminH.ListAudit)))
+	mux.Handle("GET /admin/stats", can(rbac.StatsRead)(http.HandlerFunc(adminH.Stats)))
+	mux.Handle("GET /admin/audit", can(rbac.AuditRead)(http.HandlerFunc(adminH.ListAudit)))
+
+	// Storage reconciliation (orphaned objects / dangling references)
+	mux.Handle("GET /admin/storage/gc", can(rbac.StorageManage)(http.HandlerFunc(adminH.StorageGCReport)))
+	mux.Handle("POST /admin/storage/gc", can(rbac.StorageManage)(http.HandlerFunc(adminH.StorageGCDelete)))
 
-	// --- Admin-only Books CRUD ---
-	mux.Handle("POST /admin/books", gate(books.AdminCreate(db, rdb)))
-	mux.Handle("PATCH /admin/books/{key}", gate(books.AdminPatch(db, rdb)))
-	mux.Handle("PUT /admin/books/{key}", gate(books.AdminPut(db, rdb)))
-	mux.Handle("DELETE /admin/books/{key}", gate(books.AdminDelete(db, rdb)))
-	mux.Handle("GET /admin/books", gate(books.AdminList(db, rdb)))
-	mux.Handle("GET /admin/books/{key}", gate(books.AdminGet(db, rdb)))
+	// --- Books CRUD ---
+	mux.Handle("POST /admin/books", booksWrite(books.AdminCreate(db, rdb, blobs)))
+	mux.Handle("PATCH /admin/books/{key}", booksWrite(books.AdminPatch(db, rdb)))
+	mux.Handle("PUT /admin/books/{key}", booksWrite(books.AdminPut(db, rdb)))
+	mux.Handle("DELETE /admin/books/{key}", booksWrite(books.AdminDelete(db, rdb, blobs)))
+	mux.Handle("GET /admin/books", booksRead(books.AdminList(db, rdb)))
+	mux.Handle("GET /admin/books/{key}", booksRead(books.AdminGet(db, rdb)))
 
 	// --- Admin Book Audio Upload ---
 	mux.Handle("POST /admin/books/{key}/audio",
-		gate(http.HandlerFunc(books.GenerateBookAudioURLHandler(db))),
+		can(rbac.BooksWrite)(http.HandlerFunc(books.GenerateBookAudioURLHandler(db, rdb, blobs))),
+	)
+	mux.Handle("POST /admin/books/{key}/audio/complete",
+		can(rbac.BooksWrite)(http.HandlerFunc(books.CompleteBookAudioHandler(db, rdb, blobs))),
 	)
 	
 	// --- Admin Book Audio Direct Upload (CORS workaround) ---
 	mux.Handle("PUT /admin/books/{key}/audio/upload",
-		gate(http.HandlerFunc(books.DirectAudioUploadHandler(db))),
+		can(rbac.BooksWrite)(http.HandlerFunc(books.DirectAudioUploadHandler(db, blobs))),
 	)
 
+	// --- Admin Book Audio Chapters & Captions ---
+	mux.Handle("PUT /admin/books/{key}/audio/chapters", can(rbac.BooksWrite)(books.AdminPutAudioChapters(db)))
+	mux.Handle("PUT /admin/books/{key}/audio/captions", can(rbac.BooksWrite)(books.AdminUploadCaptions(db, blobs)))
+
+	// --- Resumable (tus) uploads for audio and covers ---
+	tusH := tus.New(rdb, blobs, "/admin/uploads/tus", books.TusAudioKind(db), books.TusCoverKind(db))
+	mux.Handle("POST /admin/books/{key}/audio/tus", can(rbac.BooksWrite)(tusH.Create("audio")))
+	mux.Handle("POST /admin/books/{key}/cover/tus", can(rbac.BooksWrite)(tusH.Create("cover")))
+	mux.Handle("HEAD /admin/uploads/tus/{id}", can(rbac.BooksWrite)(http.HandlerFunc(tusH.Head)))
+	mux.Handle("PATCH /admin/uploads/tus/{id}", can(rbac.BooksWrite)(http.HandlerFunc(tusH.Patch)))
+	mux.Handle("DELETE /admin/uploads/tus/{id}", can(rbac.BooksWrite)(http.HandlerFunc(tusH.Delete)))
+
 	// --- Admin Book Cover Upload ---
 	mux.Handle("POST /admin/books/{key}/cover",
-		gate(http.HandlerFunc(books.UploadBookCoverHandler(db))),
+		can(rbac.BooksWrite)(http.HandlerFunc(books.UploadBookCoverHandler(db, blobs))),
 	)
 
 	// --- Admin autocomplete endpoints ---
-	mux.Handle("GET /admin/categories", gate(books.AdminGetCategories(db, rdb)))
-	mux.Handle("GET /admin/authors", gate(books.AdminGetAuthors(db, rdb)))
+	mux.Handle("GET /admin/categories", can(rbac.BooksRead)(books.AdminGetCategories(db, rdb)))
+	mux.Handle("GET /admin/authors", can(rbac.BooksRead)(books.AdminGetAuthors(db, rdb)))
 }
@@ -3,84 +3,188 @@ package router
 import (
 	"database/sql"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
 
 	"github.com/5w1tchy/books-api/internal/api/handlers"
 	"github.com/5w1tchy/books-api/internal/api/handlers/books"
+	"github.com/5w1tchy/books-api/internal/api/handlers/feeds"
 	"github.com/5w1tchy/books-api/internal/api/handlers/foryou"
+	"github.com/5w1tchy/books-api/internal/api/handlers/opds"
 	"github.com/5w1tchy/books-api/internal/api/handlers/search"
+	"github.com/5w1tchy/books-api/internal/api/handlers/seo"
 	"github.com/5w1tchy/books-api/internal/api/handlers/userbooks"
 	"github.com/5w1tchy/books-api/internal/api/middlewares"
 	"github.com/5w1tchy/books-api/internal/auth"
+	"github.com/5w1tchy/books-api/internal/mail"
+	"github.com/5w1tchy/books-api/internal/security/apikey"
+	"github.com/5w1tchy/books-api/internal/storage/blob"
 	"github.com/redis/go-redis/v9"
 )
 
-func Router(db *sql.DB, rdb *redis.Client) http.Handler {
+const opdsRealm = "books-api catalog"
+
+func Router(db *sql.DB, rdb *redis.Client, blobs blob.BlobStore, mailer mail.Mailer) http.Handler {
 	mux := http.NewServeMux()
 
+	// Auth (created up front: Basic credentials share its login lockout)
+	authStore := auth.NewSQLStore(db)
+	authH := auth.New(authStore, rdb)
+	authH.Mailer = mailer
+	sessions := &auth.SessionStore{RDB: rdb}
+	basicAuth := func(h http.Handler) http.Handler {
+		return middlewares.RequireAuthOrBasic(db, sessions, opdsRealm, h,
+			middlewares.BasicLoginLimit(rdb), middlewares.BasicLockout(authH))
+	}
+
 	// Root & health
 	mux.HandleFunc("GET /", handlers.RootHandler)
 	mux.HandleFunc("GET /healthz", handlers.Healthz)
 	mux.HandleFunc("HEAD /healthz", handlers.Healthz)
 
+	// Catalog reads also take partner API keys (catalog:read)
+	keys := middlewares.NewAPIKeyAuth(db, rdb)
+	catalogPublic := keys.Or(apikey.ScopeCatalogRead, nil)
+	catalogAuth := keys.Or(apikey.ScopeCatalogRead, func(next http.Handler) http.Handler {
+		return middlewares.RequireAuth(db, sessions, next)
+	})
+
 	// Books
-	mux.Handle("GET /books", books.Handler(db, rdb))
+	mux.Handle("GET /books", catalogPublic(books.Handler(db, rdb)))
 	mux.Handle("OPTIONS /books", books.Handler(db, rdb))
 
 	// Protected single-book view
-	mux.Handle("GET /books/{key}", middlewares.RequireAuth(db, books.Get(db)))
-	mux.Handle("HEAD /books/{key}", middlewares.RequireAuth(db, books.Head(db)))
+	mux.Handle("GET /books/{key}", catalogAuth(books.Get(db)))
+	mux.Handle("HEAD /books/{key}", catalogAuth(books.Head(db)))
 	mux.Handle("OPTIONS /books/{key}", books.Handler(db, rdb))
 
 	// --- Book audio streaming (presigned download) ---
-	mux.Handle("GET /books/{key}/audio", books.GetBookAudioURLHandler(db))
+	mux.Handle("GET /books/{key}/audio", books.GetBookAudioURLHandler(db, blobs))
+	// Range-capable proxy (same auth as the book page; survives long listens)
+	mux.Handle("GET /books/{key}/audio/stream", catalogAuth(books.StreamBookAudioHandler(db, blobs)))
+	mux.Handle("GET /books/{key}/audio/chapters", catalogAuth(books.GetAudioChapters(db)))
+	mux.Handle("GET /books/{key}/audio/captions.vtt", catalogAuth(books.GetCaptions(db, blobs)))
+	mux.Handle("GET /books/{key}/audio/transcript/search", catalogAuth(books.SearchTranscript(db, blobs)))
+
+	mux.Handle("GET /books/{key}/cover", catalogPublic(books.GetBookCoverURLHandler(db, blobs)))
+
+	// EPUB export (same auth as the book page; Basic also accepted for OPDS readers)
+	mux.Handle("GET /books/{key}/export.epub", basicAuth(books.ExportEPUB(db, blobs)))
+
+	// schema.org markup for the public book page
+	seoH := seo.New(db)
+	mux.HandleFunc("GET /books/{key}/jsonld", seoH.BookJSONLD)
+
+	// Link previews: oEmbed provider + Open Graph share card
+	mux.HandleFunc("GET /oembed", seoH.OEmbed)
+	mux.HandleFunc("GET /books/{key}/share", seoH.BookShare)
 
-	mux.Handle("GET /books/{key}/cover", books.GetBookCoverURLHandler(db))
+	// Sitemaps (index + one file per 50k URLs)
+	mux.HandleFunc("GET /sitemap.xml", seoH.SitemapIndex)
+	mux.HandleFunc("GET /sitemaps/{file}", seoH.SitemapPage)
 
 	// Search
 	mux.Handle("GET /search/suggest", search.Suggest(db))
 
+	// OPDS catalog (Bearer JWT or HTTP Basic)
+	catalog := opds.New(db)
+	opdsAuth := func(h http.HandlerFunc) http.Handler {
+		return basicAuth(h)
+	}
+	mux.Handle("GET /opds", opdsAuth(catalog.Root))
+	mux.Handle("GET /opds/new", opdsAuth(catalog.Newest))
+	mux.Handle("GET /opds/categories", opdsAuth(catalog.Categories))
+	mux.Handle("GET /opds/categories/{slug}", opdsAuth(catalog.Category))
+	mux.Handle("GET /opds/authors", opdsAuth(catalog.Authors))
+	mux.Handle("GET /opds/authors/{slug}", opdsAuth(catalog.Author))
+	mux.Handle("GET /opds/search", opdsAuth(catalog.Search))
+	mux.Handle("GET /opds/opensearch.xml", opdsAuth(catalog.OpenSearch))
+
+	// Public Atom/RSS feeds ({format} is "atom" or "rss")
+	syndicate := feeds.New(db)
+	mux.HandleFunc("GET /feeds/new/{format}", syndicate.Newest)
+	mux.HandleFunc("GET /feeds/categories/{slug}/{format}", syndicate.Category)
+	mux.HandleFunc("GET /feeds/authors/{slug}/{format}", syndicate.Author)
+	mux.HandleFunc("GET /feeds/shorts/{format}", syndicate.Shorts)
+
 	// For-You feed
 	feed := foryou.Handler(db, rdb)
 	mux.Handle("GET /for-you", feed)
 	mux.Handle("GET /for-you/", feed)
 
 	// Auth
-	authStore := auth.NewSQLStore(db)
-	authH := auth.New(authStore, rdb)
-
 	mux.HandleFunc("POST /auth/register", authH.Register)
 	mux.Handle("POST /auth/login", middlewares.LoginRateLimit(rdb, http.HandlerFunc(authH.Login)))
+	mux.Handle("POST /auth/login/mfa", middlewares.LoginRateLimit(rdb, http.HandlerFunc(authH.LoginMFA)))
 	mux.HandleFunc("POST /auth/refresh", authH.Refresh)
 	mux.HandleFunc("POST /auth/logout", authH.Logout)
+	mux.HandleFunc("GET /.well-known/jwks.json", auth.JWKS)
 
 	// Protected auth endpoints
-	mux.Handle("GET /auth/me", middlewares.RequireAuth(db, http.HandlerFunc(authH.Me)))
-	mux.Handle("POST /auth/logout-all", middlewares.RequireAuth(db, http.HandlerFunc(authH.LogoutAll)))
-	mux.Handle("POST /auth/change-password", middlewares.RequireAuth(db, http.HandlerFunc(authH.ChangePassword)))
+	mux.Handle("GET /auth/me", middlewares.RequireAuth(db, sessions, http.HandlerFunc(authH.Me)))
+	mux.Handle("POST /auth/logout-all", middlewares.RequireAuth(db, sessions, http.HandlerFunc(authH.LogoutAll)))
+	mux.Handle("POST /auth/change-password", middlewares.RequireAuth(db, sessions, http.HandlerFunc(authH.ChangePassword)))
+	mux.Handle("GET /auth/sessions", middlewares.RequireAuth(db, sessions, http.HandlerFunc(authH.ListSessions)))
+	mux.Handle("DELETE /auth/sessions/{id}", middlewares.RequireAuth(db, sessions, http.HandlerFunc(authH.RevokeSession)))
+
+	// Two-factor auth (TOTP)
+	mux.Handle("GET /auth/2fa", middlewares.RequireAuth(db, sessions, http.HandlerFunc(authH.MFAStatus)))
+	mux.Handle("POST /auth/2fa/enroll", middlewares.RequireAuth(db, sessions, http.HandlerFunc(authH.EnrollMFA)))
+	mux.Handle("POST /auth/2fa/confirm", middlewares.RequireAuth(db, sessions, http.HandlerFunc(authH.ConfirmMFA)))
+	mux.Handle("POST /auth/2fa/disable", middlewares.RequireAuth(db, sessions, http.HandlerFunc(authH.DisableMFA)))
+	mux.Handle("POST /auth/2fa/recovery-codes", middlewares.RequireAuth(db, sessions, http.HandlerFunc(authH.RegenerateRecoveryCodes)))
+
+	// Passkeys (WebAuthn)
+	mux.HandleFunc("POST /auth/passkeys/login/begin", authH.BeginPasskeyLogin)
+	mux.Handle("POST /auth/passkeys/login/finish", middlewares.LoginRateLimit(rdb, http.HandlerFunc(authH.FinishPasskeyLogin)))
+	mux.Handle("POST /auth/passkeys/register/begin", middlewares.RequireAuth(db, sessions, http.HandlerFunc(authH.BeginPasskeyRegistration)))
+	mux.Handle("POST /auth/passkeys/register/finish", middlewares.RequireAuth(db, sessions, http.HandlerFunc(authH.FinishPasskeyRegistration)))
+	mux.Handle("GET /auth/passkeys", middlewares.RequireAuth(db, sessions, http.HandlerFunc(authH.ListPasskeys)))
+	mux.Handle("PATCH /auth/passkeys/{id}", middlewares.RequireAuth(db, sessions, http.HandlerFunc(authH.RenamePasskey)))
+	mux.Handle("DELETE /auth/passkeys/{id}", middlewares.RequireAuth(db, sessions, http.HandlerFunc(authH.RevokePasskey)))
 
 	// User book features (require auth)
-	mux.Handle("POST /user/reading-progress", middlewares.RequireAuth(db, userbooks.UpdateProgress(db)))
-	mux.Handle("GET /user/reading-progress/{bookId}", middlewares.RequireAuth(db, userbooks.GetProgress(db)))
-	mux.Handle("GET /user/continue-reading", middlewares.RequireAuth(db, userbooks.ContinueReading(db)))
-
-	mux.Handle("POST /user/favorites/{bookId}", middlewares.RequireAuth(db, userbooks.AddFavorite(db)))
-	mux.Handle("DELETE /user/favorites/{bookId}", middlewares.RequireAuth(db, userbooks.RemoveFavorite(db)))
-	mux.Handle("GET /user/favorites", middlewares.RequireAuth(db, userbooks.GetFavorites(db)))
-
-	mux.Handle("POST /user/books/{bookId}/notes", middlewares.RequireAuth(db, userbooks.AddNote(db)))
-	mux.Handle("GET /user/books/{bookId}/notes", middlewares.RequireAuth(db, userbooks.GetNotes(db)))
-
-	// Email verification
-	verify := &auth.VerifyDeps{DB: db, RDB: rdb, BaseURL: ""}
+	mux.Handle("POST /user/reading-progress", middlewares.RequireAuth(db, sessions, userbooks.UpdateProgress(db)))
+	mux.Handle("GET /user/reading-progress/{bookId}", middlewares.RequireAuth(db, sessions, userbooks.GetProgress(db)))
+	mux.Handle("POST /user/listening-progress", middlewares.RequireAuth(db, sessions, userbooks.UpdateListening(db)))
+	mux.Handle("GET /user/listening-progress/{bookId}", middlewares.RequireAuth(db, sessions, userbooks.GetListening(db)))
+	mux.Handle("GET /user/continue-reading", middlewares.RequireAuth(db, sessions, userbooks.ContinueReading(db)))
+
+	mux.Handle("POST /user/favorites/{bookId}", middlewares.RequireAuth(db, sessions, userbooks.AddFavorite(db)))
+	mux.Handle("DELETE /user/favorites/{bookId}", middlewares.RequireAuth(db, sessions, userbooks.RemoveFavorite(db)))
+	mux.Handle("GET /user/favorites", middlewares.RequireAuth(db, sessions, userbooks.GetFavorites(db)))
+
+	mux.Handle("POST /user/books/{bookId}/notes", middlewares.RequireAuth(db, sessions, userbooks.AddNote(db)))
+	mux.Handle("GET /user/books/{bookId}/notes", middlewares.RequireAuth(db, sessions, userbooks.GetNotes(db)))
+
+	// Email verification. AUTH_EXPOSE_VERIFY_LINKS=true echoes the link back
+	// for local frontends without a mailbox; never outside development.
+	production := strings.EqualFold(os.Getenv("APP_ENV"), "production")
+	expose, _ := strconv.ParseBool(os.Getenv("AUTH_EXPOSE_VERIFY_LINKS"))
+	verify := &auth.VerifyDeps{
+		DB:          db,
+		RDB:         rdb,
+		Mailer:      mailer,
+		BaseURL:     os.Getenv("PUBLIC_BASE_URL"),
+		ExposeLinks: expose && !production,
+	}
 	mux.Handle("POST /auth/send-verification",
-		middlewares.RequireAuth(db, verify.HandleSendVerification(
+		middlewares.RequireAuth(db, sessions, verify.HandleSendVerification(
 			func(r *http.Request) (string, bool) { return middlewares.UserIDFrom(r.Context()) },
 		)),
 	)
 	mux.HandleFunc("GET /auth/verify", verify.HandleVerify())
 
+	// Password reset (unauthenticated; responses never reveal whether an email exists).
+	// The emailed link opens the web app's reset form, so it uses APP_BASE_URL
+	// where the verify link above targets the API itself.
+	reset := &auth.ResetDeps{DB: db, RDB: rdb, Mailer: mailer, BaseURL: os.Getenv("APP_BASE_URL")}
+	mux.HandleFunc("POST /auth/forgot-password", reset.HandleForgotPassword())
+	mux.HandleFunc("POST /auth/reset-password", reset.HandleResetPassword())
+
 	// Admin (users, audit, stats, and admin-only book CRUD) — mounted via helper
-	MountAdmin(mux, db, rdb)
+	MountAdmin(mux, db, rdb, blobs, verify, sessions, auth.NewLockout(rdb))
 
 	return mux
 }
@@ -2,14 +2,15 @@ package auth
 
 import (
 	"encoding/json"
-	"fmt"
+	"errors"
 	"net/http"
-	"strconv"
 	"strings"
 
 	"github.com/5w1tchy/books-api/internal/api/httpx"
 	jwtutil "github.com/5w1tchy/books-api/internal/security/jwt"
 	"github.com/5w1tchy/books-api/internal/security/password"
+	"github.com/5w1tchy/books-api/internal/security/rbac"
+	"github.com/redis/go-redis/v9"
 )
 
 // Register creates a new user account
@@ -20,9 +21,6 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// DEBUG: Log what we received
-	fmt.Printf("DEBUG: Received registration request - Email: %s, Role: %s\n", req.Email, req.Role)
-
 	// Password policy: trim + min length (8). Warn-only strength info.
 	req.Password = strings.TrimSpace(req.Password)
 	if len(req.Password) < 8 || req.Email == "" {
@@ -30,11 +28,6 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// DEFAULT role to "user" ONLY if not specified or empty
-	if req.Role == "" {
-		req.Role = "user"
-	}
-
 	// Strength scoring (warn-only)
 	score, warnMsg, sugg := simpleStrength(req.Password, req.Email, req.Username)
 
@@ -44,28 +37,24 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	u, err := h.Store.CreateUser(req.Email, req.Username, hash, req.Role)
+	// Self-service accounts are always plain users; staff roles are granted
+	// through /admin/users/{id}/role
+	u, err := h.Store.CreateUser(req.Email, req.Username, hash, rbac.RoleUser)
 	if err != nil {
 		httpx.ErrorCode(w, http.StatusConflict, "conflict", "Cannot create user")
 		return
 	}
 
-	access, _, err := jwtutil.SignAccess(u.ID, u.TokenVersion, jwtutil.DefaultAccessTTL())
-	if err != nil {
-		httpx.ErrorCode(w, http.StatusInternalServerError, "jwt_error", "Failed to sign access token")
-		return
-	}
-
-	refresh, err := h.issueRefresh(r.Context(), u.ID, u.TokenVersion)
+	pair, err := h.issueTokens(r.Context(), u.ID, u.TokenVersion, SessionMetaFrom(r))
 	if err != nil {
-		httpx.ErrorCode(w, http.StatusInternalServerError, "refresh_error", "Failed to issue refresh token")
+		httpx.ErrorCode(w, http.StatusInternalServerError, "refresh_error", "Failed to issue tokens")
 		return
 	}
 
 	// Build response: always include password_score; include warning when score < 4
 	resp := map[string]any{
-		"access_token":   access,
-		"refresh_token":  refresh,
+		"access_token":   pair.AccessToken,
+		"refresh_token":  pair.RefreshToken,
 		"password_score": score,
 	}
 	if score < 4 && warnMsg != "" {
@@ -85,13 +74,18 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 		httpx.ErrorCode(w, http.StatusBadRequest, "bad_request", "Invalid JSON")
 		return
 	}
+	if !h.checkLockout(w, r, req.Email) {
+		return
+	}
 	u, err := h.Store.FindUserByEmail(req.Email)
 	if err != nil || u.ID == "" {
+		h.loginFailed(r, req.Email, "")
 		httpx.ErrorCode(w, http.StatusUnauthorized, "invalid_credentials", "Invalid email or password")
 		return
 	}
 	ok, needsRehash, err := password.Verify(req.Password, u.PasswordHash)
 	if err != nil || !ok {
+		h.loginFailed(r, req.Email, u.ID)
 		httpx.ErrorCode(w, http.StatusUnauthorized, "invalid_credentials", "Invalid email or password")
 		return
 	}
@@ -101,18 +95,26 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	access, _, err := jwtutil.SignAccess(u.ID, u.TokenVersion, jwtutil.DefaultAccessTTL())
-	if err != nil {
-		httpx.ErrorCode(w, http.StatusInternalServerError, "jwt_error", "Failed to sign access token")
+	// Second step: tokens only come from /auth/login/mfa, which clears the
+	// failures once the code is right
+	if u.MFAEnabled {
+		ch, err := h.newMFAChallenge(r.Context(), u.ID, req.Email, u.TokenVersion)
+		if err != nil {
+			httpx.ErrorCode(w, http.StatusInternalServerError, "mfa_error", "Failed to start two-factor login")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, ch)
 		return
 	}
-	refresh, err := h.issueRefresh(r.Context(), u.ID, u.TokenVersion)
+
+	pair, err := h.issueTokens(r.Context(), u.ID, u.TokenVersion, SessionMetaFrom(r))
 	if err != nil {
-		httpx.ErrorCode(w, http.StatusInternalServerError, "refresh_error", "Failed to issue refresh token")
+		httpx.ErrorCode(w, http.StatusInternalServerError, "refresh_error", "Failed to issue tokens")
 		return
 	}
+	h.loginSucceeded(r, req.Email)
 
-	httpx.WriteJSON(w, http.StatusOK, TokenPair{AccessToken: access, RefreshToken: refresh})
+	httpx.WriteJSON(w, http.StatusOK, pair)
 }
 
 // Refresh generates new tokens using a refresh token
@@ -122,40 +124,50 @@ func (h *Handler) Refresh(w http.ResponseWriter, r *http.Request) {
 		httpx.ErrorCode(w, http.StatusBadRequest, "bad_request", "Invalid JSON")
 		return
 	}
-	key := "rt:" + req.RefreshToken
-
+	// Claiming the token spends it: every outcome below ends its use
 	ctx := r.Context()
-	val, err := h.RDB.Get(ctx, key).Result()
+	val, err := h.sessions().claim(ctx, req.RefreshToken)
+	if errors.Is(err, redis.Nil) && h.handleRefreshReuse(r, req.RefreshToken) {
+		httpx.ErrorCode(w, http.StatusUnauthorized, "token_reused", "Refresh token was already used; the session has been ended")
+		return
+	}
 	if err != nil {
 		httpx.ErrorCode(w, http.StatusUnauthorized, "invalid_refresh", "Invalid refresh token")
 		return
 	}
 
-	parts := strings.SplitN(val, "|", 2) // value: userID|tokenVersion
-	if len(parts) != 2 {
+	userID, tv, sid, ok := parseRefresh(val)
+	if !ok {
 		httpx.ErrorCode(w, http.StatusUnauthorized, "invalid_refresh", "Invalid refresh token")
 		return
 	}
-	userID := parts[0]
-	tv, _ := strconv.Atoi(parts[1])
 
 	// confirm token_version is current
 	var dbVer int
-	if err := h.Store.(*SQLStore).DB.QueryRowContext(ctx,
+	if err := h.DB.QueryRowContext(ctx,
 		`SELECT COALESCE(token_version,1) FROM public.users WHERE id=$1`, userID).Scan(&dbVer); err != nil || dbVer != tv {
 		httpx.ErrorCode(w, http.StatusUnauthorized, "token_revoked", "Token has been revoked")
 		return
 	}
 
-	// rotate refresh
-	_ = h.RDB.Del(ctx, key).Err()
-	newRefresh, err := h.issueRefresh(ctx, userID, dbVer)
+	// rotate refresh within its session; tokens from before sessions get one
+	meta := SessionMetaFrom(r)
+	var newRefresh string
+	if sid == "" {
+		newRefresh, sid, err = h.issueRefresh(ctx, userID, dbVer, meta)
+	} else {
+		newRefresh, err = h.sessions().rotate(ctx, userID, dbVer, sid, req.RefreshToken, meta)
+	}
+	if errors.Is(err, errSessionGone) {
+		httpx.ErrorCode(w, http.StatusUnauthorized, "token_revoked", "Token has been revoked")
+		return
+	}
 	if err != nil {
 		httpx.ErrorCode(w, http.StatusInternalServerError, "refresh_error", "Failed to issue refresh token")
 		return
 	}
 
-	access, _, err := jwtutil.SignAccess(userID, dbVer, jwtutil.DefaultAccessTTL())
+	access, _, err := jwtutil.SignSessionAccess(userID, sid, dbVer, jwtutil.DefaultAccessTTL())
 	if err != nil {
 		httpx.ErrorCode(w, http.StatusInternalServerError, "jwt_error", "Failed to sign access token")
 		return
@@ -164,6 +176,32 @@ func (h *Handler) Refresh(w http.ResponseWriter, r *http.Request) {
 	httpx.WriteJSON(w, http.StatusOK, TokenPair{AccessToken: access, RefreshToken: newRefresh})
 }
 
+// handleRefreshReuse deals with a refresh token that was already rotated.
+// Either the client or an attacker holds a copy, and there is no telling
+// which, so the whole session (token family) is ended; with
+// AUTH_REFRESH_REUSE_LOGOUT_ALL every other session goes too.
+func (h *Handler) handleRefreshReuse(r *http.Request, token string) bool {
+	ctx := r.Context()
+	userID, sid, ok := h.sessions().rotatedFamily(ctx, token)
+	if !ok {
+		return false
+	}
+	_ = h.sessions().drop(ctx, userID, sid)
+
+	logoutAll := reuseLogsOutAll()
+	if logoutAll {
+		if _, err := h.DB.ExecContext(ctx,
+			`UPDATE public.users SET token_version = COALESCE(token_version,1) + 1, updated_at=now() WHERE id=$1`, userID); err == nil {
+			_ = revokeRefreshTokens(ctx, h.RDB, userID)
+		}
+	}
+	recordSecurityEvent(ctx, h.DB, r, userID, EventRefreshReuse, map[string]any{
+		"session_id": sid,
+		"logout_all": logoutAll,
+	})
+	return true
+}
+
 // Logout invalidates a single refresh token
 func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
 	var req RefreshRequest
@@ -172,6 +210,12 @@ func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
 		httpx.WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 		return
 	}
-	_ = h.RDB.Del(r.Context(), "rt:"+req.RefreshToken).Err()
+	ctx := r.Context()
+	val, err := h.RDB.Get(ctx, "rt:"+req.RefreshToken).Result()
+	if userID, _, sid, ok := parseRefresh(val); err == nil && ok && sid != "" {
+		_ = h.sessions().drop(ctx, userID, sid)
+	} else {
+		h.RDB.Del(ctx, "rt:"+req.RefreshToken)
+	}
 	httpx.WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/5w1tchy/books-api/internal/security/rbac"
+)
+
+// fakeUsers is an in-memory UserStore.
+type fakeUsers struct {
+	users map[string]User // by email
+}
+
+func (f *fakeUsers) CreateUser(email, username, passwordHash, role string) (User, error) {
+	u := User{ID: "u-" + email, Email: email, Username: username, PasswordHash: passwordHash, Role: role, TokenVersion: 1}
+	if f.users == nil {
+		f.users = map[string]User{}
+	}
+	f.users[email] = u
+	return u, nil
+}
+
+func (f *fakeUsers) FindUserByEmail(email string) (User, error) {
+	return f.users[email], nil
+}
+
+func (f *fakeUsers) UpdateUserPasswordHash(userID, newHash string) error { return nil }
+
+func TestRegisterIgnoresRequestedRole(t *testing.T) {
+	_, rdb := newTestRedis(t)
+	store := &fakeUsers{}
+	h := &Handler{Store: store, RDB: rdb}
+
+	body := `{"email":"mallory@example.com","password":"long enough password","role":"admin"}`
+	rec := httptest.NewRecorder()
+	h.Register(rec, httptest.NewRequest(http.MethodPost, "/auth/register", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("register: %d %s", rec.Code, rec.Body)
+	}
+	if role := store.users["mallory@example.com"].Role; role != rbac.RoleUser {
+		t.Fatalf("registered with role %q, want %q", role, rbac.RoleUser)
+	}
+}
@@ -0,0 +1,22 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/5w1tchy/books-api/internal/api/httpx"
+	jwtutil "github.com/5w1tchy/books-api/internal/security/jwt"
+)
+
+// JWKS handles GET /.well-known/jwks.json: the public keys access tokens
+// are signed with, by "kid", so other services can verify them. The set is
+// empty while tokens are HS256.
+func JWKS(w http.ResponseWriter, r *http.Request) {
+	set, err := jwtutil.JWKS()
+	if err != nil {
+		httpx.ErrorCode(w, http.StatusInternalServerError, "jwks_error", "Failed to load signing keys")
+		return
+	}
+	// Short enough that verifiers pick up a rotation before the old key goes
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	httpx.WriteJSON(w, http.StatusOK, set)
+}
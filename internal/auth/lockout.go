@@ -0,0 +1,322 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/5w1tchy/books-api/internal/api/httpx"
+	"github.com/5w1tchy/books-api/internal/api/middlewares"
+	"github.com/5w1tchy/books-api/internal/mail"
+	"github.com/redis/go-redis/v9"
+)
+
+// Failed logins are counted per account, keyed by the normalized email so
+// unknown addresses behave exactly like real ones:
+//
+//	lo:fail:<email>  failure count, forgotten after LockoutPolicy.Window
+//	lo:wait:<email>  exists while the next attempt must wait (backoff)
+//	lo:lock:<email>  AccountLock JSON while the account is locked
+//	lo:locked        sorted set email → lock expiry, for the admin list
+//
+// This sits behind the per-IP LoginRateLimit: that one stops a single
+// client, this one stops many clients going after one account.
+const (
+	lockoutFailPrefix = "lo:fail:"
+	lockoutWaitPrefix = "lo:wait:"
+	lockoutLockPrefix = "lo:lock:"
+	lockoutIndexKey   = "lo:locked"
+)
+
+// LockoutPolicy decides how failures slow down and lock an account.
+type LockoutPolicy struct {
+	FreeAttempts int           // failures allowed before delays start
+	BaseDelay    time.Duration // first delay, doubled with each further failure
+	MaxDelay     time.Duration
+	Threshold    int           // failures that lock the account
+	LockFor      time.Duration // how long a lock lasts
+	Window       time.Duration // failures are forgotten after this long without one
+}
+
+// LockoutPolicyFromEnv reads AUTH_LOCKOUT_FREE_ATTEMPTS (3),
+// AUTH_LOCKOUT_BASE_DELAY (1s), AUTH_LOCKOUT_MAX_DELAY (1m),
+// AUTH_LOCKOUT_THRESHOLD (10), AUTH_LOCKOUT_DURATION (15m) and
+// AUTH_LOCKOUT_WINDOW (1h).
+func LockoutPolicyFromEnv() LockoutPolicy {
+	return LockoutPolicy{
+		FreeAttempts: envPositiveInt("AUTH_LOCKOUT_FREE_ATTEMPTS", 3),
+		BaseDelay:    envPositiveDuration("AUTH_LOCKOUT_BASE_DELAY", time.Second),
+		MaxDelay:     envPositiveDuration("AUTH_LOCKOUT_MAX_DELAY", time.Minute),
+		Threshold:    envPositiveInt("AUTH_LOCKOUT_THRESHOLD", 10),
+		LockFor:      envPositiveDuration("AUTH_LOCKOUT_DURATION", 15*time.Minute),
+		Window:       envPositiveDuration("AUTH_LOCKOUT_WINDOW", time.Hour),
+	}
+}
+
+// delay is the wait imposed after the nth failure in a row.
+func (p LockoutPolicy) delay(n int) time.Duration {
+	if n <= p.FreeAttempts {
+		return 0
+	}
+	d := p.BaseDelay
+	for i := p.FreeAttempts + 1; i < n && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	return min(d, p.MaxDelay)
+}
+
+// AccountLock is the lockout state of one account.
+type AccountLock struct {
+	Email    string     `json:"email"`
+	UserID   string     `json:"user_id,omitempty"` // empty for unknown emails
+	Failures int        `json:"failures"`
+	Locked   bool       `json:"locked"`
+	LockedAt *time.Time `json:"locked_at,omitempty"`
+	Until    *time.Time `json:"until,omitempty"`
+	LastIP   string     `json:"last_ip,omitempty"`
+}
+
+// Lockout tracks failed logins per account in Redis.
+type Lockout struct {
+	RDB    *redis.Client
+	Policy LockoutPolicy
+}
+
+// NewLockout returns a Lockout with the policy from the environment.
+func NewLockout(rdb *redis.Client) *Lockout {
+	return &Lockout{RDB: rdb, Policy: LockoutPolicyFromEnv()}
+}
+
+func (h *Handler) lockout() *Lockout { return NewLockout(h.RDB) }
+
+// normalizeEmail is the account key failures are counted under.
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// Check returns how long the account must wait before its next attempt,
+// and whether that is because it is locked.
+func (l *Lockout) Check(ctx context.Context, email string) (time.Duration, bool, error) {
+	email = normalizeEmail(email)
+	pipe := l.RDB.Pipeline()
+	lock := pipe.PTTL(ctx, lockoutLockPrefix+email)
+	wait := pipe.PTTL(ctx, lockoutWaitPrefix+email)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, false, err
+	}
+	if d := lock.Val(); d > 0 {
+		return d, true, nil
+	}
+	if d := wait.Val(); d > 0 {
+		return d, false, nil
+	}
+	return 0, false, nil
+}
+
+// Fail records a failed attempt. It returns the lock when this failure
+// locked the account (nil otherwise), so the caller can tell the owner
+// once per lock.
+func (l *Lockout) Fail(ctx context.Context, email, userID, ip string) (*AccountLock, error) {
+	email = normalizeEmail(email)
+	p := l.Policy
+	pipe := l.RDB.TxPipeline()
+	incr := pipe.Incr(ctx, lockoutFailPrefix+email)
+	pipe.Expire(ctx, lockoutFailPrefix+email, p.Window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	n := int(incr.Val())
+
+	if n < p.Threshold {
+		if d := p.delay(n); d > 0 {
+			return nil, l.RDB.Set(ctx, lockoutWaitPrefix+email, "1", d).Err()
+		}
+		return nil, nil
+	}
+
+	now := time.Now().UTC()
+	until := now.Add(p.LockFor)
+	lock := AccountLock{Email: email, UserID: userID, Failures: n, Locked: true, LockedAt: &now, Until: &until, LastIP: ip}
+	b, err := json.Marshal(lock)
+	if err != nil {
+		return nil, err
+	}
+	locked, err := l.RDB.SetNX(ctx, lockoutLockPrefix+email, b, p.LockFor).Result()
+	if err != nil || !locked {
+		return nil, err // already locked
+	}
+	// Keep the count through the lock, so one more failure after it ends
+	// locks again straight away
+	pipe = l.RDB.TxPipeline()
+	pipe.Expire(ctx, lockoutFailPrefix+email, p.LockFor+p.Window)
+	pipe.ZAdd(ctx, lockoutIndexKey, redis.Z{Score: float64(until.Unix()), Member: email})
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	return &lock, nil
+}
+
+// Succeed forgets the failures once a login has issued tokens.
+func (l *Lockout) Succeed(ctx context.Context, email string) error {
+	email = normalizeEmail(email)
+	return l.RDB.Del(ctx, lockoutFailPrefix+email, lockoutWaitPrefix+email).Err()
+}
+
+// LockStatus returns the failures and any lock on an account.
+func (l *Lockout) LockStatus(ctx context.Context, email string) (AccountLock, error) {
+	email = normalizeEmail(email)
+	st := AccountLock{Email: email}
+	raw, err := l.RDB.Get(ctx, lockoutLockPrefix+email).Bytes()
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(raw, &st); err != nil {
+			return AccountLock{}, err
+		}
+	case !errors.Is(err, redis.Nil):
+		return AccountLock{}, err
+	}
+	n, err := l.RDB.Get(ctx, lockoutFailPrefix+email).Int()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return AccountLock{}, err
+	}
+	st.Failures = n
+	return st, nil
+}
+
+// ListLocks returns the accounts that are locked right now.
+func (l *Lockout) ListLocks(ctx context.Context) ([]AccountLock, error) {
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	if err := l.RDB.ZRemRangeByScore(ctx, lockoutIndexKey, "-inf", now).Err(); err != nil {
+		return nil, err
+	}
+	emails, err := l.RDB.ZRange(ctx, lockoutIndexKey, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	out := []AccountLock{}
+	for _, email := range emails {
+		st, err := l.LockStatus(ctx, email)
+		if err != nil {
+			return nil, err
+		}
+		if st.Locked { // else cleared or expired since the index was read
+			out = append(out, st)
+		}
+	}
+	return out, nil
+}
+
+// ClearLock unlocks an account and forgets its failures. It reports
+// whether the account was locked.
+func (l *Lockout) ClearLock(ctx context.Context, email string) (bool, error) {
+	email = normalizeEmail(email)
+	pipe := l.RDB.TxPipeline()
+	lock := pipe.Del(ctx, lockoutLockPrefix+email)
+	pipe.Del(ctx, lockoutFailPrefix+email, lockoutWaitPrefix+email)
+	pipe.ZRem(ctx, lockoutIndexKey, email)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, err
+	}
+	return lock.Val() > 0, nil
+}
+
+// checkLockout answers 429 when the account must wait or is locked. Redis
+// trouble lets the attempt through; the per-IP limiter still applies.
+func (h *Handler) checkLockout(w http.ResponseWriter, r *http.Request, email string) bool {
+	if h.RDB == nil {
+		return true
+	}
+	wait, locked, err := h.lockout().Check(r.Context(), email)
+	if err != nil {
+		log.Printf("[auth] lockout check: %v", err)
+		return true
+	}
+	if wait <= 0 {
+		return true
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	if locked {
+		httpx.ErrorCode(w, http.StatusTooManyRequests, "account_locked", "Too many failed sign-ins; this account is temporarily locked")
+		return false
+	}
+	httpx.ErrorCode(w, http.StatusTooManyRequests, "login_delayed", "Too many failed sign-ins; wait before trying again")
+	return false
+}
+
+// loginFailed counts a failed login and, when it locks a real account,
+// records a security event and emails the owner.
+func (h *Handler) loginFailed(r *http.Request, email, userID string) {
+	if h.RDB == nil {
+		return
+	}
+	ctx := r.Context()
+	lock, err := h.lockout().Fail(ctx, email, userID, middlewares.ClientIP(r))
+	if err != nil {
+		log.Printf("[auth] recording login failure: %v", err)
+		return
+	}
+	if lock == nil || userID == "" {
+		return
+	}
+	recordSecurityEvent(ctx, h.DB, r, userID, EventAccountLocked, map[string]any{
+		"failures": lock.Failures, "until": lock.Until,
+	})
+	if h.Mailer == nil {
+		return
+	}
+	// The request came from whoever is guessing, so its Accept-Language
+	// says nothing about the owner
+	msg, err := mail.Render(mail.TemplateAccountLocked, mail.DefaultLocale, lock.Email, mail.Data{
+		"Attempts": lock.Failures,
+		"IP":       lock.LastIP,
+		"Minutes":  int(math.Ceil(h.lockout().Policy.LockFor.Minutes())),
+	})
+	if err == nil {
+		err = h.Mailer.Send(ctx, msg)
+	}
+	if err != nil {
+		log.Printf("[auth] account locked mail for %s: %v", userID, err)
+	}
+}
+
+// loginSucceeded forgets the account's failures once it has signed in.
+func (h *Handler) loginSucceeded(r *http.Request, email string) {
+	if h.RDB == nil {
+		return
+	}
+	if err := h.lockout().Succeed(r.Context(), email); err != nil {
+		log.Printf("[auth] clearing login failures: %v", err)
+	}
+}
+
+// AllowLogin, LoginFailed and LoginSucceeded put password checks made
+// outside this package (HTTP Basic) behind the same lockout, see
+// middlewares.AccountGuard.
+func (h *Handler) AllowLogin(w http.ResponseWriter, r *http.Request, email string) bool {
+	return h.checkLockout(w, r, email)
+}
+
+func (h *Handler) LoginFailed(r *http.Request, email, userID string) { h.loginFailed(r, email, userID) }
+
+func (h *Handler) LoginSucceeded(r *http.Request, email string) { h.loginSucceeded(r, email) }
+
+func envPositiveInt(key string, def int) int {
+	if n, err := strconv.Atoi(os.Getenv(key)); err == nil && n > 0 {
+		return n
+	}
+	return def
+}
+
+func envPositiveDuration(key string, def time.Duration) time.Duration {
+	if d, err := time.ParseDuration(os.Getenv(key)); err == nil && d > 0 {
+		return d
+	}
+	return def
+}
@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLockoutDelay(t *testing.T) {
+	p := LockoutPolicy{FreeAttempts: 3, BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+	for n, want := range map[int]time.Duration{
+		1: 0,
+		3: 0,
+		4: time.Second,
+		5: 2 * time.Second,
+		6: 4 * time.Second,
+		7: 5 * time.Second, // capped
+		9: 5 * time.Second,
+	} {
+		if got := p.delay(n); got != want {
+			t.Errorf("delay(%d) = %s, want %s", n, got, want)
+		}
+	}
+}
+
+func testLockout(t *testing.T) *Lockout {
+	t.Helper()
+	_, rdb := newTestRedis(t)
+	return &Lockout{RDB: rdb, Policy: LockoutPolicy{
+		FreeAttempts: 1, BaseDelay: time.Second, MaxDelay: time.Minute,
+		Threshold: 3, LockFor: 15 * time.Minute, Window: time.Hour,
+	}}
+}
+
+func TestLockoutFailLocksOnce(t *testing.T) {
+	l := testLockout(t)
+	ctx := t.Context()
+
+	if lock, err := l.Fail(ctx, "Reader@Example.com", "u-1", "203.0.113.7"); err != nil || lock != nil {
+		t.Fatalf("first failure = %+v, %v", lock, err)
+	}
+	if wait, _, _ := l.Check(ctx, "reader@example.com"); wait != 0 {
+		t.Fatalf("wait after a free attempt = %s", wait)
+	}
+	if _, err := l.Fail(ctx, "reader@example.com", "u-1", "203.0.113.7"); err != nil {
+		t.Fatal(err)
+	}
+	if wait, locked, _ := l.Check(ctx, "reader@example.com"); wait <= 0 || locked {
+		t.Fatalf("after the free attempts: wait %s, locked %v", wait, locked)
+	}
+
+	lock, err := l.Fail(ctx, "reader@example.com", "u-1", "203.0.113.7")
+	if err != nil || lock == nil || lock.Failures != 3 || lock.UserID != "u-1" {
+		t.Fatalf("threshold failure = %+v, %v", lock, err)
+	}
+	if wait, locked, _ := l.Check(ctx, "reader@example.com"); !locked || wait <= 0 {
+		t.Fatal("account not locked at the threshold")
+	}
+	// Further failures while locked don't report a new lock
+	if again, err := l.Fail(ctx, "reader@example.com", "u-1", "198.51.100.1"); err != nil || again != nil {
+		t.Fatalf("failure while locked = %+v, %v", again, err)
+	}
+	if st, _ := l.LockStatus(ctx, "reader@example.com"); st.LastIP != "203.0.113.7" || st.Failures != 4 {
+		t.Fatalf("LockStatus = %+v", st)
+	}
+	locks, err := l.ListLocks(ctx)
+	if err != nil || len(locks) != 1 || locks[0].Email != "reader@example.com" {
+		t.Fatalf("ListLocks = %+v, %v", locks, err)
+	}
+}
+
+func TestLockoutSucceedForgetsFailures(t *testing.T) {
+	l := testLockout(t)
+	ctx := t.Context()
+
+	for range 2 {
+		if _, err := l.Fail(ctx, "reader@example.com", "u-1", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.Succeed(ctx, " Reader@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if wait, _, _ := l.Check(ctx, "reader@example.com"); wait != 0 {
+		t.Fatalf("wait after success = %s", wait)
+	}
+	if st, _ := l.LockStatus(ctx, "reader@example.com"); st.Failures != 0 {
+		t.Fatalf("failures after success = %d", st.Failures)
+	}
+}
+
+func TestLockoutClearLock(t *testing.T) {
+	l := testLockout(t)
+	ctx := t.Context()
+
+	for range 3 {
+		if _, err := l.Fail(ctx, "reader@example.com", "u-1", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if cleared, err := l.ClearLock(ctx, "reader@example.com"); err != nil || !cleared {
+		t.Fatalf("ClearLock = %v, %v", cleared, err)
+	}
+	if wait, locked, _ := l.Check(ctx, "reader@example.com"); wait != 0 || locked {
+		t.Fatal("account still waiting after ClearLock")
+	}
+	if locks, _ := l.ListLocks(ctx); len(locks) != 0 {
+		t.Fatalf("ListLocks after clear = %+v", locks)
+	}
+	if cleared, err := l.ClearLock(ctx, "reader@example.com"); err != nil || cleared {
+		t.Fatalf("second ClearLock = %v, %v", cleared, err)
+	}
+}
@@ -0,0 +1,508 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/5w1tchy/books-api/internal/api/httpx"
+	"github.com/5w1tchy/books-api/internal/api/middlewares"
+	"github.com/5w1tchy/books-api/internal/mail"
+	"github.com/5w1tchy/books-api/internal/security/password"
+	"github.com/5w1tchy/books-api/internal/security/totp"
+)
+
+const (
+	mfaEnrollPrefix    = "mfa:enroll:" // user_id → sealed secret awaiting confirmation
+	mfaChallengePrefix = "mfa:ch:"     // challenge token → userID|tokenVersion|email
+	mfaTriesPrefix     = "mfa:tries:"  // challenge token → failed attempts
+	mfaUserTriesPrefix = "mfa:utries:" // user_id → step-up attempts (2FA settings, passkeys)
+	mfaEnrollTTL       = 15 * time.Minute
+	mfaChallengeTTL    = 5 * time.Minute
+	mfaUserTriesTTL    = 15 * time.Minute
+	mfaMaxTries        = 5
+	recoveryCodeCount  = 10
+)
+
+type mfaCodeRequest struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+	Password     string `json:"password"`
+}
+
+// MFAStatus handles GET /auth/2fa
+func (h *Handler) MFAStatus(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middlewares.UserIDFrom(r.Context())
+	if !ok {
+		httpx.ErrorCode(w, http.StatusUnauthorized, "unauthorized", "Unauthorized")
+		return
+	}
+	var (
+		enabledAt *time.Time
+		remaining int
+	)
+	err := h.DB.QueryRowContext(r.Context(), `
+		SELECT u.totp_enabled_at,
+		       (SELECT count(*) FROM public.user_recovery_codes c
+		         WHERE c.user_id = u.id AND c.used_at IS NULL)
+		FROM public.users u WHERE u.id = $1`, userID).Scan(&enabledAt, &remaining)
+	if err != nil {
+		httpx.ErrorCode(w, http.StatusNotFound, "not_found", "User not found")
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, map[string]any{
+		"enabled":                  enabledAt != nil,
+		"enabled_at":               enabledAt,
+		"recovery_codes_remaining": remaining,
+	})
+}
+
+// EnrollMFA handles POST /auth/2fa/enroll {password}: it creates a secret and
+// keeps it pending until ConfirmMFA proves the authenticator app has it. The
+// password is asked for as in DisableMFA, so an access token alone can't put
+// an attacker's authenticator on the account.
+func (h *Handler) EnrollMFA(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middlewares.UserIDFrom(r.Context())
+	if !ok {
+		httpx.ErrorCode(w, http.StatusUnauthorized, "unauthorized", "Unauthorized")
+		return
+	}
+	var req mfaCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Password == "" {
+		httpx.ErrorCode(w, http.StatusBadRequest, "invalid_input", "Invalid input")
+		return
+	}
+	ctx := r.Context()
+
+	var (
+		email, storedHash string
+		enabled           bool
+	)
+	if err := h.DB.QueryRowContext(ctx,
+		`SELECT email, password_hash, totp_enabled_at IS NOT NULL FROM public.users WHERE id=$1`, userID).
+		Scan(&email, &storedHash, &enabled); err != nil {
+		httpx.ErrorCode(w, http.StatusNotFound, "not_found", "User not found")
+		return
+	}
+	if enabled {
+		httpx.ErrorCode(w, http.StatusConflict, "mfa_already_enabled", "Two-factor authentication is already enabled")
+		return
+	}
+	if !h.takeMFATry(w, r, userID, email) {
+		return
+	}
+	if okPass, _, err := password.Verify(req.Password, storedHash); err != nil || !okPass {
+		h.loginFailed(r, email, userID)
+		httpx.ErrorCode(w, http.StatusForbidden, "forbidden", "Invalid password")
+		return
+	}
+	h.RDB.Del(ctx, mfaUserTriesPrefix+userID)
+
+	secret, err := totp.NewSecret()
+	if err != nil {
+		httpx.ErrorCode(w, http.StatusInternalServerError, "mfa_error", "Failed to create secret")
+		return
+	}
+	sealed, err := totp.Seal(h.TOTPKey, secret)
+	if err != nil {
+		httpx.ErrorCode(w, http.StatusInternalServerError, "mfa_error", "Failed to create secret")
+		return
+	}
+	if err := h.RDB.Set(ctx, mfaEnrollPrefix+userID, sealed, mfaEnrollTTL).Err(); err != nil {
+		httpx.ErrorCode(w, http.StatusInternalServerError, "mfa_error", "Failed to store secret")
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, map[string]any{
+		"secret":      secret,
+		"otpauth_uri": totp.URI(totpIssuer(), email, secret),
+		"expires_in":  int(mfaEnrollTTL / time.Second),
+	})
+}
+
+// ConfirmMFA handles POST /auth/2fa/confirm {code}. It turns 2FA on, returns
+// the recovery codes (the only time they are shown), rotates the user's
+// tokens, so every token of an enrolled account has been through 2FA, and
+// emails the owner.
+func (h *Handler) ConfirmMFA(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middlewares.UserIDFrom(r.Context())
+	if !ok {
+		httpx.ErrorCode(w, http.StatusUnauthorized, "unauthorized", "Unauthorized")
+		return
+	}
+	var req mfaCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		httpx.ErrorCode(w, http.StatusBadRequest, "invalid_input", "Invalid input")
+		return
+	}
+	ctx := r.Context()
+
+	sealed, err := h.RDB.Get(ctx, mfaEnrollPrefix+userID).Result()
+	if err != nil {
+		httpx.ErrorCode(w, http.StatusConflict, "no_pending_enrollment", "Start enrollment first")
+		return
+	}
+	secret, err := totp.Open(h.TOTPKey, sealed)
+	if err != nil {
+		httpx.ErrorCode(w, http.StatusConflict, "no_pending_enrollment", "Start enrollment first")
+		return
+	}
+	step, ok := totp.Validate(secret, req.Code, time.Now())
+	if !ok {
+		httpx.ErrorCode(w, http.StatusForbidden, "invalid_code", "Invalid code")
+		return
+	}
+
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil {
+		httpx.ErrorCode(w, http.StatusInternalServerError, "update_failed", "Failed to enable two-factor authentication")
+		return
+	}
+	defer tx.Rollback()
+
+	var (
+		tv    int
+		email string
+	)
+	err = tx.QueryRowContext(ctx, `
+		UPDATE public.users
+		   SET totp_secret=$1, totp_enabled_at=now(), totp_last_step=$2,
+		       token_version=COALESCE(token_version,1)+1, updated_at=now()
+		 WHERE id=$3 AND totp_enabled_at IS NULL
+		RETURNING token_version, email`, sealed, step, userID).Scan(&tv, &email)
+	if errors.Is(err, sql.ErrNoRows) {
+		httpx.ErrorCode(w, http.StatusConflict, "mfa_already_enabled", "Two-factor authentication is already enabled")
+		return
+	}
+	if err != nil {
+		httpx.ErrorCode(w, http.StatusInternalServerError, "update_failed", "Failed to enable two-factor authentication")
+		return
+	}
+	codes, err := replaceRecoveryCodes(ctx, tx, userID)
+	if err != nil || tx.Commit() != nil {
+		httpx.ErrorCode(w, http.StatusInternalServerError, "update_failed", "Failed to enable two-factor authentication")
+		return
+	}
+
+	h.RDB.Del(ctx, mfaEnrollPrefix+userID)
+	_ = revokeRefreshTokens(ctx, h.RDB, userID)
+	h.notifyOwner(r, userID, email, EventMFAEnabled, mail.TemplateMFAEnabled, nil)
+
+	pair, err := h.issueTokens(ctx, userID, tv, SessionMetaFrom(r))
+	if err != nil {
+		httpx.ErrorCode(w, http.StatusInternalServerError, "refresh_error", "Failed to issue tokens")
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, map[string]any{
+		"access_token":   pair.AccessToken,
+		"refresh_token":  pair.RefreshToken,
+		"recovery_codes": codes,
+	})
+}
+
+// DisableMFA handles POST /auth/2fa/disable {password, code | recovery_code}
+func (h *Handler) DisableMFA(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middlewares.UserIDFrom(r.Context())
+	if !ok {
+		httpx.ErrorCode(w, http.StatusUnauthorized, "unauthorized", "Unauthorized")
+		return
+	}
+	var req mfaCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Password == "" {
+		httpx.ErrorCode(w, http.StatusBadRequest, "invalid_input", "Invalid input")
+		return
+	}
+	ctx := r.Context()
+
+	var email, storedHash string
+	if err := h.DB.QueryRowContext(ctx,
+		`SELECT email, password_hash FROM public.users WHERE id=$1`, userID).Scan(&email, &storedHash); err != nil {
+		httpx.ErrorCode(w, http.StatusNotFound, "not_found", "User not found")
+		return
+	}
+	if !h.takeMFATry(w, r, userID, email) {
+		return
+	}
+	if okPass, _, err := password.Verify(req.Password, storedHash); err != nil || !okPass {
+		h.loginFailed(r, email, userID)
+		httpx.ErrorCode(w, http.StatusForbidden, "forbidden", "Invalid password")
+		return
+	}
+	if ok, err := h.verifySecondFactor(ctx, userID, req.Code, req.RecoveryCode); err != nil {
+		httpx.ErrorCode(w, http.StatusInternalServerError, "mfa_error", "Failed to check code")
+		return
+	} else if !ok {
+		h.loginFailed(r, email, userID)
+		httpx.ErrorCode(w, http.StatusForbidden, "invalid_code", "Invalid code")
+		return
+	}
+	h.RDB.Del(ctx, mfaUserTriesPrefix+userID)
+
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil {
+		httpx.ErrorCode(w, http.StatusInternalServerError, "update_failed", "Failed to disable two-factor authentication")
+		return
+	}
+	defer tx.Rollback()
+	_, err = tx.ExecContext(ctx, `
+		UPDATE public.users
+		   SET totp_secret=NULL, totp_enabled_at=NULL, totp_last_step=0, updated_at=now()
+		 WHERE id=$1`, userID)
+	if err == nil {
+		_, err = tx.ExecContext(ctx, `DELETE FROM public.user_recovery_codes WHERE user_id=$1`, userID)
+	}
+	if err != nil || tx.Commit() != nil {
+		httpx.ErrorCode(w, http.StatusInternalServerError, "update_failed", "Failed to disable two-factor authentication")
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// RegenerateRecoveryCodes handles POST /auth/2fa/recovery-codes {code}; the
+// old codes stop working.
+func (h *Handler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middlewares.UserIDFrom(r.Context())
+	if !ok {
+		httpx.ErrorCode(w, http.StatusUnauthorized, "unauthorized", "Unauthorized")
+		return
+	}
+	var req mfaCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		httpx.ErrorCode(w, http.StatusBadRequest, "invalid_input", "Invalid input")
+		return
+	}
+	ctx := r.Context()
+
+	var email string
+	if err := h.DB.QueryRowContext(ctx,
+		`SELECT email FROM public.users WHERE id=$1`, userID).Scan(&email); err != nil {
+		httpx.ErrorCode(w, http.StatusNotFound, "not_found", "User not found")
+		return
+	}
+	if !h.takeMFATry(w, r, userID, email) {
+		return
+	}
+	if ok, err := h.verifySecondFactor(ctx, userID, req.Code, ""); err != nil {
+		httpx.ErrorCode(w, http.StatusInternalServerError, "mfa_error", "Failed to check code")
+		return
+	} else if !ok {
+		h.loginFailed(r, email, userID)
+		httpx.ErrorCode(w, http.StatusForbidden, "invalid_code", "Invalid code")
+		return
+	}
+	h.RDB.Del(ctx, mfaUserTriesPrefix+userID)
+
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil {
+		httpx.ErrorCode(w, http.StatusInternalServerError, "update_failed", "Failed to create recovery codes")
+		return
+	}
+	defer tx.Rollback()
+	codes, err := replaceRecoveryCodes(ctx, tx, userID)
+	if err != nil || tx.Commit() != nil {
+		httpx.ErrorCode(w, http.StatusInternalServerError, "update_failed", "Failed to create recovery codes")
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, map[string]any{"recovery_codes": codes})
+}
+
+// LoginMFA handles POST /auth/login/mfa, the second step of Login.
+func (h *Handler) LoginMFA(w http.ResponseWriter, r *http.Request) {
+	var req LoginMFARequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.MFAToken == "" ||
+		(req.Code == "" && req.RecoveryCode == "") {
+		httpx.ErrorCode(w, http.StatusBadRequest, "bad_request", "Invalid JSON")
+		return
+	}
+	ctx := r.Context()
+	chKey := mfaChallengePrefix + req.MFAToken
+	triesKey := mfaTriesPrefix + req.MFAToken
+
+	val, err := h.RDB.Get(ctx, chKey).Result()
+	if err != nil {
+		httpx.ErrorCode(w, http.StatusUnauthorized, "invalid_mfa_token", "Login expired, sign in again")
+		return
+	}
+	userID, rest, _ := strings.Cut(val, "|")
+	tvStr, email, _ := strings.Cut(rest, "|")
+	tv, _ := strconv.Atoi(tvStr)
+
+	// Wrong codes count towards the account lockout like wrong passwords
+	if email != "" && !h.checkLockout(w, r, email) {
+		return
+	}
+
+	// A challenge gets a handful of guesses, then the password step again
+	n, err := h.RDB.Incr(ctx, triesKey).Result()
+	if err == nil && n == 1 {
+		h.RDB.Expire(ctx, triesKey, mfaChallengeTTL)
+	}
+	if err == nil && n > mfaMaxTries {
+		h.RDB.Del(ctx, chKey, triesKey)
+		httpx.ErrorCode(w, http.StatusUnauthorized, "invalid_mfa_token", "Too many attempts, sign in again")
+		return
+	}
+
+	var dbVer int
+	if err := h.DB.QueryRowContext(ctx,
+		`SELECT COALESCE(token_version,1) FROM public.users WHERE id=$1`, userID).Scan(&dbVer); err != nil || dbVer != tv {
+		httpx.ErrorCode(w, http.StatusUnauthorized, "token_revoked", "Token has been revoked")
+		return
+	}
+
+	ok, err := h.verifySecondFactor(ctx, userID, req.Code, req.RecoveryCode)
+	if err != nil {
+		httpx.ErrorCode(w, http.StatusInternalServerError, "mfa_error", "Failed to check code")
+		return
+	}
+	if !ok {
+		if email != "" {
+			h.loginFailed(r, email, userID)
+		}
+		httpx.ErrorCode(w, http.StatusUnauthorized, "invalid_code", "Invalid code")
+		return
+	}
+	// Deleting the challenge is what makes it single use
+	if n, err := h.RDB.Del(ctx, chKey, triesKey).Result(); err != nil || n == 0 {
+		httpx.ErrorCode(w, http.StatusUnauthorized, "invalid_mfa_token", "Login expired, sign in again")
+		return
+	}
+
+	pair, err := h.issueTokens(ctx, userID, tv, SessionMetaFrom(r))
+	if err != nil {
+		httpx.ErrorCode(w, http.StatusInternalServerError, "refresh_error", "Failed to issue tokens")
+		return
+	}
+	if email != "" {
+		h.loginSucceeded(r, email)
+	}
+	httpx.WriteJSON(w, http.StatusOK, pair)
+}
+
+// newMFAChallenge stores a short-lived token standing in for "password
+// checked" until the second factor arrives. email is the address the
+// password was checked for, so the second step shares its lockout.
+func (h *Handler) newMFAChallenge(ctx context.Context, userID, email string, tokenVersion int) (MFAChallenge, error) {
+	if h.RDB == nil {
+		return MFAChallenge{}, errors.New("redis not configured")
+	}
+	token, err := randToken()
+	if err != nil {
+		return MFAChallenge{}, err
+	}
+	if err := h.RDB.Set(ctx, mfaChallengePrefix+token, userID+"|"+itoa(tokenVersion)+"|"+email, mfaChallengeTTL).Err(); err != nil {
+		return MFAChallenge{}, err
+	}
+	return MFAChallenge{
+		MFARequired: true,
+		MFAToken:    token,
+		ExpiresIn:   int(mfaChallengeTTL / time.Second),
+	}, nil
+}
+
+// takeMFATry counts an attempt on the 2FA settings endpoints and passkey
+// registration, which a stolen access token could otherwise use to guess
+// passwords or codes without limit. It answers 429 once the user is out of
+// tries or the account is locked out; a correct answer resets the count.
+func (h *Handler) takeMFATry(w http.ResponseWriter, r *http.Request, userID, email string) bool {
+	if !h.checkLockout(w, r, email) {
+		return false
+	}
+	ctx := r.Context()
+	key := mfaUserTriesPrefix + userID
+	n, err := h.RDB.Incr(ctx, key).Result()
+	if err == nil && n == 1 {
+		h.RDB.Expire(ctx, key, mfaUserTriesTTL)
+	}
+	if err == nil && n > mfaMaxTries {
+		if ttl := h.RDB.TTL(ctx, key).Val(); ttl > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(ttl.Seconds())+1))
+		}
+		httpx.ErrorCode(w, http.StatusTooManyRequests, "too_many_attempts", "Too many wrong codes; try again later")
+		return false
+	}
+	return true
+}
+
+// verifySecondFactor accepts an authenticator code (each time step only once)
+// or an unused recovery code, consuming it. It reports false for users
+// without 2FA.
+func (h *Handler) verifySecondFactor(ctx context.Context, userID, code, recoveryCode string) (bool, error) {
+	db := h.DB
+	switch {
+	case code != "":
+		var sealed string
+		var last int64
+		err := db.QueryRowContext(ctx, `
+			SELECT totp_secret, totp_last_step FROM public.users
+			 WHERE id=$1 AND totp_enabled_at IS NOT NULL`, userID).Scan(&sealed, &last)
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		secret, err := totp.Open(h.TOTPKey, sealed)
+		if err != nil {
+			return false, err
+		}
+		step, ok := totp.Validate(secret, code, time.Now())
+		if !ok || step <= last {
+			return false, nil
+		}
+		res, err := db.ExecContext(ctx,
+			`UPDATE public.users SET totp_last_step=$1 WHERE id=$2 AND totp_last_step < $1`, step, userID)
+		if err != nil {
+			return false, err
+		}
+		n, _ := res.RowsAffected()
+		return n == 1, nil
+
+	case recoveryCode != "":
+		res, err := db.ExecContext(ctx, `
+			UPDATE public.user_recovery_codes c SET used_at=now()
+			  FROM public.users u
+			 WHERE c.user_id=u.id AND u.id=$1 AND u.totp_enabled_at IS NOT NULL
+			   AND c.code_hash=$2 AND c.used_at IS NULL`, userID, totp.HashRecoveryCode(recoveryCode))
+		if err != nil {
+			return false, err
+		}
+		n, _ := res.RowsAffected()
+		return n == 1, nil
+	}
+	return false, nil
+}
+
+// replaceRecoveryCodes swaps the user's recovery codes for a fresh set and
+// returns them in plain text.
+func replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userID string) ([]string, error) {
+	codes, err := totp.RecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM public.user_recovery_codes WHERE user_id=$1`, userID); err != nil {
+		return nil, err
+	}
+	for _, c := range codes {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO public.user_recovery_codes (user_id, code_hash) VALUES ($1, $2)`,
+			userID, totp.HashRecoveryCode(c)); err != nil {
+			return nil, err
+		}
+	}
+	return codes, nil
+}
+
+// totpIssuer labels the account in authenticator apps.
+func totpIssuer() string {
+	if s := os.Getenv("AUTH_TOTP_ISSUER"); s != "" {
+		return s
+	}
+	return "Books"
+}
@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/5w1tchy/books-api/internal/api/middlewares"
+	"github.com/5w1tchy/books-api/internal/mail"
+	"github.com/5w1tchy/books-api/internal/security/password"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestDisableMFALimitsWrongCodes(t *testing.T) {
+	// Keep the account lockout out of the way so the per-user cap decides
+	t.Setenv("AUTH_LOCKOUT_FREE_ATTEMPTS", "100")
+	t.Setenv("AUTH_LOCKOUT_THRESHOLD", "100")
+	mr, rdb := newTestRedis(t)
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	h := &Handler{DB: db, RDB: rdb}
+
+	hash, err := password.Hash("correct horse battery")
+	if err != nil {
+		t.Fatal(err)
+	}
+	disable := func() *httptest.ResponseRecorder {
+		body := strings.NewReader(`{"password":"correct horse battery","code":"000000"}`)
+		req := httptest.NewRequest(http.MethodPost, "/auth/2fa/disable", body)
+		req = req.WithContext(middlewares.WithUserID(req.Context(), "u-1"))
+		rec := httptest.NewRecorder()
+		h.DisableMFA(rec, req)
+		return rec
+	}
+	expectUser := func() {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT email, password_hash FROM public.users WHERE id=$1`)).
+			WithArgs("u-1").
+			WillReturnRows(sqlmock.NewRows([]string{"email", "password_hash"}).AddRow("reader@example.com", hash))
+	}
+
+	for i := range mfaMaxTries {
+		expectUser()
+		mock.ExpectQuery(`SELECT totp_secret, totp_last_step`).WithArgs("u-1").
+			WillReturnRows(sqlmock.NewRows([]string{"totp_secret", "totp_last_step"}))
+		if rec := disable(); rec.Code != http.StatusForbidden {
+			t.Fatalf("attempt %d: %d %s", i+1, rec.Code, rec.Body)
+		}
+	}
+	expectUser()
+	rec := disable()
+	if rec.Code != http.StatusTooManyRequests || !strings.Contains(rec.Body.String(), "too_many_attempts") {
+		t.Fatalf("attempt past the cap: %d %s", rec.Code, rec.Body)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Fatal("no Retry-After on the refusal")
+	}
+	// The wrong codes also count towards the account lockout
+	if n, _ := mr.Get(lockoutFailPrefix + "reader@example.com"); n != "5" {
+		t.Fatalf("lockout failures = %q, want 5", n)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// sentMail is a Mailer that keeps what it was given.
+type sentMail struct{ msgs []mail.Message }
+
+func (s *sentMail) Send(_ context.Context, msg mail.Message) error {
+	s.msgs = append(s.msgs, msg)
+	return nil
+}
+
+func TestEnrollMFARequiresPassword(t *testing.T) {
+	t.Setenv("AUTH_LOCKOUT_FREE_ATTEMPTS", "100")
+	t.Setenv("AUTH_LOCKOUT_THRESHOLD", "100")
+	mr, rdb := newTestRedis(t)
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	h := &Handler{DB: db, RDB: rdb, TOTPKey: make([]byte, 32)}
+
+	hash, err := password.Hash("correct horse battery")
+	if err != nil {
+		t.Fatal(err)
+	}
+	enroll := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/auth/2fa/enroll", strings.NewReader(body))
+		req = req.WithContext(middlewares.WithUserID(req.Context(), "u-1"))
+		rec := httptest.NewRecorder()
+		h.EnrollMFA(rec, req)
+		return rec
+	}
+	expectUser := func() {
+		mock.ExpectQuery(`SELECT email, password_hash, totp_enabled_at IS NOT NULL FROM public.users`).
+			WithArgs("u-1").
+			WillReturnRows(sqlmock.NewRows([]string{"email", "password_hash", "enabled"}).AddRow("reader@example.com", hash, false))
+	}
+
+	if rec := enroll(`{}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("no password: %d %s", rec.Code, rec.Body)
+	}
+	expectUser()
+	if rec := enroll(`{"password":"wrong password"}`); rec.Code != http.StatusForbidden {
+		t.Fatalf("wrong password: %d %s", rec.Code, rec.Body)
+	}
+	if mr.Exists(mfaEnrollPrefix + "u-1") {
+		t.Fatal("secret stored without the password")
+	}
+	expectUser()
+	if rec := enroll(`{"password":"correct horse battery"}`); rec.Code != http.StatusOK {
+		t.Fatalf("right password: %d %s", rec.Code, rec.Body)
+	}
+	if !mr.Exists(mfaEnrollPrefix + "u-1") {
+		t.Fatal("pending secret not stored")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNotifyOwnerMailsOwner(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	sent := &sentMail{}
+	h := &Handler{DB: db, Mailer: sent}
+
+	mock.ExpectExec(`INSERT INTO public.security_events`).
+		WithArgs("u-1", EventMFAEnabled, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	req := httptest.NewRequest(http.MethodPost, "/auth/2fa/confirm", nil)
+	req.Header.Set("X-Device-Name", "Work laptop")
+	h.notifyOwner(req, "u-1", "reader@example.com", EventMFAEnabled, mail.TemplateMFAEnabled, nil)
+
+	if len(sent.msgs) != 1 || sent.msgs[0].To != "reader@example.com" || !strings.Contains(sent.msgs[0].Text, "Work laptop") {
+		t.Fatalf("sent = %+v", sent.msgs)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,398 @@
+package auth
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/5w1tchy/books-api/internal/api/httpx"
+	"github.com/5w1tchy/books-api/internal/api/middlewares"
+	"github.com/5w1tchy/books-api/internal/mail"
+	"github.com/5w1tchy/books-api/internal/security/password"
+	"github.com/5w1tchy/books-api/internal/security/webauthn"
+)
+
+const (
+	passkeyRegPrefix   = "wa:reg:"   // user_id → registration challenge
+	passkeyLoginPrefix = "wa:login:" // challenge → "1" while unused
+	passkeyNameMax     = 64
+)
+
+type passkeyRegisterRequest struct {
+	Name       string                       `json:"name"`
+	Credential webauthn.AttestationResponse `json:"credential"`
+}
+
+type passkeyLoginBeginRequest struct {
+	Email string `json:"email"`
+}
+
+type passkeyRenameRequest struct {
+	Name string `json:"name"`
+}
+
+// Passkey is a registered passkey as shown to its owner.
+type Passkey struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Transports []string   `json:"transports"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+// BeginPasskeyRegistration handles POST /auth/passkeys/register/begin
+// {password | code | recovery_code} and returns options for
+// navigator.credentials.create(). A passkey outlives any token and skips
+// 2FA at sign in, so an access token alone isn't enough to add one: the
+// caller proves the password, or a 2FA code when 2FA is on.
+func (h *Handler) BeginPasskeyRegistration(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middlewares.UserIDFrom(r.Context())
+	if !ok {
+		httpx.ErrorCode(w, http.StatusUnauthorized, "unauthorized", "Unauthorized")
+		return
+	}
+	var req mfaCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpx.ErrorCode(w, http.StatusBadRequest, "invalid_input", "Invalid input")
+		return
+	}
+	ctx := r.Context()
+
+	var (
+		email, username, storedHash string
+		mfa                         bool
+	)
+	if err := h.DB.QueryRowContext(ctx, `
+		SELECT email, COALESCE(username,''), password_hash, totp_enabled_at IS NOT NULL
+		FROM public.users WHERE id=$1`, userID).Scan(&email, &username, &storedHash, &mfa); err != nil {
+		httpx.ErrorCode(w, http.StatusNotFound, "not_found", "User not found")
+		return
+	}
+	if (mfa && req.Code == "" && req.RecoveryCode == "") || (!mfa && req.Password == "") {
+		httpx.ErrorCode(w, http.StatusBadRequest, "step_up_required", "Confirm with your password, or a 2FA code if 2FA is on")
+		return
+	}
+	if !h.takeMFATry(w, r, userID, email) {
+		return
+	}
+	if mfa {
+		if ok, err := h.verifySecondFactor(ctx, userID, req.Code, req.RecoveryCode); err != nil {
+			httpx.ErrorCode(w, http.StatusInternalServerError, "mfa_error", "Failed to check code")
+			return
+		} else if !ok {
+			h.loginFailed(r, email, userID)
+			httpx.ErrorCode(w, http.StatusForbidden, "invalid_code", "Invalid code")
+			return
+		}
+	} else if okPass, _, err := password.Verify(req.Password, storedHash); err != nil || !okPass {
+		h.loginFailed(r, email, userID)
+		httpx.ErrorCode(w, http.StatusForbidden, "forbidden", "Invalid password")
+		return
+	}
+	h.RDB.Del(ctx, mfaUserTriesPrefix+userID)
+	rows, err := h.DB.QueryContext(ctx, `SELECT credential_id FROM public.user_passkeys WHERE user_id=$1`, userID)
+	if err != nil {
+		httpx.ErrorCode(w, http.StatusInternalServerError, "db_error", "Failed to load passkeys")
+		return
+	}
+	defer rows.Close()
+	var exclude [][]byte
+	for rows.Next() {
+		var id []byte
+		if err := rows.Scan(&id); err != nil {
+			httpx.ErrorCode(w, http.StatusInternalServerError, "db_error", "Failed to load passkeys")
+			return
+		}
+		exclude = append(exclude, id)
+	}
+
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		httpx.ErrorCode(w, http.StatusInternalServerError, "passkey_error", "Failed to start registration")
+		return
+	}
+	if err := h.RDB.Set(ctx, passkeyRegPrefix+userID, challenge, h.WebAuthn.Timeout).Err(); err != nil {
+		httpx.ErrorCode(w, http.StatusInternalServerError, "passkey_error", "Failed to start registration")
+		return
+	}
+	display := username
+	if display == "" {
+		display = email
+	}
+	httpx.WriteJSON(w, http.StatusOK, h.WebAuthn.CreationOptions(challenge, []byte(userID), email, display, exclude))
+}
+
+// FinishPasskeyRegistration handles POST /auth/passkeys/register/finish
+// {name, credential} and emails the owner about the new passkey.
+func (h *Handler) FinishPasskeyRegistration(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middlewares.UserIDFrom(r.Context())
+	if !ok {
+		httpx.ErrorCode(w, http.StatusUnauthorized, "unauthorized", "Unauthorized")
+		return
+	}
+	var req passkeyRegisterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpx.ErrorCode(w, http.StatusBadRequest, "bad_request", "Invalid JSON")
+		return
+	}
+	name, ok := passkeyName(req.Name)
+	if !ok {
+		httpx.ErrorCode(w, http.StatusBadRequest, "invalid_input", "Name must be at most 64 characters")
+		return
+	}
+	ctx := r.Context()
+
+	challenge, err := h.RDB.GetDel(ctx, passkeyRegPrefix+userID).Result()
+	if err != nil {
+		httpx.ErrorCode(w, http.StatusConflict, "no_pending_registration", "Start registration first")
+		return
+	}
+	cred, err := h.WebAuthn.VerifyRegistration(req.Credential, challenge)
+	if err != nil {
+		httpx.ErrorCode(w, http.StatusBadRequest, "invalid_credential", err.Error())
+		return
+	}
+
+	var email string
+	pk := Passkey{Name: name, Transports: cred.Transports}
+	if pk.Transports == nil {
+		pk.Transports = []string{}
+	}
+	err = h.DB.QueryRowContext(ctx, `
+		INSERT INTO public.user_passkeys
+		       (user_id, credential_id, public_key, algorithm, sign_count, aaguid, transports, name)
+		VALUES ($1, $2, $3, $4, $5, $6, string_to_array(NULLIF($7,''), ','), $8)
+		ON CONFLICT (credential_id) DO NOTHING
+		RETURNING id, created_at, (SELECT email FROM public.users WHERE id=$1)`,
+		userID, cred.ID, cred.PublicKey, cred.Algorithm, int64(cred.SignCount), cred.AAGUID,
+		strings.Join(cred.Transports, ","), name).Scan(&pk.ID, &pk.CreatedAt, &email)
+	if errors.Is(err, sql.ErrNoRows) {
+		httpx.ErrorCode(w, http.StatusConflict, "passkey_exists", "This passkey is already registered")
+		return
+	}
+	if err != nil {
+		httpx.ErrorCode(w, http.StatusInternalServerError, "db_error", "Failed to save passkey")
+		return
+	}
+	h.notifyOwner(r, userID, email, EventPasskeyAdded, mail.TemplatePasskeyAdded, mail.Data{"Name": pk.Name})
+	httpx.WriteJSON(w, http.StatusCreated, pk)
+}
+
+// ListPasskeys handles GET /auth/passkeys
+func (h *Handler) ListPasskeys(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middlewares.UserIDFrom(r.Context())
+	if !ok {
+		httpx.ErrorCode(w, http.StatusUnauthorized, "unauthorized", "Unauthorized")
+		return
+	}
+	rows, err := h.DB.QueryContext(r.Context(), `
+		SELECT id, name, array_to_string(transports, ','), created_at, last_used_at
+		FROM public.user_passkeys
+		WHERE user_id=$1
+		ORDER BY created_at`, userID)
+	if err != nil {
+		httpx.ErrorCode(w, http.StatusInternalServerError, "db_error", "Failed to load passkeys")
+		return
+	}
+	defer rows.Close()
+	out := []Passkey{}
+	for rows.Next() {
+		var pk Passkey
+		var transports string
+		if err := rows.Scan(&pk.ID, &pk.Name, &transports, &pk.CreatedAt, &pk.LastUsedAt); err != nil {
+			httpx.ErrorCode(w, http.StatusInternalServerError, "db_error", "Failed to load passkeys")
+			return
+		}
+		pk.Transports = []string{}
+		if transports != "" {
+			pk.Transports = strings.Split(transports, ",")
+		}
+		out = append(out, pk)
+	}
+	httpx.WriteJSON(w, http.StatusOK, map[string]any{"passkeys": out})
+}
+
+// RenamePasskey handles PATCH /auth/passkeys/{id} {name}
+func (h *Handler) RenamePasskey(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middlewares.UserIDFrom(r.Context())
+	if !ok {
+		httpx.ErrorCode(w, http.StatusUnauthorized, "unauthorized", "Unauthorized")
+		return
+	}
+	var req passkeyRenameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Name) == "" {
+		httpx.ErrorCode(w, http.StatusBadRequest, "invalid_input", "Name is required")
+		return
+	}
+	name, ok := passkeyName(req.Name)
+	if !ok {
+		httpx.ErrorCode(w, http.StatusBadRequest, "invalid_input", "Name must be at most 64 characters")
+		return
+	}
+	res, err := h.DB.ExecContext(r.Context(),
+		`UPDATE public.user_passkeys SET name=$1 WHERE id::text=$2 AND user_id=$3`, name, r.PathValue("id"), userID)
+	if err != nil {
+		httpx.ErrorCode(w, http.StatusInternalServerError, "db_error", "Failed to rename passkey")
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		httpx.ErrorCode(w, http.StatusNotFound, "not_found", "Passkey not found")
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// RevokePasskey handles DELETE /auth/passkeys/{id}
+func (h *Handler) RevokePasskey(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middlewares.UserIDFrom(r.Context())
+	if !ok {
+		httpx.ErrorCode(w, http.StatusUnauthorized, "unauthorized", "Unauthorized")
+		return
+	}
+	res, err := h.DB.ExecContext(r.Context(),
+		`DELETE FROM public.user_passkeys WHERE id::text=$1 AND user_id=$2`, r.PathValue("id"), userID)
+	if err != nil {
+		httpx.ErrorCode(w, http.StatusInternalServerError, "db_error", "Failed to revoke passkey")
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		httpx.ErrorCode(w, http.StatusNotFound, "not_found", "Passkey not found")
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// BeginPasskeyLogin handles POST /auth/passkeys/login/begin {email?}. With
+// no email (or an unknown one) the options allow any discoverable passkey,
+// so the response doesn't reveal whether an account exists.
+func (h *Handler) BeginPasskeyLogin(w http.ResponseWriter, r *http.Request) {
+	var req passkeyLoginBeginRequest
+	_ = json.NewDecoder(r.Body).Decode(&req)
+	ctx := r.Context()
+
+	var allow []webauthn.CredentialDescriptor
+	if email := strings.TrimSpace(req.Email); email != "" {
+		rows, err := h.DB.QueryContext(ctx, `
+			SELECT p.credential_id, array_to_string(p.transports, ',')
+			FROM public.user_passkeys p JOIN public.users u ON u.id = p.user_id
+			WHERE u.email = $1`, email)
+		if err != nil {
+			httpx.ErrorCode(w, http.StatusInternalServerError, "db_error", "Failed to start sign-in")
+			return
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var id []byte
+			var transports string
+			if err := rows.Scan(&id, &transports); err != nil {
+				httpx.ErrorCode(w, http.StatusInternalServerError, "db_error", "Failed to start sign-in")
+				return
+			}
+			d := webauthn.CredentialDescriptor{Type: "public-key", ID: webauthn.Encode(id)}
+			if transports != "" {
+				d.Transports = strings.Split(transports, ",")
+			}
+			allow = append(allow, d)
+		}
+	}
+
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		httpx.ErrorCode(w, http.StatusInternalServerError, "passkey_error", "Failed to start sign-in")
+		return
+	}
+	if err := h.RDB.Set(ctx, passkeyLoginPrefix+challenge, "1", h.WebAuthn.Timeout).Err(); err != nil {
+		httpx.ErrorCode(w, http.StatusInternalServerError, "passkey_error", "Failed to start sign-in")
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, h.WebAuthn.RequestOptions(challenge, allow))
+}
+
+// FinishPasskeyLogin handles POST /auth/passkeys/login/finish and issues
+// the same token pair as Login. A user-verifying passkey counts as both
+// factors, so there is no TOTP step.
+func (h *Handler) FinishPasskeyLogin(w http.ResponseWriter, r *http.Request) {
+	var resp webauthn.AssertionResponse
+	if err := json.NewDecoder(r.Body).Decode(&resp); err != nil {
+		httpx.ErrorCode(w, http.StatusBadRequest, "bad_request", "Invalid JSON")
+		return
+	}
+	ctx := r.Context()
+
+	// The challenge is single use: consume it before anything else
+	challenge, err := webauthn.Challenge(resp.Response.ClientDataJSON)
+	if err != nil {
+		httpx.ErrorCode(w, http.StatusBadRequest, "bad_request", "Invalid client data")
+		return
+	}
+	if n, err := h.RDB.Del(ctx, passkeyLoginPrefix+challenge).Result(); err != nil || n == 0 {
+		httpx.ErrorCode(w, http.StatusUnauthorized, "invalid_challenge", "Sign-in expired, try again")
+		return
+	}
+
+	rawID, err := webauthn.Decode(resp.RawID)
+	if err != nil {
+		httpx.ErrorCode(w, http.StatusUnauthorized, "invalid_credentials", "Unknown passkey")
+		return
+	}
+	var (
+		passkeyID, userID string
+		tv                int
+		signCount         int64
+		cred              = webauthn.Credential{ID: rawID}
+	)
+	err = h.DB.QueryRowContext(ctx, `
+		SELECT p.id, p.user_id, p.public_key, p.algorithm, p.sign_count, COALESCE(u.token_version,1)
+		FROM public.user_passkeys p JOIN public.users u ON u.id = p.user_id
+		WHERE p.credential_id = $1`, rawID).
+		Scan(&passkeyID, &userID, &cred.PublicKey, &cred.Algorithm, &signCount, &tv)
+	if err != nil {
+		httpx.ErrorCode(w, http.StatusUnauthorized, "invalid_credentials", "Unknown passkey")
+		return
+	}
+	cred.SignCount = uint32(signCount)
+	if resp.Response.UserHandle != "" {
+		if handle, err := webauthn.Decode(resp.Response.UserHandle); err != nil || !bytes.Equal(handle, []byte(userID)) {
+			httpx.ErrorCode(w, http.StatusUnauthorized, "invalid_credentials", "Unknown passkey")
+			return
+		}
+	}
+
+	newCount, err := h.WebAuthn.VerifyAssertion(resp, challenge, cred)
+	if err != nil {
+		httpx.ErrorCode(w, http.StatusUnauthorized, "invalid_credentials", err.Error())
+		return
+	}
+	// Guarded so two racing sign-ins can't both pass with the same counter
+	res, err := h.DB.ExecContext(ctx, `
+		UPDATE public.user_passkeys SET sign_count=$1, last_used_at=now()
+		 WHERE id=$2 AND (sign_count < $1 OR $1 = 0)`, int64(newCount), passkeyID)
+	if err != nil {
+		httpx.ErrorCode(w, http.StatusInternalServerError, "db_error", "Failed to update passkey")
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		httpx.ErrorCode(w, http.StatusUnauthorized, "invalid_credentials", webauthn.ErrSignCount.Error())
+		return
+	}
+
+	pair, err := h.issueTokens(ctx, userID, tv, SessionMetaFrom(r))
+	if err != nil {
+		httpx.ErrorCode(w, http.StatusInternalServerError, "refresh_error", "Failed to issue tokens")
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, pair)
+}
+
+// passkeyName trims a user-supplied label, defaulting to "Passkey".
+func passkeyName(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "Passkey", true
+	}
+	return s, len([]rune(s)) <= passkeyNameMax
+}
@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/5w1tchy/books-api/internal/api/middlewares"
+	"github.com/5w1tchy/books-api/internal/security/password"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestBeginPasskeyRegistrationRequiresStepUp(t *testing.T) {
+	t.Setenv("AUTH_LOCKOUT_FREE_ATTEMPTS", "100")
+	t.Setenv("AUTH_LOCKOUT_THRESHOLD", "100")
+	mr, rdb := newTestRedis(t)
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	h := &Handler{DB: db, RDB: rdb}
+
+	hash, err := password.Hash("correct horse battery")
+	if err != nil {
+		t.Fatal(err)
+	}
+	begin := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/auth/passkeys/register/begin", strings.NewReader(body))
+		req = req.WithContext(middlewares.WithUserID(req.Context(), "u-1"))
+		rec := httptest.NewRecorder()
+		h.BeginPasskeyRegistration(rec, req)
+		return rec
+	}
+	expectUser := func(mfa bool) {
+		mock.ExpectQuery(`SELECT email, COALESCE\(username,''\), password_hash, totp_enabled_at IS NOT NULL`).
+			WithArgs("u-1").
+			WillReturnRows(sqlmock.NewRows([]string{"email", "username", "password_hash", "mfa"}).
+				AddRow("reader@example.com", "reader", hash, mfa))
+	}
+
+	expectUser(false)
+	if rec := begin(`{}`); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "step_up_required") {
+		t.Fatalf("bare token: %d %s", rec.Code, rec.Body)
+	}
+	expectUser(false)
+	if rec := begin(`{"password":"wrong password"}`); rec.Code != http.StatusForbidden {
+		t.Fatalf("wrong password: %d %s", rec.Code, rec.Body)
+	}
+	// With 2FA on the password alone doesn't do
+	expectUser(true)
+	if rec := begin(`{"password":"correct horse battery"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("2FA account without a code: %d %s", rec.Code, rec.Body)
+	}
+	if mr.Exists(passkeyRegPrefix + "u-1") {
+		t.Fatal("challenge issued without step-up")
+	}
+
+	expectUser(false)
+	mock.ExpectQuery(`SELECT credential_id FROM public.user_passkeys`).WithArgs("u-1").
+		WillReturnRows(sqlmock.NewRows([]string{"credential_id"}))
+	if rec := begin(`{"password":"correct horse battery"}`); rec.Code != http.StatusOK {
+		t.Fatalf("right password: %d %s", rec.Code, rec.Body)
+	}
+	if !mr.Exists(passkeyRegPrefix + "u-1") {
+		t.Fatal("no challenge stored")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -7,24 +7,70 @@ import (
 	"errors"
 	"os"
 	"strconv"
+	"strings"
 	"time"
+
+	jwtutil "github.com/5w1tchy/books-api/internal/security/jwt"
+	"github.com/redis/go-redis/v9"
 )
 
-// issueRefresh creates and stores a refresh token in Redis
-func (h *Handler) issueRefresh(ctx context.Context, userID string, tokenVersion int) (string, error) {
-	token, err := randToken()
+// Refresh tokens live in Redis as rt:<token> → "userID|tokenVersion|sessionID".
+// Every token belongs to a session (see session.go) that survives rotation,
+// so users can see and revoke where they are signed in.
+
+// issueRefresh starts a new session and returns its first refresh token.
+func (h *Handler) issueRefresh(ctx context.Context, userID string, tokenVersion int, meta SessionMeta) (string, string, error) {
+	if h.RDB == nil {
+		return "", "", errors.New("redis not configured")
+	}
+	sid, err := randID()
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
-	if h.RDB == nil {
-		return "", errors.New("redis not configured")
+	token, err := h.sessions().start(ctx, userID, tokenVersion, sid, meta)
+	if err != nil {
+		return "", "", err
 	}
-	key := "rt:" + token
-	val := userID + "|" + itoa(tokenVersion)
-	if err := h.RDB.Set(ctx, key, val, refreshTTL()).Err(); err != nil {
-		return "", err
+	return token, sid, nil
+}
+
+// issueTokens signs an access token and starts a session for a user who has
+// fully authenticated.
+func (h *Handler) issueTokens(ctx context.Context, userID string, tokenVersion int, meta SessionMeta) (TokenPair, error) {
+	refresh, sid, err := h.issueRefresh(ctx, userID, tokenVersion, meta)
+	if err != nil {
+		return TokenPair{}, err
 	}
-	return token, nil
+	access, _, err := jwtutil.SignSessionAccess(userID, sid, tokenVersion, jwtutil.DefaultAccessTTL())
+	if err != nil {
+		return TokenPair{}, err
+	}
+	return TokenPair{AccessToken: access, RefreshToken: refresh}, nil
+}
+
+// parseRefresh splits an rt: value. Tokens issued before sessions existed
+// have no session ID.
+func parseRefresh(val string) (userID string, tokenVersion int, sid string, ok bool) {
+	parts := strings.SplitN(val, "|", 3)
+	if len(parts) < 2 || parts[0] == "" {
+		return "", 0, "", false
+	}
+	tv, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return "", 0, "", false
+	}
+	if len(parts) == 3 {
+		sid = parts[2]
+	}
+	return parts[0], tv, sid, true
+}
+
+// revokeRefreshTokens ends every session of a user
+func revokeRefreshTokens(ctx context.Context, rdb *redis.Client, userID string) error {
+	if rdb == nil {
+		return errors.New("redis not configured")
+	}
+	return (&SessionStore{RDB: rdb}).RevokeSessions(ctx, userID)
 }
 
 // refreshTTL returns the refresh token TTL from environment or default 30 days
@@ -37,6 +83,25 @@ func refreshTTL() time.Duration {
 	return 30 * 24 * time.Hour
 }
 
+// sessionMaxAge is the absolute session lifetime from AUTH_SESSION_MAX_AGE
+// (default 90 days): a session can't be refreshed past it, however active.
+func sessionMaxAge() time.Duration {
+	if s := os.Getenv("AUTH_SESSION_MAX_AGE"); s != "" {
+		if d, err := time.ParseDuration(s); err == nil && d > 0 {
+			return d
+		}
+	}
+	return 90 * 24 * time.Hour
+}
+
+// reuseLogsOutAll reports whether refresh token reuse should also bump
+// token_version, ending every session and access token of the user
+// (AUTH_REFRESH_REUSE_LOGOUT_ALL).
+func reuseLogsOutAll() bool {
+	on, _ := strconv.ParseBool(os.Getenv("AUTH_REFRESH_REUSE_LOGOUT_ALL"))
+	return on
+}
+
 // randToken generates a random hex token
 func randToken() (string, error) {
 	var b [32]byte
@@ -46,6 +111,15 @@ func randToken() (string, error) {
 	return hex.EncodeToString(b[:]), nil
 }
 
+// randID generates a shorter random hex ID for things that aren't secrets
+func randID() (string, error) {
+	var b [12]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
+
 // itoa converts int to string
 func itoa(i int) string {
 	return strconv.FormatInt(int64(i), 10)
@@ -0,0 +1,242 @@
+package auth
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/5w1tchy/books-api/internal/api/httpx"
+	"github.com/5w1tchy/books-api/internal/api/middlewares"
+	"github.com/5w1tchy/books-api/internal/mail"
+	"github.com/5w1tchy/books-api/internal/security/password"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	prKeyPrefix     = "pr:"          // token → user_id
+	prUserPrefix    = "pr:user:"     // user_id → latest token (older ones die with it)
+	prQuotaPrefix   = "pr:quota:"    // user_id → count (24h TTL)
+	prIPQuotaPrefix = "pr:quota:ip:" // ip → count (1h TTL)
+	prTTL           = time.Hour
+	prQuotaMax      = 3
+	prIPQuotaMax    = 10
+)
+
+type ResetDeps struct {
+	DB     *sql.DB
+	RDB    *redis.Client
+	Mailer mail.Mailer
+	// Frontend origin for the reset link (e.g., https://localhost:3000).
+	// Unlike the verification link, which hits GET /auth/verify on the API
+	// (PUBLIC_BASE_URL), this one opens the web app's form that asks for
+	// the new password, hence APP_BASE_URL. Leave empty to use just the path.
+	BaseURL string
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+// POST /auth/forgot-password
+// Behavior:
+//   - Always 202 with the same body, whether or not the email exists
+//   - Per-IP quota (10/h) → 429; per-user quota (3/24h) silently drops the send
+//   - Known user → single-use token in Redis for 1h (a newer token replaces it),
+//     emailed in the caller's language (Accept-Language)
+func (d *ResetDeps) HandleForgotPassword() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		var req ForgotPasswordRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Email) == "" {
+			httpx.ErrorCode(w, http.StatusBadRequest, "invalid_input", "Invalid email")
+			return
+		}
+
+		// Quota per IP first, so the answer can't depend on the email
+		if ip := middlewares.ClientIP(r); ip != "" {
+			over, err := d.overQuota(r, prIPQuotaPrefix+ip, prIPQuotaMax, time.Hour)
+			if err != nil {
+				httpx.ErrorCode(w, http.StatusInternalServerError, "rate_limit_error", "Rate limit error")
+				return
+			}
+			if over {
+				w.Header().Set("Retry-After", strconv.Itoa(int(time.Hour/time.Second)))
+				httpx.ErrorCode(w, http.StatusTooManyRequests, "too_many_requests", "Too many reset requests")
+				return
+			}
+		}
+
+		accepted := func() {
+			httpx.WriteJSON(w, http.StatusAccepted, map[string]string{
+				"status":  "ok",
+				"message": "If an account exists for that email, a reset link has been sent.",
+			})
+		}
+
+		var userID, email string
+		err := d.DB.QueryRowContext(ctx,
+			`SELECT id, email FROM public.users WHERE email=$1 LIMIT 1`, strings.TrimSpace(req.Email)).Scan(&userID, &email)
+		if errors.Is(err, sql.ErrNoRows) {
+			accepted()
+			return
+		}
+		if err != nil {
+			httpx.ErrorCode(w, http.StatusInternalServerError, "db_error", "Database error")
+			return
+		}
+
+		// Quota per user: 3 sends per 24h, over it we just don't send
+		if over, err := d.overQuota(r, prQuotaPrefix+userID, prQuotaMax, 24*time.Hour); err != nil || over {
+			accepted()
+			return
+		}
+
+		token, err := randomToken(32)
+		if err != nil {
+			httpx.ErrorCode(w, http.StatusInternalServerError, "token_error", "Failed to create token")
+			return
+		}
+		prev, _ := d.RDB.Get(ctx, prUserPrefix+userID).Result()
+		pipe := d.RDB.TxPipeline()
+		if prev != "" {
+			pipe.Del(ctx, prKeyPrefix+prev)
+		}
+		pipe.Set(ctx, prKeyPrefix+token, userID, prTTL)
+		pipe.Set(ctx, prUserPrefix+userID, token, prTTL)
+		if _, err := pipe.Exec(ctx); err != nil {
+			httpx.ErrorCode(w, http.StatusInternalServerError, "redis_error", "Failed to store token")
+			return
+		}
+
+		msg, err := mail.Render(mail.TemplateResetPassword, mail.Locale(r.Header.Get("Accept-Language")), email, mail.Data{
+			"Link":  d.buildResetURL(token),
+			"Hours": int(prTTL / time.Hour),
+		})
+		if err == nil {
+			err = d.Mailer.Send(ctx, msg)
+		}
+		if err != nil {
+			// Still 202: a failure here must look the same as an unknown email
+			log.Printf("[auth] password reset mail for %s: %v", userID, err)
+		}
+		accepted()
+	}
+}
+
+// POST /auth/reset-password
+// Behavior:
+//   - Valid token → new password, token_version+1, all refresh tokens revoked, 200
+//   - Passkeys are removed too: a reset is how an owner takes a compromised
+//     account back, and a passkey added by whoever had it would skip the
+//     new password. Two-factor auth stays on.
+//   - The token is consumed on first use; invalid/expired → 400
+func (d *ResetDeps) HandleResetPassword() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		var req ResetPasswordRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			httpx.ErrorCode(w, http.StatusBadRequest, "invalid_input", "Invalid input")
+			return
+		}
+		token := strings.TrimSpace(req.Token)
+		np := strings.TrimSpace(req.NewPassword)
+		if token == "" || len(np) < 8 {
+			httpx.ErrorCode(w, http.StatusBadRequest, "invalid_input", "Invalid input")
+			return
+		}
+
+		// Hash before consuming the token so a slow hash can't strand it
+		newPHC, err := password.Hash(np)
+		if err != nil {
+			httpx.ErrorCode(w, http.StatusInternalServerError, "hash_error", "Failed to hash new password")
+			return
+		}
+
+		userID, err := d.RDB.GetDel(ctx, prKeyPrefix+token).Result()
+		if errors.Is(err, redis.Nil) || (err == nil && userID == "") {
+			httpx.ErrorCode(w, http.StatusBadRequest, "invalid_token", "Invalid or expired token")
+			return
+		}
+		if err != nil {
+			httpx.ErrorCode(w, http.StatusInternalServerError, "redis_error", "Redis error")
+			return
+		}
+		_ = d.RDB.Del(ctx, prUserPrefix+userID).Err()
+
+		tx, err := d.DB.BeginTx(ctx, nil)
+		if err != nil {
+			httpx.ErrorCode(w, http.StatusInternalServerError, "update_failed", "Failed to update password")
+			return
+		}
+		defer tx.Rollback()
+		res, err := tx.ExecContext(ctx,
+			`UPDATE public.users
+			   SET password_hash=$1, token_version=COALESCE(token_version,1)+1, updated_at=now()
+			 WHERE id=$2`,
+			newPHC, userID)
+		if err == nil {
+			if n, _ := res.RowsAffected(); n == 0 {
+				httpx.ErrorCode(w, http.StatusBadRequest, "invalid_token", "Invalid or expired token")
+				return
+			}
+			_, err = tx.ExecContext(ctx, `DELETE FROM public.user_passkeys WHERE user_id=$1`, userID)
+		}
+		if err != nil || tx.Commit() != nil {
+			httpx.ErrorCode(w, http.StatusInternalServerError, "update_failed", "Failed to update password")
+			return
+		}
+		if err := revokeRefreshTokens(ctx, d.RDB, userID); err != nil {
+			// token_version already invalidates them; this only frees Redis
+			log.Printf("[auth] revoke refresh tokens for %s: %v", userID, err)
+		}
+
+		score, warnMsg, sugg := simpleStrength(np)
+		resp := map[string]any{
+			"status":         "ok",
+			"password_score": score,
+		}
+		if score < 4 && warnMsg != "" {
+			resp["password_warning"] = map[string]any{
+				"message":     warnMsg,
+				"suggestions": sugg,
+			}
+		}
+		httpx.WriteJSON(w, http.StatusOK, resp)
+	}
+}
+
+// overQuota counts one more hit on key and reports whether it passed max.
+// The window starts at the first hit; later hits don't extend it.
+func (d *ResetDeps) overQuota(r *http.Request, key string, max int, window time.Duration) (bool, error) {
+	ctx := r.Context()
+	n, err := d.RDB.Incr(ctx, key).Result()
+	if err != nil {
+		return false, err
+	}
+	if n == 1 {
+		if err := d.RDB.Expire(ctx, key, window).Err(); err != nil {
+			return false, err
+		}
+	}
+	return n > int64(max), nil
+}
+
+func (d *ResetDeps) buildResetURL(token string) string {
+	path := "/reset-password?token=" + token
+	if d.BaseURL == "" {
+		return path
+	}
+	return strings.TrimRight(d.BaseURL, "/") + path
+}
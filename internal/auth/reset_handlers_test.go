@@ -0,0 +1,28 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestOverQuotaWindowStartsAtFirstHit(t *testing.T) {
+	mr, rdb := newTestRedis(t)
+	d := &ResetDeps{RDB: rdb}
+	r := httptest.NewRequest(http.MethodPost, "/auth/forgot-password", nil)
+
+	for i := range 3 {
+		if over, err := d.overQuota(r, "pr:quota:u-1", 2, time.Hour); err != nil || over != (i == 2) {
+			t.Fatalf("hit %d: over=%v err=%v", i+1, over, err)
+		}
+		mr.FastForward(20 * time.Minute)
+	}
+	// Retrying while over the limit must not push the window back
+	if ttl := mr.TTL("pr:quota:u-1"); ttl != 0 {
+		t.Fatalf("quota key still has TTL %s after its hour", ttl)
+	}
+	if over, _ := d.overQuota(r, "pr:quota:u-1", 2, time.Hour); over {
+		t.Fatal("still over quota in a new window")
+	}
+}
@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/5w1tchy/books-api/internal/api/middlewares"
+	"github.com/5w1tchy/books-api/internal/mail"
+)
+
+// Security event kinds (public.security_events.kind).
+const (
+	EventRefreshReuse  = "refresh_token_reuse"
+	EventAccountLocked = "account_locked"
+	EventMFAEnabled    = "mfa_enabled"
+	EventPasskeyAdded  = "passkey_added"
+)
+
+// recordSecurityEvent stores an event about userID, with the client's IP and
+// User-Agent from r. Failures are logged, never returned: the event must not
+// change the response. Without a database (h.DB unset) nothing is recorded.
+func recordSecurityEvent(ctx context.Context, db *sql.DB, r *http.Request, userID, kind string, meta map[string]any) {
+	if db == nil {
+		return
+	}
+	if meta == nil {
+		meta = map[string]any{}
+	}
+	b, err := json.Marshal(meta)
+	if err != nil {
+		b = []byte("{}")
+	}
+	const q = `
+INSERT INTO public.security_events (user_id, kind, ip, user_agent, meta)
+VALUES ($1, $2, NULLIF($3,''), NULLIF($4,''), $5::jsonb)`
+	if _, err := db.ExecContext(ctx, q, userID, kind, middlewares.ClientIP(r), truncate(r.UserAgent(), sessionUAMax), string(b)); err != nil {
+		log.Printf("[auth] recording %s for %s failed: %v", kind, userID, err)
+	}
+	log.Printf("[auth] security event %s for user %s from %s", kind, userID, middlewares.ClientIP(r))
+}
+
+// notifyOwner records a security event about a change to the account and
+// emails the owner, naming the device and IP that made it, so someone
+// holding a stolen token can't add a credential unnoticed. data is passed
+// to the template and stored with the event. Mail failures are only logged.
+func (h *Handler) notifyOwner(r *http.Request, userID, email, kind, template string, data mail.Data) {
+	ctx := r.Context()
+	meta := SessionMetaFrom(r)
+	recordSecurityEvent(ctx, h.DB, r, userID, kind, data)
+	if h.Mailer == nil || email == "" {
+		return
+	}
+	// As with lockouts, the request may not be the owner's, so its
+	// Accept-Language isn't used
+	vars := mail.Data{"Device": meta.Device, "IP": meta.IP}
+	for k, v := range data {
+		vars[k] = v
+	}
+	msg, err := mail.Render(template, mail.DefaultLocale, email, vars)
+	if err == nil {
+		err = h.Mailer.Send(ctx, msg)
+	}
+	if err != nil {
+		log.Printf("[auth] %s mail for %s: %v", kind, userID, err)
+	}
+}
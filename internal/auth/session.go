@@ -0,0 +1,338 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/5w1tchy/books-api/internal/api/middlewares"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	sessionPrefix     = "sess:"  // session_id → hash (see Session)
+	sessionUserPrefix = "sessu:" // user_id → set of session IDs
+	// rotated refresh token → userID|sessionID, kept for the rest of the
+	// session's life so a replayed old token can be recognised
+	rotatedPrefix = "rtr:"
+
+	sessionDeviceMax = 64
+	sessionUAMax     = 256
+)
+
+var errSessionGone = errors.New("session revoked")
+
+// SessionMeta describes the client a session was started from.
+type SessionMeta struct {
+	Device    string
+	UserAgent string
+	IP        string
+}
+
+// SessionMetaFrom reads the client's X-Device-Name (falling back to a
+// summary of its User-Agent), User-Agent and IP.
+func SessionMetaFrom(r *http.Request) SessionMeta {
+	ua := truncate(r.UserAgent(), sessionUAMax)
+	device := truncate(strings.TrimSpace(r.Header.Get("X-Device-Name")), sessionDeviceMax)
+	if device == "" {
+		device = describeUA(ua)
+	}
+	return SessionMeta{Device: device, UserAgent: ua, IP: middlewares.ClientIP(r)}
+}
+
+// Session is one signed-in device: a family of refresh tokens, each
+// replacing the last. It ends at ExpiresAt (AUTH_SESSION_MAX_AGE after sign
+// in) however often it is refreshed, or earlier if left unused for
+// AUTH_REFRESH_TTL.
+type Session struct {
+	ID         string    `json:"id"`
+	Device     string    `json:"device"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current,omitempty"`
+}
+
+// SessionStore keeps sessions in Redis. Each session is a hash holding the
+// current refresh token, and expires with it.
+type SessionStore struct {
+	RDB *redis.Client
+}
+
+func (h *Handler) sessions() *SessionStore { return &SessionStore{RDB: h.RDB} }
+
+// start creates session sid and returns its first refresh token.
+func (s *SessionStore) start(ctx context.Context, userID string, tokenVersion int, sid string, meta SessionMeta) (string, error) {
+	token, err := randToken()
+	if err != nil {
+		return "", err
+	}
+	start := time.Now()
+	now := strconv.FormatInt(start.Unix(), 10)
+	maxAge := sessionMaxAge()
+	ttl := min(refreshTTL(), maxAge)
+	pipe := s.RDB.TxPipeline()
+	pipe.Set(ctx, "rt:"+token, userID+"|"+itoa(tokenVersion)+"|"+sid, ttl)
+	pipe.HSet(ctx, sessionPrefix+sid,
+		"uid", userID,
+		"token", token,
+		"device", meta.Device,
+		"ua", meta.UserAgent,
+		"ip", meta.IP,
+		"created_at", now,
+		"last_used_at", now,
+		"expires_at", strconv.FormatInt(start.Add(maxAge).Unix(), 10),
+	)
+	pipe.Expire(ctx, sessionPrefix+sid, ttl)
+	pipe.SAdd(ctx, sessionUserPrefix+userID, sid)
+	pipe.Expire(ctx, sessionUserPrefix+userID, refreshTTL())
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// claimScript deletes rt:<token> and returns its value, recording the token
+// as rotated in the same step (KEYS: rt:, rtr:; ARGV: record TTL in ms).
+var claimScript = redis.NewScript(`
+local v = redis.call('GET', KEYS[1])
+if not v then return false end
+redis.call('DEL', KEYS[1])
+local uid, sid = string.match(v, '^([^|]+)|[^|]*|(.+)$')
+if uid then redis.call('SET', KEYS[2], uid .. '|' .. sid, 'PX', ARGV[1]) end
+return v
+`)
+
+// claim takes a refresh token out of circulation and returns its rt: value
+// (redis.Nil if there is none). It is atomic, so of two requests presenting
+// the same token only one wins; the other finds it already rotated and is
+// treated as reuse. rotate gives the rotated record its real lifetime.
+func (s *SessionStore) claim(ctx context.Context, token string) (string, error) {
+	return claimScript.Run(ctx, s.RDB, []string{"rt:" + token, rotatedPrefix + token},
+		sessionMaxAge().Milliseconds()).Text()
+}
+
+// rotate replaces a session's refresh token, claimed by the caller, and
+// records the use. The old token is remembered so a replay can be caught
+// (see rotatedFamily).
+func (s *SessionStore) rotate(ctx context.Context, userID string, tokenVersion int, sid, oldToken string, meta SessionMeta) (string, error) {
+	exp, err := s.RDB.HGet(ctx, sessionPrefix+sid, "expires_at").Result()
+	if errors.Is(err, redis.Nil) {
+		return "", errSessionGone
+	}
+	if err != nil {
+		return "", err
+	}
+	remaining := time.Until(unixField(exp))
+	if remaining <= 0 {
+		_ = s.drop(ctx, userID, sid)
+		return "", errSessionGone
+	}
+	token, err := randToken()
+	if err != nil {
+		return "", err
+	}
+	ttl := min(refreshTTL(), remaining)
+	pipe := s.RDB.TxPipeline()
+	pipe.Set(ctx, rotatedPrefix+oldToken, userID+"|"+sid, remaining)
+	pipe.Set(ctx, "rt:"+token, userID+"|"+itoa(tokenVersion)+"|"+sid, ttl)
+	pipe.HSet(ctx, sessionPrefix+sid,
+		"token", token,
+		"ua", meta.UserAgent,
+		"ip", meta.IP,
+		"last_used_at", strconv.FormatInt(time.Now().Unix(), 10),
+	)
+	pipe.Expire(ctx, sessionPrefix+sid, ttl)
+	pipe.Expire(ctx, sessionUserPrefix+userID, refreshTTL())
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// rotatedFamily reports the session a token belonged to if it has already
+// been rotated, i.e. someone is presenting a refresh token that was
+// replaced.
+func (s *SessionStore) rotatedFamily(ctx context.Context, token string) (userID, sid string, ok bool) {
+	val, err := s.RDB.Get(ctx, rotatedPrefix+token).Result()
+	if err != nil {
+		return "", "", false
+	}
+	userID, sid, ok = strings.Cut(val, "|")
+	return userID, sid, ok && userID != "" && sid != ""
+}
+
+// drop ends one session and its current refresh token.
+func (s *SessionStore) drop(ctx context.Context, userID, sid string) error {
+	token, err := s.RDB.HGet(ctx, sessionPrefix+sid, "token").Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return err
+	}
+	pipe := s.RDB.TxPipeline()
+	if token != "" {
+		pipe.Del(ctx, "rt:"+token)
+	}
+	pipe.Del(ctx, sessionPrefix+sid)
+	pipe.SRem(ctx, sessionUserPrefix+userID, sid)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// ListSessions returns a user's live sessions, most recently used first.
+func (s *SessionStore) ListSessions(ctx context.Context, userID string) ([]Session, error) {
+	sids, err := s.RDB.SMembers(ctx, sessionUserPrefix+userID).Result()
+	if err != nil {
+		return nil, err
+	}
+	pipe := s.RDB.Pipeline()
+	cmds := make([]*redis.MapStringStringCmd, len(sids))
+	for i, sid := range sids {
+		cmds[i] = pipe.HGetAll(ctx, sessionPrefix+sid)
+	}
+	if len(sids) > 0 {
+		if _, err := pipe.Exec(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	out := []Session{}
+	var expired []any
+	for i, sid := range sids {
+		m := cmds[i].Val()
+		if len(m) == 0 || m["uid"] != userID {
+			expired = append(expired, sid)
+			continue
+		}
+		out = append(out, Session{
+			ID:         sid,
+			Device:     m["device"],
+			UserAgent:  m["ua"],
+			IP:         m["ip"],
+			CreatedAt:  unixField(m["created_at"]),
+			LastUsedAt: unixField(m["last_used_at"]),
+			ExpiresAt:  unixField(m["expires_at"]),
+		})
+	}
+	if len(expired) > 0 {
+		s.RDB.SRem(ctx, sessionUserPrefix+userID, expired...)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].LastUsedAt.After(out[j].LastUsedAt) })
+	return out, nil
+}
+
+// SessionActive reports whether session sid is live and belongs to userID
+// (middlewares.SessionChecker).
+func (s *SessionStore) SessionActive(ctx context.Context, userID, sid string) (bool, error) {
+	owner, err := s.RDB.HGet(ctx, sessionPrefix+sid, "uid").Result()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return owner == userID, nil
+}
+
+// RevokeSession ends one of the user's sessions; false if there is no such
+// session.
+func (s *SessionStore) RevokeSession(ctx context.Context, userID, sid string) (bool, error) {
+	owner, err := s.RDB.HGet(ctx, sessionPrefix+sid, "uid").Result()
+	if errors.Is(err, redis.Nil) || (err == nil && owner != userID) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, s.drop(ctx, userID, sid)
+}
+
+// RevokeSessions ends every session of a user.
+func (s *SessionStore) RevokeSessions(ctx context.Context, userID string) error {
+	sids, err := s.RDB.SMembers(ctx, sessionUserPrefix+userID).Result()
+	if err != nil {
+		return err
+	}
+	pipe := s.RDB.Pipeline()
+	cmds := make([]*redis.StringCmd, len(sids))
+	for i, sid := range sids {
+		cmds[i] = pipe.HGet(ctx, sessionPrefix+sid, "token")
+	}
+	if len(sids) > 0 {
+		_, _ = pipe.Exec(ctx) // missing hashes are just expired sessions
+	}
+
+	keys := []string{sessionUserPrefix + userID}
+	for i, sid := range sids {
+		keys = append(keys, sessionPrefix+sid)
+		if t := cmds[i].Val(); t != "" {
+			keys = append(keys, "rt:"+t)
+		}
+	}
+	return s.RDB.Del(ctx, keys...).Err()
+}
+
+func unixField(s string) time.Time {
+	n, _ := strconv.ParseInt(s, 10, 64)
+	return time.Unix(n, 0).UTC()
+}
+
+// truncate cuts s to at most n bytes without splitting a rune.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// describeUA turns a User-Agent into a rough "Browser on OS" label for
+// clients that don't send X-Device-Name.
+func describeUA(ua string) string {
+	var browser, os string
+	switch {
+	case strings.Contains(ua, "Edg/"):
+		browser = "Edge"
+	case strings.Contains(ua, "OPR/"):
+		browser = "Opera"
+	case strings.Contains(ua, "Firefox/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "Chrome/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "Safari/"):
+		browser = "Safari"
+	case strings.HasPrefix(ua, "okhttp/"), strings.Contains(ua, "CFNetwork/"), strings.HasPrefix(ua, "Dart/"):
+		browser = "App"
+	}
+	switch {
+	case strings.Contains(ua, "iPhone"), strings.Contains(ua, "iPad"), strings.Contains(ua, "CFNetwork/"):
+		os = "iOS"
+	case strings.Contains(ua, "Android"), strings.HasPrefix(ua, "okhttp/"):
+		os = "Android"
+	case strings.Contains(ua, "Windows"):
+		os = "Windows"
+	case strings.Contains(ua, "CrOS"):
+		os = "ChromeOS"
+	case strings.Contains(ua, "Mac OS X"), strings.Contains(ua, "Macintosh"):
+		os = "macOS"
+	case strings.Contains(ua, "Linux"):
+		os = "Linux"
+	}
+	switch {
+	case browser != "" && os != "":
+		return browser + " on " + os
+	case browser != "":
+		return browser
+	case os != "":
+		return os
+	}
+	return "Unknown device"
+}
@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/5w1tchy/books-api/internal/api/httpx"
+	"github.com/5w1tchy/books-api/internal/api/middlewares"
+)
+
+// ListSessions handles GET /auth/sessions and marks the caller's own session
+// as current.
+func (h *Handler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middlewares.UserIDFrom(r.Context())
+	if !ok {
+		httpx.ErrorCode(w, http.StatusUnauthorized, "unauthorized", "Unauthorized")
+		return
+	}
+	sessions, err := h.sessions().ListSessions(r.Context(), userID)
+	if err != nil {
+		httpx.ErrorCode(w, http.StatusInternalServerError, "session_error", "Failed to load sessions")
+		return
+	}
+	if sid, ok := middlewares.SessionIDFrom(r.Context()); ok {
+		for i := range sessions {
+			sessions[i].Current = sessions[i].ID == sid
+		}
+	}
+	httpx.WriteJSON(w, http.StatusOK, map[string]any{"sessions": sessions})
+}
+
+// RevokeSession handles DELETE /auth/sessions/{id}. The session's refresh
+// token and the access tokens issued to it stop working at once (RequireAuth
+// checks the session).
+func (h *Handler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middlewares.UserIDFrom(r.Context())
+	if !ok {
+		httpx.ErrorCode(w, http.StatusUnauthorized, "unauthorized", "Unauthorized")
+		return
+	}
+	found, err := h.sessions().RevokeSession(r.Context(), userID, r.PathValue("id"))
+	if err != nil {
+		httpx.ErrorCode(w, http.StatusInternalServerError, "session_error", "Failed to revoke session")
+		return
+	}
+	if !found {
+		httpx.ErrorCode(w, http.StatusNotFound, "not_found", "Session not found")
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
@@ -0,0 +1,269 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/5w1tchy/books-api/internal/api/middlewares"
+	jwtutil "github.com/5w1tchy/books-api/internal/security/jwt"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestMain(m *testing.M) {
+	os.Setenv("AUTH_JWT_SECRET", "test-secret-that-is-at-least-32-characters")
+	os.Exit(m.Run())
+}
+
+func newTestRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	return mr, redis.NewClient(&redis.Options{Addr: mr.Addr()})
+}
+
+var testMeta = SessionMeta{Device: "Firefox on Linux", UserAgent: "Mozilla/5.0", IP: "203.0.113.7"}
+
+func TestSessionRotation(t *testing.T) {
+	mr, rdb := newTestRedis(t)
+	s := &SessionStore{RDB: rdb}
+	ctx := t.Context()
+
+	first, err := s.start(ctx, "u-1", 1, "s-1", testMeta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := mr.Get("rt:" + first); got != "u-1|1|s-1" {
+		t.Fatalf("rt value = %q", got)
+	}
+
+	val, err := s.claim(ctx, first)
+	if err != nil || val != "u-1|1|s-1" {
+		t.Fatalf("claim = %q, %v", val, err)
+	}
+	second, err := s.rotate(ctx, "u-1", 1, "s-1", first, testMeta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mr.Exists("rt:"+first) || !mr.Exists("rt:"+second) {
+		t.Fatal("old token still live or new token missing")
+	}
+	if tok := mr.HGet(sessionPrefix+"s-1", "token"); tok != second {
+		t.Fatalf("session token = %q, want the rotated one", tok)
+	}
+	// The replaced token is remembered for the rest of the session's life
+	if ttl := mr.TTL(rotatedPrefix + first); ttl < sessionMaxAge()-time.Minute {
+		t.Fatalf("rotated record TTL = %s", ttl)
+	}
+	if uid, sid, ok := s.rotatedFamily(ctx, first); !ok || uid != "u-1" || sid != "s-1" {
+		t.Fatalf("rotatedFamily = %q, %q, %v", uid, sid, ok)
+	}
+	if _, _, ok := s.rotatedFamily(ctx, second); ok {
+		t.Fatal("live token reported as rotated")
+	}
+
+	sessions, err := s.ListSessions(ctx, "u-1")
+	if err != nil || len(sessions) != 1 || sessions[0].Device != testMeta.Device {
+		t.Fatalf("ListSessions = %+v, %v", sessions, err)
+	}
+}
+
+func TestSessionClaimIsSingleUse(t *testing.T) {
+	_, rdb := newTestRedis(t)
+	s := &SessionStore{RDB: rdb}
+	ctx := t.Context()
+
+	token, err := s.start(ctx, "u-1", 1, "s-1", testMeta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.claim(ctx, token); err != nil {
+		t.Fatal(err)
+	}
+	// A second request with the same token loses, and looks like reuse
+	// straight away, before the winner has rotated
+	if _, err := s.claim(ctx, token); !errors.Is(err, redis.Nil) {
+		t.Fatalf("second claim err = %v, want redis.Nil", err)
+	}
+	if _, sid, ok := s.rotatedFamily(ctx, token); !ok || sid != "s-1" {
+		t.Fatal("lost claim not recorded as rotated")
+	}
+	if _, err := s.claim(ctx, "unknown"); !errors.Is(err, redis.Nil) {
+		t.Fatalf("unknown token err = %v", err)
+	}
+}
+
+func TestSessionMaxAge(t *testing.T) {
+	t.Setenv("AUTH_REFRESH_TTL", "720h")
+	t.Setenv("AUTH_SESSION_MAX_AGE", "1h")
+	mr, rdb := newTestRedis(t)
+	s := &SessionStore{RDB: rdb}
+	ctx := t.Context()
+
+	token, err := s.start(ctx, "u-1", 1, "s-1", testMeta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Refresh tokens never outlive the session
+	if ttl := mr.TTL("rt:" + token); ttl > time.Hour {
+		t.Fatalf("refresh TTL %s exceeds the session max age", ttl)
+	}
+
+	// Past expires_at the session can't be refreshed, however recently used
+	mr.HSet(sessionPrefix+"s-1", "expires_at", strconv.FormatInt(time.Now().Add(-time.Second).Unix(), 10))
+	if _, err := s.claim(ctx, token); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.rotate(ctx, "u-1", 1, "s-1", token, testMeta); !errors.Is(err, errSessionGone) {
+		t.Fatalf("rotate past max age err = %v", err)
+	}
+	if member, _ := mr.IsMember(sessionUserPrefix+"u-1", "s-1"); member || mr.Exists(sessionPrefix+"s-1") {
+		t.Fatal("expired session not dropped")
+	}
+}
+
+const tokenVersionQuery = `SELECT COALESCE(token_version,1) FROM public.users WHERE id=$1`
+
+func refresh(h *Handler, token string) *httptest.ResponseRecorder {
+	body := strings.NewReader(`{"refresh_token":"` + token + `"}`)
+	rec := httptest.NewRecorder()
+	h.Refresh(rec, httptest.NewRequest(http.MethodPost, "/auth/refresh", body))
+	return rec
+}
+
+func TestRefreshReuseEndsSession(t *testing.T) {
+	mr, rdb := newTestRedis(t)
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	h := &Handler{DB: db, RDB: rdb}
+
+	pair, err := h.issueTokens(t.Context(), "u-1", 1, testMeta)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(tokenVersionQuery)).WithArgs("u-1").
+		WillReturnRows(sqlmock.NewRows([]string{"token_version"}).AddRow(1))
+	rec := refresh(h, pair.RefreshToken)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("refresh: %d %s", rec.Code, rec.Body)
+	}
+	var next TokenPair
+	if err := json.NewDecoder(rec.Body).Decode(&next); err != nil || next.RefreshToken == "" {
+		t.Fatalf("refresh response: %v", err)
+	}
+
+	// Replaying the first token ends the whole family, new token included
+	mock.ExpectExec(`INSERT INTO public.security_events`).
+		WithArgs("u-1", EventRefreshReuse, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	rec = refresh(h, pair.RefreshToken)
+	if rec.Code != http.StatusUnauthorized || !strings.Contains(rec.Body.String(), "token_reused") {
+		t.Fatalf("replay: %d %s", rec.Code, rec.Body)
+	}
+	if mr.Exists("rt:" + next.RefreshToken) {
+		t.Fatal("rotated token survived reuse")
+	}
+	if rec := refresh(h, next.RefreshToken); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("refresh after reuse: %d", rec.Code)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRefreshReuseLogsOutAll(t *testing.T) {
+	t.Setenv("AUTH_REFRESH_REUSE_LOGOUT_ALL", "true")
+	mr, rdb := newTestRedis(t)
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	h := &Handler{DB: db, RDB: rdb}
+
+	stolen, err := h.issueTokens(t.Context(), "u-1", 1, testMeta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := h.issueTokens(t.Context(), "u-1", 1, testMeta)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(tokenVersionQuery)).WithArgs("u-1").
+		WillReturnRows(sqlmock.NewRows([]string{"token_version"}).AddRow(1))
+	if rec := refresh(h, stolen.RefreshToken); rec.Code != http.StatusOK {
+		t.Fatalf("refresh: %d %s", rec.Code, rec.Body)
+	}
+
+	mock.ExpectExec(`UPDATE public.users SET token_version`).WithArgs("u-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO public.security_events`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	if rec := refresh(h, stolen.RefreshToken); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("replay: %d", rec.Code)
+	}
+	if mr.Exists("rt:"+other.RefreshToken) || mr.Exists(sessionUserPrefix+"u-1") {
+		t.Fatal("other sessions survived reuse with AUTH_REFRESH_REUSE_LOGOUT_ALL")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRevokedSessionRefusesAccessToken(t *testing.T) {
+	_, rdb := newTestRedis(t)
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	h := &Handler{DB: db, RDB: rdb}
+	s := h.sessions()
+
+	if _, err := s.start(t.Context(), "u-1", 1, "s-1", testMeta); err != nil {
+		t.Fatal(err)
+	}
+	access, _, err := jwtutil.SignSessionAccess("u-1", "s-1", 1, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	protected := middlewares.RequireAuth(db, s, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	call := func() int {
+		mock.ExpectQuery(`SELECT COALESCE\(token_version,1\) FROM public.users`).WithArgs("u-1").
+			WillReturnRows(sqlmock.NewRows([]string{"token_version"}).AddRow(1))
+		req := httptest.NewRequest(http.MethodGet, "/auth/me", nil)
+		req.Header.Set("Authorization", "Bearer "+access)
+		rec := httptest.NewRecorder()
+		protected.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := call(); code != http.StatusNoContent {
+		t.Fatalf("live session: %d", code)
+	}
+	if found, err := s.RevokeSession(t.Context(), "u-1", "s-1"); err != nil || !found {
+		t.Fatalf("RevokeSession = %v, %v", found, err)
+	}
+	if code := call(); code != http.StatusUnauthorized {
+		t.Fatalf("revoked session: %d, want 401", code)
+	}
+	if ok, _ := s.SessionActive(t.Context(), "u-2", "s-1"); ok {
+		t.Fatal("session active for another user")
+	}
+}
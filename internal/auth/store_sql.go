@@ -17,12 +17,13 @@ func (s *SQLStore) CreateUser(email, username, passwordHash, role string) (User,
         VALUES ($1, $2, $3, $4)
         RETURNING id, email, username, password_hash,
                  COALESCE(token_version,1) AS token_version,
-                 status, email_verified_at, created_at, updated_at, role;
+                 status, email_verified_at, created_at, updated_at, role,
+                 totp_enabled_at IS NOT NULL AS mfa_enabled;
     `
 	var u User
 	err := s.DB.QueryRowContext(context.Background(), q, email, username, passwordHash, role).Scan(
 		&u.ID, &u.Email, &u.Username, &u.PasswordHash, &u.TokenVersion,
-		&u.Status, &u.EmailVerified, &u.CreatedAt, &u.UpdatedAt, &u.Role,
+		&u.Status, &u.EmailVerified, &u.CreatedAt, &u.UpdatedAt, &u.Role, &u.MFAEnabled,
 	)
 	return u, err
 }
@@ -31,7 +32,8 @@ func (s *SQLStore) FindUserByEmail(email string) (User, error) {
 	const q = `
 		SELECT id, email, username, password_hash,
 		       COALESCE(token_version,1) AS token_version,
-		       status, email_verified_at, created_at, updated_at, role
+		       status, email_verified_at, created_at, updated_at, role,
+		       totp_enabled_at IS NOT NULL AS mfa_enabled
 		FROM public.users
 		WHERE email = $1
 		LIMIT 1;
@@ -39,7 +41,7 @@ func (s *SQLStore) FindUserByEmail(email string) (User, error) {
 	var u User
 	err := s.DB.QueryRowContext(context.Background(), q, email).Scan(
 		&u.ID, &u.Email, &u.Username, &u.PasswordHash, &u.TokenVersion,
-		&u.Status, &u.EmailVerified, &u.CreatedAt, &u.UpdatedAt, &u.Role,
+		&u.Status, &u.EmailVerified, &u.CreatedAt, &u.UpdatedAt, &u.Role, &u.MFAEnabled,
 	)
 	return u, err
 }
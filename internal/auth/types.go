@@ -1,15 +1,29 @@
 package auth
 
 import (
+	"database/sql"
 	"time"
 
+	"github.com/5w1tchy/books-api/internal/mail"
+	"github.com/5w1tchy/books-api/internal/security/totp"
+	"github.com/5w1tchy/books-api/internal/security/webauthn"
 	"github.com/redis/go-redis/v9"
 )
 
 // Handler holds dependencies for auth operations
 type Handler struct {
 	Store UserStore
-	RDB   *redis.Client
+	// DB serves the queries UserStore doesn't cover (sessions, 2FA, passkeys,
+	// security events). New fills it in for a *SQLStore.
+	DB  *sql.DB
+	RDB *redis.Client
+	// TOTPKey seals 2FA secrets at rest (see totp.KeyFromEnv).
+	TOTPKey []byte
+	// WebAuthn describes this API as a passkey relying party.
+	WebAuthn webauthn.Config
+	// Mailer tells owners their account was locked or gained a second
+	// factor or passkey; optional, set by the router.
+	Mailer mail.Mailer
 }
 
 // Request types
@@ -18,7 +32,6 @@ type RegisterRequest struct {
 	Email    string `json:"email"`
 	Username string `json:"username"`
 	Password string `json:"password"`
-	Role     string `json:"role"`
 }
 
 type LoginRequest struct {
@@ -26,6 +39,14 @@ type LoginRequest struct {
 	Password string `json:"password"`
 }
 
+// LoginMFARequest completes a login that answered with an MFAChallenge,
+// using either an authenticator code or a recovery code.
+type LoginMFARequest struct {
+	MFAToken     string `json:"mfa_token"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
 type RefreshRequest struct {
 	RefreshToken string `json:"refresh_token"`
 }
@@ -42,6 +63,14 @@ type TokenPair struct {
 	RefreshToken string `json:"refresh_token"`
 }
 
+// MFAChallenge is what Login returns instead of a TokenPair when the account
+// has 2FA on; post MFAToken with a code to /auth/login/mfa.
+type MFAChallenge struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
 type MeResponse struct {
 	ID            string     `json:"id"`
 	Email         string     `json:"email"`
@@ -63,6 +92,7 @@ type User struct {
 	Status        string
 	Role          string
 	EmailVerified *time.Time
+	MFAEnabled    bool
 	CreatedAt     time.Time
 	UpdatedAt     time.Time
 }
@@ -77,5 +107,9 @@ type UserStore interface {
 
 // New creates a new auth handler instance
 func New(store UserStore, rdb *redis.Client) *Handler {
-	return &Handler{Store: store, RDB: rdb}
+	h := &Handler{Store: store, RDB: rdb, TOTPKey: totp.KeyFromEnv(), WebAuthn: webauthn.ConfigFromEnv()}
+	if s, ok := store.(*SQLStore); ok {
+		h.DB = s.DB
+	}
+	return h
 }
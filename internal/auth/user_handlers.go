@@ -8,7 +8,6 @@ import (
 
 	"github.com/5w1tchy/books-api/internal/api/httpx"
 	"github.com/5w1tchy/books-api/internal/api/middlewares"
-	jwtutil "github.com/5w1tchy/books-api/internal/security/jwt"
 	"github.com/5w1tchy/books-api/internal/security/password"
 )
 
@@ -24,7 +23,7 @@ func (h *Handler) Me(w http.ResponseWriter, r *http.Request) {
         FROM public.users WHERE id=$1 LIMIT 1;
     `
 	var resp MeResponse
-	if err := h.Store.(*SQLStore).DB.QueryRowContext(r.Context(), q, userID).Scan(
+	if err := h.DB.QueryRowContext(r.Context(), q, userID).Scan(
 		&resp.ID, &resp.Email, &resp.Username, &resp.Role, &resp.Status, &resp.EmailVerified, &resp.CreatedAt,
 	); err != nil {
 		httpx.ErrorCode(w, http.StatusNotFound, "not_found", "User not found")
@@ -55,7 +54,7 @@ func (h *Handler) ChangePassword(w http.ResponseWriter, r *http.Request) {
 	// load current hash + tv
 	var storedHash string
 	var tv int
-	err := h.Store.(*SQLStore).DB.QueryRowContext(r.Context(),
+	err := h.DB.QueryRowContext(r.Context(),
 		`SELECT password_hash, COALESCE(token_version,1) FROM public.users WHERE id=$1`, userID).
 		Scan(&storedHash, &tv)
 	if err != nil {
@@ -87,7 +86,7 @@ func (h *Handler) ChangePassword(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// set new hash + bump token_version
-	_, err = h.Store.(*SQLStore).DB.ExecContext(r.Context(),
+	_, err = h.DB.ExecContext(r.Context(),
 		`UPDATE public.users
 		   SET password_hash=$1, token_version=COALESCE(token_version,1)+1, updated_at=now()
 		 WHERE id=$2`,
@@ -97,22 +96,19 @@ func (h *Handler) ChangePassword(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// issue fresh tokens (tv+1)
-	access, _, err := jwtutil.SignAccess(userID, tv+1, jwtutil.DefaultAccessTTL())
-	if err != nil {
-		httpx.ErrorCode(w, http.StatusInternalServerError, "jwt_error", "Failed to sign access token")
-		return
-	}
-	newRefresh, err := h.issueRefresh(r.Context(), userID, tv+1)
+	_ = revokeRefreshTokens(r.Context(), h.RDB, userID)
+
+	// issue fresh tokens (tv+1) in a new session for this device
+	pair, err := h.issueTokens(r.Context(), userID, tv+1, SessionMetaFrom(r))
 	if err != nil {
-		httpx.ErrorCode(w, http.StatusInternalServerError, "refresh_error", "Failed to issue refresh token")
+		httpx.ErrorCode(w, http.StatusInternalServerError, "refresh_error", "Failed to issue tokens")
 		return
 	}
 
 	// JSON: always include password_score; include warning when score < 4 (for consistency with Register)
 	resp := map[string]any{
-		"access_token":   access,
-		"refresh_token":  newRefresh,
+		"access_token":   pair.AccessToken,
+		"refresh_token":  pair.RefreshToken,
 		"password_score": score,
 	}
 	if score < 4 && warnMsg != "" {
@@ -132,11 +128,12 @@ func (h *Handler) LogoutAll(w http.ResponseWriter, r *http.Request) {
 		httpx.ErrorCode(w, http.StatusUnauthorized, "unauthorized", "Unauthorized")
 		return
 	}
-	_, err := h.Store.(*SQLStore).DB.ExecContext(r.Context(),
+	_, err := h.DB.ExecContext(r.Context(),
 		`UPDATE public.users SET token_version = COALESCE(token_version,1) + 1, updated_at=now() WHERE id=$1`, userID)
 	if err != nil {
 		httpx.ErrorCode(w, http.StatusInternalServerError, "update_failed", "Failed to update token version")
 		return
 	}
+	_ = revokeRefreshTokens(r.Context(), h.RDB, userID) // already dead via token_version; this frees Redis
 	httpx.WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"context"
 	"crypto/rand"
 	"database/sql"
 	"encoding/hex"
@@ -11,6 +12,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/5w1tchy/books-api/internal/mail"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -22,74 +24,110 @@ const (
 )
 
 type VerifyDeps struct {
-	DB  *sql.DB
-	RDB *redis.Client
-	// If you want to show a full URL, set BaseURL (e.g., https://localhost:3000).
-	// Leave empty to return just the path for dev.
+	DB     *sql.DB
+	RDB    *redis.Client
+	Mailer mail.Mailer
+	// Public origin of this API (e.g., https://api.example.com), used for the
+	// emailed link. Leave empty to use just the path for dev.
 	BaseURL string
+	// ExposeLinks also returns verify_url in the JSON response (DEV only;
+	// never set it in production, the link is a bearer token).
+	ExposeLinks bool
 }
 
+var (
+	ErrAlreadyVerified    = errors.New("email already verified")
+	ErrVerifyQuotaReached = errors.New("verification send limit reached")
+)
+
 // POST /auth/send-verification (auth required)
 // Behavior:
 //   - If already verified → 204
 //   - Else generate token, store in Redis for 24h, increment per-user quota (max 3/24h)
-//   - DEV: returns verify_url in JSON so frontend can call it directly
+//   - Email the link in the caller's language (Accept-Language)
+//   - DEV (ExposeLinks): also returns verify_url in JSON so frontend can call it directly
 func (d *VerifyDeps) HandleSendVerification(getUserID func(*http.Request) (string, bool)) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := r.Context()
-
 		userID, ok := getUserID(r)
 		if !ok || userID == "" {
 			http.Error(w, "unauthorized", http.StatusUnauthorized)
 			return
 		}
 
-		// Check already verified
-		var verifiedAt sql.NullTime
-		if err := d.DB.QueryRowContext(ctx, `SELECT email_verified_at FROM users WHERE id=$1`, userID).Scan(&verifiedAt); err != nil {
-			http.Error(w, "db error", http.StatusInternalServerError)
-			return
-		}
-		if verifiedAt.Valid {
+		link, err := d.SendVerification(r.Context(), userID, mail.Locale(r.Header.Get("Accept-Language")), true)
+		switch {
+		case errors.Is(err, ErrAlreadyVerified):
 			w.WriteHeader(http.StatusNoContent)
 			return
+		case errors.Is(err, ErrVerifyQuotaReached):
+			// Optional: help client decide when to retry
+			w.Header().Set("Retry-After", strconv.Itoa(int(24*time.Hour/time.Second)))
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return
+		case err != nil:
+			http.Error(w, "verification send failed", http.StatusInternalServerError)
+			return
+		}
+
+		resp := map[string]any{"status": "ok"}
+		if d.ExposeLinks {
+			resp["verify_url"] = link
 		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// SendVerification issues a fresh token for userID and emails the link.
+// With enforceQuota the per-user limit (3/24h) applies; admins resending on a
+// user's behalf skip it. Returns the link for dev responses.
+func (d *VerifyDeps) SendVerification(ctx context.Context, userID, locale string, enforceQuota bool) (string, error) {
+	// Check already verified
+	var email string
+	var verifiedAt sql.NullTime
+	if err := d.DB.QueryRowContext(ctx, `SELECT email, email_verified_at FROM users WHERE id=$1`, userID).Scan(&email, &verifiedAt); err != nil {
+		return "", err
+	}
+	if verifiedAt.Valid {
+		return "", ErrAlreadyVerified
+	}
 
-		// Quota: 3 sends per 24h
+	// Quota: 3 sends per 24h
+	if enforceQuota {
 		qKey := evQuotaPrefix + userID
 		pipe := d.RDB.TxPipeline()
 		incr := pipe.Incr(ctx, qKey)
 		pipe.Expire(ctx, qKey, 24*time.Hour)
 		if _, err := pipe.Exec(ctx); err != nil {
-			http.Error(w, "rate limit error", http.StatusInternalServerError)
-			return
+			return "", err
 		}
 		if incr.Val() > int64(evQuotaMax) {
-			// Optional: help client decide when to retry
-			w.Header().Set("Retry-After", strconv.Itoa(int(24*time.Hour/time.Second)))
-			http.Error(w, "verification send limit reached", http.StatusTooManyRequests)
-			return
+			return "", ErrVerifyQuotaReached
 		}
+	}
 
-		// New token
-		token, err := randomToken(32)
-		if err != nil {
-			http.Error(w, "token error", http.StatusInternalServerError)
-			return
-		}
-		if err := d.RDB.SetEx(ctx, evKeyPrefix+token, userID, evTTL).Err(); err != nil {
-			http.Error(w, "redis error", http.StatusInternalServerError)
-			return
-		}
+	// New token
+	token, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+	if err := d.RDB.SetEx(ctx, evKeyPrefix+token, userID, evTTL).Err(); err != nil {
+		return "", err
+	}
 
-		// DEV convenience: return clickable path (or absolute if BaseURL set)
-		path := d.buildVerifyURL(token)
-		w.Header().Set("Content-Type", "application/json")
-		_ = json.NewEncoder(w).Encode(map[string]any{
-			"status":     "ok",
-			"verify_url": path,
-		})
+	link := d.buildVerifyURL(token)
+	msg, err := mail.Render(mail.TemplateVerifyEmail, locale, email, mail.Data{
+		"Link":  link,
+		"Hours": int(evTTL / time.Hour),
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := d.Mailer.Send(ctx, msg); err != nil {
+		_ = d.RDB.Del(ctx, evKeyPrefix+token).Err()
+		return "", err
 	}
+	return link, nil
 }
 
 // GET /auth/verify?token=...
@@ -0,0 +1,393 @@
+package epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"strings"
+	"time"
+)
+
+const mimetype = "application/epub+zip"
+
+// Image is an embedded picture (the cover).
+type Image struct {
+	Data      []byte
+	MediaType string // image/jpeg | image/png | image/webp | image/gif
+}
+
+// Note is a single user note/highlight rendered into the appendix.
+type Note struct {
+	Kind       string // "note" | "highlight"
+	Content    string
+	PageNumber *int
+	CreatedAt  time.Time
+}
+
+// Book is everything needed to render one summary as an EPUB 3 package.
+type Book struct {
+	ID       string // stable identifier (book UUID)
+	Title    string
+	Authors  []string
+	Language string // BCP 47, defaults to "en"
+	Summary  string
+	Coda     string
+	Cover    *Image
+	Notes    []Note // optional appendix
+	Modified time.Time
+}
+
+// chapter is one XHTML document in the spine.
+type chapter struct {
+	id, href, title string
+	body            string // already-escaped XHTML body content
+}
+
+// Write renders b as an EPUB 3 container into w.
+// The mimetype entry is written first and stored uncompressed, as the OCF spec requires.
+func Write(w io.Writer, b Book) error {
+	if strings.TrimSpace(b.Title) == "" {
+		return errors.New("epub: title is required")
+	}
+	if b.Language == "" {
+		b.Language = "en"
+	}
+	if b.Modified.IsZero() {
+		b.Modified = time.Now()
+	}
+
+	zw := zip.NewWriter(w)
+
+	if err := writeMimetype(zw); err != nil {
+		return err
+	}
+	if err := writeFile(zw, "META-INF/container.xml", containerXML()); err != nil {
+		return err
+	}
+
+	chapters := buildChapters(b)
+
+	var coverExt string
+	if b.Cover != nil && len(b.Cover.Data) > 0 {
+		if b.Cover.MediaType == "image/jpg" {
+			b.Cover.MediaType = "image/jpeg"
+		}
+		coverExt = imageExt(b.Cover.MediaType)
+		if coverExt == "" {
+			return fmt.Errorf("epub: unsupported cover media type %q", b.Cover.MediaType)
+		}
+		if err := writeFile(zw, "OEBPS/images/cover"+coverExt, b.Cover.Data); err != nil {
+			return err
+		}
+		page := xhtmlDoc(b.Language, b.Title, `<div class="cover"><img src="images/cover`+coverExt+`" alt="`+escape(b.Title)+`"/></div>`)
+		if err := writeFile(zw, "OEBPS/cover.xhtml", page); err != nil {
+			return err
+		}
+	}
+
+	for _, c := range chapters {
+		if err := writeFile(zw, "OEBPS/"+c.href, xhtmlDoc(b.Language, c.title, c.body)); err != nil {
+			return err
+		}
+	}
+
+	if err := writeFile(zw, "OEBPS/nav.xhtml", navXHTML(b, chapters)); err != nil {
+		return err
+	}
+	if err := writeFile(zw, "OEBPS/style.css", []byte(stylesheet)); err != nil {
+		return err
+	}
+
+	opf, err := packageOPF(b, chapters, coverExt)
+	if err != nil {
+		return err
+	}
+	if err := writeFile(zw, "OEBPS/content.opf", opf); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+// ---------- zip helpers ----------
+
+func writeMimetype(zw *zip.Writer) error {
+	// CreateRaw avoids the data-descriptor flag and extra fields that
+	// CreateHeader would add; readers sniff the first 58 bytes of the file.
+	data := []byte(mimetype)
+	fh := &zip.FileHeader{
+		Name:               "mimetype",
+		Method:             zip.Store,
+		CRC32:              crc32.ChecksumIEEE(data),
+		CompressedSize64:   uint64(len(data)),
+		UncompressedSize64: uint64(len(data)),
+	}
+	fw, err := zw.CreateRaw(fh)
+	if err != nil {
+		return fmt.Errorf("epub: mimetype: %w", err)
+	}
+	_, err = fw.Write(data)
+	return err
+}
+
+func writeFile(zw *zip.Writer, name string, data []byte) error {
+	fw, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate})
+	if err != nil {
+		return fmt.Errorf("epub: %s: %w", name, err)
+	}
+	if _, err := fw.Write(data); err != nil {
+		return fmt.Errorf("epub: %s: %w", name, err)
+	}
+	return nil
+}
+
+// ---------- content ----------
+
+func buildChapters(b Book) []chapter {
+	var out []chapter
+
+	var title strings.Builder
+	title.WriteString("<h1>" + escape(b.Title) + "</h1>\n")
+	if len(b.Authors) > 0 {
+		title.WriteString(`<p class="authors">` + escape(strings.Join(b.Authors, ", ")) + "</p>\n")
+	}
+	out = append(out, chapter{id: "titlepage", href: "title.xhtml", title: b.Title, body: title.String()})
+
+	if strings.TrimSpace(b.Summary) != "" {
+		out = append(out, chapter{
+			id: "summary", href: "summary.xhtml", title: "Summary",
+			body: "<h2>Summary</h2>\n" + paragraphs(b.Summary),
+		})
+	}
+	if strings.TrimSpace(b.Coda) != "" {
+		out = append(out, chapter{
+			id: "coda", href: "coda.xhtml", title: "Coda",
+			body: "<h2>Coda</h2>\n" + paragraphs(b.Coda),
+		})
+	}
+	if len(b.Notes) > 0 {
+		out = append(out, chapter{
+			id: "notes", href: "notes.xhtml", title: "My notes",
+			body: notesBody(b.Notes),
+		})
+	}
+	return out
+}
+
+func notesBody(notes []Note) string {
+	var sb strings.Builder
+	sb.WriteString("<h2>My notes</h2>\n")
+	for _, n := range notes {
+		class := "note"
+		if n.Kind == "highlight" {
+			class = "highlight"
+		}
+		sb.WriteString(`<div class="` + class + `">` + "\n")
+		var meta []string
+		if n.PageNumber != nil {
+			meta = append(meta, fmt.Sprintf("Page %d", *n.PageNumber))
+		}
+		if !n.CreatedAt.IsZero() {
+			meta = append(meta, n.CreatedAt.UTC().Format("2006-01-02"))
+		}
+		if len(meta) > 0 {
+			sb.WriteString(`<p class="meta">` + escape(strings.Join(meta, " · ")) + "</p>\n")
+		}
+		if class == "highlight" {
+			sb.WriteString("<blockquote>" + paragraphs(n.Content) + "</blockquote>\n")
+		} else {
+			sb.WriteString(paragraphs(n.Content))
+		}
+		sb.WriteString("</div>\n")
+	}
+	return sb.String()
+}
+
+// paragraphs splits free text on blank lines (or single newlines) into <p> elements.
+func paragraphs(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	var sb strings.Builder
+	for _, p := range strings.Split(s, "\n") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		sb.WriteString("<p>" + escape(p) + "</p>\n")
+	}
+	return sb.String()
+}
+
+func xhtmlDoc(lang, title, body string) []byte {
+	var sb strings.Builder
+	sb.WriteString(xml.Header)
+	sb.WriteString("<!DOCTYPE html>\n")
+	sb.WriteString(`<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" xml:lang="` + escape(lang) + `" lang="` + escape(lang) + `">` + "\n")
+	sb.WriteString("<head>\n<meta charset=\"utf-8\"/>\n<title>" + escape(title) + "</title>\n")
+	sb.WriteString(`<link rel="stylesheet" type="text/css" href="style.css"/>` + "\n")
+	sb.WriteString("</head>\n<body>\n")
+	sb.WriteString(body)
+	sb.WriteString("</body>\n</html>\n")
+	return []byte(sb.String())
+}
+
+func navXHTML(b Book, chapters []chapter) []byte {
+	var sb strings.Builder
+	sb.WriteString(`<nav epub:type="toc" id="toc">` + "\n<h1>Contents</h1>\n<ol>\n")
+	for _, c := range chapters {
+		sb.WriteString(`<li><a href="` + c.href + `">` + escape(c.title) + "</a></li>\n")
+	}
+	sb.WriteString("</ol>\n</nav>\n")
+	return xhtmlDoc(b.Language, b.Title, sb.String())
+}
+
+func containerXML() []byte {
+	type rootfile struct {
+		FullPath  string `xml:"full-path,attr"`
+		MediaType string `xml:"media-type,attr"`
+	}
+	type container struct {
+		XMLName   xml.Name   `xml:"urn:oasis:names:tc:opendocument:xmlns:container container"`
+		Version   string     `xml:"version,attr"`
+		Rootfiles []rootfile `xml:"rootfiles>rootfile"`
+	}
+	out, _ := xml.MarshalIndent(container{
+		Version:   "1.0",
+		Rootfiles: []rootfile{{FullPath: "OEBPS/content.opf", MediaType: "application/oebps-package+xml"}},
+	}, "", "  ")
+	return append([]byte(xml.Header), out...)
+}
+
+// ---------- OPF ----------
+
+type opfMeta struct {
+	Property string `xml:"property,attr,omitempty"`
+	Refines  string `xml:"refines,attr,omitempty"`
+	Scheme   string `xml:"scheme,attr,omitempty"`
+	Name     string `xml:"name,attr,omitempty"`
+	Content  string `xml:"content,attr,omitempty"`
+	Value    string `xml:",chardata"`
+}
+
+type opfDC struct {
+	ID    string `xml:"id,attr,omitempty"`
+	Value string `xml:",chardata"`
+}
+
+type opfItem struct {
+	ID         string `xml:"id,attr"`
+	Href       string `xml:"href,attr"`
+	MediaType  string `xml:"media-type,attr"`
+	Properties string `xml:"properties,attr,omitempty"`
+}
+
+type opfItemref struct {
+	IDRef  string `xml:"idref,attr"`
+	Linear string `xml:"linear,attr,omitempty"`
+}
+
+type opfPackage struct {
+	XMLName  xml.Name `xml:"http://www.idpf.org/2007/opf package"`
+	Version  string   `xml:"version,attr"`
+	UniqueID string   `xml:"unique-identifier,attr"`
+	Lang     string   `xml:"xml:lang,attr"`
+	Metadata struct {
+		DC         string    `xml:"xmlns:dc,attr"`
+		Identifier opfDC     `xml:"dc:identifier"`
+		Title      opfDC     `xml:"dc:title"`
+		Language   opfDC     `xml:"dc:language"`
+		Creators   []opfDC   `xml:"dc:creator"`
+		Meta       []opfMeta `xml:"meta"`
+	} `xml:"metadata"`
+	Manifest []opfItem    `xml:"manifest>item"`
+	Spine    []opfItemref `xml:"spine>itemref"`
+}
+
+func packageOPF(b Book, chapters []chapter, coverExt string) ([]byte, error) {
+	var p opfPackage
+	p.Version = "3.0"
+	p.UniqueID = "book-id"
+	p.Lang = b.Language
+	p.Metadata.DC = "http://purl.org/dc/elements/1.1/"
+	p.Metadata.Identifier = opfDC{ID: "book-id", Value: "urn:uuid:" + b.ID}
+	p.Metadata.Title = opfDC{ID: "title", Value: b.Title}
+	p.Metadata.Language = opfDC{Value: b.Language}
+	for i, a := range b.Authors {
+		id := fmt.Sprintf("creator%d", i+1)
+		p.Metadata.Creators = append(p.Metadata.Creators, opfDC{ID: id, Value: a})
+		p.Metadata.Meta = append(p.Metadata.Meta,
+			opfMeta{Refines: "#" + id, Property: "role", Scheme: "marc:relators", Value: "aut"},
+			opfMeta{Refines: "#" + id, Property: "display-seq", Value: fmt.Sprint(i + 1)},
+		)
+	}
+	p.Metadata.Meta = append(p.Metadata.Meta, opfMeta{
+		Property: "dcterms:modified",
+		Value:    b.Modified.UTC().Truncate(time.Second).Format("2006-01-02T15:04:05Z"),
+	})
+
+	p.Manifest = append(p.Manifest,
+		opfItem{ID: "nav", Href: "nav.xhtml", MediaType: "application/xhtml+xml", Properties: "nav"},
+		opfItem{ID: "css", Href: "style.css", MediaType: "text/css"},
+	)
+	if coverExt != "" {
+		// EPUB 2 readers look for <meta name="cover">; EPUB 3 uses the cover-image property.
+		p.Metadata.Meta = append(p.Metadata.Meta, opfMeta{Name: "cover", Content: "cover-image"})
+		p.Manifest = append(p.Manifest,
+			opfItem{ID: "cover-image", Href: "images/cover" + coverExt, MediaType: b.Cover.MediaType, Properties: "cover-image"},
+			opfItem{ID: "cover", Href: "cover.xhtml", MediaType: "application/xhtml+xml"},
+		)
+		p.Spine = append(p.Spine, opfItemref{IDRef: "cover"})
+	}
+	for _, c := range chapters {
+		p.Manifest = append(p.Manifest, opfItem{ID: c.id, Href: c.href, MediaType: "application/xhtml+xml"})
+		p.Spine = append(p.Spine, opfItemref{IDRef: c.id})
+	}
+
+	out, err := xml.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("epub: opf: %w", err)
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
+// ---------- small utils ----------
+
+// SupportedCover reports whether Write can embed a cover of this media type.
+func SupportedCover(mediaType string) bool {
+	return mediaType == "image/jpg" || imageExt(mediaType) != ""
+}
+
+func imageExt(mediaType string) string {
+	switch mediaType {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/png":
+		return ".png"
+	case "image/webp":
+		return ".webp"
+	case "image/gif":
+		return ".gif"
+	}
+	return ""
+}
+
+func escape(s string) string {
+	var buf bytes.Buffer
+	_ = xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+const stylesheet = `body { font-family: serif; line-height: 1.5; margin: 0 5%; }
+h1 { font-size: 1.6em; margin-top: 2em; text-align: center; }
+h2 { font-size: 1.3em; margin-top: 1.5em; }
+p { margin: 0 0 0.8em 0; text-align: justify; }
+.authors { text-align: center; font-style: italic; }
+.cover { text-align: center; }
+.cover img { max-width: 100%; max-height: 100%; }
+.note, .highlight { margin-bottom: 1.2em; }
+.meta { font-size: 0.85em; color: #666; margin-bottom: 0.2em; }
+blockquote { margin: 0 0 0 1em; padding-left: 0.8em; border-left: 3px solid #ccc; font-style: italic; }
+`
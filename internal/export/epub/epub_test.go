@@ -0,0 +1,152 @@
+package epub_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/5w1tchy/books-api/internal/export/epub"
+)
+
+func TestWrite_MimetypeFirstAndStored(t *testing.T) {
+	var buf bytes.Buffer
+	err := epub.Write(&buf, epub.Book{
+		ID:       "3f1c2b9e-8d2a-4c1e-9a6b-1234567890ab",
+		Title:    "Deep Work",
+		Authors:  []string{"Cal Newport"},
+		Summary:  "First paragraph.\n\nSecond <paragraph> & more.",
+		Modified: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	raw := buf.Bytes()
+	// OCF: "mimetype" must be the first local header, uncompressed, at offset 38.
+	if string(raw[30:38]) != "mimetype" {
+		t.Fatalf("first entry name = %q", raw[30:38])
+	}
+	if string(raw[38:58]) != "application/epub+zip" {
+		t.Fatalf("mimetype payload = %q", raw[38:58])
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(raw), int64(len(raw)))
+	if err != nil {
+		t.Fatalf("zip: %v", err)
+	}
+	if zr.File[0].Method != zip.Store {
+		t.Fatalf("mimetype compressed with method %d", zr.File[0].Method)
+	}
+
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	for _, name := range []string{"META-INF/container.xml", "OEBPS/content.opf", "OEBPS/nav.xhtml", "OEBPS/summary.xhtml"} {
+		if files[name] == nil {
+			t.Fatalf("missing %s", name)
+		}
+	}
+	if files["OEBPS/coda.xhtml"] != nil || files["OEBPS/notes.xhtml"] != nil {
+		t.Fatalf("empty coda/notes should not produce documents")
+	}
+
+	// Every XHTML document must be well-formed XML.
+	for name, f := range files {
+		if !strings.HasSuffix(name, ".xhtml") && !strings.HasSuffix(name, ".opf") && !strings.HasSuffix(name, ".xml") {
+			continue
+		}
+		body := readAll(t, f)
+		dec := xml.NewDecoder(bytes.NewReader(body))
+		dec.Strict = true
+		for {
+			if _, err := dec.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%s not well-formed: %v", name, err)
+			}
+		}
+	}
+
+	opf := string(readAll(t, files["OEBPS/content.opf"]))
+	for _, want := range []string{
+		`version="3.0"`,
+		`<dc:identifier id="book-id">urn:uuid:3f1c2b9e-8d2a-4c1e-9a6b-1234567890ab</dc:identifier>`,
+		`<dc:creator id="creator1">Cal Newport</dc:creator>`,
+		`<meta property="dcterms:modified">2025-01-02T03:04:05Z</meta>`,
+		`properties="nav"`,
+	} {
+		if !strings.Contains(opf, want) {
+			t.Errorf("opf missing %s\n%s", want, opf)
+		}
+	}
+
+	summary := string(readAll(t, files["OEBPS/summary.xhtml"]))
+	if !strings.Contains(summary, "<p>Second &lt;paragraph&gt; &amp; more.</p>") {
+		t.Errorf("summary not escaped/paragraphed:\n%s", summary)
+	}
+}
+
+func TestWrite_CoverAndNotes(t *testing.T) {
+	page := 12
+	var buf bytes.Buffer
+	err := epub.Write(&buf, epub.Book{
+		ID:    "id",
+		Title: "T",
+		Cover: &epub.Image{Data: []byte{0x89, 'P', 'N', 'G'}, MediaType: "image/png"},
+		Notes: []epub.Note{{Kind: "highlight", Content: "quoted", PageNumber: &page}},
+	})
+	if err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var opf, notes string
+	var hasCover bool
+	for _, f := range zr.File {
+		switch f.Name {
+		case "OEBPS/content.opf":
+			opf = string(readAll(t, f))
+		case "OEBPS/notes.xhtml":
+			notes = string(readAll(t, f))
+		case "OEBPS/images/cover.png":
+			hasCover = true
+		}
+	}
+	if !hasCover || !strings.Contains(opf, `properties="cover-image"`) {
+		t.Fatalf("cover not embedded:\n%s", opf)
+	}
+	if !strings.Contains(notes, "Page 12") || !strings.Contains(notes, "<blockquote>") {
+		t.Fatalf("notes appendix wrong:\n%s", notes)
+	}
+}
+
+func TestWrite_RejectsUnsupportedCover(t *testing.T) {
+	err := epub.Write(io.Discard, epub.Book{
+		Title: "T",
+		Cover: &epub.Image{Data: []byte("x"), MediaType: "image/tiff"},
+	})
+	if err == nil {
+		t.Fatal("expected error for tiff cover")
+	}
+}
+
+func readAll(t *testing.T, f *zip.File) []byte {
+	t.Helper()
+	rc, err := f.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	b, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
@@ -0,0 +1,51 @@
+package mail
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileDrop writes each message as an .eml file into a maildir (tmp/, new/,
+// cur/ under Dir), which mail clients and test tooling can open directly.
+type FileDrop struct {
+	Dir  string
+	From string
+}
+
+// NewFileDrop creates the maildir layout under dir if needed.
+func NewFileDrop(dir, from string) (*FileDrop, error) {
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o750); err != nil {
+			return nil, fmt.Errorf("mail: maildir: %w", err)
+		}
+	}
+	return &FileDrop{Dir: dir, From: from}, nil
+}
+
+// Send writes into tmp/ and renames into new/, so readers never see a
+// partial file.
+func (f *FileDrop) Send(_ context.Context, msg Message) error {
+	now := time.Now()
+	raw, err := Encode(f.From, msg, now)
+	if err != nil {
+		return err
+	}
+	var b [6]byte
+	_, _ = rand.Read(b[:])
+	name := fmt.Sprintf("%d.%s.eml", now.UnixNano(), hex.EncodeToString(b[:]))
+
+	tmp := filepath.Join(f.Dir, "tmp", name)
+	if err := os.WriteFile(tmp, raw, 0o640); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(f.Dir, "new", name)); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
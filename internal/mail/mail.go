@@ -0,0 +1,108 @@
+// Package mail sends transactional email (verification, password reset, ...).
+// A Mailer delivers one message; Queue wraps any Mailer with a bounded,
+// retrying background sender so request handlers never wait on SMTP.
+package mail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"os"
+	"strings"
+)
+
+// Message is one email. HTML is optional; Text is always sent.
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer delivers a message.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+var ErrNoRecipient = errors.New("mail: message has no valid recipient")
+
+// permanentError marks failures that retrying cannot fix (bad address,
+// rejected by the server, ...).
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so Queue gives up on the message immediately.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err}
+}
+
+// IsPermanent reports whether err (or anything it wraps) came from Permanent.
+func IsPermanent(err error) bool {
+	var p permanentError
+	return errors.As(err, &p)
+}
+
+func (m Message) validate() error {
+	if _, err := mail.ParseAddress(m.To); err != nil {
+		return Permanent(fmt.Errorf("%w: %q", ErrNoRecipient, m.To))
+	}
+	if strings.ContainsAny(m.Subject, "\r\n") {
+		return Permanent(errors.New("mail: subject contains a line break"))
+	}
+	return nil
+}
+
+// FromEnv builds the Mailer selected by MAIL_BACKEND:
+//   - "log" (default): writes messages to the application log; refused when
+//     APP_ENV=production, since reset and verify links are bearer tokens
+//   - "file": drops .eml files into a maildir at MAIL_DIR (default ./data/mail)
+//   - "smtp": SMTP_HOST, SMTP_PORT (587), SMTP_USERNAME, SMTP_PASSWORD
+//
+// MAIL_FROM is the sender address for every backend.
+func FromEnv() (Mailer, error) {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "Books <no-reply@localhost>"
+	}
+	if _, err := mail.ParseAddress(from); err != nil {
+		return nil, fmt.Errorf("MAIL_FROM: %w", err)
+	}
+
+	switch backend := strings.ToLower(os.Getenv("MAIL_BACKEND")); backend {
+	case "", "log":
+		if strings.EqualFold(os.Getenv("APP_ENV"), "production") {
+			return nil, errors.New("MAIL_BACKEND=log is not allowed in production; set MAIL_BACKEND=smtp")
+		}
+		return NewLog(from), nil
+	case "file":
+		dir := os.Getenv("MAIL_DIR")
+		if dir == "" {
+			dir = "./data/mail"
+		}
+		return NewFileDrop(dir, from)
+	case "smtp":
+		host := os.Getenv("SMTP_HOST")
+		if host == "" {
+			return nil, errors.New("MAIL_BACKEND=smtp needs SMTP_HOST")
+		}
+		port := os.Getenv("SMTP_PORT")
+		if port == "" {
+			port = "587"
+		}
+		return &SMTP{
+			Host:     host,
+			Port:     port,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     from,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown MAIL_BACKEND %q (want log, file or smtp)", backend)
+	}
+}
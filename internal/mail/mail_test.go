@@ -0,0 +1,211 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEncodeMultipart(t *testing.T) {
+	raw, err := Encode("Books <no-reply@example.com>", Message{
+		To:      "reader@example.com",
+		Subject: "Გამარჯობა",
+		Text:    "line one\nline two",
+		HTML:    "<p>hi</p>",
+	}, time.Unix(1700000000, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := mail.ReadMessage(strings.NewReader(string(raw)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subj, _ := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject")); subj != "Გამარჯობა" {
+		t.Errorf("subject = %q", subj)
+	}
+	if !strings.HasSuffix(m.Header.Get("Message-ID"), "@example.com>") {
+		t.Errorf("message-id = %q", m.Header.Get("Message-ID"))
+	}
+	_, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mr := multipart.NewReader(m.Body, params["boundary"])
+	var parts []string
+	for {
+		p, err := mr.NextPart() // decodes quoted-printable
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := io.ReadAll(p)
+		parts = append(parts, p.Header.Get("Content-Type")+"|"+string(b))
+	}
+	want := []string{"text/plain; charset=utf-8|line one\r\nline two", "text/html; charset=utf-8|<p>hi</p>"}
+	if strings.Join(parts, "\n") != strings.Join(want, "\n") {
+		t.Errorf("parts = %q", parts)
+	}
+}
+
+func TestEncodeRejectsBadHeaders(t *testing.T) {
+	for _, msg := range []Message{
+		{To: "not an address", Subject: "x"},
+		{To: "a@example.com", Subject: "x\r\nBcc: evil@example.com"},
+	} {
+		if _, err := Encode("a@example.com", msg, time.Now()); !IsPermanent(err) {
+			t.Errorf("%+v: err = %v", msg, err)
+		}
+	}
+}
+
+func TestRenderLocales(t *testing.T) {
+	en, err := Render(TemplateResetPassword, "en", "a@example.com", Data{"Link": "https://x.test/r?token=a&b", "Hours": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if en.Subject != "Reset your password" || !strings.Contains(en.Text, "https://x.test/r?token=a&b") {
+		t.Errorf("en = %+v", en)
+	}
+	if !strings.Contains(en.HTML, `href="https://x.test/r?token=a&amp;b"`) || !strings.Contains(en.HTML, `lang="en"`) {
+		t.Errorf("en html = %s", en.HTML)
+	}
+
+	ka, err := Render(TemplateVerifyEmail, Locale("ka-GE,ka;q=0.9,en;q=0.5"), "a@example.com", Data{"Link": "l", "Hours": 24})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ka.Subject != "დაადასტურეთ თქვენი ელფოსტა" || !strings.Contains(ka.HTML, `lang="ka"`) {
+		t.Errorf("ka = %+v", ka)
+	}
+
+	locked, err := Render(TemplateAccountLocked, "ka", "a@example.com", Data{"Attempts": 10, "IP": "203.0.113.9", "Minutes": 15})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if locked.Subject != "თქვენი ანგარიში დროებით დაიბლოკა" || !strings.Contains(locked.HTML, "203.0.113.9") {
+		t.Errorf("account_locked = %+v", locked)
+	}
+
+	mfa, err := Render(TemplateMFAEnabled, "en", "a@example.com", Data{"Device": "Firefox on Linux", "IP": "203.0.113.9"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mfa.Subject != "Two-factor authentication was turned on" || !strings.Contains(mfa.Text, "Firefox on Linux (203.0.113.9)") {
+		t.Errorf("mfa_enabled = %+v", mfa)
+	}
+
+	pk, err := Render(TemplatePasskeyAdded, "ka", "a@example.com", Data{"Name": "YubiKey", "Device": "Safari on macOS", "IP": "203.0.113.9"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(pk.Text, "YubiKey") || !strings.Contains(pk.HTML, "203.0.113.9") {
+		t.Errorf("passkey_added = %+v", pk)
+	}
+
+	if got := Locale("fr-FR"); got != DefaultLocale {
+		t.Errorf("Locale(fr) = %s", got)
+	}
+	if _, err := Render("nope", "en", "a@example.com", nil); !errors.Is(err, ErrUnknownTemplate) {
+		t.Errorf("unknown template: %v", err)
+	}
+	if _, err := Render(TemplateVerifyEmail, "en", "a@example.com", Data{"Link": "l"}); err == nil {
+		t.Error("missing data should fail")
+	}
+}
+
+func TestFileDrop(t *testing.T) {
+	dir := t.TempDir()
+	fd, err := NewFileDrop(dir, "no-reply@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fd.Send(t.Context(), Message{To: "a@example.com", Subject: "hi", Text: "body"}); err != nil {
+		t.Fatal(err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "new", "*.eml"))
+	tmp, _ := os.ReadDir(filepath.Join(dir, "tmp"))
+	if len(files) != 1 || len(tmp) != 0 {
+		t.Fatalf("new=%v tmp=%d", files, len(tmp))
+	}
+}
+
+func TestFromEnvRefusesLogInProduction(t *testing.T) {
+	t.Setenv("APP_ENV", "production")
+	for _, backend := range []string{"", "log", "LOG"} {
+		t.Setenv("MAIL_BACKEND", backend)
+		if _, err := FromEnv(); err == nil {
+			t.Errorf("MAIL_BACKEND=%q allowed in production", backend)
+		}
+	}
+
+	t.Setenv("MAIL_BACKEND", "smtp")
+	t.Setenv("SMTP_HOST", "smtp.example.com")
+	if _, err := FromEnv(); err != nil {
+		t.Fatalf("smtp in production: %v", err)
+	}
+
+	t.Setenv("APP_ENV", "")
+	t.Setenv("MAIL_BACKEND", "")
+	if m, err := FromEnv(); err != nil {
+		t.Fatalf("log outside production: %v", err)
+	} else if _, ok := m.(*Log); !ok {
+		t.Fatalf("default backend = %T, want *Log", m)
+	}
+}
+
+type flaky struct {
+	mu    sync.Mutex
+	fails int
+	err   error
+	calls int
+	sent  []Message
+}
+
+func (f *flaky) Send(_ context.Context, msg Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.calls <= f.fails {
+		return f.err
+	}
+	f.sent = append(f.sent, msg)
+	return nil
+}
+
+func TestQueueRetries(t *testing.T) {
+	m := &flaky{fails: 2, err: errors.New("421 try later")}
+	q := NewQueue(m, QueueOptions{Workers: 1, Backoff: time.Millisecond})
+	if err := q.Send(t.Context(), Message{To: "a@example.com", Subject: "s"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Close(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if m.calls != 3 || len(m.sent) != 1 {
+		t.Errorf("calls=%d sent=%d", m.calls, len(m.sent))
+	}
+	if err := q.Send(t.Context(), Message{To: "a@example.com"}); !errors.Is(err, ErrQueueClosed) {
+		t.Errorf("send after close: %v", err)
+	}
+}
+
+func TestQueueGivesUpOnPermanent(t *testing.T) {
+	m := &flaky{fails: 10, err: Permanent(errors.New("550 no such user"))}
+	q := NewQueue(m, QueueOptions{Workers: 1, Backoff: time.Millisecond})
+	_ = q.Send(t.Context(), Message{To: "a@example.com", Subject: "s"})
+	_ = q.Close(t.Context())
+	if m.calls != 1 {
+		t.Errorf("calls = %d", m.calls)
+	}
+}
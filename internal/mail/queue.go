@@ -0,0 +1,148 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+var (
+	ErrQueueFull   = errors.New("mail: send queue is full")
+	ErrQueueClosed = errors.New("mail: send queue is closed")
+)
+
+// Queue sends messages in the background through a Mailer, retrying
+// temporary failures with exponential backoff. It implements Mailer itself,
+// so handlers take a Mailer and don't care whether sends are queued.
+type Queue struct {
+	m        Mailer
+	jobs     chan Message
+	done     chan struct{}
+	stop     sync.Once
+	wg       sync.WaitGroup
+	mu       sync.RWMutex
+	closed   bool
+	attempts int
+	backoff  time.Duration
+	timeout  time.Duration
+}
+
+// QueueOptions tunes NewQueue; zero values pick the defaults.
+type QueueOptions struct {
+	Size     int           // buffered messages (default 1000)
+	Workers  int           // concurrent senders (default 2)
+	Attempts int           // tries per message (default 5)
+	Backoff  time.Duration // first retry delay, doubled each time (default 2s)
+	Timeout  time.Duration // per attempt (default 30s)
+}
+
+// NewQueue starts the workers; call Close on shutdown.
+func NewQueue(m Mailer, opts QueueOptions) *Queue {
+	if opts.Size <= 0 {
+		opts.Size = 1000
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = 2
+	}
+	if opts.Attempts <= 0 {
+		opts.Attempts = 5
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = 2 * time.Second
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = smtpTimeout
+	}
+	q := &Queue{
+		m:        m,
+		jobs:     make(chan Message, opts.Size),
+		done:     make(chan struct{}),
+		attempts: opts.Attempts,
+		backoff:  opts.Backoff,
+		timeout:  opts.Timeout,
+	}
+	for i := 0; i < opts.Workers; i++ {
+		q.wg.Add(1)
+		go q.worker()
+	}
+	return q
+}
+
+// Send validates and enqueues msg without blocking.
+func (q *Queue) Send(_ context.Context, msg Message) error {
+	if err := msg.validate(); err != nil {
+		return err
+	}
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		return ErrQueueClosed
+	}
+	select {
+	case q.jobs <- msg:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Close stops accepting messages and waits for queued ones (including their
+// retries) until ctx is done; whatever is left is logged and dropped.
+func (q *Queue) Close(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.jobs)
+	}
+	q.mu.Unlock()
+
+	finished := make(chan struct{})
+	go func() { q.wg.Wait(); close(finished) }()
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		q.stop.Do(func() { close(q.done) }) // cut backoff sleeps short
+		<-finished
+		return ctx.Err()
+	}
+}
+
+func (q *Queue) worker() {
+	defer q.wg.Done()
+	for msg := range q.jobs {
+		select {
+		case <-q.done:
+			log.Printf("[mail] shutting down, dropped %q", msg.Subject)
+			continue
+		default:
+		}
+		q.deliver(msg)
+	}
+}
+
+func (q *Queue) deliver(msg Message) {
+	delay := q.backoff
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), q.timeout)
+		err := q.m.Send(ctx, msg)
+		cancel()
+		if err == nil {
+			return
+		}
+		if IsPermanent(err) || attempt >= q.attempts {
+			log.Printf("[mail] giving up on %q after %d attempt(s): %v", msg.Subject, attempt, err)
+			return
+		}
+		log.Printf("[mail] sending %q failed (attempt %d/%d), retrying in %s: %v", msg.Subject, attempt, q.attempts, delay, err)
+		select {
+		case <-time.After(delay):
+		case <-q.done:
+			log.Printf("[mail] shutting down, dropped %q", msg.Subject)
+			return
+		}
+		delay *= 2
+	}
+}
@@ -0,0 +1,104 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"time"
+)
+
+const smtpTimeout = 30 * time.Second
+
+// SMTP delivers through a submission server. Port 465 uses implicit TLS;
+// any other port upgrades with STARTTLS when the server offers it (which
+// PlainAuth requires for anything but localhost).
+type SMTP struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func (s *SMTP) Send(ctx context.Context, msg Message) error {
+	raw, err := Encode(s.From, msg, time.Now())
+	if err != nil {
+		return err
+	}
+	sender, _ := mail.ParseAddress(s.From)
+	rcpt, _ := mail.ParseAddress(msg.To)
+
+	conn, err := s.dial(ctx)
+	if err != nil {
+		return err
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(smtpTimeout)
+	}
+	_ = conn.SetDeadline(deadline)
+
+	c, err := smtp.NewClient(conn, s.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if s.Port != "465" {
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			if err := c.StartTLS(s.tlsConfig()); err != nil {
+				return err
+			}
+		}
+	}
+	if s.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", s.Username, s.Password, s.Host)); err != nil {
+			return classify(err)
+		}
+	}
+	if err := c.Mail(sender.Address); err != nil {
+		return classify(err)
+	}
+	if err := c.Rcpt(rcpt.Address); err != nil {
+		return classify(err)
+	}
+	w, err := c.Data()
+	if err != nil {
+		return classify(err)
+	}
+	if _, err := w.Write(raw); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return classify(err)
+	}
+	return c.Quit()
+}
+
+func (s *SMTP) dial(ctx context.Context) (net.Conn, error) {
+	addr := net.JoinHostPort(s.Host, s.Port)
+	d := &net.Dialer{Timeout: 10 * time.Second}
+	if s.Port == "465" {
+		td := &tls.Dialer{NetDialer: d, Config: s.tlsConfig()}
+		return td.DialContext(ctx, "tcp", addr)
+	}
+	return d.DialContext(ctx, "tcp", addr)
+}
+
+func (s *SMTP) tlsConfig() *tls.Config {
+	return &tls.Config{ServerName: s.Host, MinVersion: tls.VersionTLS12}
+}
+
+// classify marks 5xx replies as permanent; 4xx and network errors are retried.
+func classify(err error) error {
+	var te *textproto.Error
+	if errors.As(err, &te) && te.Code >= 500 {
+		return Permanent(err)
+	}
+	return err
+}
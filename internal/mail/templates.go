@@ -0,0 +1,138 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"strings"
+	texttemplate "text/template"
+
+	"golang.org/x/text/language"
+)
+
+// Template names. Each exists as templates/<locale>/<name>.txt (first line is
+// the subject, then a blank line, then the text body) and <name>.html (a
+// "content" block rendered inside layout.html).
+const (
+	TemplateVerifyEmail   = "verify_email"
+	TemplateResetPassword = "reset_password"
+	TemplateAccountLocked = "account_locked"
+	TemplateMFAEnabled    = "mfa_enabled"
+	TemplatePasskeyAdded  = "passkey_added"
+)
+
+// DefaultLocale is used when nothing better matches.
+const DefaultLocale = "en"
+
+//go:embed templates
+var templateFS embed.FS
+
+var ErrUnknownTemplate = errors.New("mail: unknown template")
+
+// Data is what templates see; Render adds "Locale".
+type Data map[string]any
+
+var (
+	locales = supportedLocales()
+	matcher = newMatcher(locales)
+)
+
+// Locales lists the locales templates exist for, DefaultLocale first.
+func Locales() []string { return append([]string(nil), locales...) }
+
+// Locale picks the best supported locale for an Accept-Language header
+// (or a bare tag like "ka").
+func Locale(acceptLanguage string) string {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return DefaultLocale
+	}
+	_, i, conf := matcher.Match(tags...)
+	if conf == language.No {
+		return DefaultLocale
+	}
+	return locales[i]
+}
+
+// Render fills a template for to in the given locale (see Locale), falling
+// back to DefaultLocale when the template hasn't been translated.
+func Render(name, locale, to string, data Data) (Message, error) {
+	if !isLocale(locale) {
+		locale = Locale(locale)
+	}
+	txt, err := fs.ReadFile(templateFS, "templates/"+locale+"/"+name+".txt")
+	if errors.Is(err, fs.ErrNotExist) && locale != DefaultLocale {
+		locale = DefaultLocale
+		txt, err = fs.ReadFile(templateFS, "templates/"+locale+"/"+name+".txt")
+	}
+	if err != nil {
+		return Message{}, fmt.Errorf("%w: %s", ErrUnknownTemplate, name)
+	}
+
+	vars := Data{"Locale": locale}
+	for k, v := range data {
+		vars[k] = v
+	}
+
+	tt, err := texttemplate.New(name).Option("missingkey=error").Parse(string(txt))
+	if err != nil {
+		return Message{}, err
+	}
+	var text bytes.Buffer
+	if err := tt.Execute(&text, vars); err != nil {
+		return Message{}, err
+	}
+	subject, body, _ := strings.Cut(text.String(), "\n")
+	msg := Message{
+		To:      to,
+		Subject: strings.TrimSpace(subject),
+		Text:    strings.TrimLeft(body, "\n"),
+	}
+
+	htmlPath := "templates/" + locale + "/" + name + ".html"
+	if _, err := fs.Stat(templateFS, htmlPath); err != nil {
+		return msg, nil // text-only template
+	}
+	ht, err := htmltemplate.New(name).Option("missingkey=error").
+		ParseFS(templateFS, "templates/layout.html", htmlPath)
+	if err != nil {
+		return Message{}, err
+	}
+	var html bytes.Buffer
+	if err := ht.ExecuteTemplate(&html, "layout", vars); err != nil {
+		return Message{}, err
+	}
+	msg.HTML = html.String()
+	return msg, nil
+}
+
+func isLocale(s string) bool {
+	for _, l := range locales {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+func supportedLocales() []string {
+	out := []string{DefaultLocale}
+	entries, _ := templateFS.ReadDir("templates")
+	for _, e := range entries {
+		if e.IsDir() && e.Name() != DefaultLocale {
+			out = append(out, e.Name())
+		}
+	}
+	return out
+}
+
+func newMatcher(locales []string) language.Matcher {
+	tags := make([]language.Tag, len(locales))
+	for i, l := range locales {
+		tags[i] = language.Make(l)
+	}
+	return language.NewMatcher(tags)
+}
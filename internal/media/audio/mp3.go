@@ -0,0 +1,173 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// MPEG audio frame header tables, indexed [version][layer][index].
+// version: 0 = MPEG-1, 1 = MPEG-2/2.5. layer: 0 = I, 1 = II, 2 = III.
+var mp3Bitrates = [2][3][16]int{
+	{
+		{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448, 0},
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384, 0},
+		{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0},
+	},
+	{
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256, 0},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},
+	},
+}
+
+// sample rates by version bits (00 = 2.5, 10 = 2, 11 = 1)
+var mp3SampleRates = map[byte][3]int{
+	0: {11025, 12000, 8000},
+	2: {22050, 24000, 16000},
+	3: {44100, 48000, 32000},
+}
+
+type mp3Frame struct {
+	versionBits byte
+	layer       int // 1..3
+	bitrate     int // bps
+	sampleRate  int
+	channels    int
+	size        int // bytes incl. header
+	samples     int // per frame
+}
+
+func parseMP3Frame(h []byte) (mp3Frame, bool) {
+	if len(h) < 4 || h[0] != 0xFF || h[1]&0xE0 != 0xE0 {
+		return mp3Frame{}, false
+	}
+	vb := (h[1] >> 3) & 0x03
+	lb := (h[1] >> 1) & 0x03
+	bi := h[2] >> 4
+	si := (h[2] >> 2) & 0x03
+	if vb == 1 || lb == 0 || bi == 0 || bi == 15 || si == 3 {
+		return mp3Frame{}, false
+	}
+	f := mp3Frame{versionBits: vb, layer: int(4 - lb)}
+	ver := 1
+	if vb == 3 {
+		ver = 0
+	}
+	f.bitrate = mp3Bitrates[ver][f.layer-1][bi] * 1000
+	f.sampleRate = mp3SampleRates[vb][si]
+	padding := int((h[2] >> 1) & 0x01)
+	f.channels = 2
+	if h[3]>>6 == 3 {
+		f.channels = 1
+	}
+
+	switch {
+	case f.layer == 1:
+		f.samples = 384
+		f.size = (12*f.bitrate/f.sampleRate + padding) * 4
+	case f.layer == 3 && ver == 1:
+		f.samples = 576
+		f.size = 72*f.bitrate/f.sampleRate + padding
+	default:
+		f.samples = 1152
+		f.size = 144*f.bitrate/f.sampleRate + padding
+	}
+	return f, f.size > 4
+}
+
+// probeMP3 skips ID3v2, syncs on two consecutive frames and reads the
+// Xing/Info or VBRI header when present; otherwise assumes CBR.
+func probeMP3(r io.ReaderAt, size int64) (Info, error) {
+	start := int64(0)
+	id3 := make([]byte, 10)
+	if _, err := r.ReadAt(id3, 0); err == nil && string(id3[0:3]) == "ID3" {
+		tag := int64(id3[6]&0x7F)<<21 | int64(id3[7]&0x7F)<<14 | int64(id3[8]&0x7F)<<7 | int64(id3[9]&0x7F)
+		start = 10 + tag
+		if id3[5]&0x10 != 0 { // footer present
+			start += 10
+		}
+	}
+	end := size
+	tail := make([]byte, 3)
+	if size > 128 {
+		if _, err := r.ReadAt(tail, size-128); err == nil && string(tail) == "TAG" {
+			end -= 128
+		}
+	}
+
+	// Scan up to 64 KiB past the tag for a frame followed by another frame.
+	buf := make([]byte, 64<<10)
+	n, _ := r.ReadAt(buf, start)
+	buf = buf[:n]
+	var (
+		f   mp3Frame
+		pos = -1
+	)
+	for i := 0; i+4 <= len(buf); i++ {
+		cand, ok := parseMP3Frame(buf[i:])
+		if !ok {
+			continue
+		}
+		next := i + cand.size
+		if next+4 <= len(buf) {
+			nf, ok := parseMP3Frame(buf[next:])
+			if !ok || nf.sampleRate != cand.sampleRate || nf.layer != cand.layer {
+				continue
+			}
+		} else if int64(next) < end-start {
+			continue
+		}
+		f, pos = cand, i
+		break
+	}
+	if pos < 0 {
+		return Info{}, fmt.Errorf("%w: no mpeg audio frames", ErrCorrupt)
+	}
+
+	info := Info{
+		Format:     FormatMP3,
+		Codec:      fmt.Sprintf("mpeg-layer%d", f.layer),
+		SampleRate: f.sampleRate,
+		Channels:   f.channels,
+	}
+	audioBytes := end - start - int64(pos)
+
+	frame := buf[pos:min(len(buf), pos+f.size)]
+	if frames := vbrFrameCount(frame, f); frames > 0 {
+		info.Duration = seconds(int64(frames)*int64(f.samples), int64(f.sampleRate))
+		info.Bitrate = int(float64(audioBytes*8) / info.Duration.Seconds())
+		return info, nil
+	}
+
+	info.Bitrate = f.bitrate
+	info.Duration = seconds(audioBytes*8, int64(f.bitrate))
+	return info, nil
+}
+
+// vbrFrameCount reads the frame count from a Xing/Info or VBRI header, or 0.
+func vbrFrameCount(frame []byte, f mp3Frame) int {
+	// Xing offset = 4-byte header + side info (depends on version and channels)
+	side := 32
+	switch {
+	case f.versionBits == 3 && f.channels == 1:
+		side = 17
+	case f.versionBits != 3 && f.channels == 2:
+		side = 17
+	case f.versionBits != 3:
+		side = 9
+	}
+	if x := 4 + side; len(frame) >= x+12 {
+		tag := string(frame[x : x+4])
+		if tag == "Xing" || tag == "Info" {
+			if binary.BigEndian.Uint32(frame[x+4:x+8])&0x1 != 0 {
+				return int(binary.BigEndian.Uint32(frame[x+8 : x+12]))
+			}
+		}
+	}
+	if i := bytes.Index(frame, []byte("VBRI")); i == 36 && len(frame) >= i+18 {
+		return int(binary.BigEndian.Uint32(frame[i+14 : i+18]))
+	}
+	return 0
+}
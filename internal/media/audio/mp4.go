@@ -0,0 +1,107 @@
+package audio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// probeMP4 reads moov/mvhd for duration and the first audio sample entry
+// (moov/trak/mdia/minf/stbl/stsd) for channels and sample rate.
+func probeMP4(r io.ReaderAt, size int64) (Info, error) {
+	moovOff, moovLen, ok := findBox(r, 0, size, "moov")
+	if !ok {
+		return Info{}, fmt.Errorf("%w: no moov box", ErrCorrupt)
+	}
+	info := Info{Format: FormatMP4}
+
+	if off, n, ok := findBox(r, moovOff, moovLen, "mvhd"); ok && n >= 32 {
+		b := make([]byte, 32)
+		if _, err := r.ReadAt(b, off); err != nil {
+			return Info{}, ErrCorrupt
+		}
+		var scale, dur int64
+		if b[0] == 1 { // version 1: 64-bit times
+			scale = int64(binary.BigEndian.Uint32(b[20:24]))
+			dur = int64(binary.BigEndian.Uint64(b[24:32]))
+		} else {
+			scale = int64(binary.BigEndian.Uint32(b[12:16]))
+			dur = int64(binary.BigEndian.Uint32(b[16:20]))
+		}
+		info.Duration = seconds(dur, scale)
+	}
+
+	// Walk every trak until one carries an audio sample entry.
+	cur, end := moovOff, moovOff+moovLen
+	for cur < end {
+		trakOff, trakLen, ok := findBox(r, cur, end-cur, "trak")
+		if !ok {
+			break
+		}
+		cur = trakOff + trakLen
+		off, n, ok := descend(r, trakOff, trakLen, "mdia", "minf", "stbl", "stsd")
+		if !ok || n < 8+36 {
+			continue
+		}
+		// stsd: fullbox(4) + entry_count(4), then sample entry:
+		// size(4) type(4) reserved(6) dref(2) reserved(8) channels(2) bits(2) pre(2) res(2) rate(4, 16.16)
+		e := make([]byte, 36)
+		if _, err := r.ReadAt(e, off+8); err != nil {
+			return Info{}, ErrCorrupt
+		}
+		switch typ := string(e[4:8]); typ {
+		case "mp4a":
+			info.Codec = "aac"
+		case "alac", "Opus", "fLaC", "ac-3", "ec-3":
+			info.Codec = typ
+		default:
+			continue // video/text track
+		}
+		info.Channels = int(binary.BigEndian.Uint16(e[24:26]))
+		info.SampleRate = int(binary.BigEndian.Uint32(e[32:36]) >> 16)
+		return info, nil
+	}
+	return Info{}, fmt.Errorf("%w: no audio track", ErrCorrupt)
+}
+
+// findBox scans sibling boxes in [off, off+n) and returns the payload range of
+// the first box of type typ.
+func findBox(r io.ReaderAt, off, n int64, typ string) (int64, int64, bool) {
+	end := off + n
+	hdr := make([]byte, 16)
+	for off+8 <= end {
+		if _, err := r.ReadAt(hdr[:8], off); err != nil {
+			return 0, 0, false
+		}
+		size := int64(binary.BigEndian.Uint32(hdr[0:4]))
+		head := int64(8)
+		switch size {
+		case 0: // extends to end of parent
+			size = end - off
+		case 1: // 64-bit largesize
+			if _, err := r.ReadAt(hdr[8:16], off+8); err != nil {
+				return 0, 0, false
+			}
+			size = int64(binary.BigEndian.Uint64(hdr[8:16]))
+			head = 16
+		}
+		if size < head || off+size > end {
+			return 0, 0, false
+		}
+		if string(hdr[4:8]) == typ {
+			return off + head, size - head, true
+		}
+		off += size
+	}
+	return 0, 0, false
+}
+
+func descend(r io.ReaderAt, off, n int64, path ...string) (int64, int64, bool) {
+	for _, typ := range path {
+		var ok bool
+		if off, n, ok = findBox(r, off, n, typ); !ok {
+			return 0, 0, false
+		}
+	}
+	return off, n, true
+}
@@ -0,0 +1,69 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// probeOgg reads the identification header from the first page (Vorbis or Opus)
+// and the granule position of the last page for duration.
+func probeOgg(r io.ReaderAt, size int64) (Info, error) {
+	first := make([]byte, 27+255+64)
+	n, _ := r.ReadAt(first, 0)
+	first = first[:n]
+	if n < 28 {
+		return Info{}, ErrCorrupt
+	}
+	segs := int(first[26])
+	if 27+segs >= n {
+		return Info{}, ErrCorrupt
+	}
+	pkt := first[27+segs:]
+
+	info := Info{Format: FormatOgg}
+	var rate, preSkip int64
+	switch {
+	case len(pkt) >= 30 && pkt[0] == 0x01 && string(pkt[1:7]) == "vorbis":
+		info.Codec = "vorbis"
+		info.Channels = int(pkt[11])
+		info.SampleRate = int(binary.LittleEndian.Uint32(pkt[12:16]))
+		info.Bitrate = int(int32(binary.LittleEndian.Uint32(pkt[20:24]))) // nominal
+		if info.Bitrate < 0 {
+			info.Bitrate = 0
+		}
+		rate = int64(info.SampleRate)
+	case len(pkt) >= 19 && string(pkt[0:8]) == "OpusHead":
+		info.Codec = "opus"
+		info.Channels = int(pkt[9])
+		preSkip = int64(binary.LittleEndian.Uint16(pkt[10:12]))
+		info.SampleRate = int(binary.LittleEndian.Uint32(pkt[12:16])) // original input rate
+		if info.SampleRate == 0 {
+			info.SampleRate = 48000
+		}
+		rate = 48000 // Opus granules always count 48 kHz samples
+	default:
+		return Info{}, fmt.Errorf("%w: unsupported ogg codec", ErrCorrupt)
+	}
+
+	// Last page: search the final 64 KiB backwards for a capture pattern.
+	tailLen := int64(64 << 10)
+	if tailLen > size {
+		tailLen = size
+	}
+	tail := make([]byte, tailLen)
+	if _, err := r.ReadAt(tail, size-tailLen); err != nil && err != io.EOF {
+		return Info{}, ErrCorrupt
+	}
+	i := bytes.LastIndex(tail, []byte("OggS"))
+	if i < 0 || i+14 > len(tail) {
+		return Info{}, fmt.Errorf("%w: no final ogg page", ErrCorrupt)
+	}
+	granule := int64(binary.LittleEndian.Uint64(tail[i+6 : i+14]))
+	if granule <= preSkip {
+		return Info{}, fmt.Errorf("%w: empty ogg stream", ErrCorrupt)
+	}
+	info.Duration = seconds(granule-preSkip, rate)
+	return info, nil
+}
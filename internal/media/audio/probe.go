@@ -0,0 +1,122 @@
+// Package audio inspects uploaded audio files: it identifies the real container
+// from its header and extracts duration, bitrate, sample rate and channel count.
+package audio
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+var (
+	ErrUnknownFormat = errors.New("audio: unrecognised format")
+	ErrCorrupt       = errors.New("audio: corrupt or truncated file")
+)
+
+// Format names; they double as the stored audio_format value.
+const (
+	FormatMP3 = "mp3"
+	FormatMP4 = "mp4"
+	FormatOgg = "ogg"
+	FormatWAV = "wav"
+)
+
+// Info describes a probed file. Bitrate is bits per second (average for VBR).
+type Info struct {
+	Format     string
+	Codec      string // e.g. "mpeg1-layer3", "aac", "vorbis", "opus", "pcm"
+	Duration   time.Duration
+	Bitrate    int
+	SampleRate int
+	Channels   int
+}
+
+// ContentType is the canonical MIME type for the detected container.
+func (i Info) ContentType() string {
+	switch i.Format {
+	case FormatMP4:
+		return "audio/mp4"
+	case FormatOgg:
+		return "audio/ogg"
+	case FormatWAV:
+		return "audio/wav"
+	default:
+		return "audio/mpeg"
+	}
+}
+
+// Ext is the object-key extension for the detected container.
+func (i Info) Ext() string {
+	switch i.Format {
+	case FormatMP4:
+		return ".m4a"
+	case FormatOgg:
+		return ".ogg"
+	case FormatWAV:
+		return ".wav"
+	default:
+		return ".mp3"
+	}
+}
+
+// Probe reads the header (and, for some formats, the tail) of r to identify
+// and measure it. size is the full length of the file.
+func Probe(r io.ReaderAt, size int64) (Info, error) {
+	head := make([]byte, 12)
+	if n, _ := r.ReadAt(head, 0); n < len(head) {
+		return Info{}, ErrCorrupt
+	}
+
+	var (
+		info Info
+		err  error
+	)
+	switch {
+	case string(head[0:4]) == "RIFF" && string(head[8:12]) == "WAVE":
+		info, err = probeWAV(r, size)
+	case string(head[0:4]) == "OggS":
+		info, err = probeOgg(r, size)
+	case string(head[4:8]) == "ftyp":
+		info, err = probeMP4(r, size)
+	case string(head[0:3]) == "ID3" || (head[0] == 0xFF && head[1]&0xE0 == 0xE0):
+		info, err = probeMP3(r, size)
+	default:
+		return Info{}, ErrUnknownFormat
+	}
+	if err != nil {
+		return Info{}, err
+	}
+	if info.Duration <= 0 || info.SampleRate <= 0 || info.Channels <= 0 {
+		return Info{}, fmt.Errorf("%w: missing stream parameters", ErrCorrupt)
+	}
+	if info.Bitrate == 0 {
+		info.Bitrate = int(float64(size*8) / info.Duration.Seconds())
+	}
+	return info, nil
+}
+
+// Matches reports whether a client-declared Content-Type agrees with the
+// detected container. An empty declaration always matches.
+func Matches(contentType string, info Info) bool {
+	switch contentType {
+	case "":
+		return true
+	case "audio/mpeg", "audio/mp3":
+		return info.Format == FormatMP3
+	case "audio/mp4", "audio/x-m4a", "audio/m4a", "audio/aac":
+		return info.Format == FormatMP4
+	case "audio/ogg", "audio/opus", "audio/vorbis":
+		return info.Format == FormatOgg
+	case "audio/wav", "audio/x-wav", "audio/wave":
+		return info.Format == FormatWAV
+	}
+	return false
+}
+
+func seconds(samples, rate int64) time.Duration {
+	if rate <= 0 {
+		return 0
+	}
+	return time.Duration(float64(samples) / float64(rate) * float64(time.Second))
+}
@@ -0,0 +1,149 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+)
+
+func probeBytes(t *testing.T, b []byte) (Info, error) {
+	t.Helper()
+	return Probe(bytes.NewReader(b), int64(len(b)))
+}
+
+func TestProbeWAV(t *testing.T) {
+	const rate, ch, secs = 8000, 1, 2
+	data := make([]byte, rate*ch*2*secs)
+	var b bytes.Buffer
+	b.WriteString("RIFF")
+	binary.Write(&b, binary.LittleEndian, uint32(36+len(data)))
+	b.WriteString("WAVEfmt ")
+	binary.Write(&b, binary.LittleEndian, uint32(16))
+	binary.Write(&b, binary.LittleEndian, uint16(1))
+	binary.Write(&b, binary.LittleEndian, uint16(ch))
+	binary.Write(&b, binary.LittleEndian, uint32(rate))
+	binary.Write(&b, binary.LittleEndian, uint32(rate*ch*2))
+	binary.Write(&b, binary.LittleEndian, uint16(ch*2))
+	binary.Write(&b, binary.LittleEndian, uint16(16))
+	b.WriteString("data")
+	binary.Write(&b, binary.LittleEndian, uint32(len(data)))
+	b.Write(data)
+
+	info, err := probeBytes(t, b.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Format != FormatWAV || info.Channels != 1 || info.SampleRate != rate || info.Duration != secs*time.Second {
+		t.Errorf("unexpected info: %+v", info)
+	}
+	if info.Bitrate != rate*16 {
+		t.Errorf("bitrate = %d", info.Bitrate)
+	}
+}
+
+func TestProbeMP3CBR(t *testing.T) {
+	// MPEG-1 Layer III, 128 kbps, 44.1 kHz, no padding, stereo => 417-byte frames.
+	frame := make([]byte, 417)
+	copy(frame, []byte{0xFF, 0xFB, 0x90, 0x00})
+	var b bytes.Buffer
+	// ID3v2 tag with 20 bytes of payload
+	b.Write([]byte{'I', 'D', '3', 4, 0, 0, 0, 0, 0, 20})
+	b.Write(make([]byte, 20))
+	for i := 0; i < 100; i++ {
+		b.Write(frame)
+	}
+
+	info, err := probeBytes(t, b.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Format != FormatMP3 || info.SampleRate != 44100 || info.Channels != 2 || info.Bitrate != 128000 {
+		t.Errorf("unexpected info: %+v", info)
+	}
+	// 100 * 417 bytes at 128 kbps ~= 2.606 s
+	if d := info.Duration.Seconds(); d < 2.5 || d > 2.7 {
+		t.Errorf("duration = %v", info.Duration)
+	}
+	if !Matches("audio/mpeg", info) || Matches("audio/ogg", info) {
+		t.Error("content type matching is wrong")
+	}
+}
+
+func box(typ string, payload ...[]byte) []byte {
+	body := bytes.Join(payload, nil)
+	out := make([]byte, 8, 8+len(body))
+	binary.BigEndian.PutUint32(out, uint32(8+len(body)))
+	copy(out[4:], typ)
+	return append(out, body...)
+}
+
+func TestProbeMP4(t *testing.T) {
+	mvhd := make([]byte, 100)
+	binary.BigEndian.PutUint32(mvhd[12:], 1000)  // timescale
+	binary.BigEndian.PutUint32(mvhd[16:], 90500) // 90.5 s
+
+	entry := make([]byte, 36)
+	binary.BigEndian.PutUint32(entry[0:], 36)
+	copy(entry[4:], "mp4a")
+	binary.BigEndian.PutUint16(entry[24:], 2)
+	binary.BigEndian.PutUint32(entry[32:], 44100<<16)
+	stsd := append([]byte{0, 0, 0, 0, 0, 0, 0, 1}, entry...)
+
+	trak := box("trak", box("mdia", box("minf", box("stbl", box("stsd", stsd)))))
+	file := bytes.Join([][]byte{
+		box("ftyp", []byte("M4A \x00\x00\x00\x00")),
+		box("moov", box("mvhd", mvhd), trak),
+		box("mdat", make([]byte, 1024)),
+	}, nil)
+
+	info, err := probeBytes(t, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Format != FormatMP4 || info.Codec != "aac" || info.Channels != 2 || info.SampleRate != 44100 {
+		t.Errorf("unexpected info: %+v", info)
+	}
+	if info.Duration != 90500*time.Millisecond {
+		t.Errorf("duration = %v", info.Duration)
+	}
+}
+
+func oggPage(granule uint64, packet []byte) []byte {
+	p := make([]byte, 27)
+	copy(p, "OggS")
+	binary.LittleEndian.PutUint64(p[6:], granule)
+	p[26] = 1
+	p = append(p, byte(len(packet)))
+	return append(p, packet...)
+}
+
+func TestProbeOggOpus(t *testing.T) {
+	head := make([]byte, 19)
+	copy(head, "OpusHead")
+	head[8] = 1
+	head[9] = 2
+	binary.LittleEndian.PutUint16(head[10:], 312)
+	binary.LittleEndian.PutUint32(head[12:], 48000)
+
+	file := append(oggPage(0, head), oggPage(48000*3+312, []byte("audio"))...)
+	info, err := probeBytes(t, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Codec != "opus" || info.Channels != 2 || info.Duration != 3*time.Second {
+		t.Errorf("unexpected info: %+v", info)
+	}
+}
+
+func TestProbeRejectsGarbage(t *testing.T) {
+	if _, err := probeBytes(t, []byte("<html><body>not audio</body></html>")); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("expected ErrUnknownFormat, got %v", err)
+	}
+	// Looks like MP3 (ID3) but has no frames.
+	fake := append([]byte{'I', 'D', '3', 4, 0, 0, 0, 0, 0, 4, 0, 0, 0, 0}, make([]byte, 64)...)
+	if _, err := probeBytes(t, fake); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("expected ErrCorrupt, got %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+package audio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// probeWAV walks RIFF chunks for "fmt " and "data".
+func probeWAV(r io.ReaderAt, size int64) (Info, error) {
+	info := Info{Format: FormatWAV}
+	var byteRate, dataSize int64
+	var haveFmt bool
+
+	off := int64(12)
+	hdr := make([]byte, 8)
+	for off+8 <= size {
+		if _, err := r.ReadAt(hdr, off); err != nil {
+			return Info{}, ErrCorrupt
+		}
+		id := string(hdr[0:4])
+		n := int64(binary.LittleEndian.Uint32(hdr[4:8]))
+		body := off + 8
+
+		switch id {
+		case "fmt ":
+			if n < 16 {
+				return Info{}, fmt.Errorf("%w: short fmt chunk", ErrCorrupt)
+			}
+			f := make([]byte, 16)
+			if _, err := r.ReadAt(f, body); err != nil {
+				return Info{}, ErrCorrupt
+			}
+			switch binary.LittleEndian.Uint16(f[0:2]) {
+			case 1, 0xFFFE:
+				info.Codec = "pcm"
+			case 3:
+				info.Codec = "pcm-float"
+			default:
+				info.Codec = "wav-compressed"
+			}
+			info.Channels = int(binary.LittleEndian.Uint16(f[2:4]))
+			info.SampleRate = int(binary.LittleEndian.Uint32(f[4:8]))
+			byteRate = int64(binary.LittleEndian.Uint32(f[8:12]))
+			haveFmt = true
+		case "data":
+			dataSize = n
+			if body+n > size {
+				dataSize = size - body // tolerate streaming writers that leave 0/-1
+			}
+		}
+		if haveFmt && dataSize > 0 {
+			break
+		}
+		off = body + n + n%2 // chunks are word aligned
+	}
+
+	if !haveFmt || byteRate == 0 || dataSize <= 0 {
+		return Info{}, fmt.Errorf("%w: missing fmt or data chunk", ErrCorrupt)
+	}
+	info.Bitrate = int(byteRate * 8)
+	info.Duration = seconds(dataSize, byteRate)
+	return info, nil
+}
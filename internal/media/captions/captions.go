@@ -0,0 +1,250 @@
+// Package captions parses, validates and writes WebVTT transcripts. SRT input
+// is accepted and converted so storage only ever holds VTT.
+package captions
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var ErrInvalid = errors.New("captions: invalid transcript")
+
+const maxCues = 20000
+
+// Key is where the transcript of an audio object is stored, next to it:
+// books/foo-audio-123.mp3 -> books/foo-audio-123.vtt. Replacing the audio
+// leaves the old transcript behind unreferenced; storage GC collects it.
+func Key(audioKey string) string {
+	return strings.TrimSuffix(audioKey, path.Ext(audioKey)) + ".vtt"
+}
+
+// Cue is one timed block of transcript text.
+type Cue struct {
+	ID       string
+	Start    time.Duration
+	End      time.Duration
+	Settings string // VTT cue settings, e.g. "align:start"
+	Text     string
+}
+
+// Parse detects WebVTT (by its header) or SRT and returns validated cues.
+// duration bounds cue end times when > 0.
+func Parse(data []byte, duration time.Duration) ([]Cue, error) {
+	if !utf8.Valid(data) {
+		return nil, fmt.Errorf("%w: not UTF-8", ErrInvalid)
+	}
+	s := strings.TrimPrefix(string(data), "\uFEFF")
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\r", "\n")
+
+	var (
+		cues []Cue
+		err  error
+	)
+	if strings.HasPrefix(s, "WEBVTT") {
+		cues, err = parseVTT(s)
+	} else {
+		cues, err = parseSRT(s)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := validate(cues, duration); err != nil {
+		return nil, err
+	}
+	return cues, nil
+}
+
+func parseVTT(s string) ([]Cue, error) {
+	blocks := strings.Split(s, "\n\n")
+	header := strings.SplitN(blocks[0], "\n", 2)[0]
+	if header != "WEBVTT" && !strings.HasPrefix(header, "WEBVTT ") && !strings.HasPrefix(header, "WEBVTT\t") {
+		return nil, fmt.Errorf("%w: bad WEBVTT header", ErrInvalid)
+	}
+
+	var cues []Cue
+	for n, block := range blocks[1:] {
+		block = strings.Trim(block, "\n")
+		if block == "" {
+			continue
+		}
+		lines := strings.Split(block, "\n")
+		first := lines[0]
+		if strings.HasPrefix(first, "NOTE") || first == "STYLE" || first == "REGION" {
+			continue
+		}
+
+		var c Cue
+		if !strings.Contains(first, "-->") {
+			c.ID = first
+			lines = lines[1:]
+			if len(lines) == 0 {
+				return nil, fmt.Errorf("%w: block %d has no timing line", ErrInvalid, n+1)
+			}
+		}
+		if err := parseTiming(lines[0], '.', &c); err != nil {
+			return nil, fmt.Errorf("%w: block %d: %v", ErrInvalid, n+1, err)
+		}
+		c.Text = strings.Join(lines[1:], "\n")
+		cues = append(cues, c)
+	}
+	return cues, nil
+}
+
+func parseSRT(s string) ([]Cue, error) {
+	var cues []Cue
+	for n, block := range strings.Split(s, "\n\n") {
+		block = strings.Trim(block, "\n")
+		if block == "" {
+			continue
+		}
+		lines := strings.Split(block, "\n")
+		if len(lines) < 2 {
+			return nil, fmt.Errorf("%w: srt block %d is incomplete", ErrInvalid, n+1)
+		}
+		if _, err := strconv.Atoi(strings.TrimSpace(lines[0])); err != nil {
+			return nil, fmt.Errorf("%w: srt block %d has no index", ErrInvalid, n+1)
+		}
+		c := Cue{ID: strings.TrimSpace(lines[0])}
+		if err := parseTiming(lines[1], ',', &c); err != nil {
+			return nil, fmt.Errorf("%w: srt block %d: %v", ErrInvalid, n+1, err)
+		}
+		c.Settings = "" // SRT coordinates (X1:..) have no VTT equivalent
+		c.Text = strings.Join(lines[2:], "\n")
+		cues = append(cues, c)
+	}
+	return cues, nil
+}
+
+func parseTiming(line string, frac byte, c *Cue) error {
+	left, right, ok := strings.Cut(line, "-->")
+	if !ok {
+		return errors.New("missing -->")
+	}
+	start, err := parseTimestamp(strings.TrimSpace(left), frac)
+	if err != nil {
+		return err
+	}
+	right = strings.TrimSpace(right)
+	endTok, settings, _ := strings.Cut(right, " ")
+	end, err := parseTimestamp(endTok, frac)
+	if err != nil {
+		return err
+	}
+	c.Start, c.End, c.Settings = start, end, strings.TrimSpace(settings)
+	return nil
+}
+
+// parseTimestamp accepts [hh:]mm:ss<frac>ttt.
+func parseTimestamp(s string, frac byte) (time.Duration, error) {
+	i := strings.LastIndexByte(s, frac)
+	if i < 0 || len(s)-i-1 != 3 {
+		return 0, fmt.Errorf("bad timestamp %q", s)
+	}
+	ms, err := strconv.Atoi(s[i+1:])
+	if err != nil {
+		return 0, fmt.Errorf("bad timestamp %q", s)
+	}
+	parts := strings.Split(s[:i], ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("bad timestamp %q", s)
+	}
+	var nums [3]int
+	for k, p := range parts {
+		if p == "" || (len(p) > 2 && k != 0) {
+			return 0, fmt.Errorf("bad timestamp %q", s)
+		}
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("bad timestamp %q", s)
+		}
+		nums[3-len(parts)+k] = n
+	}
+	h, m, sec := nums[0], nums[1], nums[2]
+	if m > 59 || sec > 59 {
+		return 0, fmt.Errorf("bad timestamp %q", s)
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute +
+		time.Duration(sec)*time.Second + time.Duration(ms)*time.Millisecond, nil
+}
+
+func validate(cues []Cue, duration time.Duration) error {
+	if len(cues) == 0 {
+		return fmt.Errorf("%w: no cues", ErrInvalid)
+	}
+	if len(cues) > maxCues {
+		return fmt.Errorf("%w: more than %d cues", ErrInvalid, maxCues)
+	}
+	for i, c := range cues {
+		if c.End <= c.Start {
+			return fmt.Errorf("%w: cue %d ends before it starts", ErrInvalid, i+1)
+		}
+		if i > 0 && c.Start < cues[i-1].Start {
+			return fmt.Errorf("%w: cue %d starts before the previous cue", ErrInvalid, i+1)
+		}
+		// allow a second of slack for encoder padding
+		if duration > 0 && c.End > duration+time.Second {
+			return fmt.Errorf("%w: cue %d ends after the audio", ErrInvalid, i+1)
+		}
+		if strings.Contains(c.Text, "-->") {
+			return fmt.Errorf("%w: cue %d text contains -->", ErrInvalid, i+1)
+		}
+	}
+	return nil
+}
+
+// WriteVTT renders cues as a WebVTT document.
+func WriteVTT(cues []Cue) []byte {
+	var b bytes.Buffer
+	b.WriteString("WEBVTT\n")
+	for _, c := range cues {
+		b.WriteByte('\n')
+		if c.ID != "" {
+			b.WriteString(c.ID + "\n")
+		}
+		b.WriteString(formatTimestamp(c.Start) + " --> " + formatTimestamp(c.End))
+		if c.Settings != "" {
+			b.WriteString(" " + c.Settings)
+		}
+		b.WriteByte('\n')
+		b.WriteString(c.Text)
+		b.WriteByte('\n')
+	}
+	return b.Bytes()
+}
+
+func formatTimestamp(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
+// Match is a cue whose text contains the search query.
+type Match struct {
+	Start float64 `json:"start_seconds"`
+	End   float64 `json:"end_seconds"`
+	Text  string  `json:"text"`
+}
+
+// Search returns cues containing q (case-insensitive), in time order.
+func Search(cues []Cue, q string, limit int) []Match {
+	q = strings.ToLower(strings.TrimSpace(q))
+	out := []Match{}
+	if q == "" {
+		return out
+	}
+	for _, c := range cues {
+		if strings.Contains(strings.ToLower(c.Text), q) {
+			out = append(out, Match{Start: c.Start.Seconds(), End: c.End.Seconds(), Text: c.Text})
+			if limit > 0 && len(out) >= limit {
+				break
+			}
+		}
+	}
+	return out
+}
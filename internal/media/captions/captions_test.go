@@ -0,0 +1,83 @@
+package captions
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSRTConvertsToVTT(t *testing.T) {
+	srt := "1\r\n00:00:01,000 --> 00:00:03,500\r\nHello there\r\n\r\n2\r\n00:00:04,000 --> 00:01:02,250\r\nSecond line\r\nwraps\r\n"
+	cues, err := Parse([]byte(srt), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cues) != 2 || cues[1].End != time.Minute+2250*time.Millisecond {
+		t.Fatalf("unexpected cues: %+v", cues)
+	}
+	got := string(WriteVTT(cues))
+	want := "WEBVTT\n\n1\n00:00:01.000 --> 00:00:03.500\nHello there\n\n2\n00:00:04.000 --> 00:01:02.250\nSecond line\nwraps\n"
+	if got != want {
+		t.Errorf("WriteVTT:\n%s\nwant:\n%s", got, want)
+	}
+
+	// Round-trip through the VTT parser.
+	again, err := Parse([]byte(got), 0)
+	if err != nil || len(again) != 2 || again[0].Text != "Hello there" {
+		t.Errorf("round trip failed: %v %+v", err, again)
+	}
+}
+
+func TestParseVTTSkipsNotesAndKeepsSettings(t *testing.T) {
+	vtt := "WEBVTT - transcript\n\nNOTE written by hand\n\n00:05.000 --> 00:07.000 align:start\nShort form timestamp\n"
+	cues, err := Parse([]byte(vtt), 10*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cues) != 1 || cues[0].Start != 5*time.Second || cues[0].Settings != "align:start" {
+		t.Errorf("unexpected cues: %+v", cues)
+	}
+}
+
+func TestParseRejectsBadTiming(t *testing.T) {
+	cases := map[string]string{
+		"end before start": "WEBVTT\n\n00:00:05.000 --> 00:00:04.000\nx\n",
+		"out of order":     "WEBVTT\n\n00:00:05.000 --> 00:00:06.000\na\n\n00:00:01.000 --> 00:00:02.000\nb\n",
+		"bad seconds":      "WEBVTT\n\n00:00:75.000 --> 00:00:76.000\nx\n",
+		"past the audio":   "WEBVTT\n\n00:00:01.000 --> 00:10:00.000\nx\n",
+		"no cues":          "WEBVTT\n",
+		"srt missing idx":  "00:00:01,000 --> 00:00:02,000\nx\n",
+	}
+	for name, in := range cases {
+		if _, err := Parse([]byte(in), time.Minute); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: expected ErrInvalid, got %v", name, err)
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	cues, _ := Parse([]byte("WEBVTT\n\n00:00:01.000 --> 00:00:02.000\nThe Habit loop\n\n00:00:03.000 --> 00:00:04.000\nCue, routine, reward\n\n00:00:05.000 --> 00:00:06.000\nhabits compound\n"), 0)
+	got := Search(cues, "HABIT", 0)
+	if len(got) != 2 || got[0].Start != 1 || got[1].Start != 5 {
+		t.Errorf("unexpected matches: %+v", got)
+	}
+	if len(Search(cues, "habit", 1)) != 1 {
+		t.Error("limit not applied")
+	}
+	if !strings.Contains(got[1].Text, "compound") {
+		t.Error("text missing from match")
+	}
+}
+
+func TestKeySitsNextToAudio(t *testing.T) {
+	for in, want := range map[string]string{
+		"books/foo-audio-123.mp3": "books/foo-audio-123.vtt",
+		"books/a/b.m4a":           "books/a/b.vtt",
+		"books/noext":             "books/noext.vtt",
+	} {
+		if got := Key(in); got != want {
+			t.Errorf("Key(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+// Package cover decodes uploaded cover images (JPEG, PNG, WebP), computes a
+// placeholder (dominant colour and BlurHash) and renders resized variants for
+// thumbnails. It is pure Go: WebP can be read but not written, so variants are
+// JPEG, or PNG when the image has transparency.
+package cover
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"path"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // register the WebP decoder
+)
+
+var (
+	ErrUnknownFormat = errors.New("cover: unrecognised image format")
+	ErrTooLarge      = errors.New("cover: image dimensions too large")
+)
+
+// Format names as reported by image.DecodeConfig.
+const (
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png"
+	FormatWebP = "webp"
+)
+
+// Widths are the variant widths rendered for every cover (never upscaled).
+var Widths = []int{128, 256, 512, 1024}
+
+// MaxPixels bounds width*height so a tiny file can't decode to gigabytes.
+const MaxPixels = 40_000_000
+
+const jpegQuality = 82
+
+// Info describes a decoded image.
+type Info struct {
+	Format string
+	Width  int
+	Height int
+}
+
+// ContentType is the canonical MIME type for the detected encoding.
+func (i Info) ContentType() string { return contentType(i.Format) }
+
+// Ext is the object-key extension for the detected encoding.
+func (i Info) Ext() string { return ext(i.Format) }
+
+// Variant is one resized rendition.
+type Variant struct {
+	Width  int
+	Height int
+	Format string
+	Data   []byte
+}
+
+func (v Variant) ContentType() string { return contentType(v.Format) }
+func (v Variant) Ext() string         { return ext(v.Format) }
+
+// Processed is a decoded cover, its placeholder and its variants (smallest first).
+type Processed struct {
+	Info
+	Placeholder
+	Image    image.Image
+	Variants []Variant
+}
+
+// Probe identifies the encoding and dimensions without decoding pixels.
+func Probe(data []byte) (Info, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Info{}, ErrUnknownFormat
+	}
+	switch format {
+	case FormatJPEG, FormatPNG, FormatWebP:
+	default:
+		return Info{}, fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || int64(cfg.Width)*int64(cfg.Height) > MaxPixels {
+		return Info{}, fmt.Errorf("%w: %dx%d", ErrTooLarge, cfg.Width, cfg.Height)
+	}
+	return Info{Format: format, Width: cfg.Width, Height: cfg.Height}, nil
+}
+
+// Process decodes data, computes its placeholder and renders a variant for
+// every width in Widths that is smaller than the original.
+func Process(data []byte) (*Processed, error) {
+	info, err := Probe(data)
+	if err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("cover: decode %s: %w", info.Format, err)
+	}
+
+	p := &Processed{Info: info, Placeholder: NewPlaceholder(img), Image: img}
+	format := FormatJPEG
+	if !opaque(img) {
+		format = FormatPNG
+	}
+	for _, w := range Widths {
+		if w >= info.Width {
+			break
+		}
+		small := Resize(img, w)
+		data, err := encode(small, format)
+		if err != nil {
+			return nil, err
+		}
+		p.Variants = append(p.Variants, Variant{
+			Width:  w,
+			Height: small.Bounds().Dy(),
+			Format: format,
+			Data:   data,
+		})
+	}
+	return p, nil
+}
+
+// Resize scales img to width w, keeping the aspect ratio.
+func Resize(img image.Image, w int) *image.NRGBA {
+	b := img.Bounds()
+	h := max(1, (b.Dy()*w+b.Dx()/2)/b.Dx())
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst
+}
+
+// Nearest picks the variant to serve for a requested width: the smallest one
+// at least that wide, or 0 (the original) when none is.
+func Nearest(widths []int, want int) int {
+	best := 0
+	for _, w := range widths {
+		if w >= want && (best == 0 || w < best) {
+			best = w
+		}
+	}
+	return best
+}
+
+// VariantPrefix is the "directory" holding the variants of a cover object.
+func VariantPrefix(coverKey string) string {
+	return strings.TrimSuffix(coverKey, path.Ext(coverKey)) + "/"
+}
+
+// VariantKey names the object for one variant of a cover.
+func VariantKey(coverKey string, v Variant) string {
+	return VariantPrefix(coverKey) + strconv.Itoa(v.Width) + v.Ext()
+}
+
+func encode(img image.Image, format string) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	if format == FormatPNG {
+		err = (&png.Encoder{CompressionLevel: png.BestCompression}).Encode(&buf, img)
+	} else {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+	}
+	return buf.Bytes(), err
+}
+
+func opaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	return false
+}
+
+func contentType(format string) string {
+	switch format {
+	case FormatPNG:
+		return "image/png"
+	case FormatWebP:
+		return "image/webp"
+	default:
+		return "image/jpeg"
+	}
+}
+
+func ext(format string) string {
+	switch format {
+	case FormatPNG:
+		return ".png"
+	case FormatWebP:
+		return ".webp"
+	default:
+		return ".jpg"
+	}
+}
@@ -0,0 +1,159 @@
+package cover
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"testing"
+)
+
+func testImage(w, h int, alpha uint8) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 200, A: alpha})
+		}
+	}
+	return img
+}
+
+func TestProcessJPEG(t *testing.T) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, testImage(600, 900, 255), nil); err != nil {
+		t.Fatal(err)
+	}
+	p, err := Process(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Format != FormatJPEG || p.Width != 600 || p.Height != 900 || p.Ext() != ".jpg" {
+		t.Fatalf("info = %+v", p.Info)
+	}
+	// 1024 would upscale, so only 128/256/512 are rendered.
+	if len(p.Variants) != 3 {
+		t.Fatalf("variants = %d", len(p.Variants))
+	}
+	for i, want := range []struct{ w, h int }{{128, 192}, {256, 384}, {512, 768}} {
+		v := p.Variants[i]
+		if v.Width != want.w || v.Height != want.h || v.ContentType() != "image/jpeg" {
+			t.Errorf("variant %d = %dx%d %s", i, v.Width, v.Height, v.ContentType())
+		}
+		cfg, format, err := image.DecodeConfig(bytes.NewReader(v.Data))
+		if err != nil || format != "jpeg" || cfg.Width != want.w || cfg.Height != want.h {
+			t.Errorf("variant %d decodes as %s %dx%d (%v)", i, format, cfg.Width, cfg.Height, err)
+		}
+	}
+}
+
+func TestProcessKeepsTransparencyAsPNG(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage(300, 300, 128)); err != nil {
+		t.Fatal(err)
+	}
+	p, err := Process(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.ContentType() != "image/png" || len(p.Variants) != 2 {
+		t.Fatalf("got %s with %d variants", p.ContentType(), len(p.Variants))
+	}
+	if v := p.Variants[0]; v.Ext() != ".png" || http.DetectContentType(v.Data) != "image/png" {
+		t.Errorf("transparent variant encoded as %s", http.DetectContentType(v.Data))
+	}
+}
+
+func TestProbeWebP(t *testing.T) {
+	// 1x1 lossless WebP.
+	data, _ := base64.StdEncoding.DecodeString("UklGRhoAAABXRUJQVlA4TA0AAAAvAAAAEAcQERGIiP4HAA==")
+	info, err := Probe(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Format != FormatWebP || info.Width != 1 || info.Height != 1 || info.ContentType() != "image/webp" {
+		t.Fatalf("info = %+v", info)
+	}
+	p, err := Process(data)
+	if err != nil || len(p.Variants) != 0 {
+		t.Fatalf("process = %+v, %v", p, err)
+	}
+}
+
+func TestProbeRejects(t *testing.T) {
+	if _, err := Probe([]byte("GIF89a not really")); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("gif/garbage: %v", err)
+	}
+	// A PNG header claiming 100k x 100k pixels must not be decoded.
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, testImage(1, 1, 255))
+	huge := buf.Bytes()
+	copy(huge[16:24], []byte{0, 1, 0x86, 0xA0, 0, 1, 0x86, 0xA0})
+	binary.BigEndian.PutUint32(huge[29:33], crc32.ChecksumIEEE(huge[12:29]))
+	if _, err := Probe(huge); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("huge: %v", err)
+	}
+}
+
+func TestNearestAndKeys(t *testing.T) {
+	widths := []int{128, 256, 512}
+	for _, c := range []struct{ want, got int }{{1, 128}, {128, 128}, {200, 256}, {512, 512}, {600, 0}} {
+		if got := Nearest(widths, c.want); got != c.got {
+			t.Errorf("Nearest(%d) = %d, want %d", c.want, got, c.got)
+		}
+	}
+	v := Variant{Width: 256, Format: FormatJPEG}
+	if got := VariantKey("books/covers/dune-1700000000.webp", v); got != "books/covers/dune-1700000000/256.jpg" {
+		t.Errorf("VariantKey = %s", got)
+	}
+}
+
+func TestBlurHash(t *testing.T) {
+	// Reference hashes from the canonical encoder for the same pixels.
+	flat := image.NewNRGBA(image.Rect(0, 0, 40, 60))
+	for i := range flat.Pix {
+		flat.Pix[i] = []uint8{0x33, 0x66, 0x99, 0xff}[i%4]
+	}
+	if got, want := BlurHash(flat, 3, 4), "T25?}ktofQp1fkfQfQfQfQp1fkfQ"; got != want {
+		t.Errorf("flat = %s, want %s", got, want)
+	}
+	grad := image.NewNRGBA(image.Rect(0, 0, 30, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 30; x++ {
+			grad.SetNRGBA(x, y, color.NRGBA{R: uint8(x * 8), G: uint8(y * 12), B: 200, A: 255})
+		}
+	}
+	if got, want := BlurHash(grad, 4, 3), "LpF~b22twxX9qTWEjte=gJfjfQfj"; got != want {
+		t.Errorf("gradient = %s, want %s", got, want)
+	}
+}
+
+func TestPlaceholder(t *testing.T) {
+	p := NewPlaceholder(testImage(200, 300, 255))
+	if p.Color == "" || len(p.BlurHash) != 28 || p.BlurHash[0] != 'T' {
+		t.Errorf("opaque = %+v", p)
+	}
+	split := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			c := color.NRGBA{R: 0xc0 + uint8(x), A: 255}
+			if y == 0 {
+				c = color.NRGBA{B: 0xff, A: 255}
+			}
+			split.SetNRGBA(x, y, c)
+		}
+	}
+	if got := DominantColor(split); got != "#c10000" {
+		t.Errorf("DominantColor = %s", got)
+	}
+	// Fully transparent: no pixel gets a vote, and the hash is flattened onto white.
+	clear := NewPlaceholder(testImage(10, 10, 0))
+	if clear.Color != "#ffffff" || clear.BlurHash[2:6] != "TSUA" {
+		t.Errorf("transparent = %+v", clear)
+	}
+}
@@ -0,0 +1,183 @@
+package cover
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strings"
+)
+
+// BlurHash component counts used for covers: enough detail for a portrait
+// cover while keeping the hash at 28 characters.
+const (
+	blurXComponents = 3
+	blurYComponents = 4
+)
+
+// Sizes of the thumbnails the placeholder is computed from. The result barely
+// changes with resolution, so there is no point walking every pixel.
+const (
+	blurSampleWidth  = 32
+	colorSampleWidth = 64
+)
+
+// Placeholder is what a client needs to paint a cover before it loads.
+type Placeholder struct {
+	Color    string // dominant colour, "#rrggbb"
+	BlurHash string
+}
+
+// NewPlaceholder computes the dominant colour and BlurHash of img.
+// Transparent areas are flattened onto white first.
+func NewPlaceholder(img image.Image) Placeholder {
+	return Placeholder{
+		Color:    DominantColor(thumbnail(img, colorSampleWidth)),
+		BlurHash: BlurHash(thumbnail(img, blurSampleWidth), blurXComponents, blurYComponents),
+	}
+}
+
+func thumbnail(img image.Image, w int) image.Image {
+	if img.Bounds().Dx() <= w {
+		return img
+	}
+	return Resize(img, w)
+}
+
+// DominantColor returns the most common colour of img as "#rrggbb". Pixels are
+// bucketed at 4 bits per channel and the winning bucket is averaged, so JPEG
+// noise and gradients don't split an otherwise flat background.
+func DominantColor(img image.Image) string {
+	type bucket struct{ n, r, g, b int }
+	var buckets [4096]bucket
+	best := -1
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			if c.A < 128 {
+				continue
+			}
+			i := int(c.R>>4)<<8 | int(c.G>>4)<<4 | int(c.B>>4)
+			bk := &buckets[i]
+			bk.n++
+			bk.r += int(c.R)
+			bk.g += int(c.G)
+			bk.b += int(c.B)
+			if best < 0 || bk.n > buckets[best].n {
+				best = i
+			}
+		}
+	}
+	if best < 0 {
+		return "#ffffff"
+	}
+	bk := buckets[best]
+	return fmt.Sprintf("#%02x%02x%02x", bk.r/bk.n, bk.g/bk.n, bk.b/bk.n)
+}
+
+// BlurHash encodes img with the given number of components per axis (1..9);
+// see https://blurha.sh for the format.
+func BlurHash(img image.Image, xComponents, yComponents int) string {
+	xComponents = min(max(xComponents, 1), 9)
+	yComponents = min(max(yComponents, 1), 9)
+
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	pixels := make([][3]float64, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			pixels[y*w+x] = linearRGB(img.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+
+	factors := make([][3]float64, 0, xComponents*yComponents)
+	for j := 0; j < yComponents; j++ {
+		for i := 0; i < xComponents; i++ {
+			norm := 2.0
+			if i == 0 && j == 0 {
+				norm = 1
+			}
+			var f [3]float64
+			for y := 0; y < h; y++ {
+				cy := math.Cos(math.Pi * float64(j) * float64(y) / float64(h))
+				for x := 0; x < w; x++ {
+					basis := cy * math.Cos(math.Pi*float64(i)*float64(x)/float64(w))
+					p := pixels[y*w+x]
+					f[0] += basis * p[0]
+					f[1] += basis * p[1]
+					f[2] += basis * p[2]
+				}
+			}
+			scale := norm / float64(w*h)
+			factors = append(factors, [3]float64{f[0] * scale, f[1] * scale, f[2] * scale})
+		}
+	}
+
+	var sb strings.Builder
+	sb.Grow(4 + 2*len(factors))
+	writeBase83(&sb, (xComponents-1)+(yComponents-1)*9, 1)
+
+	dc, ac := factors[0], factors[1:]
+	maxValue := 1.0
+	if len(ac) > 0 {
+		actual := 0.0
+		for _, f := range ac {
+			actual = max(actual, math.Abs(f[0]), math.Abs(f[1]), math.Abs(f[2]))
+		}
+		q := min(max(int(math.Floor(actual*166-0.5)), 0), 82)
+		maxValue = float64(q+1) / 166
+		writeBase83(&sb, q, 1)
+	} else {
+		writeBase83(&sb, 0, 1)
+	}
+
+	writeBase83(&sb, srgb(dc[0])<<16|srgb(dc[1])<<8|srgb(dc[2]), 4)
+	for _, f := range ac {
+		q := func(v float64) int {
+			return min(max(int(math.Floor(signPow(v/maxValue, 0.5)*9+9.5)), 0), 18)
+		}
+		writeBase83(&sb, q(f[0])*19*19+q(f[1])*19+q(f[2]), 2)
+	}
+	return sb.String()
+}
+
+// linearRGB converts a colour to linear light, flattening alpha onto white.
+func linearRGB(c color.Color) [3]float64 {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	a := float64(n.A) / 255
+	flat := func(v uint8) float64 { return float64(v)*a + 255*(1-a) }
+	return [3]float64{toLinear(flat(n.R)), toLinear(flat(n.G)), toLinear(flat(n.B))}
+}
+
+func toLinear(v float64) float64 {
+	v /= 255
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+func srgb(v float64) int {
+	v = min(max(v, 0), 1)
+	if v <= 0.0031308 {
+		return int(v*12.92*255 + 0.5)
+	}
+	return int((1.055*math.Pow(v, 1/2.4)-0.055)*255 + 0.5)
+}
+
+func signPow(v, exp float64) float64 {
+	return math.Copysign(math.Pow(math.Abs(v), exp), v)
+}
+
+const base83Chars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~"
+
+func writeBase83(sb *strings.Builder, value, length int) {
+	for i := length - 1; i >= 0; i-- {
+		d := value
+		for k := 0; k < i; k++ {
+			d /= 83
+		}
+		sb.WriteByte(base83Chars[d%83])
+	}
+}
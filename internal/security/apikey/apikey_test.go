@@ -0,0 +1,54 @@
+package apikey
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/5w1tchy/books-api/internal/security/rbac"
+)
+
+func TestGenerate(t *testing.T) {
+	key, hash, prefix, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(key, "bk_") || !strings.HasPrefix(key, prefix) || len(key) < 40 {
+		t.Errorf("key=%q prefix=%q", key, prefix)
+	}
+	if hash != Hash(key) || hash == Hash(key+"x") {
+		t.Error("hash does not identify the key")
+	}
+	other, _, _, _ := Generate()
+	if other == key {
+		t.Error("keys repeat")
+	}
+}
+
+func TestScopes(t *testing.T) {
+	if !ValidScopes([]string{ScopeCatalogRead, ScopeAdminBooksWrite}) {
+		t.Error("known scopes rejected")
+	}
+	for _, s := range [][]string{nil, {"catalog:write"}, {ScopeCatalogRead, ""}} {
+		if ValidScopes(s) {
+			t.Errorf("%q accepted", s)
+		}
+	}
+
+	granted := []string{ScopeCatalogRead, ScopeAdminBooksWrite}
+	for want, ok := range map[string]bool{
+		ScopeCatalogRead:     true,
+		ScopeAdminBooksWrite: true,
+		ScopeAdminBooksRead:  true, // implied by write
+		"catalog:write":      false,
+	} {
+		if Allows(granted, want) != ok {
+			t.Errorf("Allows(%q) = %v", want, !ok)
+		}
+	}
+	if Allows([]string{ScopeAdminBooksRead}, ScopeAdminBooksWrite) {
+		t.Error("read implied write")
+	}
+	if Permission(ScopeCatalogRead) != "" || Permission(ScopeAdminBooksWrite) != rbac.BooksWrite {
+		t.Error("scope permissions")
+	}
+}
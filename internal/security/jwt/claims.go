@@ -7,7 +7,8 @@ import (
 )
 
 type AccessClaims struct {
-	TokenVersion int `json:"tv"`
+	TokenVersion int    `json:"tv"`
+	SessionID    string `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
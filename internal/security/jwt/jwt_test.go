@@ -0,0 +1,182 @@
+package jwtutil
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"testing"
+	"time"
+)
+
+func pemPrivate(t *testing.T, key crypto.Signer, kid string) string {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	if kid != "" {
+		b.Headers = map[string]string{"kid": kid}
+	}
+	return string(pem.EncodeToMemory(b))
+}
+
+func pemPublic(t *testing.T, key crypto.PublicKey, kid string) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Headers: map[string]string{"kid": kid}, Bytes: der}))
+}
+
+func mustLoad(t *testing.T) Config {
+	t.Helper()
+	c, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func roundTrip(t *testing.T, signer, verifier Config) (*AccessClaims, error) {
+	t.Helper()
+	tok, err := signer.Sign(NewAccessClaims("u1", "j1", 3, time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return verifier.Parse(tok)
+}
+
+func TestSignParseAlgorithms(t *testing.T) {
+	for _, alg := range []string{AlgEdDSA, AlgRS256} {
+		t.Run(alg, func(t *testing.T) {
+			signer, err := generateKey(alg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv("AUTH_JWT_PRIVATE_KEY", pemPrivate(t, signer, "k1"))
+			c := mustLoad(t)
+			if c.Alg != alg || c.KeyID != "k1" {
+				t.Fatalf("alg=%s kid=%s", c.Alg, c.KeyID)
+			}
+			claims, err := roundTrip(t, c, c)
+			if err != nil || claims.Subject != "u1" || claims.TokenVersion != 3 {
+				t.Fatalf("claims=%+v err=%v", claims, err)
+			}
+		})
+	}
+}
+
+func TestRotation(t *testing.T) {
+	oldKey, _ := generateKey(AlgEdDSA)
+	newKey, _ := generateKey(AlgEdDSA)
+
+	t.Setenv("AUTH_JWT_PRIVATE_KEY", pemPrivate(t, oldKey, "old"))
+	before := mustLoad(t)
+
+	t.Setenv("AUTH_JWT_PRIVATE_KEY", pemPrivate(t, newKey, "new"))
+	t.Setenv("AUTH_JWT_VERIFY_KEYS", pemPublic(t, oldKey.Public(), "old")+pemPublic(t, newKey.Public(), "new"))
+	after := mustLoad(t)
+	if len(after.Verify) != 2 {
+		t.Fatalf("verify keys = %d, want 2", len(after.Verify))
+	}
+	if _, err := roundTrip(t, before, after); err != nil {
+		t.Errorf("old token after rotation: %v", err)
+	}
+
+	t.Setenv("AUTH_JWT_VERIFY_KEYS", "")
+	retired := mustLoad(t)
+	if _, err := roundTrip(t, before, retired); err == nil {
+		t.Error("token for a dropped key verified")
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	key, _ := generateKey(AlgEdDSA)
+	t.Setenv("AUTH_JWT_PRIVATE_KEY", pemPrivate(t, key, "k1"))
+	c := mustLoad(t)
+
+	// Same key, unknown kid
+	other := c
+	other.KeyID = "k2"
+	if _, err := roundTrip(t, other, c); err == nil {
+		t.Error("unknown kid accepted")
+	}
+
+	// HS256 token against an EdDSA config
+	t.Setenv("AUTH_JWT_PRIVATE_KEY", "")
+	t.Setenv("AUTH_JWT_SECRET", "0123456789abcdef0123456789abcdef")
+	hs := mustLoad(t)
+	if hs.Alg != AlgHS256 {
+		t.Fatalf("alg=%s", hs.Alg)
+	}
+	if _, err := roundTrip(t, hs, c); err == nil {
+		t.Error("HS256 token accepted by EdDSA config")
+	}
+	if _, err := roundTrip(t, hs, hs); err != nil {
+		t.Errorf("HS256 round trip: %v", err)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	t.Setenv("AUTH_JWT_SECRET", "short")
+	if _, err := LoadConfig(); err == nil {
+		t.Error("short HS256 secret accepted")
+	}
+
+	t.Setenv("AUTH_JWT_ALG", AlgEdDSA)
+	if c, err := LoadConfig(); err != nil || c.Signer == nil {
+		t.Errorf("dev EdDSA without key: err=%v", err)
+	}
+	t.Setenv("APP_ENV", "production")
+	if _, err := LoadConfig(); err == nil {
+		t.Error("production EdDSA without key accepted")
+	}
+
+	rsaKey, _ := generateKey(AlgRS256)
+	t.Setenv("AUTH_JWT_PRIVATE_KEY", pemPrivate(t, rsaKey, ""))
+	if _, err := LoadConfig(); err == nil {
+		t.Error("RSA key accepted for AUTH_JWT_ALG=EdDSA")
+	}
+}
+
+// RFC 8037 appendix A.3.
+func TestThumbprintRFC8037(t *testing.T) {
+	x, _ := base64.RawURLEncoding.DecodeString("11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo")
+	got, err := Thumbprint(ed25519.PublicKey(x))
+	if want := "kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k"; err != nil || got != want {
+		t.Errorf("got %s (%v), want %s", got, err, want)
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	ed, _ := generateKey(AlgEdDSA)
+	rs, _ := generateKey(AlgRS256)
+	t.Setenv("AUTH_JWT_PRIVATE_KEY", pemPrivate(t, ed, ""))
+	t.Setenv("AUTH_JWT_VERIFY_KEYS", pemPublic(t, rs.Public(), "rsa-1"))
+	c := mustLoad(t)
+	set, err := c.JWKS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := set["keys"]
+	if len(keys) != 2 {
+		t.Fatalf("keys = %+v", keys)
+	}
+	if k := keys[0]; k.Kty != "OKP" || k.Crv != "Ed25519" || k.Alg != AlgEdDSA || k.Kid != c.KeyID || k.X == "" {
+		t.Errorf("signing key = %+v", k)
+	}
+	if k := keys[1]; k.Kty != "RSA" || k.Alg != AlgRS256 || k.Kid != "rsa-1" || k.E != "AQAB" || k.Use != "sig" {
+		t.Errorf("rsa key = %+v", k)
+	}
+
+	t.Setenv("AUTH_JWT_PRIVATE_KEY", "")
+	t.Setenv("AUTH_JWT_VERIFY_KEYS", "")
+	t.Setenv("AUTH_JWT_SECRET", "0123456789abcdef0123456789abcdef")
+	if set, _ := mustLoad(t).JWKS(); len(set["keys"]) != 0 {
+		t.Errorf("HS256 published keys: %+v", set)
+	}
+}
@@ -0,0 +1,157 @@
+package jwtutil
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+)
+
+const minRSABits = 2048
+
+var errKeyType = errors.New("key must be Ed25519 or RSA (2048+ bits)")
+
+// parsePrivateKey reads one PEM private key and its optional "kid" header.
+func parsePrivateKey(data []byte) (crypto.Signer, string, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, "", errors.New("no PEM block")
+	}
+	var (
+		key any
+		err error
+	)
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return nil, "", errors.New("unsupported PEM type " + block.Type)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok || algFor(signer.Public()) == "" {
+		return nil, "", errKeyType
+	}
+	return signer, block.Headers["kid"], nil
+}
+
+// parsePublicKeys reads every PEM public key in data.
+func parsePublicKeys(data []byte) ([]PublicKey, error) {
+	var out []PublicKey
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return out, nil
+		}
+		if block.Type != "PUBLIC KEY" {
+			return nil, errors.New("unsupported PEM type " + block.Type)
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		alg := algFor(key)
+		if alg == "" {
+			return nil, errKeyType
+		}
+		kid := block.Headers["kid"]
+		if kid == "" {
+			if kid, err = Thumbprint(key); err != nil {
+				return nil, err
+			}
+		}
+		out = append(out, PublicKey{ID: kid, Alg: alg, Key: key})
+	}
+}
+
+// algFor is the JWS algorithm used with a public key, or "" if unsupported.
+func algFor(pub crypto.PublicKey) string {
+	switch k := pub.(type) {
+	case ed25519.PublicKey:
+		return AlgEdDSA
+	case *rsa.PublicKey:
+		if k.N.BitLen() >= minRSABits {
+			return AlgRS256
+		}
+	}
+	return ""
+}
+
+// JWK is a public key in JSON Web Key form (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Crv string `json:"crv,omitempty"` // OKP
+	X   string `json:"x,omitempty"`   // OKP
+	N   string `json:"n,omitempty"`   // RSA
+	E   string `json:"e,omitempty"`   // RSA
+}
+
+func toJWK(pub crypto.PublicKey) (JWK, error) {
+	b64 := base64.RawURLEncoding.EncodeToString
+	switch k := pub.(type) {
+	case ed25519.PublicKey:
+		return JWK{Kty: "OKP", Crv: "Ed25519", X: b64(k)}, nil
+	case *rsa.PublicKey:
+		return JWK{Kty: "RSA", N: b64(k.N.Bytes()), E: b64(big.NewInt(int64(k.E)).Bytes())}, nil
+	}
+	return JWK{}, errKeyType
+}
+
+// Thumbprint is the RFC 7638 SHA-256 thumbprint of a public key, used as
+// its default key ID.
+func Thumbprint(pub crypto.PublicKey) (string, error) {
+	j, err := toJWK(pub)
+	if err != nil {
+		return "", err
+	}
+	// Required members only, in lexicographic order
+	var members any
+	if j.Kty == "OKP" {
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{j.Crv, j.Kty, j.X}
+	} else {
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{j.E, j.Kty, j.N}
+	}
+	b, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// JWKS is the public key set other services verify our tokens with. It is
+// empty under HS256, whose key can't be published.
+func (c Config) JWKS() (map[string][]JWK, error) {
+	keys := []JWK{}
+	for _, k := range c.Verify {
+		j, err := toJWK(k.Key)
+		if err != nil {
+			return nil, err
+		}
+		j.Kid, j.Use, j.Alg = k.ID, "sig", k.Alg
+		keys = append(keys, j)
+	}
+	return map[string][]JWK{"keys": keys}, nil
+}
@@ -1,26 +1,165 @@
 package jwtutil
 
 import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"log"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
+// Supported signing algorithms.
+const (
+	AlgHS256 = "HS256"
+	AlgEdDSA = "EdDSA"
+	AlgRS256 = "RS256"
+)
+
+// Config holds the signing key and every key tokens are accepted from.
+//
+// Rotation without downtime: add the new public key to
+// AUTH_JWT_VERIFY_KEYS on every instance, then switch AUTH_JWT_PRIVATE_KEY
+// to it, and drop the old public key once AUTH_ACCESS_TTL has passed.
 type Config struct {
-	Secret    []byte
+	Alg       string
+	KeyID     string        // "kid" header; empty for HS256
+	Secret    []byte        // HS256
+	Signer    crypto.Signer // EdDSA / RS256
+	Verify    []PublicKey   // accepted asymmetric keys, signing key's first
 	ClockSkew time.Duration
 }
 
-func LoadConfig() Config {
-	secret := os.Getenv("AUTH_JWT_SECRET")
-	if len(secret) < 32 {
-		// keep going in dev, but you should set a strong secret in .env / prod
+// PublicKey is a verification key and its key ID.
+type PublicKey struct {
+	ID  string
+	Alg string
+	Key crypto.PublicKey
+}
+
+// LoadConfig reads:
+//
+//	AUTH_JWT_ALG                 HS256 | EdDSA | RS256 (default: from the private key, else HS256)
+//	AUTH_JWT_SECRET              HS256 secret, at least 32 characters
+//	AUTH_JWT_PRIVATE_KEY[_FILE]  PEM signing key (PKCS#8, or PKCS#1 for RSA)
+//	AUTH_JWT_KID                 key ID for it (default: RFC 7638 thumbprint)
+//	AUTH_JWT_VERIFY_KEYS[_FILE]  PEM public keys also accepted (a "kid" PEM header sets the ID)
+//	AUTH_CLOCK_SKEW_SEC          leeway when checking exp/iat (default 60)
+//
+// Without a private key EdDSA/RS256 use a throwaway key outside production
+// (tokens die on restart) and are an error in production.
+func LoadConfig() (Config, error) {
+	c := Config{ClockSkew: time.Duration(parseInt("AUTH_CLOCK_SKEW_SEC", 60)) * time.Second}
+	production := strings.EqualFold(os.Getenv("APP_ENV"), "production")
+
+	privPEM, err := envOrFile("AUTH_JWT_PRIVATE_KEY")
+	if err != nil {
+		return Config{}, err
+	}
+	c.Alg = os.Getenv("AUTH_JWT_ALG")
+
+	if privPEM != "" {
+		signer, kid, err := parsePrivateKey([]byte(privPEM))
+		if err != nil {
+			return Config{}, fmt.Errorf("AUTH_JWT_PRIVATE_KEY: %w", err)
+		}
+		alg := algFor(signer.Public())
+		if c.Alg == "" {
+			c.Alg = alg
+		}
+		if c.Alg != alg {
+			return Config{}, fmt.Errorf("AUTH_JWT_PRIVATE_KEY is not an %s key", c.Alg)
+		}
+		c.Signer = signer
+		c.KeyID = firstNonEmpty(os.Getenv("AUTH_JWT_KID"), kid)
 	}
-	leeway := time.Duration(parseInt("AUTH_CLOCK_SKEW_SEC", 60)) * time.Second
-	return Config{
-		Secret:    []byte(secret),
-		ClockSkew: leeway,
+	if c.Alg == "" {
+		c.Alg = AlgHS256
+	}
+
+	switch c.Alg {
+	case AlgHS256:
+		secret := os.Getenv("AUTH_JWT_SECRET")
+		if len(secret) < 32 {
+			return Config{}, errors.New("AUTH_JWT_SECRET must be at least 32 characters (or configure AUTH_JWT_PRIVATE_KEY)")
+		}
+		c.Secret = []byte(secret)
+		c.KeyID = ""
+		return c, nil
+	case AlgEdDSA, AlgRS256:
+	default:
+		return Config{}, fmt.Errorf("AUTH_JWT_ALG %q is not one of HS256, EdDSA, RS256", c.Alg)
+	}
+
+	if c.Signer == nil {
+		if production {
+			return Config{}, fmt.Errorf("AUTH_JWT_ALG=%s needs AUTH_JWT_PRIVATE_KEY or AUTH_JWT_PRIVATE_KEY_FILE in production", c.Alg)
+		}
+		if c.Signer, err = generateKey(c.Alg); err != nil {
+			return Config{}, err
+		}
+		log.Printf("WARN: no AUTH_JWT_PRIVATE_KEY; signing %s tokens with a temporary key", c.Alg)
+	}
+	if c.KeyID == "" {
+		if c.KeyID, err = Thumbprint(c.Signer.Public()); err != nil {
+			return Config{}, err
+		}
+	}
+	c.Verify = []PublicKey{{ID: c.KeyID, Alg: c.Alg, Key: c.Signer.Public()}}
+
+	verifyPEM, err := envOrFile("AUTH_JWT_VERIFY_KEYS")
+	if err != nil {
+		return Config{}, err
+	}
+	extra, err := parsePublicKeys([]byte(verifyPEM))
+	if err != nil {
+		return Config{}, fmt.Errorf("AUTH_JWT_VERIFY_KEYS: %w", err)
+	}
+	for _, k := range extra {
+		if k.ID == c.KeyID {
+			continue // the signing key's own public half
+		}
+		c.Verify = append(c.Verify, k)
+	}
+	return c, nil
+}
+
+// envOrFile returns $KEY, or the contents of the file named by $KEY_FILE.
+func envOrFile(key string) (string, error) {
+	if v := os.Getenv(key); v != "" {
+		// single-line env values often carry literal "\n"
+		return strings.ReplaceAll(v, `\n`, "\n"), nil
+	}
+	if path := os.Getenv(key + "_FILE"); path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("%s_FILE: %w", key, err)
+		}
+		return string(b), nil
+	}
+	return "", nil
+}
+
+func generateKey(alg string) (crypto.Signer, error) {
+	if alg == AlgRS256 {
+		return rsa.GenerateKey(rand.Reader, 2048)
+	}
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	return priv, err
+}
+
+func firstNonEmpty(s ...string) string {
+	for _, v := range s {
+		if v != "" {
+			return v
+		}
 	}
+	return ""
 }
 
 func parseInt(key string, def int64) int64 {
@@ -5,30 +5,103 @@ import (
 	"encoding/hex"
 	"errors"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var cfg = LoadConfig()
+var (
+	cfgOnce sync.Once
+	cfg     Config
+	cfgErr  error
+)
+
+// current loads the configuration on first use, after main has read .env.
+func current() (Config, error) {
+	cfgOnce.Do(func() { cfg, cfgErr = LoadConfig() })
+	return cfg, cfgErr
+}
+
+// Init loads the key configuration now, so bad or missing keys stop
+// startup instead of failing the first login.
+func Init() error {
+	_, err := current()
+	return err
+}
+
+// JWKS returns the published verification keys (see Config.JWKS).
+func JWKS() (map[string][]JWK, error) {
+	c, err := current()
+	if err != nil {
+		return nil, err
+	}
+	return c.JWKS()
+}
 
 // SignAccess returns (tokenString, jti).
 func SignAccess(userID string, tokenVersion int, ttl time.Duration) (string, string, error) {
+	return SignSessionAccess(userID, "", tokenVersion, ttl)
+}
+
+// SignSessionAccess is SignAccess for a token tied to a refresh session
+// (the "sid" claim).
+func SignSessionAccess(userID, sessionID string, tokenVersion int, ttl time.Duration) (string, string, error) {
+	c, err := current()
+	if err != nil {
+		return "", "", err
+	}
 	jti, err := randJTI()
 	if err != nil {
 		return "", "", err
 	}
 	claims := NewAccessClaims(userID, jti, tokenVersion, ttl)
-	t := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	s, err := t.SignedString(cfg.Secret)
+	claims.SessionID = sessionID
+	s, err := c.Sign(claims)
 	return s, jti, err
 }
 
-// ParseAccess verifies HS256 signature and leeway, returning claims.
+// ParseAccess verifies signature and leeway, returning claims.
 func ParseAccess(tokenStr string) (*AccessClaims, error) {
-	parser := jwt.NewParser(jwt.WithLeeway(cfg.ClockSkew), jwt.WithValidMethods([]string{"HS256"}))
+	c, err := current()
+	if err != nil {
+		return nil, err
+	}
+	return c.Parse(tokenStr)
+}
+
+// Sign signs claims with the configured key, naming it in the "kid" header.
+func (c Config) Sign(claims AccessClaims) (string, error) {
+	if c.Alg == AlgHS256 {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(c.Secret)
+	}
+	t := jwt.NewWithClaims(jwt.GetSigningMethod(c.Alg), claims)
+	t.Header["kid"] = c.KeyID
+	return t.SignedString(c.Signer)
+}
+
+// Parse verifies a token against the key its "kid" names. Only the
+// configured algorithms are accepted, and a key only for its own one.
+func (c Config) Parse(tokenStr string) (*AccessClaims, error) {
+	algs := []string{AlgHS256}
+	if c.Alg != AlgHS256 {
+		algs = algs[:0]
+		for _, k := range c.Verify {
+			algs = append(algs, k.Alg)
+		}
+	}
+	parser := jwt.NewParser(jwt.WithLeeway(c.ClockSkew), jwt.WithValidMethods(algs))
 	token, err := parser.ParseWithClaims(tokenStr, &AccessClaims{}, func(t *jwt.Token) (interface{}, error) {
-		return cfg.Secret, nil
+		if c.Alg == AlgHS256 {
+			return c.Secret, nil
+		}
+		kid, _ := t.Header["kid"].(string)
+		for _, k := range c.Verify {
+			if k.ID == kid && k.Alg == t.Method.Alg() {
+				return k.Key, nil
+			}
+		}
+		return nil, errors.New("unknown signing key")
 	})
 	if err != nil {
 		return nil, err
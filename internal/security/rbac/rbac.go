@@ -0,0 +1,77 @@
+// Package rbac defines the permissions staff roles are made of. Roles live
+// in public.roles and map a name (users.role) to a set of permissions;
+// endpoints check permissions, never role names.
+package rbac
+
+import (
+	"regexp"
+	"slices"
+)
+
+// Permissions.
+const (
+	BooksRead     = "books.read"
+	BooksWrite    = "books.write"
+	UsersRead     = "users.read"
+	UsersBan      = "users.ban"
+	UsersManage   = "users.manage" // sessions, logout-all, verification emails
+	UsersRole     = "users.role"
+	AuditRead     = "audit.read"
+	StatsRead     = "stats.read"
+	StorageManage = "storage.manage"
+	APIKeysManage = "apikeys.manage"
+	RolesManage   = "roles.manage"
+
+	// All grants every permission, including ones added later.
+	All = "*"
+)
+
+// Built-in roles. Admin can't be changed or deleted, so someone can always
+// manage roles; user is the default for new accounts.
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+)
+
+// Permissions lists every permission a role can be given.
+var Permissions = []string{
+	BooksRead, BooksWrite,
+	UsersRead, UsersBan, UsersManage, UsersRole,
+	AuditRead, StatsRead, StorageManage, APIKeysManage, RolesManage,
+}
+
+// Has reports whether granted includes want.
+func Has(granted []string, want string) bool {
+	return slices.Contains(granted, All) || slices.Contains(granted, want)
+}
+
+// Covers reports whether granted includes every one of perms, so that a
+// holder of granted can hand perms out without escalating.
+func Covers(granted, perms []string) bool {
+	for _, p := range perms {
+		if p == All && !slices.Contains(granted, All) {
+			return false
+		}
+		if !Has(granted, p) {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidPermissions reports whether every permission is known.
+func ValidPermissions(perms []string) bool {
+	for _, p := range perms {
+		if p != All && !slices.Contains(Permissions, p) {
+			return false
+		}
+	}
+	return true
+}
+
+var roleName = regexp.MustCompile(`^[a-z][a-z0-9_-]{1,31}$`)
+
+// ValidRoleName reports whether name can name a role.
+func ValidRoleName(name string) bool {
+	return roleName.MatchString(name)
+}
@@ -0,0 +1,45 @@
+package rbac
+
+import "testing"
+
+func TestHas(t *testing.T) {
+	editor := []string{BooksRead, BooksWrite}
+	if !Has(editor, BooksWrite) || Has(editor, UsersBan) {
+		t.Error("editor permissions")
+	}
+	if !Has([]string{All}, RolesManage) {
+		t.Error("wildcard does not grant")
+	}
+	if Has(nil, BooksRead) {
+		t.Error("empty role grants")
+	}
+}
+
+func TestCovers(t *testing.T) {
+	moderator := []string{UsersRead, UsersBan}
+	if !Covers(moderator, []string{UsersBan}) || !Covers(moderator, nil) {
+		t.Error("subset not covered")
+	}
+	if Covers(moderator, []string{UsersBan, UsersRole}) || Covers(moderator, []string{All}) {
+		t.Error("escalation covered")
+	}
+	if !Covers([]string{All}, []string{All, RolesManage}) {
+		t.Error("wildcard does not cover")
+	}
+}
+
+func TestValidation(t *testing.T) {
+	if !ValidPermissions([]string{BooksRead, All}) || !ValidPermissions(nil) {
+		t.Error("valid permissions rejected")
+	}
+	if ValidPermissions([]string{"books.delete"}) {
+		t.Error("unknown permission accepted")
+	}
+	for name, ok := range map[string]bool{
+		"editor": true, "support-tier2": true, "a": false, "Editor": false, "x y": false, "": false,
+	} {
+		if ValidRoleName(name) != ok {
+			t.Errorf("ValidRoleName(%q) = %v", name, !ok)
+		}
+	}
+}
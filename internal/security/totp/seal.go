@@ -0,0 +1,68 @@
+package totp
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"os"
+)
+
+var ErrSealed = errors.New("totp: cannot open sealed secret")
+
+// KeyFromEnv returns the 32-byte key secrets are sealed with: AUTH_TOTP_KEY
+// (64 hex chars), or else one derived from AUTH_JWT_SECRET so dev setups work
+// without extra config. Set AUTH_TOTP_KEY in production; rotating the JWT
+// secret would otherwise lock everyone out of 2FA.
+func KeyFromEnv() []byte {
+	if k, err := hex.DecodeString(os.Getenv("AUTH_TOTP_KEY")); err == nil && len(k) == 32 {
+		return k
+	}
+	sum := sha256.Sum256([]byte("totp-seal:" + os.Getenv("AUTH_JWT_SECRET")))
+	return sum[:]
+}
+
+// Seal encrypts a secret for storage (AES-256-GCM, base64url).
+func Seal(key []byte, secret string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	out := gcm.Seal(nonce, nonce, []byte(secret), nil)
+	return base64.RawURLEncoding.EncodeToString(out), nil
+}
+
+// Open reverses Seal.
+func Open(key []byte, sealed string) (string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(sealed)
+	if err != nil {
+		return "", ErrSealed
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	if len(raw) < gcm.NonceSize() {
+		return "", ErrSealed
+	}
+	plain, err := gcm.Open(nil, raw[:gcm.NonceSize()], raw[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", ErrSealed
+	}
+	return string(plain), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
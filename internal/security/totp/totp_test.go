@@ -0,0 +1,87 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 6238 appendix B, SHA-1 column (last 6 of the 8 digits).
+func TestCodeRFC6238(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	for _, c := range []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	} {
+		got, err := Code(secret, Step(time.Unix(c.unix, 0)))
+		if err != nil || got != c.want {
+			t.Errorf("T=%d: got %s (%v), want %s", c.unix, got, err, c.want)
+		}
+	}
+}
+
+func TestValidateSkewAndStep(t *testing.T) {
+	secret, err := NewSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1700000000, 0)
+	prev, _ := Code(secret, Step(now)-1)
+	if step, ok := Validate(secret, prev, now); !ok || step != Step(now)-1 {
+		t.Errorf("previous step: ok=%v step=%d", ok, step)
+	}
+	old, _ := Code(secret, Step(now)-3)
+	if _, ok := Validate(secret, old, now); ok {
+		t.Error("code three steps old accepted")
+	}
+	if _, ok := Validate(secret, "12345", now); ok {
+		t.Error("short code accepted")
+	}
+}
+
+func TestURI(t *testing.T) {
+	u := URI("Books", "ana@example.com", "JBSWY3DPEHPK3PXP")
+	if !strings.HasPrefix(u, "otpauth://totp/Books:ana@example.com?") || !strings.Contains(u, "secret=JBSWY3DPEHPK3PXP") {
+		t.Errorf("URI = %s", u)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := RecoveryCodes(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := map[string]bool{}
+	for _, c := range codes {
+		if len(c) != 11 || c[5] != '-' || seen[c] {
+			t.Fatalf("bad or duplicate code %q", c)
+		}
+		seen[c] = true
+	}
+	if HashRecoveryCode(codes[0]) != HashRecoveryCode(" "+strings.ToUpper(strings.ReplaceAll(codes[0], "-", ""))) {
+		t.Error("hash should ignore case and dashes")
+	}
+}
+
+func TestSealRoundTrip(t *testing.T) {
+	key := make([]byte, 32)
+	sealed, err := Seal(key, "JBSWY3DPEHPK3PXP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := Open(key, sealed); err != nil || got != "JBSWY3DPEHPK3PXP" {
+		t.Fatalf("Open = %q, %v", got, err)
+	}
+	key[0] = 1
+	if _, err := Open(key, sealed); err != ErrSealed {
+		t.Errorf("wrong key: %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// Just enough CBOR (RFC 8949) for attestation objects and COSE keys:
+// definite-length items only, which is all CTAP2 authenticators emit.
+// Maps decode to map[any]any keyed by int64 or string, integers to int64.
+
+var errCBOR = errors.New("webauthn: malformed CBOR")
+
+const cborMaxDepth = 16
+
+// cborDecode decodes one item from b and returns it with the bytes consumed.
+func cborDecode(b []byte) (any, int, error) {
+	d := cborDecoder{b: b}
+	v, err := d.item(0)
+	return v, d.off, err
+}
+
+type cborDecoder struct {
+	b   []byte
+	off int
+}
+
+func (d *cborDecoder) head() (major byte, arg uint64, err error) {
+	if d.off >= len(d.b) {
+		return 0, 0, errCBOR
+	}
+	ib := d.b[d.off]
+	d.off++
+	major, info := ib>>5, ib&0x1f
+	switch {
+	case info < 24:
+		return major, uint64(info), nil
+	case info <= 27:
+		n := 1 << (info - 24)
+		if d.off+n > len(d.b) {
+			return 0, 0, errCBOR
+		}
+		p := d.b[d.off : d.off+n]
+		d.off += n
+		switch n {
+		case 1:
+			arg = uint64(p[0])
+		case 2:
+			arg = uint64(binary.BigEndian.Uint16(p))
+		case 4:
+			arg = uint64(binary.BigEndian.Uint32(p))
+		default:
+			arg = binary.BigEndian.Uint64(p)
+		}
+		return major, arg, nil
+	}
+	return 0, 0, errCBOR // indefinite lengths and reserved values
+}
+
+func (d *cborDecoder) item(depth int) (any, error) {
+	if depth > cborMaxDepth {
+		return nil, errCBOR
+	}
+	major, arg, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+	switch major {
+	case 0:
+		if arg > math.MaxInt64 {
+			return nil, errCBOR
+		}
+		return int64(arg), nil
+	case 1:
+		if arg > math.MaxInt64 {
+			return nil, errCBOR
+		}
+		return -1 - int64(arg), nil
+	case 2, 3:
+		if arg > uint64(len(d.b)-d.off) {
+			return nil, errCBOR
+		}
+		p := d.b[d.off : d.off+int(arg)]
+		d.off += int(arg)
+		if major == 3 {
+			return string(p), nil
+		}
+		return append([]byte(nil), p...), nil
+	case 4:
+		if arg > uint64(len(d.b)-d.off) { // every item is at least one byte
+			return nil, errCBOR
+		}
+		out := make([]any, 0, arg)
+		for i := uint64(0); i < arg; i++ {
+			v, err := d.item(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+		}
+		return out, nil
+	case 5:
+		if arg > uint64(len(d.b)-d.off)/2 {
+			return nil, errCBOR
+		}
+		out := make(map[any]any, arg)
+		for i := uint64(0); i < arg; i++ {
+			k, err := d.item(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			switch k.(type) {
+			case int64, string:
+			default:
+				return nil, errCBOR
+			}
+			v, err := d.item(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = v
+		}
+		return out, nil
+	case 6: // tag: keep the content
+		return d.item(depth + 1)
+	case 7:
+		switch arg {
+		case 20:
+			return false, nil
+		case 21:
+			return true, nil
+		case 22, 23:
+			return nil, nil
+		}
+	}
+	return nil, errCBOR
+}
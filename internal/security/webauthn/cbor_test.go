@@ -0,0 +1,29 @@
+package webauthn
+
+import "testing"
+
+func TestCBORDecode(t *testing.T) {
+	// {1: 2, "a": [-1, h'0102', true]}
+	in := []byte{0xa2, 0x01, 0x02, 0x61, 'a', 0x83, 0x20, 0x42, 0x01, 0x02, 0xf5, 0xff}
+	v, n, err := cborDecode(in)
+	if err != nil || n != len(in)-1 {
+		t.Fatalf("n=%d err=%v", n, err)
+	}
+	m := v.(map[any]any)
+	arr := m["a"].([]any)
+	if m[int64(1)] != int64(2) || arr[0] != int64(-1) || string(arr[1].([]byte)) != "\x01\x02" || arr[2] != true {
+		t.Fatalf("decoded %#v", v)
+	}
+
+	for name, bad := range map[string][]byte{
+		"truncated":  {0x43, 0x01},
+		"indefinite": {0x5f, 0x41, 0x00, 0xff},
+		"huge array": {0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		"array key":  {0xa1, 0x80, 0x00},
+		"float":      {0xf9, 0x3c, 0x00},
+	} {
+		if _, _, err := cborDecode(bad); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
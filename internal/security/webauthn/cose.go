@@ -0,0 +1,99 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+)
+
+// COSE algorithm identifiers we accept (RFC 9053, RFC 8812).
+const (
+	AlgES256 = -7
+	AlgRS256 = -257
+)
+
+// COSE key parameters.
+const (
+	coseKty    = 1
+	coseAlg    = 3
+	coseCrv    = -1 // EC2
+	coseX      = -2 // EC2
+	coseY      = -3 // EC2
+	coseN      = -1 // RSA
+	coseE      = -2 // RSA
+	ktyEC2     = 2
+	ktyRSA     = 3
+	crvP256    = 1
+	minRSABits = 2048
+)
+
+var (
+	ErrUnsupportedKey = errors.New("webauthn: unsupported public key")
+	ErrBadSignature   = errors.New("webauthn: signature check failed")
+)
+
+// parseCOSEKey turns a COSE_Key into a Go public key and its algorithm.
+func parseCOSEKey(raw []byte) (crypto.PublicKey, int, error) {
+	v, n, err := cborDecode(raw)
+	if err != nil || n != len(raw) {
+		return nil, 0, ErrUnsupportedKey
+	}
+	m, ok := v.(map[any]any)
+	if !ok {
+		return nil, 0, ErrUnsupportedKey
+	}
+	kty, _ := m[int64(coseKty)].(int64)
+	alg, _ := m[int64(coseAlg)].(int64)
+
+	switch {
+	case kty == ktyEC2 && alg == AlgES256:
+		crv, _ := m[int64(coseCrv)].(int64)
+		x, _ := m[int64(coseX)].([]byte)
+		y, _ := m[int64(coseY)].([]byte)
+		if crv != crvP256 || len(x) != 32 || len(y) != 32 {
+			return nil, 0, ErrUnsupportedKey
+		}
+		pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, 0, ErrUnsupportedKey
+		}
+		return pub, AlgES256, nil
+
+	case kty == ktyRSA && alg == AlgRS256:
+		nb, _ := m[int64(coseN)].([]byte)
+		eb, _ := m[int64(coseE)].([]byte)
+		if len(nb)*8 < minRSABits || len(eb) == 0 || len(eb) > 4 {
+			return nil, 0, ErrUnsupportedKey
+		}
+		e := int(new(big.Int).SetBytes(eb).Int64())
+		if e < 3 || e%2 == 0 {
+			return nil, 0, ErrUnsupportedKey
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(nb), E: e}, AlgRS256, nil
+	}
+	return nil, 0, ErrUnsupportedKey
+}
+
+// verifySignature checks sig over data with a stored COSE key.
+func verifySignature(coseKey, data, sig []byte) error {
+	pub, _, err := parseCOSEKey(coseKey)
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256(data)
+	switch k := pub.(type) {
+	case *ecdsa.PublicKey:
+		if ecdsa.VerifyASN1(k, digest[:], sig) {
+			return nil
+		}
+	case *rsa.PublicKey:
+		if rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig) == nil {
+			return nil
+		}
+	}
+	return ErrBadSignature
+}
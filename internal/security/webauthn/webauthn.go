@@ -0,0 +1,412 @@
+// Package webauthn implements the relying-party side of WebAuthn passkeys:
+// registration with attestation "none", ES256/RS256 assertions and
+// sign-count checks. The JSON shapes match PublicKeyCredential.toJSON() and
+// the options browsers accept via PublicKeyCredential.parse*OptionsFromJSON,
+// with every binary field base64url-encoded.
+//
+// Ceremony state (the challenge) and credential storage are left to the
+// caller. See webauthntest for a software authenticator.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	ErrClientData = errors.New("webauthn: client data does not match the ceremony")
+	ErrAuthData   = errors.New("webauthn: malformed authenticator data")
+	ErrRPID       = errors.New("webauthn: credential is for another relying party")
+	ErrUserAbsent = errors.New("webauthn: user presence not confirmed")
+	ErrUnverified = errors.New("webauthn: user verification required")
+	ErrSignCount  = errors.New("webauthn: sign count did not increase; authenticator may be cloned")
+)
+
+// Authenticator data flags.
+const (
+	flagUP = 0x01 // user present
+	flagUV = 0x04 // user verified
+	flagAT = 0x40 // attested credential data included
+)
+
+// Config describes this relying party.
+type Config struct {
+	RPID    string   // registrable domain, e.g. "example.com"
+	RPName  string   // shown by the authenticator
+	Origins []string // accepted clientData origins, e.g. "https://app.example.com"
+	Timeout time.Duration
+	// RequireUserVerification rejects assertions without the UV flag
+	// (biometric or PIN), making a passkey a full second factor on its own.
+	RequireUserVerification bool
+}
+
+// ConfigFromEnv reads WEBAUTHN_RP_ID, WEBAUTHN_RP_NAME and WEBAUTHN_ORIGINS
+// (comma-separated; mobile apps add their android:apk-key-hash:… or
+// https app origins). RP ID and origin default to APP_BASE_URL.
+func ConfigFromEnv() Config {
+	c := Config{
+		RPID:                    os.Getenv("WEBAUTHN_RP_ID"),
+		RPName:                  os.Getenv("WEBAUTHN_RP_NAME"),
+		Timeout:                 5 * time.Minute,
+		RequireUserVerification: true,
+	}
+	for _, o := range strings.Split(os.Getenv("WEBAUTHN_ORIGINS"), ",") {
+		if o = strings.TrimSpace(o); o != "" {
+			c.Origins = append(c.Origins, strings.TrimRight(o, "/"))
+		}
+	}
+	app := strings.TrimRight(os.Getenv("APP_BASE_URL"), "/")
+	if app == "" {
+		app = "http://localhost:3000"
+	}
+	if len(c.Origins) == 0 {
+		c.Origins = []string{app}
+	}
+	if c.RPID == "" {
+		if u, err := url.Parse(c.Origins[0]); err == nil && u.Hostname() != "" {
+			c.RPID = u.Hostname()
+		} else {
+			c.RPID = "localhost"
+		}
+	}
+	if c.RPName == "" {
+		c.RPName = "Books"
+	}
+	return c
+}
+
+// --- options sent to the client ---
+
+type RPEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type UserEntity struct {
+	ID          string `json:"id"` // base64url user handle
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type CredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int    `json:"alg"`
+}
+
+type CredentialDescriptor struct {
+	Type       string   `json:"type"`
+	ID         string   `json:"id"` // base64url
+	Transports []string `json:"transports,omitempty"`
+}
+
+type AuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// CreationOptions is PublicKeyCredentialCreationOptionsJSON.
+type CreationOptions struct {
+	Challenge              string                 `json:"challenge"`
+	RP                     RPEntity               `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int                    `json:"timeout"`
+	Attestation            string                 `json:"attestation"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+}
+
+// RequestOptions is PublicKeyCredentialRequestOptionsJSON.
+type RequestOptions struct {
+	Challenge        string                 `json:"challenge"`
+	Timeout          int                    `json:"timeout"`
+	RPID             string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// --- credentials returned by the client ---
+
+// AttestationResponse is RegistrationResponseJSON.
+type AttestationResponse struct {
+	ID       string `json:"id"`
+	RawID    string `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string   `json:"clientDataJSON"`
+		AttestationObject string   `json:"attestationObject"`
+		Transports        []string `json:"transports,omitempty"`
+	} `json:"response"`
+}
+
+// AssertionResponse is AuthenticationResponseJSON.
+type AssertionResponse struct {
+	ID       string `json:"id"`
+	RawID    string `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON"`
+		AuthenticatorData string `json:"authenticatorData"`
+		Signature         string `json:"signature"`
+		UserHandle        string `json:"userHandle,omitempty"`
+	} `json:"response"`
+}
+
+// Credential is what a relying party stores per passkey.
+type Credential struct {
+	ID         []byte
+	PublicKey  []byte // COSE_Key
+	Algorithm  int
+	SignCount  uint32
+	AAGUID     []byte
+	Transports []string
+}
+
+// NewChallenge returns a fresh base64url challenge.
+func NewChallenge() (string, error) {
+	var b [32]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return Encode(b[:]), nil
+}
+
+// Encode is the base64url (unpadded) encoding WebAuthn JSON uses.
+func Encode(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+// Decode accepts base64url with or without padding.
+func Decode(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+// CreationOptions builds registration options for a user. exclude lists
+// credential IDs the user already has, so the same authenticator isn't
+// registered twice.
+func (c Config) CreationOptions(challenge string, userHandle []byte, name, displayName string, exclude [][]byte) CreationOptions {
+	opts := CreationOptions{
+		Challenge: challenge,
+		RP:        RPEntity{ID: c.RPID, Name: c.RPName},
+		User:      UserEntity{ID: Encode(userHandle), Name: name, DisplayName: displayName},
+		PubKeyCredParams: []CredentialParameter{
+			{Type: "public-key", Alg: AlgES256},
+			{Type: "public-key", Alg: AlgRS256},
+		},
+		Timeout:            int(c.Timeout / time.Millisecond),
+		Attestation:        "none",
+		ExcludeCredentials: []CredentialDescriptor{},
+		AuthenticatorSelection: AuthenticatorSelection{
+			ResidentKey:      "required",
+			UserVerification: c.userVerification(),
+		},
+	}
+	for _, id := range exclude {
+		opts.ExcludeCredentials = append(opts.ExcludeCredentials, CredentialDescriptor{Type: "public-key", ID: Encode(id)})
+	}
+	return opts
+}
+
+// RequestOptions builds sign-in options. An empty allow list asks for a
+// discoverable credential (the browser offers the user's passkeys).
+func (c Config) RequestOptions(challenge string, allow []CredentialDescriptor) RequestOptions {
+	if allow == nil {
+		allow = []CredentialDescriptor{}
+	}
+	return RequestOptions{
+		Challenge:        challenge,
+		Timeout:          int(c.Timeout / time.Millisecond),
+		RPID:             c.RPID,
+		AllowCredentials: allow,
+		UserVerification: c.userVerification(),
+	}
+}
+
+func (c Config) userVerification() string {
+	if c.RequireUserVerification {
+		return "required"
+	}
+	return "preferred"
+}
+
+type clientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+// Challenge extracts the challenge from a base64url clientDataJSON, so the
+// caller can look up ceremony state keyed by it before verifying.
+func Challenge(clientDataJSON string) (string, error) {
+	raw, err := Decode(clientDataJSON)
+	if err != nil {
+		return "", ErrClientData
+	}
+	var cd clientData
+	if err := json.Unmarshal(raw, &cd); err != nil || cd.Challenge == "" {
+		return "", ErrClientData
+	}
+	return cd.Challenge, nil
+}
+
+// checkClientData verifies type, challenge and origin and returns the raw
+// JSON for hashing.
+func (c Config) checkClientData(b64, typ, challenge string) ([]byte, error) {
+	raw, err := Decode(b64)
+	if err != nil {
+		return nil, ErrClientData
+	}
+	var cd clientData
+	if err := json.Unmarshal(raw, &cd); err != nil {
+		return nil, ErrClientData
+	}
+	if cd.Type != typ || cd.CrossOrigin || challenge == "" || cd.Challenge != strings.TrimRight(challenge, "=") {
+		return nil, ErrClientData
+	}
+	for _, o := range c.Origins {
+		if cd.Origin == o {
+			return raw, nil
+		}
+	}
+	return nil, ErrClientData
+}
+
+type authData struct {
+	rpIDHash  []byte
+	flags     byte
+	signCount uint32
+	aaguid    []byte
+	credID    []byte
+	coseKey   []byte
+}
+
+func parseAuthData(b []byte, wantCred bool) (authData, error) {
+	if len(b) < 37 {
+		return authData{}, ErrAuthData
+	}
+	ad := authData{rpIDHash: b[:32], flags: b[32], signCount: binary.BigEndian.Uint32(b[33:37])}
+	if !wantCred {
+		return ad, nil
+	}
+	if ad.flags&flagAT == 0 || len(b) < 37+18 {
+		return authData{}, ErrAuthData
+	}
+	rest := b[37:]
+	ad.aaguid = rest[:16]
+	n := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[18:]
+	if n == 0 || n > 1023 || len(rest) < n {
+		return authData{}, ErrAuthData
+	}
+	ad.credID, rest = rest[:n], rest[n:]
+	_, used, err := cborDecode(rest)
+	if err != nil {
+		return authData{}, ErrAuthData
+	}
+	ad.coseKey = rest[:used]
+	return ad, nil
+}
+
+func (c Config) checkAuthData(ad authData) error {
+	want := sha256.Sum256([]byte(c.RPID))
+	if !bytes.Equal(ad.rpIDHash, want[:]) {
+		return ErrRPID
+	}
+	if ad.flags&flagUP == 0 {
+		return ErrUserAbsent
+	}
+	if c.RequireUserVerification && ad.flags&flagUV == 0 {
+		return ErrUnverified
+	}
+	return nil
+}
+
+// VerifyRegistration checks a registration against the challenge that was
+// issued for it. Attestation statements are not verified: we ask for
+// attestation "none" and don't restrict authenticator models.
+func (c Config) VerifyRegistration(resp AttestationResponse, challenge string) (Credential, error) {
+	if resp.Type != "public-key" {
+		return Credential{}, ErrClientData
+	}
+	if _, err := c.checkClientData(resp.Response.ClientDataJSON, "webauthn.create", challenge); err != nil {
+		return Credential{}, err
+	}
+	rawAtt, err := Decode(resp.Response.AttestationObject)
+	if err != nil {
+		return Credential{}, ErrAuthData
+	}
+	v, _, err := cborDecode(rawAtt)
+	if err != nil {
+		return Credential{}, ErrAuthData
+	}
+	att, _ := v.(map[any]any)
+	rawAuth, _ := att["authData"].([]byte)
+	ad, err := parseAuthData(rawAuth, true)
+	if err != nil {
+		return Credential{}, err
+	}
+	if err := c.checkAuthData(ad); err != nil {
+		return Credential{}, err
+	}
+	if rawID, err := Decode(resp.RawID); err != nil || !bytes.Equal(rawID, ad.credID) {
+		return Credential{}, ErrAuthData
+	}
+	_, alg, err := parseCOSEKey(ad.coseKey)
+	if err != nil {
+		return Credential{}, err
+	}
+	return Credential{
+		ID:         append([]byte(nil), ad.credID...),
+		PublicKey:  append([]byte(nil), ad.coseKey...),
+		Algorithm:  alg,
+		SignCount:  ad.signCount,
+		AAGUID:     append([]byte(nil), ad.aaguid...),
+		Transports: resp.Response.Transports,
+	}, nil
+}
+
+// VerifyAssertion checks a sign-in against the issued challenge and the
+// stored credential, and returns the authenticator's new sign count to
+// store. A counter that fails to increase is rejected as a likely clone;
+// authenticators that don't count (always 0) are allowed.
+func (c Config) VerifyAssertion(resp AssertionResponse, challenge string, cred Credential) (uint32, error) {
+	if rawID, err := Decode(resp.RawID); resp.Type != "public-key" || err != nil || !bytes.Equal(rawID, cred.ID) {
+		return 0, ErrClientData
+	}
+	cdRaw, err := c.checkClientData(resp.Response.ClientDataJSON, "webauthn.get", challenge)
+	if err != nil {
+		return 0, err
+	}
+	rawAuth, err := Decode(resp.Response.AuthenticatorData)
+	if err != nil {
+		return 0, ErrAuthData
+	}
+	ad, err := parseAuthData(rawAuth, false)
+	if err != nil {
+		return 0, err
+	}
+	if err := c.checkAuthData(ad); err != nil {
+		return 0, err
+	}
+	sig, err := Decode(resp.Response.Signature)
+	if err != nil {
+		return 0, ErrBadSignature
+	}
+	cdHash := sha256.Sum256(cdRaw)
+	signed := append(append([]byte(nil), rawAuth...), cdHash[:]...)
+	if err := verifySignature(cred.PublicKey, signed, sig); err != nil {
+		return 0, err
+	}
+	if (ad.signCount != 0 || cred.SignCount != 0) && ad.signCount <= cred.SignCount {
+		return 0, ErrSignCount
+	}
+	return ad.signCount, nil
+}
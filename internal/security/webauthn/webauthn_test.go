@@ -0,0 +1,120 @@
+package webauthn_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/5w1tchy/books-api/internal/security/webauthn"
+	"github.com/5w1tchy/books-api/internal/security/webauthn/webauthntest"
+)
+
+var rp = webauthn.Config{
+	RPID:                    "books.example",
+	RPName:                  "Books",
+	Origins:                 []string{"https://books.example"},
+	Timeout:                 time.Minute,
+	RequireUserVerification: true,
+}
+
+func register(t *testing.T, a *webauthntest.Authenticator) webauthn.Credential {
+	t.Helper()
+	ch, _ := webauthn.NewChallenge()
+	resp, err := a.Create(rp.CreationOptions(ch, []byte("user-1"), "ana@example.com", "ana", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cred, err := rp.VerifyRegistration(resp, ch)
+	if err != nil {
+		t.Fatalf("VerifyRegistration: %v", err)
+	}
+	return cred
+}
+
+func signIn(t *testing.T, a *webauthntest.Authenticator, cred webauthn.Credential) (uint32, error) {
+	t.Helper()
+	ch, _ := webauthn.NewChallenge()
+	resp, err := a.Get(rp.RequestOptions(ch, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := webauthn.Challenge(resp.Response.ClientDataJSON); err != nil || got != ch {
+		t.Fatalf("Challenge = %q, %v", got, err)
+	}
+	return rp.VerifyAssertion(resp, ch, cred)
+}
+
+func TestCeremonies(t *testing.T) {
+	for name, alg := range map[string]int{"ES256": webauthn.AlgES256, "RS256": webauthn.AlgRS256} {
+		t.Run(name, func(t *testing.T) {
+			a, err := webauthntest.New("https://books.example", alg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cred := register(t, a)
+			if cred.Algorithm != alg || string(cred.ID) != string(a.CredentialID()) {
+				t.Fatalf("credential = %+v", cred)
+			}
+			count, err := signIn(t, a, cred)
+			if err != nil || count != 1 {
+				t.Fatalf("sign-in: count=%d err=%v", count, err)
+			}
+		})
+	}
+}
+
+func TestSignCountMustIncrease(t *testing.T) {
+	a, _ := webauthntest.New("https://books.example", webauthn.AlgES256)
+	cred := register(t, a)
+	cred.SignCount = 5
+	a.SignCount = 4 // next assertion reports 5
+	if _, err := signIn(t, a, cred); !errors.Is(err, webauthn.ErrSignCount) {
+		t.Fatalf("err = %v, want ErrSignCount", err)
+	}
+
+	// Authenticators without a counter always report 0
+	cred.SignCount = 0
+	a.SignCount = ^uint32(0) // wraps to 0 on the next Get
+	if _, err := signIn(t, a, cred); err != nil {
+		t.Fatalf("zero counter: %v", err)
+	}
+}
+
+func TestRejects(t *testing.T) {
+	a, _ := webauthntest.New("https://books.example", webauthn.AlgES256)
+	cred := register(t, a)
+
+	ch, _ := webauthn.NewChallenge()
+	resp, _ := a.Get(rp.RequestOptions(ch, nil))
+	other, _ := webauthn.NewChallenge()
+	if _, err := rp.VerifyAssertion(resp, other, cred); !errors.Is(err, webauthn.ErrClientData) {
+		t.Errorf("wrong challenge: %v", err)
+	}
+
+	evil := rp
+	evil.Origins = []string{"https://evil.example"}
+	resp, _ = a.Get(rp.RequestOptions(ch, nil))
+	if _, err := evil.VerifyAssertion(resp, ch, cred); !errors.Is(err, webauthn.ErrClientData) {
+		t.Errorf("wrong origin: %v", err)
+	}
+
+	resp, _ = a.Get(rp.RequestOptions(ch, nil))
+	resp.Response.Signature = resp.Response.Signature[:len(resp.Response.Signature)-4] + "AAAA"
+	if _, err := rp.VerifyAssertion(resp, ch, cred); !errors.Is(err, webauthn.ErrBadSignature) {
+		t.Errorf("tampered signature: %v", err)
+	}
+
+	a.UserVerified = false
+	resp, _ = a.Get(rp.RequestOptions(ch, nil))
+	if _, err := rp.VerifyAssertion(resp, ch, cred); !errors.Is(err, webauthn.ErrUnverified) {
+		t.Errorf("no UV: %v", err)
+	}
+
+	b, _ := webauthntest.New("https://books.example", webauthn.AlgES256)
+	opts := rp.CreationOptions(ch, []byte("user-1"), "ana", "ana", nil)
+	opts.RP.ID = "other.example"
+	reg, _ := b.Create(opts)
+	if _, err := rp.VerifyRegistration(reg, ch); !errors.Is(err, webauthn.ErrRPID) {
+		t.Errorf("wrong RP ID: %v", err)
+	}
+}
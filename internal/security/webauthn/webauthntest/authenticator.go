@@ -0,0 +1,205 @@
+// Package webauthntest provides a software authenticator for exercising
+// passkey registration and sign-in without a browser, the way httptest
+// stands in for a network.
+package webauthntest
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math/big"
+
+	"github.com/5w1tchy/books-api/internal/security/webauthn"
+)
+
+// Authenticator holds a single credential, created by Create.
+type Authenticator struct {
+	Origin string // reported in clientDataJSON
+	Alg    int    // webauthn.AlgES256 or webauthn.AlgRS256
+	// UserVerified sets the UV flag (default true from New).
+	UserVerified bool
+	// SignCount is the counter reported by the next ceremony; Get
+	// increments it first. Set it back to simulate a cloned authenticator.
+	SignCount uint32
+
+	rpID       string
+	credID     []byte
+	userHandle []byte
+	key        crypto.Signer
+}
+
+// New returns an authenticator that signs with a fresh key of alg.
+func New(origin string, alg int) (*Authenticator, error) {
+	var (
+		key crypto.Signer
+		err error
+	)
+	switch alg {
+	case webauthn.AlgES256:
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case webauthn.AlgRS256:
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+	default:
+		return nil, errors.New("webauthntest: unsupported algorithm")
+	}
+	if err != nil {
+		return nil, err
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	return &Authenticator{Origin: origin, Alg: alg, UserVerified: true, credID: id, key: key}, nil
+}
+
+// CredentialID is the raw credential ID.
+func (a *Authenticator) CredentialID() []byte { return a.credID }
+
+// Create answers navigator.credentials.create() with attestation "none".
+func (a *Authenticator) Create(opts webauthn.CreationOptions) (webauthn.AttestationResponse, error) {
+	var out webauthn.AttestationResponse
+	handle, err := webauthn.Decode(opts.User.ID)
+	if err != nil {
+		return out, err
+	}
+	a.rpID, a.userHandle = opts.RP.ID, handle
+
+	cd, err := a.clientData("webauthn.create", opts.Challenge)
+	if err != nil {
+		return out, err
+	}
+	ad := a.authData(0x40)
+	ad = append(ad, make([]byte, 16)...) // AAGUID: zero for "none"
+	ad = binary.BigEndian.AppendUint16(ad, uint16(len(a.credID)))
+	ad = append(ad, a.credID...)
+	ad = append(ad, a.coseKey()...)
+
+	att := cborMap(
+		cborText("fmt"), cborText("none"),
+		cborText("attStmt"), cborMap(),
+		cborText("authData"), cborBytes(ad),
+	)
+	out.ID = webauthn.Encode(a.credID)
+	out.RawID = out.ID
+	out.Type = "public-key"
+	out.Response.ClientDataJSON = webauthn.Encode(cd)
+	out.Response.AttestationObject = webauthn.Encode(att)
+	out.Response.Transports = []string{"internal"}
+	return out, nil
+}
+
+// Get answers navigator.credentials.get().
+func (a *Authenticator) Get(opts webauthn.RequestOptions) (webauthn.AssertionResponse, error) {
+	var out webauthn.AssertionResponse
+	if a.rpID == "" {
+		return out, errors.New("webauthntest: no credential; call Create first")
+	}
+	a.SignCount++
+	cd, err := a.clientData("webauthn.get", opts.Challenge)
+	if err != nil {
+		return out, err
+	}
+	ad := a.authData(0)
+	cdHash := sha256.Sum256(cd)
+	digest := sha256.Sum256(append(append([]byte(nil), ad...), cdHash[:]...))
+	var sig []byte
+	switch k := a.key.(type) {
+	case *ecdsa.PrivateKey:
+		sig, err = ecdsa.SignASN1(rand.Reader, k, digest[:])
+	case *rsa.PrivateKey:
+		sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+	}
+	if err != nil {
+		return out, err
+	}
+	out.ID = webauthn.Encode(a.credID)
+	out.RawID = out.ID
+	out.Type = "public-key"
+	out.Response.ClientDataJSON = webauthn.Encode(cd)
+	out.Response.AuthenticatorData = webauthn.Encode(ad)
+	out.Response.Signature = webauthn.Encode(sig)
+	out.Response.UserHandle = webauthn.Encode(a.userHandle)
+	return out, nil
+}
+
+func (a *Authenticator) clientData(typ, challenge string) ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"type":        typ,
+		"challenge":   challenge,
+		"origin":      a.Origin,
+		"crossOrigin": false,
+	})
+}
+
+func (a *Authenticator) authData(extra byte) []byte {
+	rp := sha256.Sum256([]byte(a.rpID))
+	flags := byte(0x01) | extra
+	if a.UserVerified {
+		flags |= 0x04
+	}
+	ad := append(rp[:], flags)
+	return binary.BigEndian.AppendUint32(ad, a.SignCount)
+}
+
+func (a *Authenticator) coseKey() []byte {
+	switch k := a.key.(type) {
+	case *ecdsa.PrivateKey:
+		return cborMap(
+			cborInt(1), cborInt(2), // kty: EC2
+			cborInt(3), cborInt(webauthn.AlgES256),
+			cborInt(-1), cborInt(1), // crv: P-256
+			cborInt(-2), cborBytes(k.X.FillBytes(make([]byte, 32))),
+			cborInt(-3), cborBytes(k.Y.FillBytes(make([]byte, 32))),
+		)
+	case *rsa.PrivateKey:
+		return cborMap(
+			cborInt(1), cborInt(3), // kty: RSA
+			cborInt(3), cborInt(webauthn.AlgRS256),
+			cborInt(-1), cborBytes(k.N.Bytes()),
+			cborInt(-2), cborBytes(big.NewInt(int64(k.E)).Bytes()),
+		)
+	}
+	return nil
+}
+
+// --- minimal CBOR encoding ---
+
+func cborHead(major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return []byte{major<<5 | byte(n)}
+	case n <= 0xff:
+		return []byte{major<<5 | 24, byte(n)}
+	case n <= 0xffff:
+		return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(n))
+	case n <= 0xffffffff:
+		return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(n))
+	}
+	return binary.BigEndian.AppendUint64([]byte{major<<5 | 27}, n)
+}
+
+func cborInt(i int) []byte {
+	if i < 0 {
+		return cborHead(1, uint64(-1-i))
+	}
+	return cborHead(0, uint64(i))
+}
+
+func cborBytes(b []byte) []byte { return append(cborHead(2, uint64(len(b))), b...) }
+
+func cborText(s string) []byte { return append(cborHead(3, uint64(len(s))), s...) }
+
+// cborMap takes encoded keys and values alternately.
+func cborMap(kv ...[]byte) []byte {
+	out := cborHead(5, uint64(len(kv)/2))
+	for _, p := range kv {
+		out = append(out, p...)
+	}
+	return out
+}
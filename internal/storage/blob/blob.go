@@ -0,0 +1,145 @@
+// Package blob defines the object storage the API depends on. The store is
+// built once at startup (R2/S3, local filesystem or in-memory) and injected
+// into handlers; nothing else should construct storage clients.
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Errors returned by Get (and Head where applicable).
+var (
+	ErrNotFound           = errors.New("blob: object not found")
+	ErrNotModified        = errors.New("blob: not modified")
+	ErrPreconditionFailed = errors.New("blob: precondition failed")
+	ErrInvalidRange       = errors.New("blob: invalid range")
+)
+
+// Info describes a stored object.
+type Info struct {
+	Key            string
+	Size           int64
+	ContentType    string
+	ETag           string // quoted, as in HTTP
+	ChecksumSHA256 string // base64; empty unless known
+	LastModified   time.Time
+}
+
+// GetOptions are HTTP-style range and conditional parameters; zero values are omitted.
+type GetOptions struct {
+	Range             string // a single "bytes=a-b", "bytes=a-" or "bytes=-n"
+	IfMatch           string
+	IfNoneMatch       string
+	IfUnmodifiedSince time.Time
+}
+
+// Object is a (possibly partial) object body. The caller must close Body.
+type Object struct {
+	Info
+	Body          io.ReadCloser
+	ContentLength int64  // bytes in Body
+	ContentRange  string // set only for partial responses
+}
+
+// PutOptions constrain a presigned upload. Size and SHA256 (base64) are
+// enforced when set.
+type PutOptions struct {
+	ContentType string
+	Size        int64
+	SHA256      string
+	TTL         time.Duration
+}
+
+// PresignedPut is an upload URL plus the headers the client must send.
+type PresignedPut struct {
+	URL     string
+	Headers map[string]string
+	Expires time.Time
+}
+
+// BlobStore is the storage backend.
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string, opt GetOptions) (*Object, error)
+	Head(ctx context.Context, key string) (Info, error)
+	Delete(ctx context.Context, key string) error
+	// List returns objects under prefix; shallow skips keys below the next '/'.
+	List(ctx context.Context, prefix string, shallow bool) ([]Info, error)
+	PresignGet(ctx context.Context, key string, ttl time.Duration) (string, error)
+	PresignPut(ctx context.Context, key string, opt PutOptions) (PresignedPut, error)
+}
+
+// Part is one uploaded chunk of a multipart upload.
+type Part struct {
+	Number int32  `json:"n"`
+	ETag   string `json:"etag"`
+	Size   int64  `json:"size"`
+}
+
+// MinPartSize is the smallest size accepted for every part but the last.
+const MinPartSize = 5 << 20
+
+// Multipart is implemented by stores that can assemble an object from parts
+// (used by resumable uploads). All stores in this repo implement it.
+type Multipart interface {
+	CreateMultipart(ctx context.Context, key, contentType string) (uploadID string, err error)
+	// UploadPart stores one part; re-uploading a number replaces it.
+	UploadPart(ctx context.Context, key, uploadID string, number int32, data []byte) (Part, error)
+	CompleteMultipart(ctx context.Context, key, uploadID string, parts []Part) error
+	AbortMultipart(ctx context.Context, key, uploadID string) error
+}
+
+// DefaultPresignTTL matches what clients have always been given.
+const DefaultPresignTTL = 15 * time.Minute
+
+// ReadAll downloads a (small) object fully into memory and returns its content type.
+func ReadAll(ctx context.Context, s BlobStore, key string) ([]byte, string, error) {
+	obj, err := s.Get(ctx, key, GetOptions{})
+	if err != nil {
+		return nil, "", err
+	}
+	defer obj.Body.Close()
+	data, err := io.ReadAll(obj.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("blob: read %s: %w", key, err)
+	}
+	return data, obj.ContentType, nil
+}
+
+// NewReaderAt exposes an object as an io.ReaderAt backed by ranged reads, so
+// header probes can inspect large objects without downloading them.
+func NewReaderAt(ctx context.Context, s BlobStore, key string) io.ReaderAt {
+	return &readerAt{ctx: ctx, s: s, key: key}
+}
+
+type readerAt struct {
+	ctx context.Context
+	s   BlobStore
+	key string
+}
+
+func (o *readerAt) ReadAt(p []byte, off int64) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	obj, err := o.s.Get(o.ctx, o.key, GetOptions{
+		Range: fmt.Sprintf("bytes=%d-%d", off, off+int64(len(p))-1),
+	})
+	if errors.Is(err, ErrInvalidRange) {
+		return 0, io.EOF
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer obj.Body.Close()
+
+	n, err := io.ReadFull(obj.Body, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
@@ -0,0 +1,300 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type store interface {
+	BlobStore
+	Multipart
+}
+
+func backends(t *testing.T) map[string]store {
+	fs, err := NewFS(t.TempDir(), "http://api.test", []byte("0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]store{"memory": NewMemory(), "fs": fs}
+}
+
+func readBody(t *testing.T, obj *Object) string {
+	t.Helper()
+	defer obj.Body.Close()
+	b, err := io.ReadAll(obj.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestPutGetHeadDelete(t *testing.T) {
+	ctx := context.Background()
+	for name, s := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			if err := s.Put(ctx, "books/a.mp3", strings.NewReader("hello world"), 11, "audio/mpeg"); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Put(ctx, "books/short.mp3", strings.NewReader("abc"), 5, "audio/mpeg"); err == nil {
+				t.Fatal("size mismatch accepted")
+			}
+
+			info, err := s.Head(ctx, "books/a.mp3")
+			if err != nil {
+				t.Fatal(err)
+			}
+			sum := sha256.Sum256([]byte("hello world"))
+			if info.Size != 11 || info.ContentType != "audio/mpeg" || info.ETag == "" ||
+				info.ChecksumSHA256 != base64.StdEncoding.EncodeToString(sum[:]) {
+				t.Fatalf("head = %+v", info)
+			}
+
+			data, ct, err := ReadAll(ctx, s, "books/a.mp3")
+			if err != nil || string(data) != "hello world" || ct != "audio/mpeg" {
+				t.Fatalf("ReadAll = %q, %q, %v", data, ct, err)
+			}
+
+			if err := s.Delete(ctx, "books/a.mp3"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.Head(ctx, "books/a.mp3"); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("head after delete: %v", err)
+			}
+			if _, err := s.Get(ctx, "books/a.mp3", GetOptions{}); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("get after delete: %v", err)
+			}
+			if err := s.Delete(ctx, "books/a.mp3"); err != nil {
+				t.Fatalf("deleting a missing object: %v", err)
+			}
+		})
+	}
+}
+
+func TestGetRangesAndConditionals(t *testing.T) {
+	ctx := context.Background()
+	for name, s := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			_ = s.Put(ctx, "k", strings.NewReader("0123456789"), 10, "text/plain")
+			info, _ := s.Head(ctx, "k")
+
+			cases := []struct {
+				rng, body, cr string
+			}{
+				{"bytes=2-4", "234", "bytes 2-4/10"},
+				{"bytes=7-", "789", "bytes 7-9/10"},
+				{"bytes=-3", "789", "bytes 7-9/10"},
+				{"bytes=8-100", "89", "bytes 8-9/10"},
+			}
+			for _, c := range cases {
+				obj, err := s.Get(ctx, "k", GetOptions{Range: c.rng})
+				if err != nil {
+					t.Fatalf("%s: %v", c.rng, err)
+				}
+				if obj.ContentRange != c.cr || obj.ContentLength != int64(len(c.body)) {
+					t.Fatalf("%s: range %q len %d", c.rng, obj.ContentRange, obj.ContentLength)
+				}
+				if got := readBody(t, obj); got != c.body {
+					t.Fatalf("%s: body %q", c.rng, got)
+				}
+			}
+			if _, err := s.Get(ctx, "k", GetOptions{Range: "bytes=10-"}); !errors.Is(err, ErrInvalidRange) {
+				t.Fatalf("unsatisfiable range: %v", err)
+			}
+
+			if _, err := s.Get(ctx, "k", GetOptions{IfNoneMatch: info.ETag}); !errors.Is(err, ErrNotModified) {
+				t.Fatalf("If-None-Match: %v", err)
+			}
+			if _, err := s.Get(ctx, "k", GetOptions{IfMatch: `"nope"`}); !errors.Is(err, ErrPreconditionFailed) {
+				t.Fatalf("If-Match: %v", err)
+			}
+			obj, err := s.Get(ctx, "k", GetOptions{Range: "bytes=0-0", IfMatch: info.ETag})
+			if err != nil || readBody(t, obj) != "0" {
+				t.Fatalf("If-Match with current etag: %v", err)
+			}
+			past := info.LastModified.Add(-time.Hour)
+			if _, err := s.Get(ctx, "k", GetOptions{IfUnmodifiedSince: past}); !errors.Is(err, ErrPreconditionFailed) {
+				t.Fatalf("If-Unmodified-Since: %v", err)
+			}
+
+			ra := NewReaderAt(ctx, s, "k")
+			buf := make([]byte, 4)
+			if n, err := ra.ReadAt(buf, 8); n != 2 || err != io.EOF || string(buf[:n]) != "89" {
+				t.Fatalf("ReadAt tail = %d %v %q", n, err, buf[:n])
+			}
+			if n, err := ra.ReadAt(buf, 20); n != 0 || err != io.EOF {
+				t.Fatalf("ReadAt past end = %d %v", n, err)
+			}
+		})
+	}
+}
+
+func TestList(t *testing.T) {
+	ctx := context.Background()
+	for name, s := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			for _, k := range []string{"books/a.mp3", "books/b.vtt", "books/covers/c.jpg", "other/d"} {
+				_ = s.Put(ctx, k, strings.NewReader("x"), 1, "application/octet-stream")
+			}
+			keys := func(infos []Info) string {
+				var ks []string
+				for _, i := range infos {
+					ks = append(ks, i.Key)
+				}
+				return strings.Join(ks, ",")
+			}
+			deep, err := s.List(ctx, "books/", false)
+			if err != nil || keys(deep) != "books/a.mp3,books/b.vtt,books/covers/c.jpg" {
+				t.Fatalf("deep list = %s, %v", keys(deep), err)
+			}
+			shallow, err := s.List(ctx, "books/", true)
+			if err != nil || keys(shallow) != "books/a.mp3,books/b.vtt" {
+				t.Fatalf("shallow list = %s, %v", keys(shallow), err)
+			}
+			if shallow[0].Size != 1 || shallow[0].LastModified.IsZero() {
+				t.Fatalf("listed info = %+v", shallow[0])
+			}
+		})
+	}
+}
+
+func TestMultipart(t *testing.T) {
+	ctx := context.Background()
+	first := bytes.Repeat([]byte("a"), MinPartSize)
+	for name, s := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			id, err := s.CreateMultipart(ctx, "books/big.mp3", "audio/mpeg")
+			if err != nil {
+				t.Fatal(err)
+			}
+			p2, err := s.UploadPart(ctx, "books/big.mp3", id, 2, []byte("tail"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			p1, err := s.UploadPart(ctx, "books/big.mp3", id, 1, first)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := s.CompleteMultipart(ctx, "books/big.mp3", id, []Part{p1, p2}); err != nil {
+				t.Fatal(err)
+			}
+			info, err := s.Head(ctx, "books/big.mp3")
+			if err != nil || info.Size != int64(MinPartSize+4) || info.ContentType != "audio/mpeg" {
+				t.Fatalf("assembled = %+v, %v", info, err)
+			}
+			obj, _ := s.Get(ctx, "books/big.mp3", GetOptions{Range: "bytes=-5"})
+			if got := readBody(t, obj); got != "atail" {
+				t.Fatalf("assembled tail = %q", got)
+			}
+
+			// A non-final part below the minimum is rejected, like S3.
+			id, _ = s.CreateMultipart(ctx, "books/small.mp3", "audio/mpeg")
+			q1, _ := s.UploadPart(ctx, "books/small.mp3", id, 1, []byte("x"))
+			q2, _ := s.UploadPart(ctx, "books/small.mp3", id, 2, []byte("y"))
+			if err := s.CompleteMultipart(ctx, "books/small.mp3", id, []Part{q1, q2}); err == nil {
+				t.Fatal("small non-final part accepted")
+			}
+
+			id, _ = s.CreateMultipart(ctx, "books/gone.mp3", "audio/mpeg")
+			if err := s.AbortMultipart(ctx, "books/gone.mp3", id); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.UploadPart(ctx, "books/gone.mp3", id, 1, []byte("x")); err == nil {
+				t.Fatal("upload to aborted multipart accepted")
+			}
+		})
+	}
+}
+
+func TestFSPresignedURLs(t *testing.T) {
+	ctx := context.Background()
+	fs, err := NewFS(t.TempDir(), "", []byte("0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(fs)
+	defer srv.Close()
+	fs.baseURL = srv.URL
+
+	body := []byte("ID3 fake audio")
+	sum := sha256.Sum256(body)
+	sha := base64.StdEncoding.EncodeToString(sum[:])
+	put, err := fs.PresignPut(ctx, "books/x.mp3", PutOptions{ContentType: "audio/mpeg", Size: int64(len(body)), SHA256: sha})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(put.URL, srv.URL+Prefix+"books/x.mp3?") {
+		t.Fatalf("put url = %s", put.URL)
+	}
+
+	do := func(method, url string, body []byte, headers map[string]string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(method, url, bytes.NewReader(body))
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		return res
+	}
+
+	// Wrong content type, then a body whose checksum differs from the signed one.
+	bad := map[string]string{"Content-Type": "image/png", "x-amz-checksum-sha256": sha}
+	if res := do(http.MethodPut, put.URL, body, bad); res.StatusCode != http.StatusForbidden {
+		t.Fatalf("wrong content type: %d", res.StatusCode)
+	}
+	if res := do(http.MethodPut, put.URL, []byte("ID3 fake audiO"), put.Headers); res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("checksum mismatch: %d", res.StatusCode)
+	}
+	if _, err := fs.Head(ctx, "books/x.mp3"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("rejected upload was stored: %v", err)
+	}
+	if res := do(http.MethodPut, put.URL, body, put.Headers); res.StatusCode != http.StatusOK {
+		t.Fatalf("upload: %d", res.StatusCode)
+	}
+
+	get, _ := fs.PresignGet(ctx, "books/x.mp3", time.Minute)
+	res, err := http.Get(get)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK || !bytes.Equal(got, body) || res.Header.Get("Content-Type") != "audio/mpeg" {
+		t.Fatalf("download: %d %q %s", res.StatusCode, got, res.Header.Get("Content-Type"))
+	}
+
+	// Tampering with the key or the expiry breaks the signature.
+	if res := do(http.MethodGet, strings.Replace(get, "x.mp3", "y.mp3", 1), nil, nil); res.StatusCode != http.StatusForbidden {
+		t.Fatalf("tampered key: %d", res.StatusCode)
+	}
+	fs.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	if res := do(http.MethodGet, get, nil, nil); res.StatusCode != http.StatusForbidden {
+		t.Fatalf("expired url: %d", res.StatusCode)
+	}
+	// A GET signature can't be used to upload.
+	fs.now = time.Now
+	if res := do(http.MethodPut, get, body, put.Headers); res.StatusCode != http.StatusForbidden {
+		t.Fatalf("get url used for put: %d", res.StatusCode)
+	}
+}
+
+func TestFSRejectsEscapingKeys(t *testing.T) {
+	fs, _ := NewFS(t.TempDir(), "", []byte("0123456789abcdef"))
+	for _, k := range []string{"../x", "a/../../x", "/abs", "dir/", ""} {
+		if err := fs.Put(context.Background(), k, strings.NewReader("x"), 1, ""); err == nil {
+			t.Errorf("key %q accepted", k)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package blob
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// resolveGet applies conditionals and the range the way S3 does, for the
+// stores that serve bytes themselves. It returns the byte window to send.
+func resolveGet(info Info, opt GetOptions) (off, n int64, contentRange string, err error) {
+	if opt.IfMatch != "" && !etagMatches(opt.IfMatch, info.ETag) {
+		return 0, 0, "", ErrPreconditionFailed
+	}
+	if !opt.IfUnmodifiedSince.IsZero() && info.LastModified.Truncate(time.Second).After(opt.IfUnmodifiedSince) {
+		return 0, 0, "", ErrPreconditionFailed
+	}
+	if opt.IfNoneMatch != "" && etagMatches(opt.IfNoneMatch, info.ETag) {
+		return 0, 0, "", ErrNotModified
+	}
+	if opt.Range == "" {
+		return 0, info.Size, "", nil
+	}
+
+	spec, ok := strings.CutPrefix(opt.Range, "bytes=")
+	first, last, ok2 := strings.Cut(spec, "-")
+	if !ok || !ok2 {
+		return 0, 0, "", ErrInvalidRange
+	}
+	size := info.Size
+	var start, end int64
+	if first == "" {
+		suffix, perr := strconv.ParseInt(last, 10, 64)
+		if perr != nil || suffix <= 0 || size == 0 {
+			return 0, 0, "", ErrInvalidRange
+		}
+		start, end = max(size-suffix, 0), size-1
+	} else {
+		start, err = strconv.ParseInt(first, 10, 64)
+		if err != nil || start >= size {
+			return 0, 0, "", ErrInvalidRange
+		}
+		end = size - 1
+		if last != "" {
+			e, perr := strconv.ParseInt(last, 10, 64)
+			if perr != nil || e < start {
+				return 0, 0, "", ErrInvalidRange
+			}
+			end = min(e, size-1)
+		}
+	}
+	return start, end - start + 1, fmt.Sprintf("bytes %d-%d/%d", start, end, size), nil
+}
+
+func etagMatches(header, etag string) bool {
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimPrefix(strings.TrimSpace(t), "W/")
+		if t == "*" || t == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// digests returns the quoted MD5 ETag (what S3 uses for single-part objects)
+// and the base64 SHA-256 of data.
+func digests(data []byte) (etag, sha string) {
+	m := md5.Sum(data)
+	s := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(m[:]) + `"`, base64.StdEncoding.EncodeToString(s[:])
+}
@@ -0,0 +1,419 @@
+package blob
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FS stores objects on the local filesystem. Presigned URLs point back at the
+// API (mount FS as an http.Handler under Prefix) and carry an HMAC signature
+// over the operation, key, expiry and upload constraints.
+//
+// Layout under root: objects/<key>, meta/<key>.json, multipart/<id>/<n>.
+type FS struct {
+	root    string
+	baseURL string // e.g. https://api.example.com (no trailing slash)
+	secret  []byte
+	now     func() time.Time
+}
+
+// Prefix is the URL path presigned FS URLs are served under.
+const Prefix = "/blobs/"
+
+type fsMeta struct {
+	ContentType string `json:"content_type"`
+	ETag        string `json:"etag"`
+	SHA256      string `json:"sha256"`
+}
+
+// NewFS creates the directory layout under root. secret signs URLs.
+func NewFS(root, baseURL string, secret []byte) (*FS, error) {
+	if len(secret) < 16 {
+		return nil, errors.New("blob: fs signing secret must be at least 16 bytes")
+	}
+	for _, d := range []string{"objects", "meta", "multipart"} {
+		if err := os.MkdirAll(filepath.Join(root, d), 0o755); err != nil {
+			return nil, err
+		}
+	}
+	return &FS{root: root, baseURL: strings.TrimSuffix(baseURL, "/"), secret: secret, now: time.Now}, nil
+}
+
+func (f *FS) objectPath(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.HasSuffix(key, "/") || path.Clean(key) != key || strings.HasPrefix(key, "..") {
+		return "", fmt.Errorf("blob: invalid key %q", key)
+	}
+	return filepath.Join(f.root, "objects", filepath.FromSlash(key)), nil
+}
+
+func (f *FS) metaPath(key string) string {
+	return filepath.Join(f.root, "meta", filepath.FromSlash(key)+".json")
+}
+
+func (f *FS) Put(_ context.Context, key string, r io.Reader, size int64, contentType string) error {
+	_, err := f.write(key, r, size, contentType, "")
+	return err
+}
+
+// write streams r to a temp file, checks size and (optionally) SHA-256, then
+// renames it into place so readers never see partial objects.
+func (f *FS) write(key string, r io.Reader, size int64, contentType, wantSHA string) (Info, error) {
+	p, err := f.objectPath(key)
+	if err != nil {
+		return Info{}, err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return Info{}, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".tmp-*")
+	if err != nil {
+		return Info{}, err
+	}
+	defer os.Remove(tmp.Name())
+
+	m, s := md5.New(), sha256.New()
+	n, err := io.Copy(io.MultiWriter(tmp, m, s), r)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return Info{}, err
+	}
+	if size >= 0 && n != size {
+		return Info{}, fmt.Errorf("blob: put %s: got %d bytes, want %d", key, n, size)
+	}
+	meta := fsMeta{
+		ContentType: contentType,
+		ETag:        `"` + hex.EncodeToString(m.Sum(nil)) + `"`,
+		SHA256:      base64.StdEncoding.EncodeToString(s.Sum(nil)),
+	}
+	if wantSHA != "" && meta.SHA256 != wantSHA {
+		return Info{}, errors.New("blob: checksum mismatch")
+	}
+
+	raw, _ := json.Marshal(meta)
+	mp := f.metaPath(key)
+	if err := os.MkdirAll(filepath.Dir(mp), 0o755); err != nil {
+		return Info{}, err
+	}
+	if err := os.WriteFile(mp, raw, 0o644); err != nil {
+		return Info{}, err
+	}
+	if err := os.Rename(tmp.Name(), p); err != nil {
+		return Info{}, err
+	}
+	return f.Head(context.Background(), key)
+}
+
+func (f *FS) Head(_ context.Context, key string) (Info, error) {
+	p, err := f.objectPath(key)
+	if err != nil {
+		return Info{}, err
+	}
+	st, err := os.Stat(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Info{}, ErrNotFound
+	}
+	if err != nil {
+		return Info{}, err
+	}
+	var meta fsMeta
+	if raw, err := os.ReadFile(f.metaPath(key)); err == nil {
+		_ = json.Unmarshal(raw, &meta)
+	}
+	return Info{
+		Key:            key,
+		Size:           st.Size(),
+		ContentType:    meta.ContentType,
+		ETag:           meta.ETag,
+		ChecksumSHA256: meta.SHA256,
+		LastModified:   st.ModTime().UTC(),
+	}, nil
+}
+
+func (f *FS) Get(ctx context.Context, key string, opt GetOptions) (*Object, error) {
+	info, err := f.Head(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	off, n, cr, err := resolveGet(info, opt)
+	if err != nil {
+		return nil, err
+	}
+	p, _ := f.objectPath(key)
+	file, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	return &Object{
+		Info:          info,
+		Body:          readCloser{io.NewSectionReader(file, off, n), file},
+		ContentLength: n,
+		ContentRange:  cr,
+	}, nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+func (f *FS) Delete(_ context.Context, key string) error {
+	p, err := f.objectPath(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	_ = os.Remove(f.metaPath(key))
+	return nil
+}
+
+func (f *FS) List(ctx context.Context, prefix string, shallow bool) ([]Info, error) {
+	objects := filepath.Join(f.root, "objects")
+	start := objects
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		start = filepath.Join(objects, filepath.FromSlash(prefix[:i]))
+	}
+	var out []Info
+	err := filepath.WalkDir(start, func(p string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".tmp-") {
+			return nil
+		}
+		rel, _ := filepath.Rel(objects, p)
+		key := filepath.ToSlash(rel)
+		rest, ok := strings.CutPrefix(key, prefix)
+		if !ok || (shallow && strings.Contains(rest, "/")) {
+			return nil
+		}
+		info, err := f.Head(ctx, key)
+		if err != nil {
+			return err
+		}
+		out = append(out, info)
+		return nil
+	})
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out, err
+}
+
+// ---- Multipart: parts are files in multipart/<id>/ ----
+
+func (f *FS) uploadDir(id string) (string, error) {
+	if id == "" || strings.ContainsAny(id, `/\.`) {
+		return "", ErrNotFound
+	}
+	return filepath.Join(f.root, "multipart", id), nil
+}
+
+func (f *FS) CreateMultipart(_ context.Context, _, contentType string) (string, error) {
+	id := newUploadID()
+	dir, _ := f.uploadDir(id)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	return id, os.WriteFile(filepath.Join(dir, "content-type"), []byte(contentType), 0o644)
+}
+
+func (f *FS) UploadPart(_ context.Context, _, uploadID string, number int32, data []byte) (Part, error) {
+	dir, err := f.uploadDir(uploadID)
+	if err != nil {
+		return Part{}, err
+	}
+	if _, err := os.Stat(dir); err != nil {
+		return Part{}, ErrNotFound
+	}
+	if err := os.WriteFile(filepath.Join(dir, strconv.Itoa(int(number))), data, 0o644); err != nil {
+		return Part{}, err
+	}
+	etag, _ := digests(data)
+	return Part{Number: number, ETag: etag, Size: int64(len(data))}, nil
+}
+
+func (f *FS) CompleteMultipart(_ context.Context, key, uploadID string, parts []Part) error {
+	dir, err := f.uploadDir(uploadID)
+	if err != nil {
+		return err
+	}
+	ct, err := os.ReadFile(filepath.Join(dir, "content-type"))
+	if err != nil {
+		return ErrNotFound
+	}
+	readers := make([]io.Reader, 0, len(parts))
+	var total int64
+	for i, p := range parts {
+		file, err := os.Open(filepath.Join(dir, strconv.Itoa(int(p.Number))))
+		if err != nil {
+			return fmt.Errorf("blob: complete %s: missing part %d", key, p.Number)
+		}
+		defer file.Close()
+		st, _ := file.Stat()
+		if i < len(parts)-1 && st.Size() < MinPartSize {
+			return fmt.Errorf("blob: complete %s: part %d is smaller than %d bytes", key, p.Number, MinPartSize)
+		}
+		total += st.Size()
+		readers = append(readers, file)
+	}
+	if _, err := f.write(key, io.MultiReader(readers...), total, string(ct), ""); err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+func (f *FS) AbortMultipart(_ context.Context, _, uploadID string) error {
+	dir, err := f.uploadDir(uploadID)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+// ---- Signed URLs ----
+
+func (f *FS) PresignGet(_ context.Context, key string, ttl time.Duration) (string, error) {
+	if _, err := f.objectPath(key); err != nil {
+		return "", err
+	}
+	return f.signedURL("get", key, f.now().Add(ttl), PutOptions{}), nil
+}
+
+func (f *FS) PresignPut(_ context.Context, key string, opt PutOptions) (PresignedPut, error) {
+	if _, err := f.objectPath(key); err != nil {
+		return PresignedPut{}, err
+	}
+	if opt.TTL == 0 {
+		opt.TTL = DefaultPresignTTL
+	}
+	exp := f.now().Add(opt.TTL)
+	return PresignedPut{URL: f.signedURL("put", key, exp, opt), Headers: putHeaders(opt), Expires: exp}, nil
+}
+
+func (f *FS) signedURL(op, key string, exp time.Time, opt PutOptions) string {
+	q := url.Values{}
+	q.Set("exp", strconv.FormatInt(exp.Unix(), 10))
+	if op == "put" {
+		q.Set("ct", opt.ContentType)
+		if opt.Size > 0 {
+			q.Set("size", strconv.FormatInt(opt.Size, 10))
+		}
+		if opt.SHA256 != "" {
+			q.Set("sha256", opt.SHA256)
+		}
+	}
+	q.Set("sig", f.sign(op, key, q))
+	return f.baseURL + Prefix + (&url.URL{Path: key}).EscapedPath() + "?" + q.Encode()
+}
+
+func (f *FS) sign(op, key string, q url.Values) string {
+	mac := hmac.New(sha256.New, f.secret)
+	for _, s := range []string{op, key, q.Get("exp"), q.Get("ct"), q.Get("size"), q.Get("sha256")} {
+		mac.Write([]byte(s))
+		mac.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ServeHTTP serves presigned GET/HEAD and PUT requests under Prefix.
+func (f *FS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(r.URL.Path, Prefix)
+	q := r.URL.Query()
+
+	op := "get"
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+	case http.MethodPut:
+		op = "put"
+	default:
+		http.Error(w, `{"error":"method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+	exp, err := strconv.ParseInt(q.Get("exp"), 10, 64)
+	if err != nil || !hmac.Equal([]byte(q.Get("sig")), []byte(f.sign(op, key, q))) {
+		http.Error(w, `{"error":"invalid signature"}`, http.StatusForbidden)
+		return
+	}
+	if f.now().Unix() > exp {
+		http.Error(w, `{"error":"url expired"}`, http.StatusForbidden)
+		return
+	}
+
+	// Transfers can be large; the API's server timeouts are sized for JSON.
+	rc := http.NewResponseController(w)
+	_ = rc.SetReadDeadline(time.Time{})
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	if op == "put" {
+		f.servePut(w, r, key, q)
+		return
+	}
+
+	info, err := f.Head(r.Context(), key)
+	if errors.Is(err, ErrNotFound) {
+		http.Error(w, `{"error":"not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, `{"error":"storage error"}`, http.StatusInternalServerError)
+		return
+	}
+	p, _ := f.objectPath(key)
+	file, err := os.Open(p)
+	if err != nil {
+		http.Error(w, `{"error":"storage error"}`, http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+	if info.ContentType != "" {
+		w.Header().Set("Content-Type", info.ContentType)
+	}
+	w.Header().Set("ETag", info.ETag)
+	http.ServeContent(w, r, "", info.LastModified, file)
+}
+
+func (f *FS) servePut(w http.ResponseWriter, r *http.Request, key string, q url.Values) {
+	if r.Header.Get("Content-Type") != q.Get("ct") {
+		http.Error(w, `{"error":"content type does not match signature"}`, http.StatusForbidden)
+		return
+	}
+	size := int64(-1)
+	if s := q.Get("size"); s != "" {
+		size, _ = strconv.ParseInt(s, 10, 64)
+		if r.ContentLength != size {
+			http.Error(w, `{"error":"content length does not match signature"}`, http.StatusForbidden)
+			return
+		}
+	}
+	if sha := q.Get("sha256"); sha != "" && r.Header.Get("x-amz-checksum-sha256") != sha {
+		http.Error(w, `{"error":"checksum header does not match signature"}`, http.StatusForbidden)
+		return
+	}
+	if _, err := f.write(key, r.Body, size, q.Get("ct"), q.Get("sha256")); err != nil {
+		http.Error(w, `{"error":"upload rejected"}`, http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
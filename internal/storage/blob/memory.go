@@ -0,0 +1,203 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Memory is an in-process BlobStore for tests and local experiments.
+// Presigned URLs use a memory:// scheme and are not fetchable.
+type Memory struct {
+	mu      sync.RWMutex
+	objects map[string]memObject
+	uploads map[string]*memUpload
+	now     func() time.Time
+}
+
+type memUpload struct {
+	contentType string
+	parts       map[int32][]byte
+}
+
+type memObject struct {
+	data []byte
+	info Info
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		objects: map[string]memObject{},
+		uploads: map[string]*memUpload{},
+		now:     time.Now,
+	}
+}
+
+func (m *Memory) Put(_ context.Context, key string, r io.Reader, size int64, contentType string) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if size >= 0 && int64(len(data)) != size {
+		return fmt.Errorf("blob: put %s: got %d bytes, want %d", key, len(data), size)
+	}
+	m.store(key, data, contentType)
+	return nil
+}
+
+func (m *Memory) store(key string, data []byte, contentType string) {
+	etag, sha := digests(data)
+	m.mu.Lock()
+	m.objects[key] = memObject{data: data, info: Info{
+		Key:            key,
+		Size:           int64(len(data)),
+		ContentType:    contentType,
+		ETag:           etag,
+		ChecksumSHA256: sha,
+		LastModified:   m.now().UTC(),
+	}}
+	m.mu.Unlock()
+}
+
+func (m *Memory) Get(_ context.Context, key string, opt GetOptions) (*Object, error) {
+	m.mu.RLock()
+	o, ok := m.objects[key]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, ErrNotFound
+	}
+	off, n, cr, err := resolveGet(o.info, opt)
+	if err != nil {
+		return nil, err
+	}
+	return &Object{
+		Info:          o.info,
+		Body:          io.NopCloser(bytes.NewReader(o.data[off : off+n])),
+		ContentLength: n,
+		ContentRange:  cr,
+	}, nil
+}
+
+func (m *Memory) Head(_ context.Context, key string) (Info, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	o, ok := m.objects[key]
+	if !ok {
+		return Info{}, ErrNotFound
+	}
+	return o.info, nil
+}
+
+func (m *Memory) Delete(_ context.Context, key string) error {
+	m.mu.Lock()
+	delete(m.objects, key)
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *Memory) List(_ context.Context, prefix string, shallow bool) ([]Info, error) {
+	m.mu.RLock()
+	var out []Info
+	for k, o := range m.objects {
+		rest, ok := strings.CutPrefix(k, prefix)
+		if !ok || (shallow && strings.Contains(rest, "/")) {
+			continue
+		}
+		out = append(out, o.info)
+	}
+	m.mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out, nil
+}
+
+func (m *Memory) PresignGet(_ context.Context, key string, ttl time.Duration) (string, error) {
+	return "memory:///" + url.PathEscape(key) + "?exp=" + fmt.Sprint(m.now().Add(ttl).Unix()), nil
+}
+
+func (m *Memory) PresignPut(_ context.Context, key string, opt PutOptions) (PresignedPut, error) {
+	if opt.TTL == 0 {
+		opt.TTL = DefaultPresignTTL
+	}
+	exp := m.now().Add(opt.TTL)
+	return PresignedPut{
+		URL:     "memory:///" + url.PathEscape(key) + "?exp=" + fmt.Sprint(exp.Unix()),
+		Headers: putHeaders(opt),
+		Expires: exp,
+	}, nil
+}
+
+func (m *Memory) CreateMultipart(_ context.Context, _, contentType string) (string, error) {
+	id := newUploadID()
+	m.mu.Lock()
+	m.uploads[id] = &memUpload{contentType: contentType, parts: map[int32][]byte{}}
+	m.mu.Unlock()
+	return id, nil
+}
+
+func (m *Memory) UploadPart(_ context.Context, _, uploadID string, number int32, data []byte) (Part, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u, ok := m.uploads[uploadID]
+	if !ok {
+		return Part{}, ErrNotFound
+	}
+	cp := append([]byte(nil), data...)
+	u.parts[number] = cp
+	etag, _ := digests(cp)
+	return Part{Number: number, ETag: etag, Size: int64(len(cp))}, nil
+}
+
+func (m *Memory) CompleteMultipart(_ context.Context, key, uploadID string, parts []Part) error {
+	m.mu.Lock()
+	u, ok := m.uploads[uploadID]
+	delete(m.uploads, uploadID)
+	m.mu.Unlock()
+	if !ok {
+		return ErrNotFound
+	}
+	var buf bytes.Buffer
+	for i, p := range parts {
+		data, ok := u.parts[p.Number]
+		if !ok {
+			return fmt.Errorf("blob: complete %s: missing part %d", key, p.Number)
+		}
+		if i < len(parts)-1 && len(data) < MinPartSize {
+			return fmt.Errorf("blob: complete %s: part %d is smaller than %d bytes", key, p.Number, MinPartSize)
+		}
+		buf.Write(data)
+	}
+	m.store(key, buf.Bytes(), u.contentType)
+	return nil
+}
+
+func (m *Memory) AbortMultipart(_ context.Context, _, uploadID string) error {
+	m.mu.Lock()
+	delete(m.uploads, uploadID)
+	m.mu.Unlock()
+	return nil
+}
+
+func putHeaders(opt PutOptions) map[string]string {
+	h := map[string]string{"Content-Type": opt.ContentType}
+	if opt.Size > 0 {
+		h["Content-Length"] = fmt.Sprint(opt.Size)
+	}
+	if opt.SHA256 != "" {
+		h["x-amz-checksum-sha256"] = opt.SHA256
+	}
+	return h
+}
+
+func newUploadID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
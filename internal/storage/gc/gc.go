@@ -0,0 +1,208 @@
+// Package gc reconciles the bucket with the books table: objects nobody
+// references (orphans) and references to objects that no longer exist
+// (dangling). Uploads and deletes elsewhere are best-effort, so the two drift.
+package gc
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/5w1tchy/books-api/internal/media/captions"
+	coverpkg "github.com/5w1tchy/books-api/internal/media/cover"
+	"github.com/5w1tchy/books-api/internal/storage/blob"
+)
+
+const (
+	CoverPrefix = "books/covers/"
+	AudioPrefix = "books/" // audio and captions live directly under it
+
+	DefaultGrace = 72 * time.Hour
+	// MinGrace keeps freshly uploaded objects that are still waiting for
+	// their complete/attach step (pending uploads live for an hour).
+	MinGrace = 2 * time.Hour
+)
+
+// Bucket is the subset of blob.BlobStore the collector needs.
+type Bucket interface {
+	List(ctx context.Context, prefix string, shallow bool) ([]blob.Info, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Orphan is an object no book points at.
+type Orphan struct {
+	Key          string    `json:"key"`
+	Kind         string    `json:"kind"` // cover | cover_variant | audio | captions
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"last_modified"`
+	Deletable    bool      `json:"deletable"` // older than the grace period
+}
+
+// Dangling is a book column pointing at a missing object.
+type Dangling struct {
+	BookID string `json:"book_id"`
+	Slug   string `json:"slug"`
+	Field  string `json:"field"` // cover_url | audio_key
+	Key    string `json:"key"`
+}
+
+// Report is the result of a scan. Token fingerprints the deletable set so a
+// deletion can be confirmed against exactly what was reviewed.
+type Report struct {
+	ScannedAt   time.Time  `json:"scanned_at"`
+	Grace       string     `json:"grace"`
+	Objects     int        `json:"objects"`
+	References  int        `json:"references"`
+	Orphans     []Orphan   `json:"orphans"`
+	OrphanBytes int64      `json:"orphan_bytes"`
+	Deletable   int        `json:"deletable"`
+	Dangling    []Dangling `json:"dangling"`
+	Token       string     `json:"confirm_token"`
+}
+
+// DeletableKeys lists orphans past the grace period.
+func (r *Report) DeletableKeys() []string {
+	var keys []string
+	for _, o := range r.Orphans {
+		if o.Deletable {
+			keys = append(keys, o.Key)
+		}
+	}
+	return keys
+}
+
+// Scan lists the cover and audio prefixes and compares them with the books table.
+func Scan(ctx context.Context, db *sql.DB, b Bucket, grace time.Duration, now time.Time) (*Report, error) {
+	if grace < MinGrace {
+		grace = MinGrace
+	}
+
+	covers, err := b.List(ctx, CoverPrefix, false)
+	if err != nil {
+		return nil, err
+	}
+	audio, err := b.List(ctx, AudioPrefix, true)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, `
+SELECT id::text, COALESCE(slug, ''), COALESCE(cover_url, ''), COALESCE(audio_key, '')
+FROM books
+WHERE COALESCE(cover_url, '') <> '' OR COALESCE(audio_key, '') <> ''`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var refs []Dangling
+	referenced := map[string]bool{}
+	variantDirs := map[string]bool{} // cover variants live under <cover>/
+	for rows.Next() {
+		var id, slug, cover, audioKey string
+		if err := rows.Scan(&id, &slug, &cover, &audioKey); err != nil {
+			return nil, err
+		}
+		if cover != "" {
+			referenced[cover] = true
+			variantDirs[coverpkg.VariantPrefix(cover)] = true
+			refs = append(refs, Dangling{BookID: id, Slug: slug, Field: "cover_url", Key: cover})
+		}
+		if audioKey != "" {
+			referenced[audioKey] = true
+			referenced[captions.Key(audioKey)] = true
+			refs = append(refs, Dangling{BookID: id, Slug: slug, Field: "audio_key", Key: audioKey})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rep := &Report{
+		ScannedAt:  now.UTC(),
+		Grace:      grace.String(),
+		Objects:    len(covers) + len(audio),
+		References: len(refs),
+		Orphans:    []Orphan{},
+		Dangling:   []Dangling{},
+	}
+	present := make(map[string]bool, rep.Objects)
+	for _, o := range append(covers, audio...) {
+		present[o.Key] = true
+		if referenced[o.Key] || variantDirs[path.Dir(o.Key)+"/"] || strings.HasSuffix(o.Key, "/") {
+			continue
+		}
+		orphan := Orphan{
+			Key:          o.Key,
+			Kind:         kindOf(o.Key),
+			Size:         o.Size,
+			LastModified: o.LastModified,
+			Deletable:    now.Sub(o.LastModified) >= grace,
+		}
+		rep.Orphans = append(rep.Orphans, orphan)
+		rep.OrphanBytes += o.Size
+		if orphan.Deletable {
+			rep.Deletable++
+		}
+	}
+	for _, ref := range refs {
+		if managed(ref.Key) && !present[ref.Key] {
+			rep.Dangling = append(rep.Dangling, ref)
+		}
+	}
+
+	sort.Slice(rep.Orphans, func(i, j int) bool { return rep.Orphans[i].Key < rep.Orphans[j].Key })
+	rep.Token = token(rep.DeletableKeys())
+	return rep, nil
+}
+
+// Delete removes the given keys and returns those it could not delete.
+func Delete(ctx context.Context, b Bucket, keys []string) (deleted []string, failed map[string]string) {
+	failed = map[string]string{}
+	for _, k := range keys {
+		if err := b.Delete(ctx, k); err != nil {
+			failed[k] = err.Error()
+			continue
+		}
+		deleted = append(deleted, k)
+	}
+	return deleted, failed
+}
+
+// managed reports whether a reference falls inside the scanned prefixes;
+// anything else (external URLs, other folders) can't be judged dangling.
+func managed(key string) bool {
+	if strings.HasPrefix(key, CoverPrefix) {
+		return true
+	}
+	rest, ok := strings.CutPrefix(key, AudioPrefix)
+	return ok && rest != "" && !strings.Contains(rest, "/")
+}
+
+func kindOf(key string) string {
+	switch {
+	case strings.HasPrefix(key, CoverPrefix) && strings.Contains(key[len(CoverPrefix):], "/"):
+		return "cover_variant"
+	case strings.HasPrefix(key, CoverPrefix):
+		return "cover"
+	case strings.HasSuffix(key, ".vtt"):
+		return "captions"
+	default:
+		return "audio"
+	}
+}
+
+func token(keys []string) string {
+	if len(keys) == 0 {
+		return ""
+	}
+	sorted := append([]string(nil), keys...)
+	sort.Strings(sorted)
+	sum := sha256.Sum256([]byte(strings.Join(sorted, "\n")))
+	return hex.EncodeToString(sum[:12])
+}
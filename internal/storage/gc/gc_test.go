@@ -0,0 +1,88 @@
+package gc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/5w1tchy/books-api/internal/storage/blob"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+type fakeBucket struct {
+	objects map[string][]blob.Info
+	deleted []string
+}
+
+func (f *fakeBucket) List(_ context.Context, prefix string, _ bool) ([]blob.Info, error) {
+	return f.objects[prefix], nil
+}
+
+func (f *fakeBucket) Delete(_ context.Context, key string) error {
+	if key == "books/covers/locked.jpg" {
+		return errors.New("access denied")
+	}
+	f.deleted = append(f.deleted, key)
+	return nil
+}
+
+func TestScanFindsOrphansAndDangling(t *testing.T) {
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	old, fresh := now.Add(-10*24*time.Hour), now.Add(-time.Hour)
+	b := &fakeBucket{objects: map[string][]blob.Info{
+		CoverPrefix: {
+			{Key: "books/covers/atomic-1.webp", Size: 10, LastModified: old},
+			{Key: "books/covers/atomic-1/256.jpg", Size: 3, LastModified: old},
+			{Key: "books/covers/stale-1/256.jpg", Size: 7, LastModified: old},
+			{Key: "books/covers/stale-1.webp", Size: 20, LastModified: old},
+			{Key: "books/covers/new-1.png", Size: 30, LastModified: fresh},
+		},
+		AudioPrefix: {
+			{Key: "books/atomic-summary-1.mp3", Size: 100, LastModified: old},
+			{Key: "books/atomic-summary-1.vtt", Size: 5, LastModified: old},
+			{Key: "books/old-summary-0.mp3", Size: 200, LastModified: old},
+		},
+	}}
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	mock.ExpectQuery(`SELECT id::text`).WillReturnRows(
+		sqlmock.NewRows([]string{"id", "slug", "cover_url", "audio_key"}).
+			AddRow("b1", "atomic", "books/covers/atomic-1.webp", "books/atomic-summary-1.mp3").
+			AddRow("b2", "gone", "books/covers/gone.webp", "").
+			AddRow("b3", "ext", "https://cdn.example.com/x.jpg", ""),
+	)
+
+	rep, err := Scan(context.Background(), db, b, DefaultGrace, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rep.Orphans) != 4 || rep.Deletable != 3 || rep.OrphanBytes != 257 {
+		t.Fatalf("unexpected orphans: %+v", rep)
+	}
+	if len(rep.Dangling) != 1 || rep.Dangling[0].Key != "books/covers/gone.webp" {
+		t.Errorf("unexpected dangling: %+v", rep.Dangling)
+	}
+	keys := rep.DeletableKeys()
+	if len(keys) != 3 || keys[0] != "books/covers/stale-1.webp" || keys[1] != "books/covers/stale-1/256.jpg" || keys[2] != "books/old-summary-0.mp3" {
+		t.Errorf("deletable = %v", keys)
+	}
+	if kind := rep.Orphans[2].Kind; kind != "cover_variant" {
+		t.Errorf("variant kind = %q", kind)
+	}
+	if rep.Token == "" || rep.Token != token([]string{keys[2], keys[0], keys[1]}) {
+		t.Error("token must fingerprint the deletable set independent of order")
+	}
+}
+
+func TestDeleteReportsFailures(t *testing.T) {
+	b := &fakeBucket{}
+	deleted, failed := Delete(context.Background(), b, []string{"books/a.mp3", "books/covers/locked.jpg"})
+	if len(deleted) != 1 || len(failed) != 1 || failed["books/covers/locked.jpg"] == "" {
+		t.Errorf("deleted=%v failed=%v", deleted, failed)
+	}
+}
@@ -0,0 +1,72 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/5w1tchy/books-api/internal/storage/blob"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// CreateMultipart starts a multipart upload and returns its upload id.
+func (s *S3Client) CreateMultipart(ctx context.Context, objectKey, contentType string) (string, error) {
+	out, err := s.Client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:      aws.String(s.Bucket),
+		Key:         aws.String(objectKey),
+		ContentType: aws.String(contentType),
+	})
+	if err != nil {
+		return "", fmt.Errorf("s3: create multipart upload %s: %w", objectKey, err)
+	}
+	return aws.ToString(out.UploadId), nil
+}
+
+// UploadPart uploads one part. Re-uploading the same part number replaces it.
+func (s *S3Client) UploadPart(ctx context.Context, objectKey, uploadID string, number int32, data []byte) (blob.Part, error) {
+	out, err := s.Client.UploadPart(ctx, &s3.UploadPartInput{
+		Bucket:        aws.String(s.Bucket),
+		Key:           aws.String(objectKey),
+		UploadId:      aws.String(uploadID),
+		PartNumber:    aws.Int32(number),
+		Body:          bytes.NewReader(data),
+		ContentLength: aws.Int64(int64(len(data))),
+	})
+	if err != nil {
+		return blob.Part{}, fmt.Errorf("s3: upload part %d of %s: %w", number, objectKey, err)
+	}
+	return blob.Part{Number: number, ETag: aws.ToString(out.ETag), Size: int64(len(data))}, nil
+}
+
+// CompleteMultipart assembles the parts into the final object.
+func (s *S3Client) CompleteMultipart(ctx context.Context, objectKey, uploadID string, parts []blob.Part) error {
+	completed := make([]types.CompletedPart, len(parts))
+	for i, p := range parts {
+		completed[i] = types.CompletedPart{PartNumber: aws.Int32(p.Number), ETag: aws.String(p.ETag)}
+	}
+	_, err := s.Client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(s.Bucket),
+		Key:             aws.String(objectKey),
+		UploadId:        aws.String(uploadID),
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: completed},
+	})
+	if err != nil {
+		return fmt.Errorf("s3: complete multipart upload %s: %w", objectKey, err)
+	}
+	return nil
+}
+
+// AbortMultipart discards an unfinished upload and its stored parts.
+func (s *S3Client) AbortMultipart(ctx context.Context, objectKey, uploadID string) error {
+	_, err := s.Client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(s.Bucket),
+		Key:      aws.String(objectKey),
+		UploadId: aws.String(uploadID),
+	})
+	if err != nil {
+		return fmt.Errorf("s3: abort multipart upload %s: %w", objectKey, err)
+	}
+	return nil
+}
@@ -4,21 +4,30 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strconv"
 	"time"
 
+	"github.com/5w1tchy/books-api/internal/storage/blob"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 )
 
+// S3Client is the S3/R2 blob.BlobStore.
 type S3Client struct {
 	Client    *s3.Client
 	Presigner *s3.PresignClient
 	Bucket    string
 }
 
-// NewR2Client initializes an S3-compatible client for Cloudflare R2
+var (
+	_ blob.BlobStore = (*S3Client)(nil)
+	_ blob.Multipart = (*S3Client)(nil)
+)
+
+// NewR2Client initializes an S3-compatible client for Cloudflare R2.
+// Build it once at startup and share it; it is safe for concurrent use.
 func NewR2Client(ctx context.Context) (*S3Client, error) {
 	endpoint := os.Getenv("AWS_ENDPOINT")
 	region := os.Getenv("AWS_REGION")
@@ -52,31 +61,45 @@ func NewR2Client(ctx context.Context) (*S3Client, error) {
 	}, nil
 }
 
-// GeneratePresignedUploadURL creates a presigned PUT URL for direct upload
-func (s *S3Client) GeneratePresignedUploadURL(ctx context.Context, objectKey, contentType string) (string, error) {
-	req, err := s.Presigner.PresignPutObject(ctx, &s3.PutObjectInput{
-		Bucket:      aws.String(s.Bucket),
-		Key:         aws.String(objectKey),
-		ContentType: aws.String(contentType),
+// PresignGet creates a presigned GET URL for downloading/streaming.
+func (s *S3Client) PresignGet(ctx context.Context, objectKey string, ttl time.Duration) (string, error) {
+	req, err := s.Presigner.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(objectKey),
 	}, func(opts *s3.PresignOptions) {
-		opts.Expires = 15 * time.Minute // URL valid for 15 minutes
+		opts.Expires = ttl
 	})
 	if err != nil {
-		return "", fmt.Errorf("failed to presign upload: %w", err)
+		return "", fmt.Errorf("failed to presign download: %w", err)
 	}
 	return req.URL, nil
 }
 
-// GeneratePresignedDownloadURL creates a presigned GET URL for downloading/streaming
-func (s *S3Client) GeneratePresignedDownloadURL(ctx context.Context, objectKey string) (string, error) {
-	req, err := s.Presigner.PresignGetObject(ctx, &s3.GetObjectInput{
-		Bucket: aws.String(s.Bucket),
-		Key:    aws.String(objectKey),
-	}, func(opts *s3.PresignOptions) {
-		opts.Expires = 15 * time.Minute // URL valid for 15 minutes
+// PresignPut presigns a PUT that S3 only accepts with the given content type
+// and, when set, exact size and base64 SHA-256 checksum.
+func (s *S3Client) PresignPut(ctx context.Context, objectKey string, opt blob.PutOptions) (blob.PresignedPut, error) {
+	if opt.TTL == 0 {
+		opt.TTL = blob.DefaultPresignTTL
+	}
+	in := &s3.PutObjectInput{
+		Bucket:      aws.String(s.Bucket),
+		Key:         aws.String(objectKey),
+		ContentType: aws.String(opt.ContentType),
+	}
+	headers := map[string]string{"Content-Type": opt.ContentType}
+	if opt.Size > 0 {
+		in.ContentLength = aws.Int64(opt.Size)
+		headers["Content-Length"] = strconv.FormatInt(opt.Size, 10)
+	}
+	if opt.SHA256 != "" {
+		in.ChecksumSHA256 = aws.String(opt.SHA256)
+		headers["x-amz-checksum-sha256"] = opt.SHA256
+	}
+	req, err := s.Presigner.PresignPutObject(ctx, in, func(opts *s3.PresignOptions) {
+		opts.Expires = opt.TTL
 	})
 	if err != nil {
-		return "", fmt.Errorf("failed to presign download: %w", err)
+		return blob.PresignedPut{}, fmt.Errorf("failed to presign upload: %w", err)
 	}
-	return req.URL, nil
+	return blob.PresignedPut{URL: req.URL, Headers: headers, Expires: time.Now().Add(opt.TTL)}, nil
 }
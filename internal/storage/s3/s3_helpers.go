@@ -2,14 +2,37 @@ package s3
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
 
+	"github.com/5w1tchy/books-api/internal/storage/blob"
 	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
-// DeleteObject deletes an object from the bucket (used for cleanup).
-func (s *S3Client) DeleteObject(ctx context.Context, objectKey string) error {
+// Put uploads an object. size may be -1 when unknown.
+func (s *S3Client) Put(ctx context.Context, objectKey string, r io.Reader, size int64, contentType string) error {
+	in := &s3.PutObjectInput{
+		Bucket:      aws.String(s.Bucket),
+		Key:         aws.String(objectKey),
+		Body:        r,
+		ContentType: aws.String(contentType),
+	}
+	if size >= 0 {
+		in.ContentLength = aws.Int64(size)
+	}
+	if _, err := s.Client.PutObject(ctx, in); err != nil {
+		return fmt.Errorf("s3: put object %s: %w", objectKey, err)
+	}
+	return nil
+}
+
+// Delete deletes an object from the bucket (used for cleanup).
+func (s *S3Client) Delete(ctx context.Context, objectKey string) error {
 	_, err := s.Client.DeleteObject(ctx, &s3.DeleteObjectInput{
 		Bucket: aws.String(s.Bucket),
 		Key:    aws.String(objectKey),
@@ -19,3 +42,106 @@ func (s *S3Client) DeleteObject(ctx context.Context, objectKey string) error {
 	}
 	return nil
 }
+
+// Get opens an object for streaming. The caller must close Body.
+func (s *S3Client) Get(ctx context.Context, objectKey string, opt blob.GetOptions) (*blob.Object, error) {
+	in := &s3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(objectKey),
+	}
+	if opt.Range != "" {
+		in.Range = aws.String(opt.Range)
+	}
+	if opt.IfMatch != "" {
+		in.IfMatch = aws.String(opt.IfMatch)
+	}
+	if opt.IfNoneMatch != "" {
+		in.IfNoneMatch = aws.String(opt.IfNoneMatch)
+	}
+	if !opt.IfUnmodifiedSince.IsZero() {
+		in.IfUnmodifiedSince = aws.Time(opt.IfUnmodifiedSince)
+	}
+
+	out, err := s.Client.GetObject(ctx, in)
+	if err != nil {
+		return nil, mapStatusErr(err, objectKey)
+	}
+	return &blob.Object{
+		Info: blob.Info{
+			Key:          objectKey,
+			Size:         aws.ToInt64(out.ContentLength),
+			ContentType:  aws.ToString(out.ContentType),
+			ETag:         aws.ToString(out.ETag),
+			LastModified: aws.ToTime(out.LastModified),
+		},
+		Body:          out.Body,
+		ContentLength: aws.ToInt64(out.ContentLength),
+		ContentRange:  aws.ToString(out.ContentRange),
+	}, nil
+}
+
+// Head returns an object's metadata, including its stored checksum.
+func (s *S3Client) Head(ctx context.Context, objectKey string) (blob.Info, error) {
+	out, err := s.Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket:       aws.String(s.Bucket),
+		Key:          aws.String(objectKey),
+		ChecksumMode: types.ChecksumModeEnabled,
+	})
+	if err != nil {
+		return blob.Info{}, mapStatusErr(err, objectKey)
+	}
+	return blob.Info{
+		Key:            objectKey,
+		Size:           aws.ToInt64(out.ContentLength),
+		ContentType:    aws.ToString(out.ContentType),
+		ETag:           aws.ToString(out.ETag),
+		ChecksumSHA256: aws.ToString(out.ChecksumSHA256),
+		LastModified:   aws.ToTime(out.LastModified),
+	}, nil
+}
+
+// List returns every object under prefix. With shallow set, keys in deeper
+// "directories" (after the next '/') are skipped.
+func (s *S3Client) List(ctx context.Context, prefix string, shallow bool) ([]blob.Info, error) {
+	in := &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.Bucket),
+		Prefix: aws.String(prefix),
+	}
+	if shallow {
+		in.Delimiter = aws.String("/")
+	}
+	var out []blob.Info
+	p := s3.NewListObjectsV2Paginator(s.Client, in)
+	for p.HasMorePages() {
+		page, err := p.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("s3: list %s: %w", prefix, err)
+		}
+		for _, o := range page.Contents {
+			out = append(out, blob.Info{
+				Key:          aws.ToString(o.Key),
+				Size:         aws.ToInt64(o.Size),
+				ETag:         aws.ToString(o.ETag),
+				LastModified: aws.ToTime(o.LastModified),
+			})
+		}
+	}
+	return out, nil
+}
+
+func mapStatusErr(err error, objectKey string) error {
+	var re *awshttp.ResponseError
+	if errors.As(err, &re) {
+		switch re.HTTPStatusCode() {
+		case http.StatusNotFound:
+			return blob.ErrNotFound
+		case http.StatusNotModified:
+			return blob.ErrNotModified
+		case http.StatusPreconditionFailed:
+			return blob.ErrPreconditionFailed
+		case http.StatusRequestedRangeNotSatisfiable:
+			return blob.ErrInvalidRange
+		}
+	}
+	return fmt.Errorf("s3: get object %s: %w", objectKey, err)
+}
@@ -0,0 +1,88 @@
+package adminstore
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	admin "github.com/5w1tchy/books-api/internal/api/handlers/admin"
+)
+
+// Scopes travel as comma-joined text: the pgx stdlib driver doesn't scan
+// TEXT[] into []string.
+const apiKeyColumns = `id::text, name, prefix, array_to_string(scopes, ','), created_by::text,
+       created_at, expires_at, last_used_at, revoked_at`
+
+type rowScanner interface{ Scan(dest ...any) error }
+
+func scanAPIKey(row rowScanner) (*admin.APIKeyRow, error) {
+	var k admin.APIKeyRow
+	var scopes string
+	if err := row.Scan(&k.ID, &k.Name, &k.Prefix, &scopes, &k.CreatedBy,
+		&k.CreatedAt, &k.ExpiresAt, &k.LastUsedAt, &k.RevokedAt); err != nil {
+		return nil, err
+	}
+	k.Scopes = []string{}
+	if scopes != "" {
+		k.Scopes = strings.Split(scopes, ",")
+	}
+	return &k, nil
+}
+
+func (s *Store) ListAPIKeys(ctx context.Context) ([]admin.APIKeyRow, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+apiKeyColumns+` FROM public.api_keys ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []admin.APIKeyRow{}
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *k)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (s *Store) GetAPIKey(ctx context.Context, id string) (*admin.APIKeyRow, error) {
+	return scanAPIKey(s.db.QueryRowContext(ctx, `SELECT `+apiKeyColumns+` FROM public.api_keys WHERE id = $1`, id))
+}
+
+func (s *Store) CreateAPIKey(ctx context.Context, k admin.APIKeyRow, hash string) (*admin.APIKeyRow, error) {
+	const q = `
+INSERT INTO public.api_keys (name, prefix, key_hash, scopes, created_by, expires_at)
+VALUES ($1, $2, $3, string_to_array($4, ','), $5, $6)
+RETURNING ` + apiKeyColumns
+	return scanAPIKey(s.db.QueryRowContext(ctx, q,
+		k.Name, k.Prefix, hash, strings.Join(k.Scopes, ","), k.CreatedBy, k.ExpiresAt))
+}
+
+// UpdateAPIKey changes a live key; revoked keys stay as they were.
+func (s *Store) UpdateAPIKey(ctx context.Context, id string, req admin.UpdateAPIKeyRequest) (*admin.APIKeyRow, error) {
+	const q = `
+UPDATE public.api_keys SET
+  name       = COALESCE($2, name),
+  scopes     = COALESCE(string_to_array(NULLIF($3, ''), ','), scopes),
+  expires_at = COALESCE($4, expires_at)
+WHERE id = $1 AND revoked_at IS NULL
+RETURNING ` + apiKeyColumns
+	return scanAPIKey(s.db.QueryRowContext(ctx, q, id, req.Name, strings.Join(req.Scopes, ","), req.ExpiresAt))
+}
+
+func (s *Store) RevokeAPIKey(ctx context.Context, id string) error {
+	const q = `UPDATE public.api_keys SET revoked_at = now() WHERE id = $1 AND revoked_at IS NULL`
+	res, err := s.db.ExecContext(ctx, q, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package adminstore_test
+
+import (
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	admin "github.com/5w1tchy/books-api/internal/api/handlers/admin"
+	adminstore "github.com/5w1tchy/books-api/internal/store/admin"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var apiKeyCols = []string{"id", "name", "prefix", "scopes", "created_by", "created_at", "expires_at", "last_used_at", "revoked_at"}
+
+func TestCreateAPIKey(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	store := adminstore.New(db)
+	now := time.Now()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO public.api_keys`)).
+		WithArgs("partner", "bk_abcdef", "hash", "catalog:read,admin:books:read", "a-1", nil).
+		WillReturnRows(sqlmock.NewRows(apiKeyCols).
+			AddRow("k-1", "partner", "bk_abcdef", "catalog:read,admin:books:read", "a-1", now, nil, nil, nil))
+
+	k, err := store.CreateAPIKey(t.Context(), admin.APIKeyRow{
+		Name: "partner", Prefix: "bk_abcdef", Scopes: []string{"catalog:read", "admin:books:read"}, CreatedBy: "a-1",
+	}, "hash")
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if k.ID != "k-1" || len(k.Scopes) != 2 || k.Scopes[1] != "admin:books:read" {
+		t.Fatalf("got %+v", k)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRevokeAPIKey_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	store := adminstore.New(db)
+
+	mock.ExpectExec(regexp.QuoteMeta(
+		`UPDATE public.api_keys SET revoked_at = now() WHERE id = $1 AND revoked_at IS NULL`,
+	)).
+		WithArgs("k-1").
+		WillReturnResult(sqlmock.NewResult(0, 0)) // missing or already revoked
+
+	if err := store.RevokeAPIKey(t.Context(), "k-1"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("want sql.ErrNoRows, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
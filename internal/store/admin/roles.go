@@ -0,0 +1,101 @@
+package adminstore
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	admin "github.com/5w1tchy/books-api/internal/api/handlers/admin"
+)
+
+const roleColumns = `r.name, r.description, array_to_string(r.permissions, ','), r.builtin,
+       (SELECT COUNT(*) FROM public.users u WHERE u.role = r.name), r.created_at, r.updated_at`
+
+func scanRole(row rowScanner) (*admin.RoleRow, error) {
+	var ro admin.RoleRow
+	var perms string
+	if err := row.Scan(&ro.Name, &ro.Description, &perms, &ro.Builtin, &ro.Users, &ro.CreatedAt, &ro.UpdatedAt); err != nil {
+		return nil, err
+	}
+	ro.Permissions = []string{}
+	if perms != "" {
+		ro.Permissions = strings.Split(perms, ",")
+	}
+	return &ro, nil
+}
+
+func (s *Store) ListRoles(ctx context.Context) ([]admin.RoleRow, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+roleColumns+` FROM public.roles r ORDER BY r.builtin DESC, r.name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []admin.RoleRow{}
+	for rows.Next() {
+		ro, err := scanRole(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *ro)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (s *Store) GetRole(ctx context.Context, name string) (*admin.RoleRow, error) {
+	return scanRole(s.db.QueryRowContext(ctx, `SELECT `+roleColumns+` FROM public.roles r WHERE r.name = $1`, name))
+}
+
+// CreateRole returns sql.ErrNoRows if the name is taken.
+func (s *Store) CreateRole(ctx context.Context, req admin.CreateRoleRequest) (*admin.RoleRow, error) {
+	const q = `
+INSERT INTO public.roles AS r (name, description, permissions)
+VALUES ($1, $2, string_to_array(NULLIF($3, ''), ','))
+ON CONFLICT (name) DO NOTHING
+RETURNING ` + roleColumns
+	return scanRole(s.db.QueryRowContext(ctx, q, req.Name, req.Description, strings.Join(req.Permissions, ",")))
+}
+
+func (s *Store) UpdateRole(ctx context.Context, name string, req admin.UpdateRoleRequest) (*admin.RoleRow, error) {
+	var perms any // nil keeps the current permissions
+	if req.Permissions != nil {
+		perms = strings.Join(req.Permissions, ",")
+	}
+	const q = `
+UPDATE public.roles AS r SET
+  description = COALESCE($2, description),
+  permissions = CASE WHEN $3::text IS NULL THEN permissions
+                     ELSE COALESCE(string_to_array(NULLIF($3, ''), ','), '{}') END,
+  updated_at  = now()
+WHERE r.name = $1
+RETURNING ` + roleColumns
+	return scanRole(s.db.QueryRowContext(ctx, q, name, req.Description, perms))
+}
+
+// DeleteRole refuses built-in roles and roles users still have.
+func (s *Store) DeleteRole(ctx context.Context, name string) error {
+	const q = `
+DELETE FROM public.roles r
+WHERE r.name = $1 AND NOT r.builtin
+  AND NOT EXISTS (SELECT 1 FROM public.users u WHERE u.role = r.name)`
+	res, err := s.db.ExecContext(ctx, q, name)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		return nil
+	}
+	ro, err := s.GetRole(ctx, name)
+	switch {
+	case err != nil:
+		return err // sql.ErrNoRows when there is no such role
+	case ro.Builtin:
+		return admin.ErrRoleBuiltin
+	case ro.Users > 0:
+		return admin.ErrRoleInUse
+	}
+	return sql.ErrNoRows
+}
@@ -0,0 +1,77 @@
+package adminstore_test
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	admin "github.com/5w1tchy/books-api/internal/api/handlers/admin"
+	adminstore "github.com/5w1tchy/books-api/internal/store/admin"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var roleCols = []string{"name", "description", "permissions", "builtin", "users", "created_at", "updated_at"}
+
+func TestGetRole(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	store := adminstore.New(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM public.roles r WHERE r.name = $1`)).
+		WithArgs("editor").
+		WillReturnRows(sqlmock.NewRows(roleCols).
+			AddRow("editor", "Manages books", "books.read,books.write", true, 3, time.Now(), time.Now()))
+
+	ro, err := store.GetRole(t.Context(), "editor")
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(ro.Permissions) != 2 || ro.Permissions[1] != "books.write" || ro.Users != 3 {
+		t.Fatalf("got %+v", ro)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDeleteRole_Refused(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		builtin bool
+		users   int
+		want    error
+	}{
+		{"moderator", true, 0, admin.ErrRoleBuiltin},
+		{"support", false, 2, admin.ErrRoleInUse},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			store := adminstore.New(db)
+
+			mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM public.roles r`)).
+				WithArgs(c.name).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectQuery(regexp.QuoteMeta(`FROM public.roles r WHERE r.name = $1`)).
+				WithArgs(c.name).
+				WillReturnRows(sqlmock.NewRows(roleCols).
+					AddRow(c.name, "", "", c.builtin, c.users, time.Now(), time.Now()))
+
+			if err := store.DeleteRole(t.Context(), c.name); !errors.Is(err, c.want) {
+				t.Fatalf("want %v, got %v", c.want, err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package books
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/5w1tchy/books-api/internal/store/dbx"
+	"github.com/5w1tchy/books-api/internal/store/shared"
+)
+
+// AudioMeta is what we persist about a verified audio upload.
+type AudioMeta struct {
+	Format     string // mp3 | mp4 | ogg | wav
+	DurationMS int64
+	Bitrate    int // bits per second
+	SampleRate int
+	Channels   int
+}
+
+// AudioRef identifies a book's current audio object.
+type AudioRef struct {
+	BookID     string
+	Slug       string
+	AudioKey   string // empty if the book has no audio
+	DurationMS int64  // 0 if unknown
+}
+
+// GetAudioRef resolves a book key (uuid, short_id or slug) to its audio object.
+func GetAudioRef(ctx context.Context, db *sql.DB, key string) (AudioRef, error) {
+	cond, arg := shared.ResolveBookKeyCondArg(ctx, key)
+	var ref AudioRef
+	var audioKey sql.NullString
+	var dur sql.NullInt64
+	err := db.QueryRowContext(ctx, `
+SELECT b.id::text, b.slug, b.audio_key, b.audio_duration_ms
+FROM books b
+WHERE `+cond, arg).Scan(&ref.BookID, &ref.Slug, &audioKey, &dur)
+	ref.AudioKey = audioKey.String
+	ref.DurationMS = dur.Int64
+	return ref, err
+}
+
+// AttachAudio points a book at a new audio object and stores its metadata.
+// Returns false if no book has that id.
+func AttachAudio(ctx context.Context, db *sql.DB, bookID, audioKey string, m AudioMeta) (bool, error) {
+	return attachAudio(ctx, db, bookID, audioKey, m)
+}
+
+// SwapAudio is AttachAudio that also returns the audio key it replaced. The
+// row is locked while it is read and written, so of two concurrent swaps the
+// second sees the first one's key and each old object is reported once.
+// Returns sql.ErrNoRows if no book has that id.
+func SwapAudio(ctx context.Context, db *sql.DB, bookID, audioKey string, m AudioMeta) (string, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var old sql.NullString
+	if err := tx.QueryRowContext(ctx, `SELECT audio_key FROM books WHERE id::text = $1 FOR UPDATE`, bookID).Scan(&old); err != nil {
+		return "", err
+	}
+	if _, err := attachAudio(ctx, tx, bookID, audioKey, m); err != nil {
+		return "", err
+	}
+	return old.String, tx.Commit()
+}
+
+func attachAudio(ctx context.Context, e dbx.Execer, bookID, audioKey string, m AudioMeta) (bool, error) {
+	res, err := e.ExecContext(ctx, `
+UPDATE books
+SET audio_key = $1,
+    audio_format = $2,
+    audio_duration_ms = $3,
+    audio_bitrate = $4,
+    audio_sample_rate = $5,
+    audio_channels = $6,
+    updated_at = now()
+WHERE id::text = $7`,
+		audioKey, m.Format, m.DurationMS, m.Bitrate, m.SampleRate, m.Channels, bookID)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// durationSeconds converts a nullable audio_duration_ms into whole seconds for PublicBook.
+func durationSeconds(ms sql.NullInt64) *int {
+	if !ms.Valid || ms.Int64 <= 0 {
+		return nil
+	}
+	s := int((ms.Int64 + 500) / 1000)
+	return &s
+}
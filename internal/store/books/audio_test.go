@@ -0,0 +1,45 @@
+package books
+
+import (
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestSwapAudioLocksRowBeforeUpdating(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	m := AudioMeta{Format: "mp3", DurationMS: 60000, Bitrate: 128000, SampleRate: 44100, Channels: 2}
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT audio_key FROM books WHERE id::text = $1 FOR UPDATE`)).
+		WithArgs("b-1").
+		WillReturnRows(sqlmock.NewRows([]string{"audio_key"}).AddRow("books/dune-summary-1.mp3"))
+	mock.ExpectExec(`UPDATE books`).
+		WithArgs("books/dune-summary-2.mp3", "mp3", int64(60000), 128000, 44100, 2, "b-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	old, err := SwapAudio(t.Context(), db, "b-1", "books/dune-summary-2.mp3", m)
+	if err != nil || old != "books/dune-summary-1.mp3" {
+		t.Fatalf("SwapAudio = %q, %v", old, err)
+	}
+
+	// Unknown book: nothing is written
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT audio_key FROM books`).WithArgs("missing").WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+	if _, err := SwapAudio(t.Context(), db, "missing", "books/x.mp3", m); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("SwapAudio(missing) err = %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,101 @@
+package books
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// AudioChapter is a named start offset within a book's audio.
+type AudioChapter struct {
+	ID           string  `json:"id,omitempty"`
+	Title        string  `json:"title"`
+	StartSeconds float64 `json:"start_seconds"`
+}
+
+var ErrInvalidChapters = errors.New("invalid chapters")
+
+// ListAudioChapters returns a book's chapters ordered by start offset.
+func ListAudioChapters(ctx context.Context, db *sql.DB, bookID string) ([]AudioChapter, error) {
+	rows, err := db.QueryContext(ctx, `
+SELECT id::text, title, start_seconds
+FROM public.book_audio_chapters
+WHERE book_id = $1
+ORDER BY start_seconds`, bookID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []AudioChapter{}
+	for rows.Next() {
+		var c AudioChapter
+		if err := rows.Scan(&c.ID, &c.Title, &c.StartSeconds); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// ReplaceAudioChapters validates and swaps a book's full chapter list in one tx.
+// durationMS (0 if unknown) bounds the start offsets.
+func ReplaceAudioChapters(ctx context.Context, db *sql.DB, bookID string, in []AudioChapter, durationMS int64) ([]AudioChapter, error) {
+	chapters, err := normalizeChapters(in, durationMS)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM public.book_audio_chapters WHERE book_id = $1`, bookID); err != nil {
+		return nil, err
+	}
+	for i := range chapters {
+		if err := tx.QueryRowContext(ctx, `
+INSERT INTO public.book_audio_chapters (book_id, title, start_seconds)
+VALUES ($1, $2, $3)
+RETURNING id::text`, bookID, chapters[i].Title, chapters[i].StartSeconds).Scan(&chapters[i].ID); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return chapters, nil
+}
+
+func normalizeChapters(in []AudioChapter, durationMS int64) ([]AudioChapter, error) {
+	if len(in) > 500 {
+		return nil, fmt.Errorf("%w: at most 500 chapters", ErrInvalidChapters)
+	}
+	out := make([]AudioChapter, 0, len(in))
+	for _, c := range in {
+		c.ID = ""
+		c.Title = strings.TrimSpace(c.Title)
+		if c.Title == "" || len(c.Title) > 200 {
+			return nil, fmt.Errorf("%w: title must be 1-200 chars", ErrInvalidChapters)
+		}
+		if c.StartSeconds < 0 {
+			return nil, fmt.Errorf("%w: start_seconds must be >= 0", ErrInvalidChapters)
+		}
+		if durationMS > 0 && c.StartSeconds*1000 >= float64(durationMS) {
+			return nil, fmt.Errorf("%w: %q starts after the end of the audio", ErrInvalidChapters, c.Title)
+		}
+		out = append(out, c)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].StartSeconds < out[j].StartSeconds })
+	for i := 1; i < len(out); i++ {
+		if out[i].StartSeconds == out[i-1].StartSeconds {
+			return nil, fmt.Errorf("%w: duplicate start_seconds %.3f", ErrInvalidChapters, out[i].StartSeconds)
+		}
+	}
+	return out, nil
+}
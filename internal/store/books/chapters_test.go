@@ -0,0 +1,50 @@
+package books
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeChapters(t *testing.T) {
+	got, err := normalizeChapters([]AudioChapter{
+		{ID: "old", Title: "  Part two ", StartSeconds: 600},
+		{Title: "Intro", StartSeconds: 0},
+		{Title: "Part one", StartSeconds: 90.5},
+	}, 3_600_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []AudioChapter{
+		{Title: "Intro", StartSeconds: 0},
+		{Title: "Part one", StartSeconds: 90.5},
+		{Title: "Part two", StartSeconds: 600},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %+v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("chapter %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	// Unknown duration: no upper bound
+	if _, err := normalizeChapters([]AudioChapter{{Title: "Late", StartSeconds: 1e6}}, 0); err != nil {
+		t.Errorf("unknown duration: %v", err)
+	}
+
+	bad := map[string][]AudioChapter{
+		"empty title":     {{Title: "  ", StartSeconds: 0}},
+		"long title":      {{Title: strings.Repeat("x", 201), StartSeconds: 0}},
+		"negative start":  {{Title: "A", StartSeconds: -1}},
+		"past the end":    {{Title: "A", StartSeconds: 3600}},
+		"duplicate start": {{Title: "A", StartSeconds: 10}, {Title: "B", StartSeconds: 10}},
+		"too many":        make([]AudioChapter, 501),
+	}
+	for name, in := range bad {
+		if _, err := normalizeChapters(in, 3_600_000); !errors.Is(err, ErrInvalidChapters) {
+			t.Errorf("%s: err = %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package books
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/5w1tchy/books-api/internal/media/cover"
+	"github.com/5w1tchy/books-api/internal/storage/blob"
+	"github.com/5w1tchy/books-api/internal/store/shared"
+)
+
+// CoverVariant is one stored rendition of a cover (books.cover_variants).
+type CoverVariant struct {
+	Width       int    `json:"w"`
+	Height      int    `json:"h"`
+	Key         string `json:"key"`
+	ContentType string `json:"type"`
+}
+
+// CoverImage is a cover object, its placeholder and its variants, smallest first.
+type CoverImage struct {
+	Key         string
+	Placeholder shared.CoverPlaceholder
+	Variants    []CoverVariant
+}
+
+// PlaceholderOf converts what cover.Process computed into the stored shape.
+func PlaceholderOf(p *cover.Processed) shared.CoverPlaceholder {
+	return shared.CoverPlaceholder{
+		Width:    p.Width,
+		Height:   p.Height,
+		Color:    p.Color,
+		BlurHash: p.BlurHash,
+	}
+}
+
+// CoverRef identifies a book's current cover.
+type CoverRef struct {
+	BookID   string
+	Slug     string
+	Key      string // empty if the book has no cover
+	Variants []CoverVariant
+}
+
+// VariantFor returns the object key to serve for a requested width
+// (0 means the original).
+func (c CoverRef) VariantFor(width int) string {
+	if width <= 0 {
+		return c.Key
+	}
+	widths := make([]int, len(c.Variants))
+	for i, v := range c.Variants {
+		widths[i] = v.Width
+	}
+	w := cover.Nearest(widths, width)
+	for _, v := range c.Variants {
+		if v.Width == w {
+			return v.Key
+		}
+	}
+	return c.Key
+}
+
+// GetCoverRef resolves a book key (uuid, short_id or slug) to its cover.
+func GetCoverRef(ctx context.Context, db *sql.DB, key string) (CoverRef, error) {
+	cond, arg := shared.ResolveBookKeyCondArg(ctx, key)
+	var ref CoverRef
+	var coverKey sql.NullString
+	var variants []byte
+	err := db.QueryRowContext(ctx, `
+SELECT b.id::text, b.slug, b.cover_url, b.cover_variants
+FROM books b
+WHERE `+cond, arg).Scan(&ref.BookID, &ref.Slug, &coverKey, &variants)
+	if err != nil {
+		return ref, err
+	}
+	ref.Key = coverKey.String
+	if len(variants) > 0 {
+		_ = json.Unmarshal(variants, &ref.Variants)
+	}
+	return ref, nil
+}
+
+// SwapCover points a book at a new cover, its placeholder and its variants and
+// returns the key it replaced (empty if none). The row is locked between the
+// read and the write, as in SwapAudio. Returns sql.ErrNoRows if no book has that id.
+func SwapCover(ctx context.Context, db *sql.DB, bookID string, img CoverImage) (string, error) {
+	variants, err := json.Marshal(nonNilVariants(img.Variants))
+	if err != nil {
+		return "", err
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var old sql.NullString
+	if err := tx.QueryRowContext(ctx, `SELECT cover_url FROM books WHERE id::text = $1 FOR UPDATE`, bookID).Scan(&old); err != nil {
+		return "", err
+	}
+	ph := img.Placeholder
+	if _, err := tx.ExecContext(ctx, `
+UPDATE books
+SET cover_url = $1, cover_variants = $2,
+    cover_width = NULLIF($3, 0), cover_height = NULLIF($4, 0),
+    cover_color = NULLIF($5, ''), cover_blurhash = NULLIF($6, ''),
+    updated_at = now()
+WHERE id::text = $7`, img.Key, variants,
+		ph.Width, ph.Height, ph.Color, ph.BlurHash, bookID); err != nil {
+		return "", err
+	}
+	return old.String, tx.Commit()
+}
+
+// VariantsJSON encodes variants for the cover_variants column.
+func VariantsJSON(v []CoverVariant) []byte {
+	raw, _ := json.Marshal(nonNilVariants(v))
+	return raw
+}
+
+// StoreCoverVariants uploads the rendered variants of an already stored cover.
+// On failure the variants uploaded so far are removed again.
+func StoreCoverVariants(ctx context.Context, blobs blob.BlobStore, coverKey string, p *cover.Processed) ([]CoverVariant, error) {
+	out := make([]CoverVariant, 0, len(p.Variants))
+	for _, v := range p.Variants {
+		key := cover.VariantKey(coverKey, v)
+		if err := blobs.Put(ctx, key, bytes.NewReader(v.Data), int64(len(v.Data)), v.ContentType()); err != nil {
+			for _, done := range out {
+				_ = blobs.Delete(ctx, done.Key)
+			}
+			return nil, fmt.Errorf("store cover variant %s: %w", key, err)
+		}
+		out = append(out, CoverVariant{
+			Width:       v.Width,
+			Height:      v.Height,
+			Key:         key,
+			ContentType: v.ContentType(),
+		})
+	}
+	return out, nil
+}
+
+// DeleteCoverObjects removes a cover object and all of its variants (best effort).
+func DeleteCoverObjects(ctx context.Context, blobs blob.BlobStore, key string) {
+	if key == "" {
+		return
+	}
+	if err := blobs.Delete(ctx, key); err != nil {
+		log.Printf("[cover] delete %s: %v", key, err)
+	}
+	variants, err := blobs.List(ctx, cover.VariantPrefix(key), false)
+	if err != nil {
+		log.Printf("[cover] list variants of %s: %v", key, err)
+		return
+	}
+	for _, v := range variants {
+		if err := blobs.Delete(ctx, v.Key); err != nil {
+			log.Printf("[cover] delete %s: %v", v.Key, err)
+		}
+	}
+}
+
+func nonNilVariants(v []CoverVariant) []CoverVariant {
+	if v == nil {
+		return []CoverVariant{}
+	}
+	return v
+}
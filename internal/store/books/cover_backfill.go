@@ -0,0 +1,141 @@
+package books
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/5w1tchy/books-api/internal/media/cover"
+	"github.com/5w1tchy/books-api/internal/storage/blob"
+)
+
+// BackfillCoversOptions controls BackfillCovers.
+type BackfillCoversOptions struct {
+	Limit    int  // books to process; <= 0 means all
+	Variants bool // also render variants for covers that have none
+	DryRun   bool // process but don't write anything
+}
+
+// BackfillCoversResult summarises a BackfillCovers run.
+type BackfillCoversResult struct {
+	Scanned  int               `json:"scanned"`
+	Updated  int               `json:"updated"`
+	Variants int               `json:"variants"` // variant objects uploaded
+	Failed   map[string]string `json:"failed,omitempty"`
+}
+
+const backfillBatch = 50
+
+// BackfillCovers computes the placeholder (size, dominant colour, BlurHash)
+// for covers uploaded before it was stored, walking books by id. A row whose
+// cover changes in the meantime is left to the upload that changed it.
+func BackfillCovers(ctx context.Context, db *sql.DB, blobs blob.BlobStore, opts BackfillCoversOptions) (BackfillCoversResult, error) {
+	res := BackfillCoversResult{Failed: map[string]string{}}
+	after := ""
+	for opts.Limit <= 0 || res.Scanned < opts.Limit {
+		batch := backfillBatch
+		if opts.Limit > 0 {
+			batch = min(batch, opts.Limit-res.Scanned)
+		}
+		rows, err := db.QueryContext(ctx, `
+SELECT id::text, cover_url, jsonb_array_length(cover_variants)
+FROM books
+WHERE COALESCE(cover_url, '') <> '' AND cover_blurhash IS NULL AND id::text > $1
+ORDER BY id::text
+LIMIT $2`, after, batch)
+		if err != nil {
+			return res, err
+		}
+		type pendingCover struct {
+			id, key  string
+			variants int
+		}
+		var todo []pendingCover
+		for rows.Next() {
+			var c pendingCover
+			if err := rows.Scan(&c.id, &c.key, &c.variants); err != nil {
+				rows.Close()
+				return res, err
+			}
+			todo = append(todo, c)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return res, err
+		}
+		if len(todo) == 0 {
+			break
+		}
+
+		for _, c := range todo {
+			after = c.id
+			res.Scanned++
+			n, err := backfillCover(ctx, db, blobs, c.id, c.key, opts.Variants && c.variants == 0, opts.DryRun)
+			if err != nil {
+				log.Printf("[cover] backfill %s (%s): %v", c.id, c.key, err)
+				res.Failed[c.id] = err.Error()
+				continue
+			}
+			res.Updated++
+			res.Variants += n
+		}
+		if ctx.Err() != nil {
+			return res, ctx.Err()
+		}
+	}
+	return res, nil
+}
+
+// backfillCover processes one cover and stores its placeholder (and variants
+// if withVariants). Returns the number of variant objects uploaded.
+func backfillCover(ctx context.Context, db *sql.DB, blobs blob.BlobStore, bookID, key string, withVariants, dryRun bool) (int, error) {
+	data, _, err := blob.ReadAll(ctx, blobs, key)
+	if err != nil {
+		return 0, err
+	}
+	p, err := cover.Process(data)
+	if err != nil {
+		return 0, err
+	}
+	if dryRun {
+		if withVariants {
+			return len(p.Variants), nil
+		}
+		return 0, nil
+	}
+
+	var variants []CoverVariant
+	if withVariants {
+		if variants, err = StoreCoverVariants(ctx, blobs, key, p); err != nil {
+			return 0, err
+		}
+	}
+	ph := PlaceholderOf(p)
+	q := `
+UPDATE books
+SET cover_width = $1, cover_height = $2, cover_color = $3, cover_blurhash = $4`
+	args := []any{ph.Width, ph.Height, ph.Color, ph.BlurHash}
+	if withVariants {
+		q += `, cover_variants = $7`
+		args = append(args, bookID, key, VariantsJSON(variants))
+	} else {
+		args = append(args, bookID, key)
+	}
+	q += `
+WHERE id::text = $5 AND cover_url = $6`
+
+	r, err := db.ExecContext(ctx, q, args...)
+	if err == nil {
+		if n, _ := r.RowsAffected(); n == 0 {
+			err = fmt.Errorf("cover changed during backfill")
+		}
+	}
+	if err != nil {
+		for _, v := range variants {
+			_ = blobs.Delete(ctx, v.Key)
+		}
+		return 0, err
+	}
+	return len(variants), nil
+}
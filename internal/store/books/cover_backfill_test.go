@@ -0,0 +1,65 @@
+package books
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/5w1tchy/books-api/internal/storage/blob"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestBackfillCovers(t *testing.T) {
+	ctx := t.Context()
+	blobs := blob.NewMemory()
+
+	img := image.NewNRGBA(image.Rect(0, 0, 300, 450))
+	for i := 0; i < len(img.Pix); i += 4 {
+		copy(img.Pix[i:], []uint8{0x20, 0x40, 0x80, 0xff})
+	}
+	img.SetNRGBA(0, 0, color.NRGBA{A: 255})
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	const key = "books/covers/dune-1.png"
+	if err := blobs.Put(ctx, key, bytes.NewReader(buf.Bytes()), int64(buf.Len()), "image/png"); err != nil {
+		t.Fatal(err)
+	}
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT id::text, cover_url`).
+		WithArgs("", backfillBatch).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "cover_url", "n"}).
+			AddRow("b1", key, 0).
+			AddRow("b2", "books/covers/missing.jpg", 0))
+	mock.ExpectExec(`UPDATE books\s+SET cover_width = \$1, cover_height = \$2, cover_color = \$3, cover_blurhash = \$4, cover_variants = \$7`).
+		WithArgs(300, 450, "#204080", sqlmock.AnyArg(), "b1", key, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT id::text, cover_url`).
+		WithArgs("b2", backfillBatch).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "cover_url", "n"}))
+
+	res, err := BackfillCovers(ctx, db, blobs, BackfillCoversOptions{Variants: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Scanned != 2 || res.Updated != 1 || res.Variants != 2 || len(res.Failed) != 1 || res.Failed["b2"] == "" {
+		t.Fatalf("result = %+v", res)
+	}
+	vs, _ := blobs.List(ctx, "books/covers/dune-1/", false)
+	if len(vs) != 2 || !strings.HasSuffix(vs[0].Key, "/128.jpg") {
+		t.Errorf("variants = %+v", vs)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
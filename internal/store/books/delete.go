@@ -5,11 +5,11 @@ import (
 	"database/sql"
 	"fmt"
 
-	storage "github.com/5w1tchy/books-api/internal/storage/s3"
+	"github.com/5w1tchy/books-api/internal/storage/blob"
 )
 
-// DeleteV2 deletes a book and its relationships, and cleans up R2 files
-func DeleteV2(ctx context.Context, db *sql.DB, key string) error {
+// DeleteV2 deletes a book and its relationships, and cleans up stored files
+func DeleteV2(ctx context.Context, db *sql.DB, blobs blob.BlobStore, key string) error {
 	// First get the book ID and file keys to ensure it exists
 	existing, err := GetAdminBookByID(ctx, db, key)
 	if err != nil {
@@ -54,18 +54,13 @@ func DeleteV2(ctx context.Context, db *sql.DB, key string) error {
 		return err
 	}
 
-	// Clean up R2 files (best effort, don't fail if this fails)
-	r2, err := storage.NewR2Client(ctx)
-	if err == nil {
-		if coverURL.Valid && coverURL.String != "" {
-			if err := r2.DeleteObject(ctx, coverURL.String); err != nil {
-				fmt.Printf("Warning: failed to delete cover %s: %v\n", coverURL.String, err)
-			}
-		}
-		if audioKey.Valid && audioKey.String != "" {
-			if err := r2.DeleteObject(ctx, audioKey.String); err != nil {
-				fmt.Printf("Warning: failed to delete audio %s: %v\n", audioKey.String, err)
-			}
+	// Clean up stored files (best effort, don't fail if this fails)
+	if coverURL.Valid && coverURL.String != "" {
+		DeleteCoverObjects(ctx, blobs, coverURL.String)
+	}
+	if audioKey.Valid && audioKey.String != "" {
+		if err := blobs.Delete(ctx, audioKey.String); err != nil {
+			fmt.Printf("Warning: failed to delete audio %s: %v\n", audioKey.String, err)
 		}
 	}
 
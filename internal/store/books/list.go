@@ -6,6 +6,8 @@ import (
 	"encoding/json"
 	"strconv"
 	"strings"
+
+	"github.com/5w1tchy/books-api/internal/store/shared"
 )
 
 // List returns page of books (same filters/behavior as before) and total count.
@@ -97,7 +99,11 @@ SELECT
   b.title,
   COALESCE(jsonb_agg(DISTINCT a.name) FILTER (WHERE a.name IS NOT NULL), '[]'::jsonb) AS authors,
   COALESCE(jsonb_agg(DISTINCT c_all.slug) FILTER (WHERE c_all.slug IS NOT NULL), '[]'::jsonb) AS categories,
-  b.cover_url
+  b.cover_url,
+  ` + shared.CoverPlaceholderCol + ` AS cover_placeholder,
+  b.created_at,
+  COALESCE(b.updated_at, b.created_at) AS updated_at,
+  b.audio_duration_ms
 FROM books b
 LEFT JOIN book_authors ba ON ba.book_id = b.id
 LEFT JOIN authors a ON a.id = ba.author_id
@@ -108,7 +114,7 @@ LEFT JOIN categories c_all ON c_all.id = bc1.category_id
 		qRows += "WHERE " + strings.Join(where, " AND ") + "\n"
 	}
 	qRows += `
-GROUP BY b.id, b.short_id, b.slug, b.title, b.cover_url
+GROUP BY b.id, b.short_id, b.slug, b.title, b.cover_url, b.created_at, b.updated_at, b.audio_duration_ms
 `
 	if qIdx != -1 {
 		qRows += `
@@ -131,11 +137,13 @@ ORDER BY GREATEST(
 	for rows.Next() {
 		var pb PublicBook
 		var authorsJSON, catsJSON []byte
-		if err := rows.Scan(&pb.ID, &pb.ShortID, &pb.Slug, &pb.Title, &authorsJSON, &catsJSON, &pb.CoverURL); err != nil {
+		var durationMS sql.NullInt64
+		if err := rows.Scan(&pb.ID, &pb.ShortID, &pb.Slug, &pb.Title, &authorsJSON, &catsJSON, &pb.CoverURL, &pb.Cover, &pb.CreatedAt, &pb.UpdatedAt, &durationMS); err != nil {
 			return nil, 0, err
 		}
 		_ = json.Unmarshal(authorsJSON, &pb.Authors)
 		_ = json.Unmarshal(catsJSON, &pb.CategorySlugs)
+		pb.AudioDuration = durationSeconds(durationMS)
 		pb.URL = "/books/" + pb.Slug
 		out = append(out, pb)
 	}
@@ -16,12 +16,12 @@ func GetAdminBookByID(ctx context.Context, db *sql.DB, id string) (AdminBook, er
 	var book AdminBook
 
 	query := `
-        SELECT id, COALESCE(slug, ''), COALESCE(coda, ''), title, COALESCE(short, ''), COALESCE(summary, ''), cover_url, created_at
-        FROM books WHERE id = $1
+        SELECT id, COALESCE(slug, ''), COALESCE(coda, ''), title, COALESCE(short, ''), COALESCE(summary, ''), cover_url, ` + shared.CoverPlaceholderCol + `, created_at
+        FROM books b WHERE id = $1
     `
 
 	err := db.QueryRowContext(ctx, query, id).Scan(
-		&book.ID, &book.Slug, &book.Coda, &book.Title, &book.Short, &book.Summary, &book.CoverURL, &book.CreatedAt,
+		&book.ID, &book.Slug, &book.Coda, &book.Title, &book.Short, &book.Summary, &book.CoverURL, &book.Cover, &book.CreatedAt,
 	)
 	if err != nil {
 		return AdminBook{}, err
@@ -93,21 +93,24 @@ SELECT
     COALESCE(b.summary, '') AS summary,
     COALESCE(b.coda, '')    AS coda,
     b.cover_url,
-    COALESCE(b.audio_key, '') AS audio_key
+    ` + shared.CoverPlaceholderCol + ` AS cover_placeholder,
+    COALESCE(b.audio_key, '') AS audio_key,
+    b.audio_duration_ms
 FROM books b
 LEFT JOIN book_authors ba ON ba.book_id = b.id
 LEFT JOIN authors a       ON a.id = ba.author_id
 LEFT JOIN book_categories bc ON bc.book_id = b.id
 LEFT JOIN categories c       ON c.id = bc.category_id
 WHERE ` + cond + `
-GROUP BY b.id, b.short_id, b.slug, b.title, b.summary, b.coda, b.cover_url, b.audio_key
+GROUP BY b.id, b.short_id, b.slug, b.title, b.summary, b.coda, b.cover_url, b.audio_key, b.audio_duration_ms
 `
 
 	var pb PublicBook
 	var authorsJSON, catsJSON []byte
+	var durationMS sql.NullInt64
 
 	if err := db.QueryRowContext(ctx, q, arg).
-		Scan(&pb.ID, &pb.ShortID, &pb.Slug, &pb.Title, &authorsJSON, &catsJSON, &pb.Summary, &pb.Coda, &pb.CoverURL, &pb.AudioKey); err != nil {
+		Scan(&pb.ID, &pb.ShortID, &pb.Slug, &pb.Title, &authorsJSON, &catsJSON, &pb.Summary, &pb.Coda, &pb.CoverURL, &pb.Cover, &pb.AudioKey, &durationMS); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return PublicBook{}, sql.ErrNoRows
 		}
@@ -116,6 +119,7 @@ GROUP BY b.id, b.short_id, b.slug, b.title, b.summary, b.coda, b.cover_url, b.au
 
 	_ = json.Unmarshal(authorsJSON, &pb.Authors)
 	_ = json.Unmarshal(catsJSON, &pb.CategorySlugs)
+	pb.AudioDuration = durationSeconds(durationMS)
 
 	pb.URL = "/books/" + pb.Slug
 	pb.Short = "" // ensure short is empty on the book page
@@ -171,11 +175,11 @@ func fetchAdminBooks(ctx context.Context, db *sql.DB, baseQuery, whereClause str
 	argIndex := len(args) + 1
 
 	listQuery := fmt.Sprintf(`
-        SELECT DISTINCT b.id, COALESCE(b.slug, ''), COALESCE(b.coda, ''), b.title, COALESCE(b.short, ''), COALESCE(b.summary, ''), b.cover_url, b.created_at
+        SELECT DISTINCT b.id, COALESCE(b.slug, ''), COALESCE(b.coda, ''), b.title, COALESCE(b.short, ''), COALESCE(b.summary, ''), b.cover_url, %s, b.created_at
         %s %s
         ORDER BY b.created_at DESC
         LIMIT $%d OFFSET $%d
-    `, baseQuery, whereClause, argIndex, argIndex+1)
+    `, shared.CoverPlaceholderCol, baseQuery, whereClause, argIndex, argIndex+1)
 
 	args = append(args, filter.Size, offset)
 
@@ -188,7 +192,7 @@ func fetchAdminBooks(ctx context.Context, db *sql.DB, baseQuery, whereClause str
 	var books []AdminBook
 	for rows.Next() {
 		var book AdminBook
-		if err := rows.Scan(&book.ID, &book.Slug, &book.Coda, &book.Title, &book.Short, &book.Summary, &book.CoverURL, &book.CreatedAt); err != nil {
+		if err := rows.Scan(&book.ID, &book.Slug, &book.Coda, &book.Title, &book.Short, &book.Summary, &book.CoverURL, &book.Cover, &book.CreatedAt); err != nil {
 			return nil, err
 		}
 
@@ -0,0 +1,87 @@
+package books
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Ref is a lightweight category/author row used by catalog navigation feeds.
+type Ref struct {
+	Slug    string
+	Name    string
+	Books   int
+	Updated time.Time // newest book change in this group
+}
+
+// ListCategoryRefs returns all categories that have at least one book.
+func ListCategoryRefs(ctx context.Context, db *sql.DB) ([]Ref, error) {
+	return queryRefs(ctx, db, `
+SELECT c.slug, c.name, COUNT(DISTINCT b.id), MAX(COALESCE(b.updated_at, b.created_at))
+FROM categories c
+JOIN book_categories bc ON bc.category_id = c.id
+JOIN books b            ON b.id = bc.book_id
+GROUP BY c.slug, c.name
+ORDER BY c.name`)
+}
+
+// ListAuthorRefs returns all authors that have at least one book.
+func ListAuthorRefs(ctx context.Context, db *sql.DB) ([]Ref, error) {
+	return queryRefs(ctx, db, `
+SELECT a.slug, a.name, COUNT(DISTINCT b.id), MAX(COALESCE(b.updated_at, b.created_at))
+FROM authors a
+JOIN book_authors ba ON ba.author_id = a.id
+JOIN books b         ON b.id = ba.book_id
+GROUP BY a.slug, a.name
+ORDER BY a.name`)
+}
+
+// GetCategoryRef looks up one category by slug (sql.ErrNoRows if missing).
+func GetCategoryRef(ctx context.Context, db *sql.DB, slug string) (Ref, error) {
+	var r Ref
+	var updated sql.NullTime
+	err := db.QueryRowContext(ctx, `
+SELECT c.slug, c.name, COUNT(DISTINCT bc.book_id), MAX(COALESCE(b.updated_at, b.created_at))
+FROM categories c
+LEFT JOIN book_categories bc ON bc.category_id = c.id
+LEFT JOIN books b            ON b.id = bc.book_id
+WHERE c.slug = $1
+GROUP BY c.slug, c.name`, slug).Scan(&r.Slug, &r.Name, &r.Books, &updated)
+	r.Updated = updated.Time
+	return r, err
+}
+
+// GetAuthorRef looks up one author by slug (sql.ErrNoRows if missing).
+func GetAuthorRef(ctx context.Context, db *sql.DB, slug string) (Ref, error) {
+	var r Ref
+	var updated sql.NullTime
+	err := db.QueryRowContext(ctx, `
+SELECT a.slug, a.name, COUNT(DISTINCT ba.book_id), MAX(COALESCE(b.updated_at, b.created_at))
+FROM authors a
+LEFT JOIN book_authors ba ON ba.author_id = a.id
+LEFT JOIN books b         ON b.id = ba.book_id
+WHERE a.slug = $1
+GROUP BY a.slug, a.name`, slug).Scan(&r.Slug, &r.Name, &r.Books, &updated)
+	r.Updated = updated.Time
+	return r, err
+}
+
+func queryRefs(ctx context.Context, db *sql.DB, q string) ([]Ref, error) {
+	rows, err := db.QueryContext(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Ref
+	for rows.Next() {
+		var r Ref
+		var updated sql.NullTime
+		if err := rows.Scan(&r.Slug, &r.Name, &r.Books, &updated); err != nil {
+			return nil, err
+		}
+		r.Updated = updated.Time
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
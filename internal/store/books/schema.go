@@ -0,0 +1,79 @@
+package books
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/5w1tchy/books-api/internal/store/shared"
+)
+
+// NamedRef is a display name with its slug (authors and categories).
+type NamedRef struct {
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+// SchemaBook carries what public markup (JSON-LD, share cards) needs about a book.
+type SchemaBook struct {
+	PublicBook
+	AuthorRefs   []NamedRef
+	CategoryRefs []NamedRef
+}
+
+// FetchSchemaBook loads a book by id/short_id/slug with author and category names.
+func FetchSchemaBook(ctx context.Context, db *sql.DB, key string) (SchemaBook, error) {
+	cond, arg := shared.ResolveBookKeyCondArg(ctx, key)
+
+	q := `
+SELECT
+    b.id,
+    b.short_id,
+    b.slug,
+    b.title,
+    COALESCE(jsonb_agg(DISTINCT jsonb_build_object('name', a.name, 'slug', a.slug)) FILTER (WHERE a.id IS NOT NULL), '[]'::jsonb) AS authors,
+    COALESCE(jsonb_agg(DISTINCT jsonb_build_object('name', c.name, 'slug', c.slug)) FILTER (WHERE c.id IS NOT NULL), '[]'::jsonb) AS categories,
+    COALESCE(b.summary, '') AS summary,
+    COALESCE(b.short::text, '') AS short,
+    b.cover_url,
+    ` + shared.CoverPlaceholderCol + ` AS cover_placeholder,
+    b.created_at,
+    COALESCE(b.updated_at, b.created_at) AS updated_at
+FROM books b
+LEFT JOIN book_authors ba    ON ba.book_id = b.id
+LEFT JOIN authors a          ON a.id = ba.author_id
+LEFT JOIN book_categories bc ON bc.book_id = b.id
+LEFT JOIN categories c       ON c.id = bc.category_id
+WHERE ` + cond + `
+GROUP BY b.id, b.short_id, b.slug, b.title, b.summary, b.short, b.cover_url, b.created_at, b.updated_at
+`
+
+	var sb SchemaBook
+	var authorsJSON, catsJSON []byte
+	var short string
+	err := db.QueryRowContext(ctx, q, arg).Scan(
+		&sb.ID, &sb.ShortID, &sb.Slug, &sb.Title, &authorsJSON, &catsJSON,
+		&sb.Summary, &short, &sb.CoverURL, &sb.Cover, &sb.CreatedAt, &sb.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return SchemaBook{}, sql.ErrNoRows
+	} else if err != nil {
+		return SchemaBook{}, err
+	}
+
+	_ = json.Unmarshal(authorsJSON, &sb.AuthorRefs)
+	_ = json.Unmarshal(catsJSON, &sb.CategoryRefs)
+	for _, a := range sb.AuthorRefs {
+		sb.Authors = append(sb.Authors, a.Name)
+	}
+	for _, c := range sb.CategoryRefs {
+		sb.CategorySlugs = append(sb.CategorySlugs, c.Slug)
+	}
+	// short is stored as a JSON string
+	if err := json.Unmarshal([]byte(short), &sb.Short); err != nil {
+		sb.Short = short
+	}
+	sb.URL = "/books/" + sb.Slug
+	return sb, nil
+}
@@ -0,0 +1,99 @@
+package books
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// SitemapEntry is one public page (book, author or category) for sitemap.xml.
+type SitemapEntry struct {
+	Path    string // relative to the web app (APP_BASE_URL), e.g. /books/atomic-habits
+	Updated time.Time
+}
+
+// SitemapPage summarises one sitemap file of at most pageSize entries.
+type SitemapPage struct {
+	Page    int // 1-based
+	Updated time.Time
+}
+
+// sitemapURLs orders every public page deterministically so pages stay stable:
+// books, then authors, then categories, each by slug.
+const sitemapURLs = `
+WITH u AS (
+  SELECT 1 AS ord, '/books/' || b.slug AS path, COALESCE(b.updated_at, b.created_at) AS updated
+  FROM books b
+  UNION ALL
+  SELECT 2, '/authors/' || a.slug, MAX(COALESCE(b.updated_at, b.created_at))
+  FROM authors a
+  JOIN book_authors ba ON ba.author_id = a.id
+  JOIN books b         ON b.id = ba.book_id
+  GROUP BY a.slug
+  UNION ALL
+  SELECT 3, '/categories/' || c.slug, MAX(COALESCE(b.updated_at, b.created_at))
+  FROM categories c
+  JOIN book_categories bc ON bc.category_id = c.id
+  JOIN books b            ON b.id = bc.book_id
+  GROUP BY c.slug
+), n AS (
+  SELECT path, updated, ROW_NUMBER() OVER (ORDER BY ord, path) AS rn FROM u
+)
+`
+
+// ListSitemapPages splits all public pages into files of pageSize and returns
+// each file's newest lastmod. An empty catalog still yields page 1.
+func ListSitemapPages(ctx context.Context, db *sql.DB, pageSize int) ([]SitemapPage, error) {
+	rows, err := db.QueryContext(ctx, sitemapURLs+`
+SELECT ((rn - 1) / $1) + 1 AS page, MAX(updated)
+FROM n
+GROUP BY page
+ORDER BY page`, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []SitemapPage
+	for rows.Next() {
+		var p SitemapPage
+		var updated sql.NullTime
+		if err := rows.Scan(&p.Page, &updated); err != nil {
+			return nil, err
+		}
+		p.Updated = updated.Time
+		out = append(out, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		out = append(out, SitemapPage{Page: 1})
+	}
+	return out, nil
+}
+
+// ListSitemapEntries returns the entries of one sitemap file (1-based page).
+func ListSitemapEntries(ctx context.Context, db *sql.DB, page, pageSize int) ([]SitemapEntry, error) {
+	rows, err := db.QueryContext(ctx, sitemapURLs+`
+SELECT path, updated
+FROM n
+WHERE rn > $1 AND rn <= $2
+ORDER BY rn`, (page-1)*pageSize, page*pageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []SitemapEntry
+	for rows.Next() {
+		var e SitemapEntry
+		var updated sql.NullTime
+		if err := rows.Scan(&e.Path, &updated); err != nil {
+			return nil, err
+		}
+		e.Updated = updated.Time
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
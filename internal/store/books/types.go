@@ -2,21 +2,29 @@ package books
 
 import (
 	"time"
+
+	"github.com/5w1tchy/books-api/internal/store/shared"
 )
 
 type PublicBook struct {
-	ID            string   `json:"id"`
-	ShortID       int      `json:"short_id"`
-	Slug          string   `json:"slug"`
-	Title         string   `json:"title"`
-	Authors       []string `json:"author"`
-	CategorySlugs []string `json:"category_slugs"`
-	Summary       string   `json:"summary,omitempty"`
-	Short         string   `json:"short,omitempty"`
-	Coda          string   `json:"coda,omitempty"`
-	URL           string   `json:"url"`
-	CoverURL      *string  `json:"cover_url,omitempty"`
-	AudioKey      string   `json:"audio_key"`
+	ID            string                  `json:"id"`
+	ShortID       int                     `json:"short_id"`
+	Slug          string                  `json:"slug"`
+	Title         string                  `json:"title"`
+	Authors       []string                `json:"author"`
+	CategorySlugs []string                `json:"category_slugs"`
+	Summary       string                  `json:"summary,omitempty"`
+	Short         string                  `json:"short,omitempty"`
+	Coda          string                  `json:"coda,omitempty"`
+	URL           string                  `json:"url"`
+	CoverURL      *string                 `json:"cover_url,omitempty"`
+	Cover         shared.CoverPlaceholder `json:"cover_placeholder,omitzero"`
+	AudioKey      string                  `json:"audio_key"`
+	AudioDuration *int                    `json:"audio_duration_seconds,omitempty"`
+
+	// Feed/sitemap only; not part of the JSON shape.
+	CreatedAt time.Time `json:"-"`
+	UpdatedAt time.Time `json:"-"`
 }
 
 type ListFilters struct {
@@ -31,16 +39,17 @@ type ListFilters struct {
 
 // AdminBook is the rich shape returned by CreateV2.
 type AdminBook struct {
-	ID         string    `json:"id"`
-	Slug       string    `json:"slug"`
-	Coda       string    `json:"coda,omitempty"`
-	Title      string    `json:"title"`
-	Authors    []string  `json:"authors"`
-	Categories []string  `json:"categories"`
-	Short      string    `json:"short,omitempty"`
-	Summary    string    `json:"summary,omitempty"`
-	CreatedAt  time.Time `json:"created_at"`
-	CoverURL   *string   `json:"cover_url,omitempty"`
+	ID         string                  `json:"id"`
+	Slug       string                  `json:"slug"`
+	Coda       string                  `json:"coda,omitempty"`
+	Title      string                  `json:"title"`
+	Authors    []string                `json:"authors"`
+	Categories []string                `json:"categories"`
+	Short      string                  `json:"short,omitempty"`
+	Summary    string                  `json:"summary,omitempty"`
+	CreatedAt  time.Time               `json:"created_at"`
+	CoverURL   *string                 `json:"cover_url,omitempty"`
+	Cover      shared.CoverPlaceholder `json:"cover_placeholder,omitzero"`
 }
 
 type CreateBookV2DTO struct {
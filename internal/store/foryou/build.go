@@ -16,6 +16,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/5w1tchy/books-api/internal/store/shared"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -41,10 +42,7 @@ func errf(where string, err error) error {
 // ----------------- public entry ------------------
 
 func Build(ctx context.Context, db *sql.DB, rdb *redis.Client, lim Limits, f Fields) (Sections, error) {
-	tz := mustTZ(productTZName)
-	now := time.Now().In(tz)
-	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, tz)
-	tomorrow := today.Add(24 * time.Hour)
+	today, tomorrow := ProductDay(time.Now())
 
 	fieldsKey := ""
 	if f.Lite {
@@ -292,10 +290,21 @@ func Build(ctx context.Context, db *sql.DB, rdb *redis.Client, lim Limits, f Fie
 
 // ---------- selection helpers ----------
 
-type shortPick struct{ ID, Slug, Title, Author, Short string }
+type shortPick struct {
+	ID, Slug, Title, Author, Short string
+	Cover                          shared.CoverPlaceholder
+}
 
 // ---------- small utils ----------
 
+// ProductDay returns the [today, tomorrow) window in the product timezone (Asia/Tbilisi).
+func ProductDay(now time.Time) (today, tomorrow time.Time) {
+	tz := mustTZ(productTZName)
+	now = now.In(tz)
+	today = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, tz)
+	return today, today.Add(24 * time.Hour)
+}
+
 func containsID(xs []shortPick, id string) bool {
 	for _, x := range xs {
 		if x.ID == id {
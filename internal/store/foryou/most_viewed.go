@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+
+	"github.com/5w1tchy/books-api/internal/store/shared"
 )
 
 func BuildMostViewed(ctx context.Context, db *sql.DB, limit int, f Fields) ([]BookLite, error) {
@@ -24,6 +26,7 @@ SELECT
   a.name,
   COALESCE(jsonb_agg(DISTINCT c.slug) FILTER (WHERE c.slug IS NOT NULL), '[]'::jsonb) AS slugs,
   COALESCE(b.summary, '') AS summary,
+  ` + shared.CoverPlaceholderCol + ` AS cover_placeholder,
   v.views
 FROM views v
 JOIN books b               ON b.id = v.book_id
@@ -46,7 +49,7 @@ LIMIT $1;`
 		var slugsJSON []byte
 		var summary string
 		var _views int64
-		if err := rows.Scan(&b.ID, &b.Slug, &b.Title, &b.Author, &slugsJSON, &summary, &_views); err != nil {
+		if err := rows.Scan(&b.ID, &b.Slug, &b.Title, &b.Author, &slugsJSON, &summary, &b.Cover, &_views); err != nil {
 			return nil, err
 		}
 		if !f.Lite {
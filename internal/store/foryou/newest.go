@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+
+	"github.com/5w1tchy/books-api/internal/store/shared"
 )
 
 func BuildNewest(ctx context.Context, db *sql.DB, limit int, f Fields) ([]BookLite, error) {
@@ -13,12 +15,15 @@ func BuildNewest(ctx context.Context, db *sql.DB, limit int, f Fields) ([]BookLi
 	const q = `
 SELECT b.id, b.slug, b.title, a.name,
        COALESCE(jsonb_agg(DISTINCT c.slug) FILTER (WHERE c.slug IS NOT NULL), '[]'::jsonb) AS slugs,
-       COALESCE(b.summary, '') AS summary
+       COALESCE(b.summary, '') AS summary,
+       ` + shared.CoverPlaceholderCol + ` AS cover_placeholder,
+       b.created_at,
+       COALESCE(b.updated_at, b.created_at) AS updated_at
 FROM books b
 JOIN authors a               ON a.id = b.author_id
 LEFT JOIN book_categories bc ON bc.book_id = b.id
 LEFT JOIN categories c       ON c.id = bc.category_id
-GROUP BY b.id, b.slug, b.title, a.name, b.created_at
+GROUP BY b.id, b.slug, b.title, a.name, b.created_at, b.updated_at
 ORDER BY b.created_at DESC
 LIMIT $1;`
 	rows, err := db.QueryContext(ctx, q, limit)
@@ -32,7 +37,7 @@ LIMIT $1;`
 		var b BookLite
 		var slugsJSON []byte
 		var summary string
-		if err := rows.Scan(&b.ID, &b.Slug, &b.Title, &b.Author, &slugsJSON, &summary); err != nil {
+		if err := rows.Scan(&b.ID, &b.Slug, &b.Title, &b.Author, &slugsJSON, &summary, &b.Cover, &b.CreatedAt, &b.UpdatedAt); err != nil {
 			return nil, err
 		}
 		if !f.Lite {
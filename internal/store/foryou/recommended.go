@@ -6,6 +6,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"math/rand"
+
+	"github.com/5w1tchy/books-api/internal/store/shared"
 )
 
 func BuildRecs(ctx context.Context, db *sql.DB, limit int, shorts []ShortItem, rng *rand.Rand, f Fields) ([]BookLite, error) {
@@ -37,6 +39,7 @@ recs AS (
     b.id, b.slug, b.title, a.name,
     COALESCE(jsonb_agg(DISTINCT c.slug) FILTER (WHERE c.slug IS NOT NULL), '[]'::jsonb) AS slugs,
     COALESCE(b.summary, '') AS summary,
+    %s AS cover_placeholder,
     MAX(b.created_at) AS newest
   FROM books b
   JOIN authors a          ON a.id = b.author_id
@@ -48,7 +51,7 @@ recs AS (
   ORDER BY newest DESC
   LIMIT $1
 )
-SELECT id, slug, title, name, slugs, summary FROM recs;`
+SELECT id, slug, title, name, slugs, summary, cover_placeholder FROM recs;`
 
 	ph := ""
 	for i := range ids {
@@ -57,7 +60,7 @@ SELECT id, slug, title, name, slugs, summary FROM recs;`
 		}
 		ph += fmt.Sprintf("$%d", i+2)
 	}
-	q = fmt.Sprintf(q, ph)
+	q = fmt.Sprintf(q, ph, shared.CoverPlaceholderCol)
 
 	args := make([]any, 0, 1+len(ids))
 	args = append(args, limit)
@@ -74,7 +77,7 @@ SELECT id, slug, title, name, slugs, summary FROM recs;`
 		var b BookLite
 		var slugsJSON []byte
 		var summary string
-		if err := rows.Scan(&b.ID, &b.Slug, &b.Title, &b.Author, &slugsJSON, &summary); err != nil {
+		if err := rows.Scan(&b.ID, &b.Slug, &b.Title, &b.Author, &slugsJSON, &summary, &b.Cover); err != nil {
 			return nil, err
 		}
 		if !f.Lite {
@@ -5,15 +5,26 @@ import (
 	"database/sql"
 	"math/rand"
 	"time"
+
+	"github.com/5w1tchy/books-api/internal/store/shared"
 )
 
-func BuildShorts(ctx context.Context, db *sql.DB, limit int, today, tomorrow time.Time, rng *rand.Rand) ([]ShortItem, error) {
-	if limit <= 0 {
-		return []ShortItem{}, nil
+// TodayShorts returns the shorts already featured today, without picking
+// any: it never writes, so public readers such as the shorts feed can't
+// decide the day's selection. Returns the day start it used.
+func TodayShorts(ctx context.Context, db *sql.DB, limit int) ([]ShortItem, time.Time, error) {
+	today, tomorrow := ProductDay(time.Now())
+	picks, err := featuredShorts(ctx, db, limit, today, tomorrow)
+	if err != nil {
+		return nil, today, err
 	}
+	return shortItems(picks), today, nil
+}
 
+// featuredShorts reads the shorts marked as featured in [today, tomorrow).
+func featuredShorts(ctx context.Context, db *sql.DB, limit int, today, tomorrow time.Time) ([]shortPick, error) {
 	const qToday = `
-SELECT b.id, b.slug, b.title, a.name, b.short::text AS short
+SELECT b.id, b.slug, b.title, a.name, b.short::text AS short, ` + shared.CoverPlaceholderCol + ` AS cover_placeholder
 FROM books b
 JOIN authors a ON a.id = b.author_id
 WHERE b.short_enabled
@@ -28,22 +39,33 @@ LIMIT $3;`
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 	for rows.Next() {
 		var r shortPick
-		if err := rows.Scan(&r.ID, &r.Slug, &r.Title, &r.Author, &r.Short); err != nil {
-			rows.Close()
+		if err := rows.Scan(&r.ID, &r.Slug, &r.Title, &r.Author, &r.Short, &r.Cover); err != nil {
 			return nil, err
 		}
 		picks = append(picks, r)
 	}
-	rows.Close()
+	return picks, rows.Err()
+}
+
+func BuildShorts(ctx context.Context, db *sql.DB, limit int, today, tomorrow time.Time, rng *rand.Rand) ([]ShortItem, error) {
+	if limit <= 0 {
+		return []ShortItem{}, nil
+	}
+
+	picks, err := featuredShorts(ctx, db, limit, today, tomorrow)
+	if err != nil {
+		return nil, err
+	}
 
 	if len(picks) < limit {
 		missing := limit - len(picks)
 		cutoff := today.Add(-featureCooldown)
 
 		const qEligible = `
-SELECT b.id, b.slug, b.title, a.name, b.short::text AS short, b.short_last_featured_at
+SELECT b.id, b.slug, b.title, a.name, b.short::text AS short, ` + shared.CoverPlaceholderCol + ` AS cover_placeholder, b.short_last_featured_at
 FROM books b
 JOIN authors a ON a.id = b.author_id
 WHERE b.short_enabled
@@ -60,7 +82,7 @@ LIMIT $2;`
 		for erows.Next() {
 			var r shortPick
 			var ignore sql.NullTime
-			if err := erows.Scan(&r.ID, &r.Slug, &r.Title, &r.Author, &r.Short, &ignore); err != nil {
+			if err := erows.Scan(&r.ID, &r.Slug, &r.Title, &r.Author, &r.Short, &r.Cover, &ignore); err != nil {
 				erows.Close()
 				return nil, err
 			}
@@ -77,7 +99,7 @@ LIMIT $2;`
 
 		if len(picks) < limit {
 			const qFallback = `
-SELECT b.id, b.slug, b.title, a.name, b.short::text AS short
+SELECT b.id, b.slug, b.title, a.name, b.short::text AS short, ` + shared.CoverPlaceholderCol + ` AS cover_placeholder
 FROM books b
 JOIN authors a ON a.id = b.author_id
 WHERE b.short_enabled
@@ -92,7 +114,7 @@ LIMIT $1;`
 			var fb []shortPick
 			for frows.Next() {
 				var r shortPick
-				if err := frows.Scan(&r.ID, &r.Slug, &r.Title, &r.Author, &r.Short); err != nil {
+				if err := frows.Scan(&r.ID, &r.Slug, &r.Title, &r.Author, &r.Short, &r.Cover); err != nil {
 					frows.Close()
 					return nil, err
 				}
@@ -131,6 +153,10 @@ WHERE id = $2
 		}
 	}
 
+	return shortItems(picks), nil
+}
+
+func shortItems(picks []shortPick) []ShortItem {
 	out := make([]ShortItem, 0, len(picks))
 	for _, p := range picks {
 		out = append(out, ShortItem{
@@ -141,8 +167,9 @@ WHERE id = $2
 				Title:  p.Title,
 				Author: p.Author,
 				URL:    "/books/" + p.Slug,
+				Cover:  p.Cover,
 			},
 		})
 	}
-	return out, nil
+	return out
 }
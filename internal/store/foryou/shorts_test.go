@@ -0,0 +1,40 @@
+package foryou
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestTodayShortsOnlyReads(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	today, tomorrow := ProductDay(time.Now())
+	// Nothing featured yet today: the feed shows nothing rather than picking
+	mock.ExpectQuery(`short_last_featured_at >= \$1`).
+		WithArgs(today, tomorrow, 30).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "slug", "title", "name", "short", "cover_placeholder"}))
+	got, day, err := TodayShorts(t.Context(), db, 30)
+	if err != nil || len(got) != 0 || !day.Equal(today) {
+		t.Fatalf("TodayShorts = %v, %s, %v", got, day, err)
+	}
+
+	mock.ExpectQuery(`short_last_featured_at >= \$1`).
+		WithArgs(today, tomorrow, 30).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "slug", "title", "name", "short", "cover_placeholder"}).
+			AddRow("b-1", "dune", "Dune", "Frank Herbert", `"Fear is the mind-killer."`, nil))
+	got, _, err = TodayShorts(t.Context(), db, 30)
+	if err != nil || len(got) != 1 || got[0].Book.URL != "/books/dune" {
+		t.Fatalf("TodayShorts = %+v, %v", got, err)
+	}
+
+	// Any UPDATE would have failed as unexpected
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
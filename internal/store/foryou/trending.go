@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+
+	"github.com/5w1tchy/books-api/internal/store/shared"
 )
 
 func BuildTrending(ctx context.Context, db *sql.DB, limit int, f Fields) ([]BookLite, error) {
@@ -13,7 +15,8 @@ func BuildTrending(ctx context.Context, db *sql.DB, limit int, f Fields) ([]Book
 	const q = `
 SELECT b.id, b.slug, b.title, a.name,
        COALESCE(jsonb_agg(DISTINCT c.slug) FILTER (WHERE c.slug IS NOT NULL), '[]'::jsonb) AS slugs,
-       COALESCE(b.summary, '') AS summary
+       COALESCE(b.summary, '') AS summary,
+       ` + shared.CoverPlaceholderCol + ` AS cover_placeholder
 FROM books b
 JOIN authors a               ON a.id = b.author_id
 LEFT JOIN book_categories bc ON bc.book_id = b.id
@@ -36,7 +39,7 @@ LIMIT $1;`
 		var b BookLite
 		var slugsJSON []byte
 		var summary string
-		if err := rows.Scan(&b.ID, &b.Slug, &b.Title, &b.Author, &slugsJSON, &summary); err != nil {
+		if err := rows.Scan(&b.ID, &b.Slug, &b.Title, &b.Author, &slugsJSON, &summary, &b.Cover); err != nil {
 			return nil, err
 		}
 		if !f.Lite {
@@ -1,5 +1,11 @@
 package foryou
 
+import (
+	"time"
+
+	"github.com/5w1tchy/books-api/internal/store/shared"
+)
+
 type Limits struct {
 	Shorts, Recs, Trending, New, MostViewed int
 }
@@ -17,6 +23,11 @@ type BookLite struct {
 	CategorySlugs []string `json:"category_slugs,omitempty"`
 	Summary       string   `json:"summary,omitempty"`
 	URL           string   `json:"url"`
+
+	Cover shared.CoverPlaceholder `json:"cover_placeholder,omitzero"`
+
+	CreatedAt time.Time `json:"-"` // feeds only (set by BuildNewest)
+	UpdatedAt time.Time `json:"-"` // feeds only (set by BuildNewest)
 }
 
 type ShortItem struct {
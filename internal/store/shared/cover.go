@@ -0,0 +1,36 @@
+package shared
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// CoverPlaceholder lets clients paint a cover before the image has loaded.
+type CoverPlaceholder struct {
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+	Color    string `json:"color"`
+	BlurHash string `json:"blurhash"`
+}
+
+// CoverPlaceholderCol selects the placeholder of the book aliased b as a
+// single JSON value, NULL while the cover hasn't been processed. Scan it into
+// a *CoverPlaceholder.
+const CoverPlaceholderCol = `CASE WHEN b.cover_blurhash IS NULL THEN NULL ELSE jsonb_build_object(
+  'width', b.cover_width, 'height', b.cover_height,
+  'color', b.cover_color, 'blurhash', b.cover_blurhash) END`
+
+// Scan implements sql.Scanner for CoverPlaceholderCol; NULL leaves p zero.
+func (p *CoverPlaceholder) Scan(src any) error {
+	*p = CoverPlaceholder{}
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, p)
+	case string:
+		return json.Unmarshal([]byte(v), p)
+	default:
+		return fmt.Errorf("cover placeholder: unsupported type %T", src)
+	}
+}
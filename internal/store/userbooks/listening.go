@@ -0,0 +1,69 @@
+package userbooks
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"time"
+)
+
+type ListeningProgress struct {
+	ID              string    `json:"id"`
+	UserID          string    `json:"user_id"`
+	BookID          string    `json:"book_id"`
+	PositionSeconds float64   `json:"position_seconds"`
+	PlaybackSpeed   float64   `json:"playback_speed"`
+	Device          string    `json:"device,omitempty"`
+	LastListenedAt  time.Time `json:"last_listened_at"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// UpdateListeningProgress creates or updates the user's audio position (defensive clamp + rounding)
+func UpdateListeningProgress(ctx context.Context, db *sql.DB, userID, bookID string, positionSeconds, playbackSpeed float64, device string) error {
+	if positionSeconds < 0 {
+		positionSeconds = 0
+	}
+	// round to match NUMERIC(10,3) / NUMERIC(3,2)
+	positionSeconds = math.Round(positionSeconds*1000) / 1000
+	if playbackSpeed <= 0 {
+		playbackSpeed = 1
+	}
+	playbackSpeed = math.Round(playbackSpeed*100) / 100
+	if len(device) > 120 {
+		device = device[:120]
+	}
+
+	_, err := db.ExecContext(ctx, `
+        INSERT INTO public.user_listening_progress (user_id, book_id, position_seconds, playback_speed, device, last_listened_at, updated_at)
+        VALUES ($1, $2, $3, $4, NULLIF($5, ''), NOW(), NOW())
+        ON CONFLICT (user_id, book_id)
+        DO UPDATE SET
+            position_seconds = EXCLUDED.position_seconds,
+            playback_speed = EXCLUDED.playback_speed,
+            device = COALESCE(EXCLUDED.device, public.user_listening_progress.device),
+            last_listened_at = NOW(),
+            updated_at = NOW()
+    `, userID, bookID, positionSeconds, playbackSpeed, device)
+	return err
+}
+
+// GetListeningProgress gets user's audio position for a specific book
+func GetListeningProgress(ctx context.Context, db *sql.DB, userID, bookID string) (*ListeningProgress, error) {
+	var p ListeningProgress
+	var device sql.NullString
+	err := db.QueryRowContext(ctx, `
+        SELECT id::text, user_id::text, book_id::text, position_seconds, playback_speed,
+               device, last_listened_at, created_at, updated_at
+        FROM public.user_listening_progress
+        WHERE user_id = $1 AND book_id = $2
+    `, userID, bookID).Scan(
+		&p.ID, &p.UserID, &p.BookID, &p.PositionSeconds, &p.PlaybackSpeed,
+		&device, &p.LastListenedAt, &p.CreatedAt, &p.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	p.Device = device.String
+	return &p, nil
+}
@@ -44,8 +44,11 @@ type ContinueReadingItem struct {
 	Authors         []string  `json:"authors"`
 	Slug            string    `json:"slug"`
 	URL             string    `json:"url"`
+	Mode            string    `json:"mode"` // "text" or "audio", whichever was used last
 	PageNumber      int       `json:"page_number"`
 	ProgressPercent float64   `json:"progress_percent"`
+	PositionSeconds *float64  `json:"position_seconds,omitempty"` // audio only
+	PlaybackSpeed   *float64  `json:"playback_speed,omitempty"`   // audio only
 	LastReadAt      time.Time `json:"last_read_at"`
 }
 
@@ -93,31 +96,60 @@ func GetReadingProgress(ctx context.Context, db *sql.DB, userID, bookID string)
 	return &progress, nil
 }
 
-// GetContinueReading gets user's recently read books for "Continue Reading" section
+// GetContinueReading gets user's recently read or listened books for "Continue Reading".
+// Per book, the most recent of text and audio progress wins; the book is skipped
+// when that latest progress is finished, even if the other mode isn't.
 func GetContinueReading(ctx context.Context, db *sql.DB, userID string, limit int) ([]ContinueReadingItem, error) {
 	if limit <= 0 || limit > 50 {
 		limit = 10
 	}
 
 	rows, err := db.QueryContext(ctx, `
+        WITH progress AS (
+            SELECT p.book_id, 'text' AS mode, p.page_number, p.progress_percent::float8 AS progress_percent,
+                   NULL::float8 AS position_seconds, NULL::float8 AS playback_speed, p.last_read_at AS last_at,
+                   p.progress_percent >= 100.00 AS finished
+            FROM public.user_reading_progress p
+            WHERE p.user_id = $1
+            UNION ALL
+            SELECT l.book_id, 'audio', 0,
+                   CASE WHEN COALESCE(b.audio_duration_ms, 0) > 0
+                        THEN LEAST(100, ROUND((l.position_seconds * 100000 / b.audio_duration_ms)::numeric, 2))::float8
+                        ELSE 0 END,
+                   l.position_seconds::float8, l.playback_speed::float8, l.last_listened_at,
+                   COALESCE(l.position_seconds * 1000 >= b.audio_duration_ms, false)
+            FROM public.user_listening_progress l
+            JOIN public.books b ON b.id = l.book_id
+            WHERE l.user_id = $1
+        ), latest AS (
+            -- Pick the latest row per book first, then drop finished ones, so
+            -- finishing in one mode hides an older position in the other
+            SELECT DISTINCT ON (book_id) *
+            FROM progress
+            ORDER BY book_id, last_at DESC
+        )
         SELECT 
-            p.book_id::text,
+            x.book_id::text,
             b.title,
             b.slug,
-            p.page_number,
-            p.progress_percent,
-            p.last_read_at,
+            x.mode,
+            x.page_number,
+            x.progress_percent,
+            x.position_seconds,
+            x.playback_speed,
+            x.last_at,
             COALESCE(
                 jsonb_agg(DISTINCT a.name ORDER BY a.name) FILTER (WHERE a.name IS NOT NULL),
                 '[]'::jsonb
             ) AS authors
-        FROM public.user_reading_progress p
-        JOIN public.books b ON b.id = p.book_id
+        FROM latest x
+        JOIN public.books b ON b.id = x.book_id
         LEFT JOIN public.book_authors ba ON ba.book_id = b.id
         LEFT JOIN public.authors a ON a.id = ba.author_id
-        WHERE p.user_id = $1 AND p.progress_percent < 100.00
-        GROUP BY p.book_id, b.title, b.slug, p.page_number, p.progress_percent, p.last_read_at
-        ORDER BY p.last_read_at DESC
+        WHERE NOT x.finished
+        GROUP BY x.book_id, b.title, b.slug, x.mode, x.page_number, x.progress_percent,
+                 x.position_seconds, x.playback_speed, x.last_at
+        ORDER BY x.last_at DESC
         LIMIT $2
     `, userID, limit)
 	if err != nil {
@@ -129,15 +161,22 @@ func GetContinueReading(ctx context.Context, db *sql.DB, userID string, limit in
 	for rows.Next() {
 		var item ContinueReadingItem
 		var authorsJSON []byte
+		var position, speed sql.NullFloat64
 
-		if err := rows.Scan(&item.BookID, &item.Title, &item.Slug, &item.PageNumber,
-			&item.ProgressPercent, &item.LastReadAt, &authorsJSON); err != nil {
+		if err := rows.Scan(&item.BookID, &item.Title, &item.Slug, &item.Mode, &item.PageNumber,
+			&item.ProgressPercent, &position, &speed, &item.LastReadAt, &authorsJSON); err != nil {
 			return nil, err
 		}
 
 		if len(authorsJSON) > 0 {
 			_ = json.Unmarshal(authorsJSON, &item.Authors)
 		}
+		if position.Valid {
+			item.PositionSeconds = &position.Float64
+		}
+		if speed.Valid {
+			item.PlaybackSpeed = &speed.Float64
+		}
 		item.URL = "/books/" + item.Slug
 
 		items = append(items, item)
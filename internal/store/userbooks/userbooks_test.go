@@ -0,0 +1,50 @@
+package userbooks
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestGetContinueReadingFiltersAfterPickingLatest(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// Finished rows must survive into DISTINCT ON and only be dropped once
+	// the latest row per book is known; filtering them earlier lets an older
+	// position in the other mode resurface.
+	shape := regexp.MustCompile(`(?s)WHERE p\.user_id = \$1\s+UNION ALL.*` +
+		`WHERE l\.user_id = \$1\s+\), latest AS.*DISTINCT ON \(book_id\).*WHERE NOT x\.finished`)
+	at := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(shape.String()).
+		WithArgs("u-1", 10).
+		WillReturnRows(sqlmock.NewRows([]string{"book_id", "title", "slug", "mode", "page_number",
+			"progress_percent", "position_seconds", "playback_speed", "last_at", "authors"}).
+			AddRow("b-1", "Dune", "dune", "audio", 0, 42.5, 1530.0, 1.25, at, []byte(`["Frank Herbert"]`)).
+			AddRow("b-2", "Emma", "emma", "text", 12, 30.0, nil, nil, at.Add(-time.Hour), []byte(`[]`)))
+
+	items, err := GetContinueReading(t.Context(), db, "u-1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("got %d items", len(items))
+	}
+	audio, text := items[0], items[1]
+	if audio.Mode != "audio" || audio.PositionSeconds == nil || *audio.PositionSeconds != 1530 ||
+		audio.PlaybackSpeed == nil || *audio.PlaybackSpeed != 1.25 || audio.URL != "/books/dune" ||
+		len(audio.Authors) != 1 || audio.Authors[0] != "Frank Herbert" {
+		t.Errorf("audio item = %+v", audio)
+	}
+	if text.Mode != "text" || text.PositionSeconds != nil || text.PlaybackSpeed != nil || text.PageNumber != 12 {
+		t.Errorf("text item = %+v", text)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
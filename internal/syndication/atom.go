@@ -0,0 +1,93 @@
+package syndication
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+// Atom (RFC 4287) document types shared by the OPDS catalog and the public feeds.
+// Namespaces are declared on the root so entries can use opds:/dc: prefixes.
+
+const (
+	NSAtom       = "http://www.w3.org/2005/Atom"
+	NSOPDS       = "http://opds-spec.org/2010/catalog"
+	NSDC         = "http://purl.org/dc/terms/"
+	NSOpenSearch = "http://a9.com/-/spec/opensearch/1.1/"
+)
+
+type Feed struct {
+	XMLName      xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+	XMLNSOPDS    string   `xml:"xmlns:opds,attr,omitempty"`
+	XMLNSDC      string   `xml:"xmlns:dc,attr,omitempty"`
+	XMLNSOS      string   `xml:"xmlns:opensearch,attr,omitempty"`
+	ID           string   `xml:"id"`
+	Title        string   `xml:"title"`
+	Subtitle     string   `xml:"subtitle,omitempty"`
+	Updated      string   `xml:"updated"`
+	Author       *Person  `xml:"author,omitempty"`
+	Icon         string   `xml:"icon,omitempty"`
+	Links        []Link   `xml:"link"`
+	TotalResults int      `xml:"opensearch:totalResults,omitempty"`
+	ItemsPerPage int      `xml:"opensearch:itemsPerPage,omitempty"`
+	StartIndex   int      `xml:"opensearch:startIndex,omitempty"`
+	Entries      []Entry  `xml:"entry"`
+}
+
+type Entry struct {
+	Title      string     `xml:"title"`
+	ID         string     `xml:"id"`
+	Updated    string     `xml:"updated"`
+	Published  string     `xml:"published,omitempty"`
+	Authors    []Person   `xml:"author"`
+	Issued     string     `xml:"dc:issued,omitempty"`
+	Language   string     `xml:"dc:language,omitempty"`
+	Categories []Category `xml:"category"`
+	Summary    *Text      `xml:"summary,omitempty"`
+	Content    *Text      `xml:"content,omitempty"`
+	Links      []Link     `xml:"link"`
+}
+
+type Person struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri,omitempty"`
+}
+
+type Link struct {
+	Rel   string `xml:"rel,attr,omitempty"`
+	Href  string `xml:"href,attr"`
+	Type  string `xml:"type,attr,omitempty"`
+	Title string `xml:"title,attr,omitempty"`
+}
+
+type Category struct {
+	Term   string `xml:"term,attr"`
+	Label  string `xml:"label,attr,omitempty"`
+	Scheme string `xml:"scheme,attr,omitempty"`
+}
+
+type Text struct {
+	Type  string `xml:"type,attr,omitempty"` // "text" | "html"
+	Value string `xml:",chardata"`
+}
+
+// TimeString formats t as an RFC 3339 timestamp in UTC (Atom date construct).
+func TimeString(t time.Time) string {
+	if t.IsZero() {
+		t = time.Unix(0, 0)
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// WriteXML writes the XML prolog followed by v.
+func WriteXML(w io.Writer, v any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	return enc.Close()
+}
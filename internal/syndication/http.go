@@ -0,0 +1,128 @@
+package syndication
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// BaseURL returns the absolute origin used in feed/sitemap links.
+// PUBLIC_BASE_URL wins (and is required in production); otherwise it is
+// derived from the request. X-Forwarded-Proto/-Host are only honoured with
+// TRUST_PROXY_HEADERS set: the links end up in publicly cached responses,
+// and caches don't key on those headers.
+func BaseURL(r *http.Request) string {
+	if v := strings.TrimRight(os.Getenv("PUBLIC_BASE_URL"), "/"); v != "" {
+		return v
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	host := r.Host
+	if trust, _ := strconv.ParseBool(os.Getenv("TRUST_PROXY_HEADERS")); trust {
+		if p := r.Header.Get("X-Forwarded-Proto"); p != "" {
+			scheme = strings.TrimSpace(strings.Split(p, ",")[0])
+		}
+		if h := r.Header.Get("X-Forwarded-Host"); h != "" {
+			host = strings.TrimSpace(strings.Split(h, ",")[0])
+		}
+	}
+	return scheme + "://" + host
+}
+
+// SiteURL returns the origin of the web app (APP_BASE_URL), where people
+// read book, author and category pages. The API doesn't serve those pages:
+// its /books/{key} needs a token and it has no author or category pages.
+// Empty when unset.
+func SiteURL() string {
+	return strings.TrimRight(os.Getenv("APP_BASE_URL"), "/")
+}
+
+// BookPageURL is where a shared link to a book should land: its page on the
+// web app, or the API's public share card when no web app is configured.
+func BookPageURL(r *http.Request, slug string) string {
+	if site := SiteURL(); site != "" {
+		return site + "/books/" + url.PathEscape(slug)
+	}
+	return BaseURL(r) + "/books/" + url.PathEscape(slug) + "/share"
+}
+
+// AuthorPageURL is the author's page on the web app, empty without one.
+func AuthorPageURL(slug string) string {
+	if site := SiteURL(); site != "" {
+		return site + "/authors/" + url.PathEscape(slug)
+	}
+	return ""
+}
+
+// WriteCached writes a rendered body (XML or JSON) with a strong ETag and Last-Modified, answering
+// conditional requests (If-None-Match / If-Modified-Since) with 304.
+func WriteCached(w http.ResponseWriter, r *http.Request, contentType string, body []byte, modified time.Time, maxAge int) {
+	sum := sha1.Sum(body)
+	etag := `"` + hex.EncodeToString(sum[:10]) + `"`
+
+	h := w.Header()
+	h.Set("ETag", etag)
+	h.Set("Cache-Control", "public, max-age="+strconv.Itoa(maxAge))
+	if !modified.IsZero() {
+		h.Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		if etagMatch(inm, etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	} else if ims := r.Header.Get("If-Modified-Since"); ims != "" && !modified.IsZero() {
+		if t, err := http.ParseTime(ims); err == nil && !modified.Truncate(time.Second).After(t) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	h.Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(body)
+	}
+}
+
+// Render encodes v as an XML document into memory.
+func Render(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := WriteXML(&buf, v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func etagMatch(header, etag string) bool {
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "*" || strings.TrimPrefix(part, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// Excerpt collapses whitespace and cuts s to at most n runes on a word boundary.
+func Excerpt(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	cut := string([]rune(s)[:n])
+	if i := strings.LastIndexByte(cut, ' '); i > len(cut)/2 {
+		cut = cut[:i]
+	}
+	return cut + "…"
+}
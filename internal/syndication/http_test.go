@@ -0,0 +1,28 @@
+package syndication
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBaseURLIgnoresForwardedHeadersByDefault(t *testing.T) {
+	t.Setenv("PUBLIC_BASE_URL", "")
+	r := httptest.NewRequest("GET", "/feeds/new/atom", nil)
+	r.Host = "api.example.com"
+	r.Header.Set("X-Forwarded-Proto", "https")
+	r.Header.Set("X-Forwarded-Host", "www.example.com")
+
+	if got := BaseURL(r); got != "http://api.example.com" {
+		t.Fatalf("BaseURL = %q, want the request host", got)
+	}
+
+	t.Setenv("TRUST_PROXY_HEADERS", "true")
+	if got := BaseURL(r); got != "https://www.example.com" {
+		t.Fatalf("BaseURL behind a trusted proxy = %q", got)
+	}
+
+	t.Setenv("PUBLIC_BASE_URL", "https://books.example.com/")
+	if got := BaseURL(r); got != "https://books.example.com" {
+		t.Fatalf("BaseURL with PUBLIC_BASE_URL = %q", got)
+	}
+}
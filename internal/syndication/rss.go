@@ -0,0 +1,57 @@
+package syndication
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// RSS 2.0 document types. Atom is preferred; RSS is kept for older newsletter tools.
+
+type RSS struct {
+	XMLName   xml.Name   `xml:"rss"`
+	Version   string     `xml:"version,attr"`
+	XMLNSAtom string     `xml:"xmlns:atom,attr"`
+	XMLNSDC   string     `xml:"xmlns:dc,attr"`
+	Channel   RSSChannel `xml:"channel"`
+}
+
+type RSSChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Language      string    `xml:"language,omitempty"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	TTL           int       `xml:"ttl,omitempty"`
+	AtomLink      *AtomLink `xml:"atom:link,omitempty"`
+	Items         []RSSItem `xml:"item"`
+}
+
+// AtomLink is the rel="self" link RSS validators expect.
+type AtomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type RSSItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Description string   `xml:"description,omitempty"`
+	Creator     string   `xml:"dc:creator,omitempty"` // RSS <author> must be an email
+	Categories  []string `xml:"category"`
+	GUID        RSSGUID  `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+}
+
+type RSSGUID struct {
+	IsPermaLink string `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// RFC822 formats t the way RSS 2.0 expects (RFC 1123 with numeric zone).
+func RFC822(t time.Time) string {
+	if t.IsZero() {
+		t = time.Unix(0, 0)
+	}
+	return t.UTC().Format(time.RFC1123Z)
+}
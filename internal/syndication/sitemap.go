@@ -0,0 +1,32 @@
+package syndication
+
+import "encoding/xml"
+
+// Sitemaps protocol 0.9 (https://www.sitemaps.org/protocol.html).
+
+const (
+	NSSitemap = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+	// SitemapMaxURLs is the protocol limit of <url> entries per sitemap file.
+	SitemapMaxURLs = 50000
+)
+
+type SitemapIndex struct {
+	XMLName  xml.Name     `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 sitemapindex"`
+	Sitemaps []SitemapRef `xml:"sitemap"`
+}
+
+type SitemapRef struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type URLSet struct {
+	XMLName xml.Name     `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	URLs    []SitemapURL `xml:"url"`
+}
+
+type SitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
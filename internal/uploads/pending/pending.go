@@ -0,0 +1,134 @@
+// Package pending tracks presigned uploads between "here is your URL" and
+// "the client says it finished". A pending upload that is never completed
+// expires and its object (if any arrived) is deleted by the janitor.
+package pending
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/5w1tchy/books-api/internal/storage/blob"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	DefaultTTL = time.Hour // presigned URLs last 15 minutes; leave room for slow PUTs
+
+	keyPrefix   = "upload:pending:"
+	expiringSet = "upload:pending:expiring"
+)
+
+// ErrNotFound means there is no (unexpired, unclaimed) pending upload for the key.
+var ErrNotFound = errors.New("pending: upload not found")
+
+// Upload is what the client declared when asking for a presigned URL.
+type Upload struct {
+	ObjectKey   string    `json:"object_key"`
+	BookID      string    `json:"book_id"`
+	Kind        string    `json:"kind"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size,omitempty"`
+	SHA256      string    `json:"sha256,omitempty"` // base64
+	CreatedBy   string    `json:"created_by,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+type Store struct {
+	rdb   *redis.Client
+	blobs blob.BlobStore
+}
+
+func New(rdb *redis.Client, blobs blob.BlobStore) *Store { return &Store{rdb: rdb, blobs: blobs} }
+
+// Put records (or re-records) a pending upload.
+func (s *Store) Put(ctx context.Context, u Upload) error {
+	raw, err := json.Marshal(u)
+	if err != nil {
+		return err
+	}
+	pipe := s.rdb.TxPipeline()
+	pipe.Set(ctx, keyPrefix+u.ObjectKey, raw, time.Until(u.ExpiresAt)+24*time.Hour)
+	pipe.ZAdd(ctx, expiringSet, redis.Z{Score: float64(u.ExpiresAt.Unix()), Member: u.ObjectKey})
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// Claim atomically takes a pending upload so only one completion (or the
+// janitor) can act on it. Put it back to release the claim.
+func (s *Store) Claim(ctx context.Context, objectKey string) (*Upload, error) {
+	pipe := s.rdb.TxPipeline()
+	get := pipe.GetDel(ctx, keyPrefix+objectKey)
+	rem := pipe.ZRem(ctx, expiringSet, objectKey)
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+	raw, err := get.Bytes()
+	if err == redis.Nil || rem.Val() == 0 {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var u Upload
+	if err := json.Unmarshal(raw, &u); err != nil {
+		return nil, err
+	}
+	if !time.Now().Before(u.ExpiresAt) {
+		// Too late: put it back for the janitor to clean up.
+		_ = s.Put(ctx, u)
+		return nil, ErrNotFound
+	}
+	return &u, nil
+}
+
+// Sweep deletes objects of expired pending uploads and returns how many it handled.
+func (s *Store) Sweep(ctx context.Context) (int, error) {
+	keys, err := s.rdb.ZRangeByScore(ctx, expiringSet, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(time.Now().Unix(), 10),
+	}).Result()
+	if err != nil || len(keys) == 0 {
+		return 0, err
+	}
+	n := 0
+	for _, key := range keys {
+		// Whoever removes the set member owns the upload; a concurrent Claim wins or loses here.
+		if removed, err := s.rdb.ZRem(ctx, expiringSet, key).Result(); err != nil || removed == 0 {
+			continue
+		}
+		s.rdb.Del(ctx, keyPrefix+key)
+		if err := s.blobs.Delete(ctx, key); err != nil {
+			log.Printf("[pending] delete %s: %v", key, err)
+			continue
+		}
+		n++
+	}
+	return n, nil
+}
+
+// StartJanitor sweeps every interval until stop is called.
+func (s *Store) StartJanitor(interval time.Duration) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				if n, err := s.Sweep(ctx); err != nil {
+					log.Printf("[pending] sweep: %v", err)
+				} else if n > 0 {
+					log.Printf("[pending] removed %d abandoned uploads", n)
+				}
+			}
+		}
+	}()
+	return cancel
+}
@@ -0,0 +1,439 @@
+package tus
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	httpx "github.com/5w1tchy/books-api/internal/api/httpx"
+	"github.com/5w1tchy/books-api/internal/api/middlewares"
+	"github.com/5w1tchy/books-api/internal/storage/blob"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	DefaultTTL  = 24 * time.Hour
+	patchWindow = 10 * time.Minute // read/write deadline for one PATCH
+	extensions  = "creation,expiration,termination"
+)
+
+// Target is where a new upload will end up, as decided by Kind.Prepare.
+type Target struct {
+	BookID      string
+	ObjectKey   string
+	ContentType string
+}
+
+// Kind describes one upload destination (book audio, book cover, ...).
+type Kind struct {
+	Name    string
+	MaxSize int64
+	// Prepare validates the creation request and picks the target object.
+	Prepare func(r *http.Request, length int64, meta map[string]string) (Target, error)
+	// Finish verifies the assembled object and attaches it. When it rejects
+	// the object (an *Error, see Reject) the object is deleted and the upload
+	// discarded; any other error keeps both so the client can retry.
+	Finish func(ctx context.Context, blobs blob.BlobStore, u *Upload) error
+}
+
+// Error carries an HTTP status out of Prepare/Finish hooks.
+type Error struct {
+	Status int
+	Msg    string
+}
+
+func (e *Error) Error() string { return e.Msg }
+
+// Reject builds an *Error for hooks.
+func Reject(status int, format string, args ...any) error {
+	return &Error{Status: status, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Objects is the storage an upload is assembled in.
+type Objects interface {
+	blob.BlobStore
+	blob.Multipart
+}
+
+// Handler serves creation (per kind) and HEAD/PATCH/DELETE on upload URLs.
+type Handler struct {
+	sto   store
+	objs  Objects
+	base  string
+	ttl   time.Duration
+	kinds map[string]Kind
+}
+
+// New returns a Handler whose upload URLs live under base (e.g. "/admin/uploads/tus").
+// The store must support multipart uploads.
+func New(rdb *redis.Client, blobs blob.BlobStore, base string, kinds ...Kind) *Handler {
+	objs, ok := blobs.(Objects)
+	if !ok {
+		panic("tus: blob store does not support multipart uploads")
+	}
+	h := &Handler{sto: store{rdb: rdb}, objs: objs, base: strings.TrimSuffix(base, "/"), ttl: DefaultTTL, kinds: map[string]Kind{}}
+	for _, k := range kinds {
+		h.kinds[k.Name] = k
+	}
+	return h
+}
+
+// Create handles POST for the named kind (creation extension).
+func (h *Handler) Create(kind string) http.Handler {
+	k, ok := h.kinds[kind]
+	if !ok {
+		panic("tus: unknown upload kind " + kind)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !checkVersion(w, r) {
+			return
+		}
+		ctx := r.Context()
+		w.Header().Set("Tus-Extension", extensions)
+		w.Header().Set("Tus-Max-Size", strconv.FormatInt(k.MaxSize, 10))
+
+		if r.Header.Get("Upload-Defer-Length") != "" {
+			httpx.ErrorJSON(w, http.StatusBadRequest, "Upload-Defer-Length is not supported")
+			return
+		}
+		length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+		if err != nil || length <= 0 {
+			httpx.ErrorJSON(w, http.StatusBadRequest, "Upload-Length must be a positive integer")
+			return
+		}
+		if length > k.MaxSize {
+			httpx.ErrorJSON(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("upload too large (max %d bytes)", k.MaxSize))
+			return
+		}
+		meta, err := ParseMetadata(r.Header.Get("Upload-Metadata"))
+		if err != nil {
+			httpx.ErrorJSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		target, err := k.Prepare(r, length, meta)
+		if err != nil {
+			writeHookErr(w, err)
+			return
+		}
+
+		mpID, err := h.objs.CreateMultipart(ctx, target.ObjectKey, target.ContentType)
+		if err != nil {
+			log.Printf("[tus] create %s: %v", target.ObjectKey, err)
+			httpx.ErrorJSON(w, http.StatusBadGateway, "failed to start upload")
+			return
+		}
+
+		now := time.Now().UTC()
+		u := &Upload{
+			ID:          newID(),
+			Kind:        k.Name,
+			BookID:      target.BookID,
+			ObjectKey:   target.ObjectKey,
+			ContentType: target.ContentType,
+			Length:      length,
+			Metadata:    meta,
+			MultipartID: mpID,
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(h.ttl),
+		}
+		u.CreatedBy, _ = middlewares.UserIDFrom(ctx)
+		if err := h.sto.save(ctx, u); err != nil {
+			_ = h.objs.AbortMultipart(ctx, u.ObjectKey, mpID)
+			httpx.ErrorJSON(w, http.StatusInternalServerError, "failed to save upload state")
+			return
+		}
+
+		w.Header().Set("Location", h.base+"/"+u.ID)
+		w.Header().Set("Upload-Offset", "0")
+		w.Header().Set("Upload-Expires", u.ExpiresAt.Format(http.TimeFormat))
+		w.WriteHeader(http.StatusCreated)
+	})
+}
+
+// Head reports the current offset so a client can resume.
+func (h *Handler) Head(w http.ResponseWriter, r *http.Request) {
+	if !checkVersion(w, r) {
+		return
+	}
+	u, ok := h.loadLive(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Upload-Offset", strconv.FormatInt(u.Offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(u.Length, 10))
+	w.Header().Set("Upload-Expires", u.ExpiresAt.Format(http.TimeFormat))
+	if len(u.Metadata) > 0 {
+		w.Header().Set("Upload-Metadata", FormatMetadata(u.Metadata))
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// Patch appends a chunk at Upload-Offset. Bytes that arrive before the
+// connection drops are kept, so the client can resume from the new offset.
+func (h *Handler) Patch(w http.ResponseWriter, r *http.Request) {
+	if !checkVersion(w, r) {
+		return
+	}
+	ctx := r.Context()
+	if r.Header.Get("Content-Type") != "application/offset+octet-stream" {
+		httpx.ErrorJSON(w, http.StatusUnsupportedMediaType, "Content-Type must be application/offset+octet-stream")
+		return
+	}
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		httpx.ErrorJSON(w, http.StatusBadRequest, "Upload-Offset must be a non-negative integer")
+		return
+	}
+
+	id := r.PathValue("id")
+	locked, unlock, err := h.sto.lock(ctx, id, patchWindow)
+	if err != nil {
+		httpx.ErrorJSON(w, http.StatusInternalServerError, "failed to lock upload")
+		return
+	}
+	if !locked {
+		httpx.ErrorJSON(w, http.StatusLocked, "upload is busy")
+		return
+	}
+	defer unlock()
+
+	u, ok := h.loadLive(w, r)
+	if !ok {
+		return
+	}
+	if offset != u.Offset {
+		w.Header().Set("Upload-Offset", strconv.FormatInt(u.Offset, 10))
+		httpx.ErrorJSON(w, http.StatusConflict, "Upload-Offset does not match")
+		return
+	}
+
+	// Chunks may be large and slow; lift the server-wide timeouts for this request.
+	rc := http.NewResponseController(w)
+	_ = rc.SetReadDeadline(time.Now().Add(patchWindow))
+	_ = rc.SetWriteDeadline(time.Now().Add(patchWindow))
+
+	// Keep persisting what arrived even if the client disconnects mid-chunk.
+	ctx = context.WithoutCancel(ctx)
+	readErr, err := h.appendChunk(ctx, u, r.Body)
+	w.Header().Set("Upload-Offset", strconv.FormatInt(u.Offset, 10))
+	w.Header().Set("Upload-Expires", u.ExpiresAt.Format(http.TimeFormat))
+	if err != nil {
+		log.Printf("[tus] patch %s: %v", u.ID, err)
+		httpx.ErrorJSON(w, http.StatusBadGateway, "failed to store chunk")
+		return
+	}
+	var tooBig *http.MaxBytesError
+	if errors.As(readErr, &tooBig) {
+		httpx.ErrorJSON(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("chunk too large; resume from offset %d with smaller chunks", u.Offset))
+		return
+	}
+	if readErr != nil {
+		httpx.ErrorJSON(w, http.StatusBadRequest, "upload interrupted")
+		return
+	}
+
+	if u.Offset == u.Length {
+		if err := h.finish(ctx, u); err != nil {
+			writeHookErr(w, err)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// appendChunk streams body into parts and persists progress. It returns the
+// body read error (if any) separately from storage/state errors.
+func (h *Handler) appendChunk(ctx context.Context, u *Upload, body io.Reader) (readErr, err error) {
+	var buf []byte
+	oldTail := u.TailKey
+	if u.TailSize > 0 {
+		if buf, _, err = blob.ReadAll(ctx, h.objs, u.TailKey); err != nil {
+			return nil, err
+		}
+		if int64(len(buf)) != u.TailSize {
+			return nil, fmt.Errorf("tail %s has %d bytes, want %d", u.TailKey, len(buf), u.TailSize)
+		}
+	}
+	durable := u.Offset - u.TailSize // bytes already in completed parts
+
+	flush := func(data []byte) error {
+		p, err := h.objs.UploadPart(ctx, u.ObjectKey, u.MultipartID, u.nextPart(), data)
+		if err != nil {
+			return err
+		}
+		u.Parts = append(u.Parts, p)
+		durable += p.Size
+		u.Offset, u.TailKey, u.TailSize = durable, "", 0
+		return h.sto.save(ctx, u)
+	}
+
+	chunk := make([]byte, 256<<10)
+	src := io.LimitReader(body, u.Length-u.Offset)
+	for {
+		n, rerr := src.Read(chunk)
+		buf = append(buf, chunk[:n]...)
+		for len(buf) >= PartSize {
+			if err := flush(buf[:PartSize]); err != nil {
+				return nil, err
+			}
+			buf = append(buf[:0], buf[PartSize:]...)
+		}
+		if rerr != nil {
+			if rerr != io.EOF {
+				readErr = rerr
+			}
+			break
+		}
+	}
+
+	switch {
+	case durable+int64(len(buf)) == u.Length && (len(buf) > 0 || len(u.Parts) == 0):
+		// Last part may be smaller than MinPartSize.
+		err = flush(buf)
+	case len(buf) > 0:
+		end := durable + int64(len(buf))
+		key := u.tailKeyAt(end)
+		if err = h.objs.Put(ctx, key, bytes.NewReader(buf), int64(len(buf)), "application/octet-stream"); err == nil {
+			u.Offset, u.TailKey, u.TailSize = end, key, int64(len(buf))
+			err = h.sto.save(ctx, u)
+		}
+	}
+	if err == nil && oldTail != "" && oldTail != u.TailKey {
+		_ = h.objs.Delete(ctx, oldTail)
+	}
+	return readErr, err
+}
+
+// finish assembles the object, runs the kind's verification and drops the
+// state. Only a rejection throws the object away: after a storage or
+// database hiccup the state stays, and a PATCH of nothing at the final
+// offset runs the verification again.
+func (h *Handler) finish(ctx context.Context, u *Upload) error {
+	if !u.Assembled {
+		if err := h.objs.CompleteMultipart(ctx, u.ObjectKey, u.MultipartID, u.Parts); err != nil {
+			log.Printf("[tus] complete %s: %v", u.ID, err)
+			return Reject(http.StatusBadGateway, "failed to assemble upload")
+		}
+		u.Assembled = true
+		if err := h.sto.save(ctx, u); err != nil {
+			log.Printf("[tus] save state %s: %v", u.ID, err)
+		}
+	}
+	k := h.kinds[u.Kind]
+	ferr := k.Finish(ctx, h.objs, u)
+	var rejected *Error
+	if ferr != nil && !errors.As(ferr, &rejected) {
+		return ferr
+	}
+	if ferr != nil {
+		_ = h.objs.Delete(ctx, u.ObjectKey)
+	}
+	if err := h.sto.remove(ctx, u.ID); err != nil {
+		log.Printf("[tus] remove state %s: %v", u.ID, err)
+	}
+	return ferr
+}
+
+// Delete terminates an upload (termination extension).
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	if !checkVersion(w, r) {
+		return
+	}
+	ctx := r.Context()
+	id := r.PathValue("id")
+	locked, unlock, err := h.sto.lock(ctx, id, time.Minute)
+	if err != nil {
+		httpx.ErrorJSON(w, http.StatusInternalServerError, "failed to lock upload")
+		return
+	}
+	if !locked {
+		httpx.ErrorJSON(w, http.StatusLocked, "upload is busy")
+		return
+	}
+	defer unlock()
+
+	u, err := h.sto.load(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		httpx.ErrorJSON(w, http.StatusNotFound, "upload not found")
+		return
+	} else if err != nil {
+		httpx.ErrorJSON(w, http.StatusInternalServerError, "failed to load upload")
+		return
+	}
+	if err := discard(ctx, h.sto, h.objs, u); err != nil {
+		log.Printf("[tus] terminate %s: %v", id, err)
+		httpx.ErrorJSON(w, http.StatusBadGateway, "failed to discard upload")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// discard aborts the multipart upload, removes the tail and forgets the state.
+func discard(ctx context.Context, sto store, objs Objects, u *Upload) error {
+	// An assembled object may already be attached if only the state cleanup
+	// failed; leave it to storage GC, which knows what books reference.
+	if u.Assembled {
+		return sto.remove(ctx, u.ID)
+	}
+	if err := objs.AbortMultipart(ctx, u.ObjectKey, u.MultipartID); err != nil {
+		return err
+	}
+	if u.TailKey != "" {
+		_ = objs.Delete(ctx, u.TailKey)
+	}
+	return sto.remove(ctx, u.ID)
+}
+
+func (h *Handler) loadLive(w http.ResponseWriter, r *http.Request) (*Upload, bool) {
+	u, err := h.sto.load(r.Context(), r.PathValue("id"))
+	if errors.Is(err, ErrNotFound) {
+		httpx.ErrorJSON(w, http.StatusNotFound, "upload not found")
+		return nil, false
+	} else if err != nil {
+		httpx.ErrorJSON(w, http.StatusInternalServerError, "failed to load upload")
+		return nil, false
+	}
+	if u.Expired(time.Now()) {
+		httpx.ErrorJSON(w, http.StatusGone, "upload expired")
+		return nil, false
+	}
+	return u, true
+}
+
+// checkVersion enforces Tus-Resumable and sets it on every response.
+func checkVersion(w http.ResponseWriter, r *http.Request) bool {
+	w.Header().Set("Tus-Resumable", Version)
+	if r.Header.Get("Tus-Resumable") != Version {
+		w.Header().Set("Tus-Version", Version)
+		httpx.ErrorJSON(w, http.StatusPreconditionFailed, "unsupported tus version")
+		return false
+	}
+	return true
+}
+
+func writeHookErr(w http.ResponseWriter, err error) {
+	var he *Error
+	if errors.As(err, &he) {
+		httpx.ErrorJSON(w, he.Status, he.Msg)
+		return
+	}
+	log.Printf("[tus] %v", err)
+	httpx.ErrorJSON(w, http.StatusInternalServerError, "upload failed")
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
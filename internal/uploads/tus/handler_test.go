@@ -0,0 +1,121 @@
+package tus
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/5w1tchy/books-api/internal/storage/blob"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// finishHarness runs one upload of body through a handler whose Finish
+// returns the queued errors in turn.
+type finishHarness struct {
+	t     *testing.T
+	mux   *http.ServeMux
+	blobs *blob.Memory
+	errs  []error
+	calls int
+}
+
+func newFinishHarness(t *testing.T, errs ...error) *finishHarness {
+	mr := miniredis.RunT(t)
+	f := &finishHarness{t: t, blobs: blob.NewMemory(), errs: errs}
+	kind := Kind{
+		Name:    "doc",
+		MaxSize: 1 << 20,
+		Prepare: func(*http.Request, int64, map[string]string) (Target, error) {
+			return Target{BookID: "b-1", ObjectKey: "books/doc.txt", ContentType: "text/plain"}, nil
+		},
+		Finish: func(context.Context, blob.BlobStore, *Upload) error {
+			f.calls++
+			if len(f.errs) == 0 {
+				return nil
+			}
+			err := f.errs[0]
+			f.errs = f.errs[1:]
+			return err
+		},
+	}
+	h := New(redis.NewClient(&redis.Options{Addr: mr.Addr()}), f.blobs, "/uploads", kind)
+	f.mux = http.NewServeMux()
+	f.mux.Handle("POST /uploads", h.Create("doc"))
+	f.mux.HandleFunc("HEAD /uploads/{id}", h.Head)
+	f.mux.HandleFunc("PATCH /uploads/{id}", h.Patch)
+	return f
+}
+
+func (f *finishHarness) do(method, target, offset, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Tus-Resumable", Version)
+	switch method {
+	case http.MethodPost:
+		req.Header.Set("Upload-Length", offset)
+	case http.MethodPatch:
+		req.Header.Set("Content-Type", "application/offset+octet-stream")
+		req.Header.Set("Upload-Offset", offset)
+	}
+	rec := httptest.NewRecorder()
+	f.mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func (f *finishHarness) create() string {
+	rec := f.do(http.MethodPost, "/uploads", "5", "")
+	if rec.Code != http.StatusCreated {
+		f.t.Fatalf("create: %d %s", rec.Code, rec.Body)
+	}
+	return rec.Header().Get("Location")
+}
+
+func (f *finishHarness) objectExists() bool {
+	_, err := f.blobs.Head(context.Background(), "books/doc.txt")
+	return err == nil
+}
+
+func TestFinishFailureKeepsUploadForRetry(t *testing.T) {
+	f := newFinishHarness(t, errors.New("database unavailable"))
+	loc := f.create() // 5 bytes
+
+	if rec := f.do(http.MethodPatch, loc, "0", "hello"); rec.Code != http.StatusInternalServerError {
+		t.Fatalf("first PATCH: %d %s", rec.Code, rec.Body)
+	}
+	if !f.objectExists() {
+		t.Fatal("assembled object deleted after a transient failure")
+	}
+	rec := f.do(http.MethodHead, loc, "", "")
+	if rec.Code != http.StatusOK || rec.Header().Get("Upload-Offset") != "5" {
+		t.Fatalf("HEAD after failure: %d offset %q", rec.Code, rec.Header().Get("Upload-Offset"))
+	}
+
+	// Empty PATCH at the final offset runs Finish again without re-assembling
+	if rec := f.do(http.MethodPatch, loc, "5", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("retry PATCH: %d %s", rec.Code, rec.Body)
+	}
+	if f.calls != 2 || !f.objectExists() {
+		t.Fatalf("calls = %d, object kept = %v", f.calls, f.objectExists())
+	}
+	if rec := f.do(http.MethodHead, loc, "", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("HEAD after finish: %d", rec.Code)
+	}
+}
+
+func TestFinishRejectionDiscardsUpload(t *testing.T) {
+	f := newFinishHarness(t, Reject(http.StatusUnprocessableEntity, "not a document"))
+	loc := f.create() // 5 bytes
+
+	if rec := f.do(http.MethodPatch, loc, "0", "hello"); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("PATCH: %d %s", rec.Code, rec.Body)
+	}
+	if f.objectExists() {
+		t.Fatal("rejected object kept")
+	}
+	if rec := f.do(http.MethodHead, loc, "", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("HEAD after rejection: %d", rec.Code)
+	}
+}
@@ -0,0 +1,72 @@
+package tus
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/5w1tchy/books-api/internal/storage/blob"
+	"github.com/redis/go-redis/v9"
+)
+
+// Sweep discards uploads past their expiry and returns how many it removed.
+func Sweep(ctx context.Context, rdb *redis.Client, blobs blob.BlobStore) (int, error) {
+	objs, ok := blobs.(Objects)
+	if !ok {
+		return 0, errors.New("tus: blob store does not support multipart uploads")
+	}
+	sto := store{rdb: rdb}
+	ids, err := rdb.ZRangeByScore(ctx, expiringSet, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(time.Now().Unix(), 10),
+	}).Result()
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for _, id := range ids {
+		locked, unlock, err := sto.lock(ctx, id, time.Minute)
+		if err != nil || !locked {
+			continue // busy; next sweep
+		}
+		u, err := sto.load(ctx, id)
+		switch {
+		case errors.Is(err, ErrNotFound):
+			sto.rdb.ZRem(ctx, expiringSet, id)
+		case err != nil:
+			log.Printf("[tus] sweep load %s: %v", id, err)
+		case u.Expired(time.Now()):
+			if err := discard(ctx, sto, objs, u); err != nil {
+				log.Printf("[tus] sweep discard %s: %v", id, err)
+			} else {
+				removed++
+			}
+		}
+		unlock()
+	}
+	return removed, nil
+}
+
+// StartJanitor sweeps expired uploads every interval until stop is called.
+func StartJanitor(rdb *redis.Client, blobs blob.BlobStore, interval time.Duration) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				if n, err := Sweep(ctx, rdb, blobs); err != nil {
+					log.Printf("[tus] sweep: %v", err)
+				} else if n > 0 {
+					log.Printf("[tus] discarded %d expired uploads", n)
+				}
+			}
+		}
+	}()
+	return cancel
+}
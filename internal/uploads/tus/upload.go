@@ -0,0 +1,159 @@
+// Package tus implements the server side of the tus 1.0 resumable upload
+// protocol (core, creation, expiration and termination extensions).
+//
+// Upload state lives in Redis; bytes are staged in an S3 multipart upload on
+// the final object key. Chunks smaller than a part are parked in a small
+// "tail" object until enough data arrives. The object is only attached to a
+// book by the Kind's Finish hook once it is complete and verified.
+package tus
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/5w1tchy/books-api/internal/storage/blob"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	Version  = "1.0.0"
+	PartSize = 8 << 20 // must be >= blob.MinPartSize
+
+	keyPrefix   = "tus:upload:"
+	lockPrefix  = "tus:lock:"
+	expiringSet = "tus:expiring"
+)
+
+// ErrNotFound is returned for unknown (or already cleaned up) uploads.
+var ErrNotFound = errors.New("tus: upload not found")
+
+// Upload is the persisted state of one resumable upload.
+type Upload struct {
+	ID          string            `json:"id"`
+	Kind        string            `json:"kind"`
+	BookID      string            `json:"book_id"`
+	ObjectKey   string            `json:"object_key"`
+	ContentType string            `json:"content_type"`
+	Length      int64             `json:"length"`
+	Offset      int64             `json:"offset"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	MultipartID string            `json:"multipart_id"`
+	Parts       []blob.Part       `json:"parts,omitempty"`
+	TailKey     string            `json:"tail_key,omitempty"`
+	TailSize    int64             `json:"tail_size,omitempty"`
+	Assembled   bool              `json:"assembled,omitempty"` // multipart completed; only Finish is left
+	CreatedBy   string            `json:"created_by,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+	ExpiresAt   time.Time         `json:"expires_at"`
+}
+
+// Expired reports whether the upload may no longer be resumed.
+func (u *Upload) Expired(now time.Time) bool { return !now.Before(u.ExpiresAt) }
+
+func (u *Upload) nextPart() int32 { return int32(len(u.Parts) + 1) }
+
+// tailKeyAt names the tail object by the offset it ends at, so a crash
+// between writing the tail and saving state never pairs state with the
+// wrong bytes.
+func (u *Upload) tailKeyAt(offset int64) string {
+	return fmt.Sprintf("uploads/tus/%s/tail-%d", u.ID, offset)
+}
+
+// store persists upload state in Redis.
+type store struct {
+	rdb *redis.Client
+}
+
+func (s store) load(ctx context.Context, id string) (*Upload, error) {
+	raw, err := s.rdb.Get(ctx, keyPrefix+id).Bytes()
+	if err == redis.Nil {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var u Upload
+	if err := json.Unmarshal(raw, &u); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// save keeps the state a day past expiry so the janitor can still find the
+// multipart upload id and abort it.
+func (s store) save(ctx context.Context, u *Upload) error {
+	raw, err := json.Marshal(u)
+	if err != nil {
+		return err
+	}
+	ttl := time.Until(u.ExpiresAt) + 24*time.Hour
+	pipe := s.rdb.TxPipeline()
+	pipe.Set(ctx, keyPrefix+u.ID, raw, ttl)
+	pipe.ZAdd(ctx, expiringSet, redis.Z{Score: float64(u.ExpiresAt.Unix()), Member: u.ID})
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (s store) remove(ctx context.Context, id string) error {
+	pipe := s.rdb.TxPipeline()
+	pipe.Del(ctx, keyPrefix+id)
+	pipe.ZRem(ctx, expiringSet, id)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// lock guards an upload against concurrent PATCH/DELETE requests.
+func (s store) lock(ctx context.Context, id string, ttl time.Duration) (bool, func(), error) {
+	ok, err := s.rdb.SetNX(ctx, lockPrefix+id, "1", ttl).Result()
+	if err != nil || !ok {
+		return false, func() {}, err
+	}
+	return true, func() { s.rdb.Del(context.Background(), lockPrefix+id) }, nil
+}
+
+// ParseMetadata decodes an Upload-Metadata header: comma-separated
+// "key base64value" pairs, where the value may be omitted.
+func ParseMetadata(h string) (map[string]string, error) {
+	out := map[string]string{}
+	if strings.TrimSpace(h) == "" {
+		return out, nil
+	}
+	for _, pair := range strings.Split(h, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if k == "" || strings.ContainsAny(k, " ,") {
+			return nil, fmt.Errorf("tus: bad metadata key %q", k)
+		}
+		if _, dup := out[k]; dup {
+			return nil, fmt.Errorf("tus: duplicate metadata key %q", k)
+		}
+		dec, err := base64.StdEncoding.DecodeString(strings.TrimSpace(v))
+		if err != nil {
+			return nil, fmt.Errorf("tus: metadata %q is not base64", k)
+		}
+		out[k] = string(dec)
+	}
+	return out, nil
+}
+
+// FormatMetadata is the inverse of ParseMetadata, with keys sorted.
+func FormatMetadata(m map[string]string) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k
+		if m[k] != "" {
+			parts[i] += " " + base64.StdEncoding.EncodeToString([]byte(m[k]))
+		}
+	}
+	return strings.Join(parts, ",")
+}
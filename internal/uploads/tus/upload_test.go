@@ -0,0 +1,46 @@
+package tus
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseMetadata(t *testing.T) {
+	// tus-js-client style: filename "book.mp3", filetype "audio/mpeg", empty flag
+	m, err := ParseMetadata("filename Ym9vay5tcDM=,filetype YXVkaW8vbXBlZw==, is_draft")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m["filename"] != "book.mp3" || m["filetype"] != "audio/mpeg" {
+		t.Errorf("unexpected metadata: %v", m)
+	}
+	if v, ok := m["is_draft"]; !ok || v != "" {
+		t.Errorf("valueless key not kept: %v", m)
+	}
+
+	if got := FormatMetadata(m); got != "filename Ym9vay5tcDM=,filetype YXVkaW8vbXBlZw==,is_draft" {
+		t.Errorf("FormatMetadata = %q", got)
+	}
+}
+
+func TestParseMetadataRejectsBadInput(t *testing.T) {
+	for _, h := range []string{
+		"filename not*base64",
+		"a YQ==,a Yg==",
+		",filetype YQ==",
+	} {
+		if _, err := ParseMetadata(h); err == nil {
+			t.Errorf("expected error for %q", h)
+		}
+	}
+}
+
+func TestTailKeyIsOffsetScoped(t *testing.T) {
+	u := &Upload{ID: "abc", ExpiresAt: time.Now().Add(time.Hour)}
+	if u.tailKeyAt(10) == u.tailKeyAt(20) {
+		t.Error("tail keys must differ per offset")
+	}
+	if u.Expired(time.Now()) || !u.Expired(u.ExpiresAt) {
+		t.Error("Expired boundary is wrong")
+	}
+}
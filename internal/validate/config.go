@@ -2,32 +2,78 @@ package validate
 
 import (
 	"context"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
 	"time"
 
+	jwtutil "github.com/5w1tchy/books-api/internal/security/jwt"
 	"github.com/redis/go-redis/v9"
 )
 
 // Env validates required env configuration for auth & security.
 // Fail-fast on bad config.
 func Env() error {
-	// JWT secret must be present & reasonably long
-	secret := os.Getenv("AUTH_JWT_SECRET")
-	if len(secret) < 32 {
-		return errors.New("AUTH_JWT_SECRET must be at least 32 characters")
+	// JWT signing keys must load: a long enough HS256 secret, or a private
+	// key (required in production for EdDSA/RS256)
+	if err := jwtutil.Init(); err != nil {
+		return err
 	}
 
 	// Access/Refresh TTLs must parse and be > 0 (defaults are fine if unset)
 	if _, err := envDuration("AUTH_ACCESS_TTL", "15m"); err != nil {
 		return fmt.Errorf("AUTH_ACCESS_TTL: %w", err)
 	}
-	if _, err := envDuration("AUTH_REFRESH_TTL", "720h"); err != nil {
+	refreshTTL, err := envDuration("AUTH_REFRESH_TTL", "720h")
+	if err != nil {
 		return fmt.Errorf("AUTH_REFRESH_TTL: %w", err)
 	}
+	maxAge, err := envDuration("AUTH_SESSION_MAX_AGE", "2160h")
+	if err != nil {
+		return fmt.Errorf("AUTH_SESSION_MAX_AGE: %w", err)
+	}
+	if maxAge < refreshTTL {
+		return fmt.Errorf("AUTH_SESSION_MAX_AGE (%s) must be at least AUTH_REFRESH_TTL (%s)", maxAge, refreshTTL)
+	}
+
+	// Per-account login lockout (defaults are fine if unset)
+	for key, def := range map[string]string{
+		"AUTH_LOCKOUT_BASE_DELAY": "1s",
+		"AUTH_LOCKOUT_MAX_DELAY":  "1m",
+		"AUTH_LOCKOUT_DURATION":   "15m",
+		"AUTH_LOCKOUT_WINDOW":     "1h",
+	} {
+		if _, err := envDuration(key, def); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	if err := envMinUint("AUTH_LOCKOUT_THRESHOLD", 1); err != nil {
+		return fmt.Errorf("AUTH_LOCKOUT_THRESHOLD: %w", err)
+	}
+
+	// 2FA sealing key is optional, but must be usable when set
+	if k := os.Getenv("AUTH_TOTP_KEY"); k != "" {
+		if b, err := hex.DecodeString(k); err != nil || len(b) != 32 {
+			return errors.New("AUTH_TOTP_KEY must be 64 hex characters (32 bytes)")
+		}
+	} else if strings.EqualFold(os.Getenv("APP_ENV"), "production") && os.Getenv("AUTH_JWT_SECRET") == "" {
+		// the fallback key is derived from AUTH_JWT_SECRET
+		return errors.New("AUTH_TOTP_KEY is required in production when AUTH_JWT_SECRET is not set")
+	}
+
+	// Feeds, sitemap and share pages put absolute links in publicly cached
+	// responses; in production their origin must not come from the request
+	if v := os.Getenv("PUBLIC_BASE_URL"); v != "" {
+		if u, err := url.Parse(v); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("PUBLIC_BASE_URL must be an absolute http(s) URL")
+		}
+	} else if strings.EqualFold(os.Getenv("APP_ENV"), "production") {
+		return errors.New("PUBLIC_BASE_URL is required in production")
+	}
 
 	// Argon2 lower bounds (only enforce if explicitly set)
 	if err := envMinUint("ARGON2_MEMORY", 65536); err != nil { // >= 64MiB
@@ -65,6 +111,19 @@ func HardeningWarnings(appEnv string) []string {
 		if u := os.Getenv("UPSTASH_REDIS_URL"); u != "" && strings.HasPrefix(u, "redis://") {
 			warns = append(warns, "UPSTASH_REDIS_URL uses redis:// (no TLS). Prefer rediss:// for TLS")
 		}
+		if os.Getenv("AUTH_JWT_PRIVATE_KEY") == "" && os.Getenv("AUTH_JWT_PRIVATE_KEY_FILE") == "" {
+			warns = append(warns, "JWTs are signed with HS256; other services can only verify them by sharing AUTH_JWT_SECRET. Prefer AUTH_JWT_PRIVATE_KEY (EdDSA/RS256)")
+		}
+		if os.Getenv("AUTH_TOTP_KEY") == "" {
+			warns = append(warns, "AUTH_TOTP_KEY not set; 2FA secrets are sealed with a key derived from AUTH_JWT_SECRET, so rotating it disables 2FA")
+		}
+		// Outgoing mail
+		if strings.EqualFold(os.Getenv("MAIL_BACKEND"), "file") {
+			warns = append(warns, "MAIL_BACKEND=file; verification and reset emails are written to disk and will not reach users")
+		}
+		if os.Getenv("APP_BASE_URL") == "" {
+			warns = append(warns, "APP_BASE_URL not set; emailed links will be relative, /sitemap.xml is disabled and shared links point at the API's share cards")
+		}
 		if os.Getenv("UPSTASH_REDIS_URL") == "" {
 			// Using REDIS_ADDR path
 			if os.Getenv("REDIS_PASSWORD") == "" || os.Getenv("REDIS_USER") == "" {
@@ -0,0 +1,7 @@
+-- Audio metadata extracted from the uploaded file header (internal/media/audio).
+ALTER TABLE books
+  ADD COLUMN IF NOT EXISTS audio_format      TEXT,
+  ADD COLUMN IF NOT EXISTS audio_duration_ms BIGINT,
+  ADD COLUMN IF NOT EXISTS audio_bitrate     INTEGER,
+  ADD COLUMN IF NOT EXISTS audio_sample_rate INTEGER,
+  ADD COLUMN IF NOT EXISTS audio_channels    SMALLINT;
@@ -0,0 +1,26 @@
+-- Admin-managed chapter markers for a book's audio.
+CREATE TABLE IF NOT EXISTS public.book_audio_chapters (
+  id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  book_id       UUID NOT NULL REFERENCES public.books(id) ON DELETE CASCADE,
+  title         TEXT NOT NULL,
+  start_seconds NUMERIC(10,3) NOT NULL CHECK (start_seconds >= 0),
+  created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+  UNIQUE (book_id, start_seconds)
+);
+
+-- Per-user audio position, the listening counterpart of user_reading_progress.
+CREATE TABLE IF NOT EXISTS public.user_listening_progress (
+  id               UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id          UUID NOT NULL REFERENCES public.users(id) ON DELETE CASCADE,
+  book_id          UUID NOT NULL REFERENCES public.books(id) ON DELETE CASCADE,
+  position_seconds NUMERIC(10,3) NOT NULL DEFAULT 0 CHECK (position_seconds >= 0),
+  playback_speed   NUMERIC(3,2)  NOT NULL DEFAULT 1.00,
+  device           TEXT,
+  last_listened_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  created_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+  UNIQUE (user_id, book_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_listening_progress_recent
+  ON public.user_listening_progress (user_id, last_listened_at DESC);
@@ -0,0 +1,4 @@
+-- Resized cover renditions (internal/media/cover), smallest first:
+-- [{"w":256,"h":384,"key":"books/covers/<name>/256.jpg","type":"image/jpeg"}, ...]
+ALTER TABLE books
+  ADD COLUMN IF NOT EXISTS cover_variants JSONB NOT NULL DEFAULT '[]'::jsonb;
@@ -0,0 +1,8 @@
+-- Cover placeholder (internal/media/cover): pixel size, dominant colour
+-- ("#rrggbb") and BlurHash of the original. NULL until the cover has been
+-- processed; existing rows are filled in by `api cover-backfill`.
+ALTER TABLE books
+  ADD COLUMN IF NOT EXISTS cover_width    INT,
+  ADD COLUMN IF NOT EXISTS cover_height   INT,
+  ADD COLUMN IF NOT EXISTS cover_color    TEXT,
+  ADD COLUMN IF NOT EXISTS cover_blurhash TEXT;
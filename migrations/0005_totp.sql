@@ -0,0 +1,17 @@
+-- TOTP two-factor auth (internal/security/totp). totp_secret is sealed with
+-- AUTH_TOTP_KEY; 2FA is on once totp_enabled_at is set. totp_last_step is the
+-- last accepted time step, so a code can't be used twice.
+ALTER TABLE public.users
+  ADD COLUMN IF NOT EXISTS totp_secret     TEXT,
+  ADD COLUMN IF NOT EXISTS totp_enabled_at TIMESTAMPTZ,
+  ADD COLUMN IF NOT EXISTS totp_last_step  BIGINT NOT NULL DEFAULT 0;
+
+-- One-time recovery codes, stored as SHA-256 hashes.
+CREATE TABLE IF NOT EXISTS public.user_recovery_codes (
+  id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id    UUID NOT NULL REFERENCES public.users(id) ON DELETE CASCADE,
+  code_hash  TEXT NOT NULL,
+  used_at    TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  UNIQUE (user_id, code_hash)
+);
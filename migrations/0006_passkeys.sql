@@ -0,0 +1,18 @@
+-- WebAuthn passkeys (internal/security/webauthn). public_key is the COSE key
+-- from registration; sign_count is the authenticator's counter, which must
+-- increase on each sign-in unless the authenticator doesn't keep one (0).
+CREATE TABLE IF NOT EXISTS public.user_passkeys (
+  id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id       UUID NOT NULL REFERENCES public.users(id) ON DELETE CASCADE,
+  credential_id BYTEA NOT NULL UNIQUE,
+  public_key    BYTEA NOT NULL,
+  algorithm     INT NOT NULL,
+  sign_count    BIGINT NOT NULL DEFAULT 0,
+  aaguid        BYTEA,
+  transports    TEXT[] NOT NULL DEFAULT '{}',
+  name          TEXT NOT NULL,
+  created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+  last_used_at  TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_passkeys_user ON public.user_passkeys (user_id, created_at);
@@ -0,0 +1,14 @@
+-- Security-relevant things that happened to an account (refresh token
+-- reuse, lockouts, ...), for investigation and support. Kept separate from
+-- admin_audit, which records what admins did.
+CREATE TABLE IF NOT EXISTS public.security_events (
+  id         BIGSERIAL PRIMARY KEY,
+  user_id    UUID REFERENCES public.users(id) ON DELETE CASCADE,
+  kind       TEXT NOT NULL,
+  ip         TEXT,
+  user_agent TEXT,
+  meta       JSONB NOT NULL DEFAULT '{}',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_security_events_user ON public.security_events (user_id, created_at DESC);
@@ -0,0 +1,18 @@
+-- API keys for partner and machine clients (internal/security/apikey).
+-- key_hash is the SHA-256 of the key, which is shown once on creation;
+-- prefix is its first characters, to tell keys apart in listings.
+-- Requests made with a key act as the admin who created it.
+CREATE TABLE IF NOT EXISTS public.api_keys (
+  id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  name         TEXT NOT NULL,
+  prefix       TEXT NOT NULL,
+  key_hash     TEXT NOT NULL UNIQUE,
+  scopes       TEXT[] NOT NULL,
+  created_by   UUID NOT NULL REFERENCES public.users(id) ON DELETE CASCADE,
+  created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+  expires_at   TIMESTAMPTZ,
+  last_used_at TIMESTAMPTZ,
+  revoked_at   TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_created ON public.api_keys (created_at DESC);
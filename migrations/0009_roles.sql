@@ -0,0 +1,17 @@
+-- Roles map users.role to permissions (internal/security/rbac); "*" grants
+-- all of them. Built-in roles can't be deleted, and admin can't be changed.
+CREATE TABLE IF NOT EXISTS public.roles (
+  name        TEXT PRIMARY KEY,
+  description TEXT NOT NULL DEFAULT '',
+  permissions TEXT[] NOT NULL DEFAULT '{}',
+  builtin     BOOLEAN NOT NULL DEFAULT false,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+INSERT INTO public.roles (name, description, permissions, builtin) VALUES
+  ('user',      'Regular account, no staff access', '{}', true),
+  ('editor',    'Manages books, audio and covers', '{books.read,books.write,stats.read}', true),
+  ('moderator', 'Handles user accounts and reviews the audit log', '{users.read,users.ban,users.manage,audit.read,stats.read}', true),
+  ('admin',     'Full access', '{*}', true)
+ON CONFLICT (name) DO NOTHING;
@@ -0,0 +1,59 @@
+package middlewares_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	mw "github.com/5w1tchy/books-api/internal/api/middlewares"
+	"github.com/5w1tchy/books-api/internal/security/apikey"
+	"github.com/5w1tchy/books-api/internal/security/rbac"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var apiKeyCols = []string{"id", "created_by", "scopes", "permissions", "mfa", "recently_used"}
+
+func TestAPIKeyAuth_RequireMFA(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	serve := func(keys *mw.APIKeyAuth) int {
+		wrapped := keys.Or(apikey.ScopeAdminBooksRead, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		req := httptest.NewRequest("GET", "/admin/books", nil)
+		req.Header.Set(apikey.Header, "bk_test")
+		rec := httptest.NewRecorder()
+		wrapped.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	expectKey := func(ownerMFA bool) {
+		mock.ExpectQuery(`FROM public.api_keys k`).
+			WillReturnRows(sqlmock.NewRows(apiKeyCols).
+				AddRow("k-1", "u-1", apikey.ScopeAdminBooksRead, rbac.BooksRead, ownerMFA, true))
+	}
+
+	// Without the option the issuer's 2FA doesn't matter.
+	expectKey(false)
+	if code := serve(mw.NewAPIKeyAuth(db, nil)); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+
+	// With it, a staff key stops working once its issuer turns 2FA off.
+	strict := mw.NewAPIKeyAuth(db, nil, mw.RequireMFA())
+	expectKey(false)
+	if code := serve(strict); code != http.StatusForbidden {
+		t.Fatalf("Expected 403 for an issuer without 2FA, got %d", code)
+	}
+	expectKey(true)
+	if code := serve(strict); code != http.StatusOK {
+		t.Fatalf("Expected 200 for an issuer with 2FA, got %d", code)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
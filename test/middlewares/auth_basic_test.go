@@ -0,0 +1,264 @@
+package middlewares_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	mw "github.com/5w1tchy/books-api/internal/api/middlewares"
+	"github.com/5w1tchy/books-api/internal/security/password"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+const basicLookup = `SELECT id::text, password_hash, COALESCE(status,'active'), totp_enabled_at IS NOT NULL FROM public.users WHERE email = $1`
+
+var basicCols = []string{"id", "password_hash", "status", "mfa"}
+
+func TestRequireAuthOrBasic_ChallengesWithoutCredentials(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	wrapped := mw.RequireAuthOrBasic(db, nil, "catalog", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler should not be called")
+	}))
+
+	req := httptest.NewRequest("GET", "/opds", nil)
+	rec := httptest.NewRecorder()
+	wrapped.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected 401, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("WWW-Authenticate"); got != `Basic realm="catalog", charset="UTF-8"` {
+		t.Errorf("Unexpected challenge: %q", got)
+	}
+}
+
+func TestRequireAuthOrBasic_BasicCredentials(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	hash, err := password.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var gotUser string
+	wrapped := mw.RequireAuthOrBasic(db, nil, "catalog", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUser, _ = mw.UserIDFrom(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// Wrong password is rejected with a challenge.
+	mock.ExpectQuery(regexp.QuoteMeta(basicLookup)).WithArgs("reader@example.com").
+		WillReturnRows(sqlmock.NewRows(basicCols).AddRow("u-1", hash, "active", false))
+	req := httptest.NewRequest("GET", "/opds", nil)
+	req.SetBasicAuth("reader@example.com", "wrong")
+	rec := httptest.NewRecorder()
+	wrapped.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized || rec.Header().Get("WWW-Authenticate") == "" {
+		t.Fatalf("Expected 401 challenge for wrong password, got %d", rec.Code)
+	}
+
+	// Correct password passes the user ID through.
+	mock.ExpectQuery(regexp.QuoteMeta(basicLookup)).WithArgs("reader@example.com").
+		WillReturnRows(sqlmock.NewRows(basicCols).AddRow("u-1", hash, "active", false))
+	req = httptest.NewRequest("GET", "/opds", nil)
+	req.SetBasicAuth("reader@example.com", "correct horse")
+	rec = httptest.NewRecorder()
+	wrapped.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+	if gotUser != "u-1" {
+		t.Errorf("Expected user u-1 in context, got %q", gotUser)
+	}
+
+	// Banned accounts are refused even with the right password.
+	mock.ExpectQuery(regexp.QuoteMeta(basicLookup)).WithArgs("reader@example.com").
+		WillReturnRows(sqlmock.NewRows(basicCols).AddRow("u-1", hash, "banned", false))
+	rec = httptest.NewRecorder()
+	wrapped.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected 401 for banned user, got %d", rec.Code)
+	}
+
+	// Two-factor accounts can't get in with the password alone.
+	mock.ExpectQuery(regexp.QuoteMeta(basicLookup)).WithArgs("reader@example.com").
+		WillReturnRows(sqlmock.NewRows(basicCols).AddRow("u-1", hash, "active", true))
+	rec = httptest.NewRecorder()
+	wrapped.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected 401 for 2FA user, got %d", rec.Code)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// fakeGuard records what RequireAuthOrBasic reports to the account lockout.
+type fakeGuard struct {
+	locked            bool
+	failed, succeeded []string
+}
+
+func (g *fakeGuard) AllowLogin(w http.ResponseWriter, r *http.Request, email string) bool {
+	if g.locked {
+		http.Error(w, "locked", http.StatusTooManyRequests)
+	}
+	return !g.locked
+}
+
+func (g *fakeGuard) LoginFailed(r *http.Request, email, userID string) {
+	g.failed = append(g.failed, email+"|"+userID)
+}
+
+func (g *fakeGuard) LoginSucceeded(r *http.Request, email string) {
+	g.succeeded = append(g.succeeded, email)
+}
+
+func TestRequireAuthOrBasic_Lockout(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	hash, err := password.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	guard := &fakeGuard{}
+	wrapped := mw.RequireAuthOrBasic(db, nil, "catalog", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}), mw.BasicLockout(guard))
+
+	serve := func(user, pass string) int {
+		req := httptest.NewRequest("GET", "/opds", nil)
+		req.SetBasicAuth(user, pass)
+		rec := httptest.NewRecorder()
+		wrapped.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(basicLookup)).WithArgs("reader@example.com").
+		WillReturnRows(sqlmock.NewRows(basicCols).AddRow("u-1", hash, "active", false))
+	if code := serve("reader@example.com ", "wrong"); code != http.StatusUnauthorized {
+		t.Fatalf("Expected 401, got %d", code)
+	}
+	mock.ExpectQuery(regexp.QuoteMeta(basicLookup)).WithArgs("nobody@example.com").
+		WillReturnRows(sqlmock.NewRows(basicCols))
+	if code := serve("nobody@example.com", "wrong"); code != http.StatusUnauthorized {
+		t.Fatalf("Expected 401, got %d", code)
+	}
+	if strings.Join(guard.failed, ",") != "reader@example.com|u-1,nobody@example.com|" {
+		t.Errorf("Unexpected failures: %v", guard.failed)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(basicLookup)).WithArgs("reader@example.com").
+		WillReturnRows(sqlmock.NewRows(basicCols).AddRow("u-1", hash, "active", false))
+	if code := serve("reader@example.com", "correct horse"); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if len(guard.succeeded) != 1 {
+		t.Errorf("Expected one success, got %v", guard.succeeded)
+	}
+
+	// Cached credentials don't report again: no lockout write per request.
+	mock.ExpectQuery(regexp.QuoteMeta(basicLookup)).WithArgs("reader@example.com").
+		WillReturnRows(sqlmock.NewRows(basicCols).AddRow("u-1", hash, "active", false))
+	if code := serve("reader@example.com", "correct horse"); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if len(guard.succeeded) != 1 {
+		t.Errorf("Cached hit reported a success: %v", guard.succeeded)
+	}
+
+	// Banned and 2FA accounts still have their password checked first, so a
+	// wrong one counts like any other.
+	mock.ExpectQuery(regexp.QuoteMeta(basicLookup)).WithArgs("banned@example.com").
+		WillReturnRows(sqlmock.NewRows(basicCols).AddRow("u-2", hash, "banned", false))
+	if code := serve("banned@example.com", "wrong"); code != http.StatusUnauthorized {
+		t.Fatalf("Expected 401, got %d", code)
+	}
+	if n := len(guard.failed); n != 3 || guard.failed[n-1] != "banned@example.com|u-2" {
+		t.Errorf("Wrong password on a banned account not counted: %v", guard.failed)
+	}
+
+	// A locked account is refused before the password is looked at.
+	guard.locked = true
+	if code := serve("reader@example.com", "correct horse"); code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 while locked, got %d", code)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRequireAuthOrBasic_LoginLimit(t *testing.T) {
+	t.Setenv("LOGIN_MAX_ATTEMPTS", "2")
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	hash, err := password.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wrapped := mw.RequireAuthOrBasic(db, nil, "catalog", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}), mw.BasicLoginLimit(rdb))
+
+	serve := func(pass string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/opds", nil)
+		req.RemoteAddr = "203.0.113.7:4000"
+		req.SetBasicAuth("reader@example.com", pass)
+		rec := httptest.NewRecorder()
+		wrapped.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Successful requests don't spend the budget.
+	for i := 0; i < 3; i++ {
+		mock.ExpectQuery(regexp.QuoteMeta(basicLookup)).
+			WillReturnRows(sqlmock.NewRows(basicCols).AddRow("u-1", hash, "active", false))
+		if rec := serve("correct horse"); rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", rec.Code)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		mock.ExpectQuery(regexp.QuoteMeta(basicLookup)).
+			WillReturnRows(sqlmock.NewRows(basicCols).AddRow("u-1", hash, "active", false))
+		if rec := serve("wrong"); rec.Code != http.StatusUnauthorized {
+			t.Fatalf("Expected 401, got %d", rec.Code)
+		}
+	}
+
+	// Budget spent: refused without touching the database.
+	rec := serve("correct horse")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("Expected 429 with Retry-After, got %d", rec.Code)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,47 @@
+package middlewares_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	mw "github.com/5w1tchy/books-api/internal/api/middlewares"
+)
+
+func TestCompression_GzipsJSON(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true}`))
+	})
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	mw.Compression(handler).ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Errorf("Expected gzip encoding, got %q", got)
+	}
+}
+
+func TestCompression_SkipsPartialAudio(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/mpeg")
+		w.Header().Set("Content-Range", "bytes 0-3/10")
+		w.Header().Set("Content-Length", "4")
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write([]byte("ID3\x04"))
+	})
+
+	req := httptest.NewRequest("GET", "/books/x/audio/stream", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	mw.Compression(handler).ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("Expected no Content-Encoding on 206, got %q", got)
+	}
+	if rec.Body.String() != "ID3\x04" {
+		t.Errorf("Body was altered: %q", rec.Body.String())
+	}
+}